@@ -25,6 +25,9 @@ var bpmConfigsCmd = &cobra.Command{
 
 This command calculates and prints the BPM configurations for all all BOSH jobs of a given
 instance group.
+
+The job properties of the instance group are validated against the specs of their
+release jobs. The findings are printed along with the BPM configs.
 `,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// Store original stdout i
@@ -68,10 +71,18 @@ instance group.
 			return err
 		}
 
-		dg, err := manifest.NewDataGatherer(log, baseDir, namespace, *m, instanceGroupName)
+		dg, err := manifest.NewDataGatherer(log, baseDir, viper.GetString("links-dir"), namespace, viper.GetString("cluster-domain"), *m, instanceGroupName)
+		if err != nil {
+			return err
+		}
+
+		// Validate the properties before gathering data, which adds
+		// the spec defaults and links to them
+		findings, err := dg.PropertyFindings()
 		if err != nil {
 			return err
 		}
+		specs := dg.ValidatedJobSpecs()
 
 		bpmConfigs, err := dg.BPMConfigs()
 		if err != nil {
@@ -83,8 +94,20 @@ instance group.
 			return errors.Wrapf(err, "could not marshal json output")
 		}
 
+		findingsBytes, err := yaml.Marshal(findings)
+		if err != nil {
+			return errors.Wrapf(err, "could not marshal property findings")
+		}
+
+		specsBytes, err := yaml.Marshal(specs)
+		if err != nil {
+			return errors.Wrapf(err, "could not marshal job specs")
+		}
+
 		jsonBytes, err := json.Marshal(map[string]string{
-			"bpm.yaml": string(bpmBytes),
+			"bpm.yaml":        string(bpmBytes),
+			"validation.yaml": string(findingsBytes),
+			"specs.yaml":      string(specsBytes),
 		})
 		if err != nil {
 			return errors.Wrapf(err, "could not marshal json output")
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// compatibilityCmd reports the fields of a manifest, which don't behave like they do on BOSH
+var compatibilityCmd = &cobra.Command{
+	Use:   "compatibility [flags]",
+	Short: "Reports BOSH features of a manifest, which are not fully supported",
+	Long: `Reports BOSH features of a manifest, which are not fully supported:
+
+This applies the ops files to the manifest and lists each field, which is
+ignored, only partially supported or emulated with kube resources. The path of
+each field uses the syntax of ops files.
+
+The report is written to STDOUT. It is empty, if all fields are supported.
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		log = newLogger()
+		defer log.Sync()
+
+		// Viper keys are global, the ops-file flag is shared with deployment-diff
+		viper.BindPFlag("ops-file", cmd.Flags().Lookup("ops-file"))
+
+		boshManifestPath := viper.GetString("bosh-manifest-path")
+		if len(boshManifestPath) == 0 {
+			return fmt.Errorf("manifest cannot be empty")
+		}
+
+		m, err := localManifest(boshManifestPath, viper.GetStringSlice("ops-file"), []string{})
+		if err != nil {
+			return errors.Wrapf(err, "loading manifest")
+		}
+
+		reportBytes, err := yaml.Marshal(m.Compatibility())
+		if err != nil {
+			return errors.Wrapf(err, "could not marshal compatibility report")
+		}
+
+		_, err = cmd.OutOrStdout().Write(reportBytes)
+		return err
+	},
+}
+
+func init() {
+	utilCmd.AddCommand(compatibilityCmd)
+
+	compatibilityCmd.Flags().StringSlice("ops-file", []string{}, "path to an ops file, which is applied to the manifest, can be repeated")
+
+	argToEnv := map[string]string{
+		"ops-file": "OPS_FILE",
+	}
+	AddEnvToUsage(compatibilityCmd, argToEnv)
+}
@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	yaml "gopkg.in/yaml.v2"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
+	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	kubeConfig "code.cloudfoundry.org/cf-operator/pkg/kube/config"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/controllers"
+)
+
+// deploymentDiffCmd previews the kube resources which change, when a new manifest or ops files are deployed
+var deploymentDiffCmd = &cobra.Command{
+	Use:   "deployment-diff [flags] <deployment>",
+	Short: "Previews the changes of deploying a new manifest",
+	Long: `Previews the changes of deploying a new manifest:
+
+This resolves the BOSHDeployment like the operator does, with its ops files,
+variables, runtime configs and cloud configs. Its manifest is replaced by the
+desired manifest, if one is given, and the ops files are applied after its own
+ops files. The result is compared with the with-ops manifest, which the
+operator has deployed.
+
+The kube resources, which would be created, updated or deleted, and the changed
+job properties are written to STDOUT. Nothing is applied.
+`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		log = newLogger()
+		defer log.Sync()
+
+		// Viper keys are global, the ops-file flag is shared with other util commands
+		viper.BindPFlag("ops-file", cmd.Flags().Lookup("ops-file"))
+
+		restConfig, err := kubeConfig.NewGetter(log).Get(viper.GetString("kubeconfig"))
+		if err != nil {
+			return err
+		}
+
+		if err := controllers.AddToScheme(scheme.Scheme); err != nil {
+			return errors.Wrap(err, "adding custom resources to scheme")
+		}
+		c, err := client.New(restConfig, client.Options{Scheme: scheme.Scheme})
+		if err != nil {
+			return errors.Wrap(err, "creating kube client")
+		}
+
+		ctx := context.Background()
+		namespace := viper.GetString("cf-operator-namespace")
+
+		deployment := &bdv1.BOSHDeployment{}
+		err = c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: args[0]}, deployment)
+		if err != nil {
+			return errors.Wrapf(err, "reading BOSHDeployment '%s/%s'", namespace, args[0])
+		}
+
+		current, err := deployedManifest(ctx, c, deployment)
+		if err != nil {
+			return err
+		}
+
+		desired, err := previewManifest(c, deployment, viper.GetString("desired-manifest-path"), viper.GetStringSlice("ops-file"), viper.GetString("registry-mirror"))
+		if err != nil {
+			return err
+		}
+		// Instances keep their AZs, like the operator places them
+		desired.PlaceInstances(current)
+
+		kubeConverter := manifest.NewKubeConverter(namespace)
+		diff, err := kubeConverter.Diff(current, desired, viper.GetString("cluster-domain"))
+		if err != nil {
+			return err
+		}
+
+		diffBytes, err := yaml.Marshal(diff)
+		if err != nil {
+			return errors.Wrapf(err, "could not marshal deployment diff")
+		}
+
+		_, err = cmd.OutOrStdout().Write(diffBytes)
+		return err
+	},
+}
+
+func init() {
+	utilCmd.AddCommand(deploymentDiffCmd)
+
+	deploymentDiffCmd.Flags().StringP("desired-manifest-path", "d", "", "path to the desired bosh manifest file, defaults to the deployment's manifest")
+	deploymentDiffCmd.Flags().StringSlice("ops-file", []string{}, "path to an ops file, which is applied after the deployment's ops files, can be repeated")
+
+	viper.BindPFlag("desired-manifest-path", deploymentDiffCmd.Flags().Lookup("desired-manifest-path"))
+
+	argToEnv := map[string]string{
+		"desired-manifest-path": "DESIRED_MANIFEST_PATH",
+		"ops-file":              "OPS_FILE",
+	}
+	AddEnvToUsage(deploymentDiffCmd, argToEnv)
+}
@@ -69,7 +69,7 @@ inside a bosh manifest file.
 			return err
 		}
 
-		dg, err := manifest.NewDataGatherer(log, baseDir, namespace, *m, instanceGroupName)
+		dg, err := manifest.NewDataGatherer(log, baseDir, viper.GetString("links-dir"), namespace, viper.GetString("cluster-domain"), *m, instanceGroupName)
 		if err != nil {
 			return err
 		}
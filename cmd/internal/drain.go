@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	"code.cloudfoundry.org/cf-operator/pkg/bosh/drain"
+	"code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
+)
+
+// namespaceFile contains the namespace of the pod's service account
+const namespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+// drainCmd runs the drain scripts of a job
+var drainCmd = &cobra.Command{
+	Use:   "drain [flags]",
+	Short: "Runs the drain scripts of a job",
+	Long: `Runs the drain scripts of a job.
+
+This is the preStop hook of the BPM process containers. The drain
+scripts of a job run once per pod, the other containers of the job
+wait for them to finish. Dynamic drain wait times are honoured.
+
+The progress is reported as events of the pod, if its service account
+is allowed to create events.
+
+With --reset, this is the postStart hook of the containers and removes
+the lock of the job, so a restarted container doesn't prevent the next
+termination from draining the job.
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		log = newLogger()
+		defer log.Sync()
+
+		jobName := viper.GetString("job-name")
+		if len(jobName) == 0 {
+			return fmt.Errorf("job-name cannot be empty")
+		}
+
+		if viper.GetBool("reset") {
+			runner := drain.NewRunner(log, drain.NewLogRecorder(), manifest.VolumeJobsDirMountPath, viper.GetString("lock-dir"))
+			return runner.Reset(jobName)
+		}
+
+		runner := drain.NewRunner(log, podRecorder(), manifest.VolumeJobsDirMountPath, viper.GetString("lock-dir"))
+		return runner.Run(jobName)
+	},
+}
+
+// podRecorder returns a recorder for the events of the current pod, when
+// running in a cluster.
+func podRecorder() drain.Recorder {
+	restConfig, err := rest.InClusterConfig()
+	if err != nil {
+		log.Infof("Not reporting drain events, not running in a cluster: %v", err)
+		return drain.NewLogRecorder()
+	}
+
+	client, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		log.Infof("Not reporting drain events, failed to create kube client: %v", err)
+		return drain.NewLogRecorder()
+	}
+
+	namespace, err := ioutil.ReadFile(namespaceFile)
+	if err != nil {
+		log.Infof("Not reporting drain events, failed to read namespace: %v", err)
+		return drain.NewLogRecorder()
+	}
+
+	podName, err := os.Hostname()
+	if err != nil {
+		log.Infof("Not reporting drain events, failed to read pod name: %v", err)
+		return drain.NewLogRecorder()
+	}
+
+	return drain.NewPodRecorder(log, client, strings.TrimSpace(string(namespace)), podName)
+}
+
+func init() {
+	utilCmd.AddCommand(drainCmd)
+
+	drainCmd.Flags().String("job-name", "", "name of the job to drain")
+	drainCmd.Flags().String("lock-dir", filepath.Join(manifest.VolumeSysDirMountPath, "run", "drain"), "directory shared by the containers of the pod, to drain each job once")
+
+	drainCmd.Flags().Bool("reset", false, "remove the lock of the job instead of draining it, when a container starts")
+
+	viper.BindPFlag("job-name", drainCmd.Flags().Lookup("job-name"))
+	viper.BindPFlag("lock-dir", drainCmd.Flags().Lookup("lock-dir"))
+	viper.BindPFlag("reset", drainCmd.Flags().Lookup("reset"))
+
+	argToEnv := map[string]string{
+		"job-name": "JOB_NAME",
+		"lock-dir": "LOCK_DIR",
+		"reset":    "RESET",
+	}
+	AddEnvToUsage(drainCmd, argToEnv)
+}
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"code.cloudfoundry.org/cf-operator/pkg/bosh/errand"
+	kubeConfig "code.cloudfoundry.org/cf-operator/pkg/kube/config"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/controllers"
+)
+
+const (
+	// errandPollInterval is how often the status of an errand run is checked
+	errandPollInterval = 2 * time.Second
+	// errandTimeout is the default time to wait for an errand to finish
+	errandTimeout = time.Hour
+)
+
+// errandCmd represents the errand subcommand
+var errandCmd = &cobra.Command{
+	Use:   "errand",
+	Short: "Manages errands of BOSH deployments",
+	Long:  `Manages errands of BOSH deployments.`,
+}
+
+// errandRunCmd runs an errand of a deployment
+var errandRunCmd = &cobra.Command{
+	Use:   "run [flags] <deployment> <errand>",
+	Short: "Runs an errand and streams its logs",
+	Long: `Runs an errand and streams its logs.
+
+This triggers the ExtendedJob of the errand instance group and waits for the
+run to finish. The logs of the errand's containers are written to STDOUT,
+prefixed with the container name.
+
+The command exits with the exit code of the first failed container, or 0 if
+the errand succeeded. The result of the run is recorded in the
+'status.lastRun' field of the ExtendedJob.
+`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		log = newLogger()
+		defer log.Sync()
+
+		restConfig, err := kubeConfig.NewGetter(log).Get(viper.GetString("kubeconfig"))
+		if err != nil {
+			return err
+		}
+
+		if err := controllers.AddToScheme(scheme.Scheme); err != nil {
+			return errors.Wrap(err, "adding custom resources to scheme")
+		}
+		c, err := client.New(restConfig, client.Options{Scheme: scheme.Scheme})
+		if err != nil {
+			return errors.Wrap(err, "creating kube client")
+		}
+		clientSet, err := kubernetes.NewForConfig(restConfig)
+		if err != nil {
+			return errors.Wrap(err, "creating kube clientset")
+		}
+
+		ctx := context.Background()
+		if timeout := viper.GetDuration("timeout"); timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+
+		runner := errand.NewRunner(log, c, errand.NewLogStreamer(clientSet.CoreV1()), cmd.OutOrStdout(), errandPollInterval)
+		exitCode, err := runner.Run(ctx, viper.GetString("cf-operator-namespace"), args[0], args[1])
+		if err != nil {
+			return err
+		}
+
+		if exitCode != 0 {
+			log.Sync()
+			os.Exit(exitCode)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(errandCmd)
+	errandCmd.AddCommand(errandRunCmd)
+
+	errandRunCmd.Flags().Duration("timeout", errandTimeout, "time to wait for the errand to finish, no timeout if 0")
+
+	viper.BindPFlag("timeout", errandRunCmd.Flags().Lookup("timeout"))
+
+	argToEnv := map[string]string{
+		"timeout": "ERRAND_TIMEOUT",
+	}
+	AddEnvToUsage(errandRunCmd, argToEnv)
+}
@@ -0,0 +1,212 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
+	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/names"
+)
+
+// variableRegexp matches the variables in manifests and ops files, like the resolver
+var variableRegexp = regexp.MustCompile(`\(\((!?[-/\.\w\pL]+)\)\)`)
+
+// previewClient serves local files as config maps, so the resolver reads them
+// like the refs of a BOSHDeployment. Everything else is read from the cluster.
+type previewClient struct {
+	client.Client
+	configMaps map[string]*corev1.ConfigMap
+	secrets    map[string]*corev1.Secret
+}
+
+func newPreviewClient(c client.Client) *previewClient {
+	return &previewClient{
+		Client:     c,
+		configMaps: map[string]*corev1.ConfigMap{},
+		secrets:    map[string]*corev1.Secret{},
+	}
+}
+
+// Get returns the config maps of local files and the secrets of kept variables,
+// other objects are read from the cluster
+func (c *previewClient) Get(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+	switch o := obj.(type) {
+	case *corev1.ConfigMap:
+		if local, ok := c.configMaps[key.Name]; ok {
+			local.DeepCopyInto(o)
+			return nil
+		}
+	case *corev1.Secret:
+		if local, ok := c.secrets[key.Name]; ok {
+			local.DeepCopyInto(o)
+			return nil
+		}
+	}
+
+	return c.Client.Get(ctx, key, obj)
+}
+
+// keepVariables serves a secret for each variable of the local files, whose value is the
+// variable itself. The resolver doesn't interpolate variables, which are neither declared
+// in the manifest nor in the vars files, then.
+func (c *previewClient) keepVariables(deploymentName string) {
+	for _, configMap := range c.configMaps {
+		for _, data := range configMap.Data {
+			for _, match := range variableRegexp.FindAllStringSubmatch(data, -1) {
+				// Remove subfields from the match, e.g. ca.private_key -> ca
+				name := strings.SplitN(match[1], ".", 2)[0]
+				secretName := names.CalculateSecretName(names.DeploymentSecretTypeVariable, deploymentName, name)
+				c.secrets[secretName] = &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: secretName},
+					Data:       map[string][]byte{bdv1.ImplicitVariableKeyName: []byte(fmt.Sprintf("((%s))", name))},
+				}
+			}
+		}
+	}
+}
+
+// offlineClient resolves manifests without a cluster, it has no objects
+type offlineClient struct {
+	client.Client
+}
+
+// Get returns a not found error, as there are no objects
+func (offlineClient) Get(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+	return apierrors.NewNotFound(schema.GroupResource{}, key.Name)
+}
+
+// List leaves the list empty, as there are no objects
+func (offlineClient) List(ctx context.Context, opts *client.ListOptions, list runtime.Object) error {
+	return nil
+}
+
+// addFile serves the content of a local file under the key of a config map and returns a ref to it
+func (c *previewClient) addFile(name string, path string, key string) (bdv1.Manifest, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return bdv1.Manifest{}, err
+	}
+
+	name = "preview." + name
+	c.configMaps[name] = &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Data:       map[string]string{key: string(data)},
+	}
+
+	return bdv1.Manifest{Type: bdv1.ConfigMapType, Ref: name}, nil
+}
+
+// previewManifest resolves the with-ops manifest of a BOSHDeployment like the operator
+// does. The manifest is replaced by the one at manifestPath, if it's given, and the ops
+// files are applied after the deployment's own ops files.
+func previewManifest(c client.Client, deployment *bdv1.BOSHDeployment, manifestPath string, opsPaths []string, registryMirror string) (*manifest.Manifest, error) {
+	local := newPreviewClient(c)
+	deployment = deployment.DeepCopy()
+
+	if manifestPath != "" {
+		ref, err := local.addFile("manifest", manifestPath, bdv1.ManifestSpecName)
+		if err != nil {
+			return nil, errors.Wrapf(err, "reading manifest")
+		}
+		deployment.Spec.Manifest = ref
+	}
+
+	if err := local.addOpsFiles(deployment, opsPaths); err != nil {
+		return nil, err
+	}
+
+	m, err := resolveManifest(local, deployment)
+	if err != nil {
+		return nil, errors.Wrapf(err, "resolving manifest of BOSHDeployment '%s'", deployment.GetName())
+	}
+
+	// Like the operator, name the manifest after the BOSHDeployment and pull from the registry mirror
+	m.Name = deployment.GetName()
+	m.ApplyRegistryMirror(registryMirror)
+
+	return m, nil
+}
+
+// localManifest resolves a manifest from local files without a cluster, like the operator
+// does for a BOSHDeployment. The ops files are applied and the variables of the vars files
+// are interpolated, later files override earlier ones. Other variables are not interpolated,
+// as they are generated or stored in the cluster.
+func localManifest(manifestPath string, opsPaths []string, varsPaths []string) (*manifest.Manifest, error) {
+	local := newPreviewClient(offlineClient{})
+	deployment := &bdv1.BOSHDeployment{}
+
+	ref, err := local.addFile("manifest", manifestPath, bdv1.ManifestSpecName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading manifest")
+	}
+	deployment.Spec.Manifest = ref
+
+	if err := local.addOpsFiles(deployment, opsPaths); err != nil {
+		return nil, err
+	}
+
+	for i, varsPath := range varsPaths {
+		ref, err := local.addFile(fmt.Sprintf("vars-%d", i), varsPath, bdv1.VarsSpecName)
+		if err != nil {
+			return nil, errors.Wrapf(err, "reading vars file")
+		}
+		deployment.Spec.Vars = append(deployment.Spec.Vars, bdv1.Var{Type: ref.Type, Ref: ref.Ref})
+	}
+	local.keepVariables(deployment.GetName())
+
+	return resolveManifest(local, deployment)
+}
+
+// addOpsFiles serves the ops files and appends refs to them to the ops of the deployment
+func (c *previewClient) addOpsFiles(deployment *bdv1.BOSHDeployment, opsPaths []string) error {
+	for i, opsPath := range opsPaths {
+		ref, err := c.addFile(fmt.Sprintf("ops-%d", i), opsPath, bdv1.OpsSpecName)
+		if err != nil {
+			return errors.Wrapf(err, "reading ops file")
+		}
+		deployment.Spec.Ops = append(deployment.Spec.Ops, bdv1.Ops(ref))
+	}
+	return nil
+}
+
+// resolveManifest returns the with-ops manifest of the deployment
+func resolveManifest(c client.Client, deployment *bdv1.BOSHDeployment) (*manifest.Manifest, error) {
+	resolver := manifest.NewResolver(c, func() manifest.Interpolator { return manifest.NewInterpolator() })
+	m, _, err := resolver.WithOpsManifest(deployment, deployment.GetNamespace())
+	return m, err
+}
+
+// deployedManifest returns the with-ops manifest the operator has deployed for a BOSHDeployment,
+// or nil if it hasn't been deployed yet
+func deployedManifest(ctx context.Context, c client.Client, deployment *bdv1.BOSHDeployment) (*manifest.Manifest, error) {
+	secretName := names.CalculateSecretName(names.DeploymentSecretTypeManifestWithOps, deployment.GetName(), "")
+
+	secret := &corev1.Secret{}
+	err := c.Get(ctx, types.NamespacedName{Namespace: deployment.GetNamespace(), Name: secretName}, secret)
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading with-ops manifest secret '%s'", secretName)
+	}
+
+	m, err := manifest.LoadYAML(secret.Data[manifest.DesiredManifestKeyName])
+	if err != nil {
+		return nil, errors.Wrapf(err, "loading with-ops manifest from secret '%s'", secretName)
+	}
+
+	return m, nil
+}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
+)
+
+// renderAllCmd renders all kube resources of a deployment without a cluster
+var renderAllCmd = &cobra.Command{
+	Use:   "render-all [flags]",
+	Short: "Renders all kube resources of a deployment",
+	Long: `Renders all kube resources of a deployment:
+
+This applies the ops files and vars files to the manifest, gathers the data of
+all instance groups from the unpacked releases in the base directory and
+converts the manifest into the kube resources, which the operator would create.
+No cluster is needed and nothing is applied.
+
+Each resource is written as YAML to '<output-dir>/<kind>/<name>.yaml'. Existing
+files are overwritten. The output doesn't change unless the inputs change, so
+it can be compared between revisions of a manifest.
+
+The manifest is resolved like the operator resolves a BOSHDeployment.
+Variables, which are not in the vars files, are not interpolated, as they are
+generated or stored in the cluster.
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		log = newLogger()
+		defer log.Sync()
+
+		// Viper keys are global, the ops-file flag is shared with deployment-diff
+		// and the output-dir flag with template-render
+		viper.BindPFlag("ops-file", cmd.Flags().Lookup("ops-file"))
+		viper.BindPFlag("output-dir", cmd.Flags().Lookup("output-dir"))
+
+		boshManifestPath := viper.GetString("bosh-manifest-path")
+		if len(boshManifestPath) == 0 {
+			return fmt.Errorf("manifest cannot be empty")
+		}
+
+		baseDir := viper.GetString("base-dir")
+		if len(baseDir) == 0 {
+			return fmt.Errorf("base directory cannot be empty")
+		}
+
+		outputDir := viper.GetString("output-dir")
+		if len(outputDir) == 0 {
+			return fmt.Errorf("output directory cannot be empty")
+		}
+
+		m, err := localManifest(boshManifestPath, viper.GetStringSlice("ops-file"), viper.GetStringSlice("vars-file"))
+		if err != nil {
+			return errors.Wrapf(err, "loading manifest")
+		}
+
+		// Pull the release images from the operator's registry mirror
+		m.ApplyRegistryMirror(viper.GetString("registry-mirror"))
+
+		namespace := viper.GetString("cf-operator-namespace")
+		clusterDomain := viper.GetString("cluster-domain")
+		gathered, err := manifest.GatherData(log, m, baseDir, viper.GetString("links-dir"), namespace, clusterDomain)
+		if err != nil {
+			return err
+		}
+
+		resources, err := manifest.NewKubeConverter(namespace).RenderAll(m, gathered, clusterDomain)
+		if err != nil {
+			return err
+		}
+
+		return resources.Write(outputDir)
+	},
+}
+
+func init() {
+	utilCmd.AddCommand(renderAllCmd)
+
+	renderAllCmd.Flags().StringSlice("ops-file", []string{}, "path to an ops file, which is applied to the manifest, can be repeated")
+	renderAllCmd.Flags().StringSlice("vars-file", []string{}, "path to a YAML file with variables, which are interpolated, can be repeated")
+	renderAllCmd.Flags().String("output-dir", "", "path to the directory the resources are written to")
+
+	viper.BindPFlag("vars-file", renderAllCmd.Flags().Lookup("vars-file"))
+
+	argToEnv := map[string]string{
+		"ops-file":   "OPS_FILE",
+		"vars-file":  "VARS_FILE",
+		"output-dir": "OUTPUT_DIR",
+	}
+	AddEnvToUsage(renderAllCmd, argToEnv)
+}
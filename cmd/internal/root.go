@@ -64,6 +64,8 @@ var rootCmd = &cobra.Command{
 			Namespace:         cfOperatorNamespace,
 			WebhookServerHost: operatorWebhookHost,
 			WebhookServerPort: operatorWebhookPort,
+			ClusterDomain:     viper.GetString("cluster-domain"),
+			RegistryMirror:    viper.GetString("registry-mirror"),
 			Fs:                afero.NewOsFs(),
 		}
 		ctx := ctxlog.NewParentContext(log)
@@ -107,6 +109,8 @@ func init() {
 	pf.StringP("operator-webhook-service-host", "w", "", "Hostname/IP under which the webhook server can be reached from the cluster")
 	pf.StringP("operator-webhook-service-port", "p", "2999", "Port the webhook server listens on")
 	pf.StringP("docker-image-tag", "t", version.Version, "Tag of the operator docker image")
+	pf.String("cluster-domain", "cluster.local", "DNS domain of the cluster, used for the addresses of BOSH instances")
+	pf.String("registry-mirror", "", "Registry to pull all release images from, instead of the registries in the manifests")
 	viper.BindPFlag("kubeconfig", pf.Lookup("kubeconfig"))
 	viper.BindPFlag("log-level", pf.Lookup("log-level"))
 	viper.BindPFlag("cf-operator-namespace", pf.Lookup("cf-operator-namespace"))
@@ -115,6 +119,8 @@ func init() {
 	viper.BindPFlag("operator-webhook-service-host", pf.Lookup("operator-webhook-service-host"))
 	viper.BindPFlag("operator-webhook-service-port", pf.Lookup("operator-webhook-service-port"))
 	viper.BindPFlag("docker-image-tag", rootCmd.PersistentFlags().Lookup("docker-image-tag"))
+	viper.BindPFlag("cluster-domain", pf.Lookup("cluster-domain"))
+	viper.BindPFlag("registry-mirror", pf.Lookup("registry-mirror"))
 
 	argToEnv := map[string]string{
 		"kubeconfig":                    "KUBECONFIG",
@@ -125,6 +131,8 @@ func init() {
 		"operator-webhook-service-host": "CF_OPERATOR_WEBHOOK_SERVICE_HOST",
 		"operator-webhook-service-port": "CF_OPERATOR_WEBHOOK_SERVICE_PORT",
 		"docker-image-tag":              "DOCKER_IMAGE_TAG",
+		"cluster-domain":                "CLUSTER_DOMAIN",
+		"registry-mirror":               "REGISTRY_MIRROR",
 	}
 
 	// Add env variables to help
@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	yaml "gopkg.in/yaml.v2"
+
+	"code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
+)
+
+// sharedLinksCmd prints the links a deployment shares with other deployments
+var sharedLinksCmd = &cobra.Command{
+	Use:   "shared-links [flags]",
+	Short: "Prints the links a bosh manifest shares with other deployments",
+	Long: `Prints the links a manifest shares with other deployments.
+
+This will collect the links of all jobs, which are provided
+with 'shared: true', so other deployments can consume them.
+
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Store original stdout i
+		origStdOut := os.Stdout
+
+		// Dump everything before the JSON bytes buffer creation
+		// into w, while we do not want any sort of noise coming
+		// into stdout, beside the JSON bytes
+		r, w, _ := os.Pipe()
+		os.Stdout = w
+
+		log = newLogger()
+		defer log.Sync()
+		boshManifestPath := viper.GetString("bosh-manifest-path")
+		if len(boshManifestPath) == 0 {
+			return fmt.Errorf("manifest cannot be empty")
+		}
+
+		baseDir := viper.GetString("base-dir")
+		if len(baseDir) == 0 {
+			return fmt.Errorf("base directory cannot be empty")
+		}
+
+		namespace := viper.GetString("cf-operator-namespace")
+		if len(namespace) == 0 {
+			return fmt.Errorf("namespace cannot be empty")
+		}
+
+		boshManifestBytes, err := ioutil.ReadFile(boshManifestPath)
+		if err != nil {
+			return err
+		}
+
+		m, err := manifest.LoadYAML(boshManifestBytes)
+		if err != nil {
+			return err
+		}
+
+		links, err := manifest.SharedLinks(log, baseDir, namespace, viper.GetString("cluster-domain"), *m)
+		if err != nil {
+			return err
+		}
+
+		linksBytes, err := yaml.Marshal(links)
+		if err != nil {
+			return err
+		}
+
+		jsonBytes, err := json.Marshal(map[string]string{
+			manifest.LinksKeyName: string(linksBytes),
+		})
+		if err != nil {
+			return errors.Wrapf(err, "could not marshal json output")
+		}
+
+		// Close w, and restore the original stdOut
+		w.Close()
+		os.Stdout = origStdOut
+
+		var buf bytes.Buffer
+		io.Copy(&buf, r)
+
+		if buf.Len() > 0 {
+			return errors.Errorf("unexpected data sent to stdOut, during the shared-links cmd: %s", buf.String())
+		}
+		// Write to an original stdOut
+		// without any undesired data
+		f := bufio.NewWriter(os.Stdout)
+
+		// Ensure bufio.NewWriter will send
+		// data at the very end of this func
+		// Therefore, we can tail=1 in other
+		// containers, and we will get the
+		// correct data.
+		defer f.Flush()
+		_, err = f.Write(jsonBytes)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	utilCmd.AddCommand(sharedLinksCmd)
+}
@@ -2,11 +2,13 @@ package cmd
 
 import (
 	"fmt"
+	"io/ioutil"
 	"net"
 	"os"
 	"regexp"
 	"strconv"
 
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
@@ -24,6 +26,9 @@ var templateRenderCmd = &cobra.Command{
 This will render a provided manifest instance-group
 `,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		log = newLogger()
+		defer log.Sync()
+
 		boshManifestPath := viper.GetString("bosh-manifest-path")
 		jobsDir := viper.GetString("jobs-dir")
 		outputDir := viper.GetString("output-dir")
@@ -35,16 +40,12 @@ This will render a provided manifest instance-group
 
 		specIndex := viper.GetInt("spec-index")
 		if specIndex < 0 {
-			// Calculate index following the formula specified in
+			// Look up the instance, which runs as this pod, see
 			// docs/rendering_templates.md.
 			azIndex := viper.GetInt("az-index")
 			if azIndex < 0 {
 				return fmt.Errorf("required parameter 'az-index' not set")
 			}
-			replicas := viper.GetInt("replicas")
-			if replicas < 0 {
-				return fmt.Errorf("required parameter 'replicas' not set")
-			}
 			podOrdinal := viper.GetInt("pod-ordinal")
 			if podOrdinal < 0 {
 				// Infer ordinal from hostname.
@@ -62,12 +63,34 @@ This will render a provided manifest instance-group
 				}
 			}
 
-			specIndex = (azIndex-1)*replicas + podOrdinal
+			resolvedYML, err := ioutil.ReadFile(boshManifestPath)
+			if err != nil {
+				return errors.Wrapf(err, "couldn't read manifest file %s", boshManifestPath)
+			}
+			boshManifest, err := manifest.LoadYAML(resolvedYML)
+			if err != nil {
+				return errors.Wrapf(err, "failed to load BOSH deployment manifest %s", boshManifestPath)
+			}
+			instanceGroup, err := boshManifest.InstanceGroupByName(instanceGroupName)
+			if err != nil {
+				return err
+			}
+
+			// The az index starts at 1
+			specIndex, err = instanceGroup.InstanceIndex(azIndex-1, podOrdinal)
+			if err != nil {
+				return err
+			}
 		}
 
 		podIP := net.ParseIP(viper.GetString("pod-ip"))
 
-		return manifest.RenderJobTemplates(boshManifestPath, jobsDir, outputDir, instanceGroupName, specIndex, podIP)
+		watchInterval := viper.GetDuration("watch-interval")
+		if watchInterval > 0 {
+			return manifest.WatchJobTemplates(log, boshManifestPath, jobsDir, outputDir, instanceGroupName, specIndex, podIP, watchInterval)
+		}
+
+		return manifest.RenderJobTemplates(log, boshManifestPath, jobsDir, outputDir, instanceGroupName, specIndex, podIP)
 	},
 }
 
@@ -79,16 +102,16 @@ func init() {
 	templateRenderCmd.Flags().IntP("spec-index", "", -1, "index of the instance spec")
 	templateRenderCmd.Flags().IntP("az-index", "", -1, "az index")
 	templateRenderCmd.Flags().IntP("pod-ordinal", "", -1, "pod ordinal")
-	templateRenderCmd.Flags().IntP("replicas", "", -1, "number of replicas")
 	templateRenderCmd.Flags().StringP("pod-ip", "", "", "pod IP")
+	templateRenderCmd.Flags().Duration("watch-interval", 0, "interval to check the addresses of link instances and render again when they change, if DNS addresses are not used")
 
 	viper.BindPFlag("jobs-dir", templateRenderCmd.Flags().Lookup("jobs-dir"))
 	viper.BindPFlag("output-dir", templateRenderCmd.Flags().Lookup("output-dir"))
 	viper.BindPFlag("az-index", templateRenderCmd.Flags().Lookup("az-index"))
 	viper.BindPFlag("spec-index", templateRenderCmd.Flags().Lookup("spec-index"))
 	viper.BindPFlag("pod-ordinal", templateRenderCmd.Flags().Lookup("pod-ordinal"))
-	viper.BindPFlag("replicas", templateRenderCmd.Flags().Lookup("replicas"))
 	viper.BindPFlag("pod-ip", templateRenderCmd.Flags().Lookup("pod-ip"))
+	viper.BindPFlag("watch-interval", templateRenderCmd.Flags().Lookup("watch-interval"))
 
 	argToEnv := map[string]string{
 		"jobs-dir":                "JOBS_DIR",
@@ -97,8 +120,8 @@ func init() {
 		"spec-index":              "SPEC_INDEX",
 		"az-index":                "AZ_INDEX",
 		"pod-ordinal":             "POD_ORDINAL",
-		"replicas":                "REPLICAS",
 		"pod-ip":                  manifest.PodIPEnvVar,
+		"watch-interval":          "WATCH_INTERVAL",
 	}
 	AddEnvToUsage(templateRenderCmd, argToEnv)
 }
@@ -18,15 +18,18 @@ func init() {
 	utilCmd.PersistentFlags().StringP("bosh-manifest-path", "m", "", "path to the bosh manifest file")
 	utilCmd.PersistentFlags().StringP("instance-group-name", "g", "", "name of the instance group for data gathering")
 	utilCmd.PersistentFlags().StringP("base-dir", "b", "", "a path to the base directory")
+	utilCmd.PersistentFlags().String("links-dir", "", "a path to the directory with the links shared by other deployments")
 
 	viper.BindPFlag("bosh-manifest-path", utilCmd.PersistentFlags().Lookup("bosh-manifest-path"))
 	viper.BindPFlag("instance-group-name", utilCmd.PersistentFlags().Lookup("instance-group-name"))
 	viper.BindPFlag("base-dir", utilCmd.PersistentFlags().Lookup("base-dir"))
+	viper.BindPFlag("links-dir", utilCmd.PersistentFlags().Lookup("links-dir"))
 
 	argToEnv := map[string]string{
 		"base-dir":            "BASE_DIR",
 		"bosh-manifest-path":  "BOSH_MANIFEST_PATH",
 		"instance-group-name": "INSTANCE_GROUP_NAME",
+		"links-dir":           "LINKS_DIR",
 	}
 	AddEnvToUsage(utilCmd, argToEnv)
 
@@ -29,6 +29,7 @@ var _ = Describe("CLI", func() {
 			Expect(err).ToNot(HaveOccurred())
 			Eventually(session.Out).Should(Say(`Flags:
   -n, --cf-operator-namespace string           \(CF_OPERATOR_NAMESPACE\) Namespace to watch for BOSH deployments \(default "default"\)
+      --cluster-domain string                  \(CLUSTER_DOMAIN\) DNS domain of the cluster, used for the addresses of BOSH instances \(default "cluster.local"\)
   -o, --docker-image-org string                \(DOCKER_IMAGE_ORG\) Dockerhub organization that provides the operator docker image \(default "cfcontainerization"\)
   -r, --docker-image-repository string         \(DOCKER_IMAGE_REPOSITORY\) Dockerhub repository that provides the operator docker image \(default "cf-operator"\)
   -t, --docker-image-tag string                \(DOCKER_IMAGE_TAG\) Tag of the operator docker image \(default "\d+.\d+.\d+"\)
@@ -36,13 +37,15 @@ var _ = Describe("CLI", func() {
   -c, --kubeconfig string                      \(KUBECONFIG\) Path to a kubeconfig, not required in-cluster
   -l, --log-level string                       \(LOG_LEVEL\) Only print log messages from this level onward \(default "debug"\)
   -w, --operator-webhook-service-host string   \(CF_OPERATOR_WEBHOOK_SERVICE_HOST\) Hostname/IP under which the webhook server can be reached from the cluster
-  -p, --operator-webhook-service-port string   \(CF_OPERATOR_WEBHOOK_SERVICE_PORT\) Port the webhook server listens on \(default "2999"\)`))
+  -p, --operator-webhook-service-port string   \(CF_OPERATOR_WEBHOOK_SERVICE_PORT\) Port the webhook server listens on \(default "2999"\)
+      --registry-mirror string                 \(REGISTRY_MIRROR\) Registry to pull all release images from, instead of the registries in the manifests`))
 		})
 
 		It("shows all available commands", func() {
 			session, err := act("help")
 			Expect(err).ToNot(HaveOccurred())
 			Eventually(session.Out).Should(Say(`Available Commands:
+  errand      Manages errands of BOSH deployments
   help        Help about any command
   util        Calls a utility subcommand
   version     Print the version number
@@ -204,14 +207,14 @@ var _ = Describe("CLI", func() {
 			session, err := act("util", "template-render", "-h")
 			Expect(err).ToNot(HaveOccurred())
 			Eventually(session.Out).Should(Say(`Flags:
-      --az-index int        \(AZ_INDEX\) az index \(default -1\)
-  -h, --help                help for template-render
-  -j, --jobs-dir string     \(JOBS_DIR\) path to the jobs dir.
-  -d, --output-dir string   \(OUTPUT_DIR\) path to output dir. \(default "/var/vcap/jobs"\)
-      --pod-ip string       \(POD_IP\) pod IP
-      --pod-ordinal int     \(POD_ORDINAL\) pod ordinal \(default -1\)
-      --replicas int        \(REPLICAS\) number of replicas \(default -1\)
-      --spec-index int      \(SPEC_INDEX\) index of the instance spec \(default -1\)
+      --az-index int              \(AZ_INDEX\) az index \(default -1\)
+  -h, --help                      help for template-render
+  -j, --jobs-dir string           \(JOBS_DIR\) path to the jobs dir.
+  -d, --output-dir string         \(OUTPUT_DIR\) path to output dir. \(default "/var/vcap/jobs"\)
+      --pod-ip string             \(POD_IP\) pod IP
+      --pod-ordinal int           \(POD_ORDINAL\) pod ordinal \(default -1\)
+      --spec-index int            \(SPEC_INDEX\) index of the instance spec \(default -1\)
+      --watch-interval duration   \(WATCH_INTERVAL\) interval to check the addresses of link instances and render again when they change, if DNS addresses are not used
 `))
 		})
 
@@ -219,7 +222,6 @@ var _ = Describe("CLI", func() {
 			session, err := act(
 				"util", "template-render",
 				"--az-index=1",
-				"--replicas=1",
 				"--pod-ordinal=1",
 				"-m", "foo.txt",
 				"-g", "log-api",
@@ -242,7 +244,6 @@ var _ = Describe("CLI", func() {
 				session, err := act(
 					"util", "template-render",
 					"--az-index=1",
-					"--replicas=1",
 					"--pod-ordinal=1",
 					"-g", "log-api",
 					"--pod-ip", "127.0.0.1",
@@ -0,0 +1,81 @@
+package cli_test
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gexec"
+)
+
+var _ = Describe("render-all", func() {
+	var (
+		tmpDir    string
+		outputDir string
+	)
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = ioutil.TempDir("", "render-all")
+		Expect(err).ToNot(HaveOccurred())
+		outputDir = filepath.Join(tmpDir, "out")
+
+		files := map[string]string{
+			"manifest.yml": `name: foo
+releases: []
+instance_groups: []
+variables:
+- name: router_ca
+  type: certificate
+  options:
+    is_ca: true
+    common_name: ((system_domain))
+    alternative_names:
+    - ((undeclared_name))
+`,
+			"ops.yml": `- type: replace
+  path: /name
+  value: bar
+`,
+			"vars.yml": `system_domain: example.com
+`,
+		}
+		for name, content := range files {
+			err = ioutil.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644)
+			Expect(err).ToNot(HaveOccurred())
+		}
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(tmpDir)).To(Succeed())
+	})
+
+	act := func() (session *gexec.Session, err error) {
+		args := []string{"util", "render-all",
+			"-m", filepath.Join(tmpDir, "manifest.yml"),
+			"-b", assetPath,
+			"--ops-file", filepath.Join(tmpDir, "ops.yml"),
+			"--vars-file", filepath.Join(tmpDir, "vars.yml"),
+			"--output-dir", outputDir,
+		}
+		cmd := exec.Command(cliPath, args...)
+		session, err = gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+		return
+	}
+
+	It("writes the resources to the output directory", func() {
+		session, err := act()
+		Expect(err).ToNot(HaveOccurred())
+		Eventually(session).Should(gexec.Exit(0))
+
+		Expect(filepath.Join(outputDir, "extendedjob", "dm-bar.yaml")).To(BeAnExistingFile())
+
+		manifestBytes, err := ioutil.ReadFile(filepath.Join(outputDir, "secret", "bar.with-ops.yaml"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(manifestBytes)).To(ContainSubstring("common_name: example.com"))
+		Expect(string(manifestBytes)).To(ContainSubstring("- ((undeclared_name))"))
+	})
+})
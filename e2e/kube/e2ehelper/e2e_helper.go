@@ -121,7 +121,9 @@ func ClusterCrdsExist() (bool, error) {
 	crds := []string{"boshdeployments.fissile.cloudfoundry.org",
 		"extendedjobs.fissile.cloudfoundry.org",
 		"extendedsecrets.fissile.cloudfoundry.org",
-		"extendedstatefulsets.fissile.cloudfoundry.org"}
+		"extendedstatefulsets.fissile.cloudfoundry.org",
+		"runtimeconfigs.fissile.cloudfoundry.org",
+		"cloudconfigs.fissile.cloudfoundry.org"}
 
 	if len(customResource.Items) > 0 {
 		for _, crdName := range crds {
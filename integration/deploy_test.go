@@ -247,6 +247,21 @@ var _ = Describe("Deploy", func() {
 				})
 			})
 
+			Context("when updating the bdm custom resource with invalid job properties", func() {
+				It("should be rejected by the validating webhook", func() {
+					tearDown, err := env.CreateConfigMap(env.Namespace, env.InvalidPropertiesOpsConfigMap("invalid-ops"))
+					Expect(err).NotTo(HaveOccurred())
+					tearDowns = append(tearDowns, tearDown)
+
+					bdm, err := env.GetBOSHDeployment(env.Namespace, "test")
+					Expect(err).NotTo(HaveOccurred())
+					bdm.Spec.Ops = []bdv1.Ops{{Ref: "invalid-ops", Type: bdv1.ConfigMapType}}
+					_, _, err = env.UpdateBOSHDeployment(env.Namespace, *bdm)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("Invalid job properties: job 'nats' of instance group 'nats': property 'nats.debug' must be of kind 'scalar', but is of kind 'hash'"))
+				})
+			})
+
 			Context("when updating referenced BOSH deployment manifest", func() {
 				It("should update the deployment", func() {
 					cm, err := env.GetConfigMap(env.Namespace, "manifest")
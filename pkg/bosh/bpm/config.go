@@ -2,6 +2,8 @@ package bpm
 
 import (
 	yaml "gopkg.in/yaml.v2"
+
+	"code.cloudfoundry.org/cf-operator/pkg/bosh/monit"
 )
 
 // Hooks from a BPM config
@@ -50,6 +52,13 @@ type Process struct {
 type Config struct {
 	Processes           []Process `yaml:"processes,omitempty" json:"processes,omitempty"`
 	UnsupportedTemplate bool      `json:"unsupported_template"`
+	// Monit holds the checks of the job's monit file, which are not part of
+	// the BPM config, but are passed on with it to derive probes
+	Monit monit.Checks `yaml:"monit,omitempty" json:"monit,omitempty"`
+	// PostStart is true if the job has a bin/post-start script
+	PostStart bool `yaml:"post_start,omitempty" json:"post_start,omitempty"`
+	// PostDeploy is true if the job has a bin/post-deploy script
+	PostDeploy bool `yaml:"post_deploy,omitempty" json:"post_deploy,omitempty"`
 }
 
 // Configs holds a collection of BPM configurations by their according job
@@ -0,0 +1,86 @@
+package drain
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// eventSource is the component reported as the source of drain events
+const eventSource = "cf-operator-drain"
+
+// NewPodRecorder returns a recorder, which creates events for the pod. The
+// service account of the pod needs the permission to create events. If it
+// hasn't, events are only logged.
+func NewPodRecorder(log *zap.SugaredLogger, client kubernetes.Interface, namespace, podName string) Recorder {
+	return &podRecorder{
+		log:       log,
+		client:    client,
+		namespace: namespace,
+		podName:   podName,
+	}
+}
+
+type podRecorder struct {
+	log       *zap.SugaredLogger
+	client    kubernetes.Interface
+	namespace string
+	podName   string
+
+	// Drain scripts run in parallel
+	mu       sync.Mutex
+	disabled bool
+}
+
+// Event creates an event for the pod
+func (r *podRecorder) Event(eventType, reason, message string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.disabled {
+		return
+	}
+
+	now := metav1.NewTime(time.Now())
+	event := &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s.%x", r.podName, now.UnixNano()),
+			Namespace: r.namespace,
+		},
+		InvolvedObject: corev1.ObjectReference{
+			Kind:       "Pod",
+			APIVersion: "v1",
+			Namespace:  r.namespace,
+			Name:       r.podName,
+		},
+		Reason:         reason,
+		Message:        message,
+		Type:           eventType,
+		Source:         corev1.EventSource{Component: eventSource},
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+	}
+
+	_, err := r.client.CoreV1().Events(r.namespace).Create(event)
+	if err != nil {
+		r.log.Infof("Not reporting drain events, failed to create event for pod '%s/%s': %v", r.namespace, r.podName, err)
+		r.disabled = true
+	}
+}
+
+// NewLogRecorder returns a recorder, which doesn't create events. The runner
+// logs all events anyway.
+func NewLogRecorder() Recorder {
+	return logRecorder{}
+}
+
+type logRecorder struct{}
+
+// Event does nothing
+func (logRecorder) Event(eventType, reason, message string) {}
@@ -0,0 +1,203 @@
+// Package drain runs the drain scripts of BOSH jobs, before the processes of
+// a pod are stopped.
+package drain
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// doneFileName marks a job as drained, in the job's lock directory
+	doneFileName = "done"
+	// pollInterval is how often containers check if the job has been drained
+	pollInterval = time.Second
+)
+
+// Recorder reports the progress of draining
+type Recorder interface {
+	Event(eventType, reason, message string)
+}
+
+// Runner runs the drain scripts of a job once per pod. The containers of a
+// pod share the lock directory: the first container of a job runs its drain
+// scripts, the others wait for them to finish.
+type Runner struct {
+	log      *zap.SugaredLogger
+	recorder Recorder
+	jobsDir  string
+	lockDir  string
+}
+
+// NewRunner returns a new drain runner for the rendered jobs in jobsDir
+func NewRunner(log *zap.SugaredLogger, recorder Recorder, jobsDir, lockDir string) *Runner {
+	return &Runner{
+		log:      log,
+		recorder: recorder,
+		jobsDir:  jobsDir,
+		lockDir:  lockDir,
+	}
+}
+
+// Run drains the job, or waits for another container of the pod to drain it.
+// Like BOSH, the drain scripts of a job run in parallel.
+func (r *Runner) Run(jobName string) error {
+	jobLockDir := filepath.Join(r.lockDir, jobName)
+	doneFile := filepath.Join(jobLockDir, doneFileName)
+
+	err := os.MkdirAll(r.lockDir, 0755)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create lock directory '%s'", r.lockDir)
+	}
+
+	err = os.Mkdir(jobLockDir, 0755)
+	if os.IsExist(err) {
+		r.log.Infof("Waiting for another container to drain job '%s'", jobName)
+		for {
+			if _, err := os.Stat(doneFile); err == nil {
+				return nil
+			}
+			time.Sleep(pollInterval)
+		}
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed to lock job '%s'", jobName)
+	}
+	defer ioutil.WriteFile(doneFile, []byte{}, 0644)
+
+	scripts, err := r.scripts(jobName)
+	if err != nil {
+		return err
+	}
+	if len(scripts) == 0 {
+		return nil
+	}
+
+	r.event(corev1.EventTypeNormal, "Draining", "Draining job '%s'", jobName)
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(scripts))
+	for i, script := range scripts {
+		wg.Add(1)
+		go func(i int, script string) {
+			defer wg.Done()
+			errs[i] = r.runScript(jobName, script)
+		}(i, script)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			r.event(corev1.EventTypeWarning, "DrainFailed", "Failed to drain job '%s': %v", jobName, err)
+			return err
+		}
+	}
+
+	r.event(corev1.EventTypeNormal, "Drained", "Drained job '%s'", jobName)
+	return nil
+}
+
+// Reset removes the lock of a job, when one of its containers starts. The lock
+// directory lives as long as the pod, but a container, which is restarted, has
+// drained the job already. The next termination has to drain it again.
+func (r *Runner) Reset(jobName string) error {
+	jobLockDir := filepath.Join(r.lockDir, jobName)
+
+	err := os.RemoveAll(jobLockDir)
+	if err != nil {
+		return errors.Wrapf(err, "failed to unlock job '%s'", jobName)
+	}
+	return nil
+}
+
+// scripts returns the drain scripts of a job. The drain script is either
+// bin/drain, or the files in the bin/drain directory.
+func (r *Runner) scripts(jobName string) ([]string, error) {
+	drainPath := filepath.Join(r.jobsDir, jobName, "bin", "drain")
+
+	info, err := os.Stat(drainPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to stat '%s'", drainPath)
+	}
+	if !info.IsDir() {
+		return []string{drainPath}, nil
+	}
+
+	files, err := ioutil.ReadDir(drainPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read '%s'", drainPath)
+	}
+
+	scripts := []string{}
+	for _, file := range files {
+		if !file.IsDir() {
+			scripts = append(scripts, filepath.Join(drainPath, file.Name()))
+		}
+	}
+	return scripts, nil
+}
+
+// runScript runs a drain script until it is done. The script prints the
+// number of seconds to wait. A negative number is a dynamic wait time, after
+// which the script is run again to check the status of the job.
+func (r *Runner) runScript(jobName, script string) error {
+	args := []string{"job_shutdown", "hash_unchanged"}
+
+	for {
+		wait, err := r.execScript(script, args)
+		if err != nil {
+			return err
+		}
+
+		if wait >= 0 {
+			r.log.Infof("Waiting %d seconds for drain script '%s' of job '%s'", wait, script, jobName)
+			time.Sleep(time.Duration(wait) * time.Second)
+			return nil
+		}
+
+		r.event(corev1.EventTypeNormal, "Draining", "Drain script '%s' of job '%s' is checked again in %d seconds", script, jobName, -wait)
+		time.Sleep(time.Duration(-wait) * time.Second)
+		args = []string{"job_check_status", "hash_unchanged"}
+	}
+}
+
+func (r *Runner) execScript(script string, args []string) (int, error) {
+	var stdout bytes.Buffer
+	cmd := exec.Command(script, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+
+	err := cmd.Run()
+	if err != nil {
+		return 0, errors.Wrapf(err, "drain script '%s' failed", script)
+	}
+
+	output := strings.TrimSpace(stdout.String())
+	wait, err := strconv.Atoi(output)
+	if err != nil {
+		return 0, fmt.Errorf("drain script '%s' printed '%s' instead of the number of seconds to wait", script, output)
+	}
+
+	return wait, nil
+}
+
+func (r *Runner) event(eventType, reason, format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+	r.log.Info(message)
+	r.recorder.Event(eventType, reason, message)
+}
@@ -0,0 +1,132 @@
+package drain_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"code.cloudfoundry.org/cf-operator/pkg/bosh/drain"
+	helper "code.cloudfoundry.org/cf-operator/pkg/testhelper"
+)
+
+type fakeRecorder struct {
+	reasons []string
+}
+
+func (r *fakeRecorder) Event(eventType, reason, message string) {
+	r.reasons = append(r.reasons, reason)
+}
+
+var _ = Describe("Runner", func() {
+	var (
+		tmpDir   string
+		jobsDir  string
+		lockDir  string
+		recorder *fakeRecorder
+		runner   *drain.Runner
+	)
+
+	writeScript := func(path, content string) {
+		Expect(os.MkdirAll(filepath.Dir(path), 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(path, []byte("#!/bin/sh\n"+content), 0755)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = ioutil.TempDir("", "drain")
+		Expect(err).ToNot(HaveOccurred())
+
+		jobsDir = filepath.Join(tmpDir, "jobs")
+		lockDir = filepath.Join(tmpDir, "run", "drain")
+		recorder = &fakeRecorder{}
+
+		_, log := helper.NewTestLogger()
+		runner = drain.NewRunner(log, recorder, jobsDir, lockDir)
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(tmpDir)).To(Succeed())
+	})
+
+	It("does nothing for jobs without drain scripts", func() {
+		Expect(runner.Run("fake-job")).To(Succeed())
+		Expect(recorder.reasons).To(BeEmpty())
+		Expect(filepath.Join(lockDir, "fake-job", "done")).To(BeAnExistingFile())
+	})
+
+	It("runs the drain script of a job", func() {
+		marker := filepath.Join(tmpDir, "args")
+		writeScript(filepath.Join(jobsDir, "fake-job", "bin", "drain"), "echo \"$@\" > "+marker+"\necho 0\n")
+
+		Expect(runner.Run("fake-job")).To(Succeed())
+		Expect(ioutil.ReadFile(marker)).To(Equal([]byte("job_shutdown hash_unchanged\n")))
+		Expect(recorder.reasons).To(Equal([]string{"Draining", "Drained"}))
+	})
+
+	It("runs all drain scripts in the drain directory", func() {
+		drainDir := filepath.Join(jobsDir, "fake-job", "bin", "drain")
+		writeScript(filepath.Join(drainDir, "a"), "touch "+filepath.Join(tmpDir, "a")+"\necho 0\n")
+		writeScript(filepath.Join(drainDir, "b"), "touch "+filepath.Join(tmpDir, "b")+"\necho 0\n")
+
+		Expect(runner.Run("fake-job")).To(Succeed())
+		Expect(filepath.Join(tmpDir, "a")).To(BeAnExistingFile())
+		Expect(filepath.Join(tmpDir, "b")).To(BeAnExistingFile())
+	})
+
+	It("checks the status again after a dynamic wait", func() {
+		marker := filepath.Join(tmpDir, "args")
+		writeScript(filepath.Join(jobsDir, "fake-job", "bin", "drain"), `
+echo "$1" >> `+marker+`
+if [ "$1" = "job_shutdown" ]; then echo -1; else echo 0; fi
+`)
+
+		Expect(runner.Run("fake-job")).To(Succeed())
+		Expect(ioutil.ReadFile(marker)).To(Equal([]byte("job_shutdown\njob_check_status\n")))
+		Expect(recorder.reasons).To(Equal([]string{"Draining", "Draining", "Drained"}))
+	})
+
+	It("fails if a drain script fails", func() {
+		writeScript(filepath.Join(jobsDir, "fake-job", "bin", "drain"), "exit 1\n")
+
+		err := runner.Run("fake-job")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("drain script"))
+		Expect(recorder.reasons).To(Equal([]string{"Draining", "DrainFailed"}))
+	})
+
+	It("fails if a drain script doesn't print a number", func() {
+		writeScript(filepath.Join(jobsDir, "fake-job", "bin", "drain"), "echo done\n")
+
+		err := runner.Run("fake-job")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("printed 'done'"))
+	})
+
+	It("drains each job only once per pod", func() {
+		counter := filepath.Join(tmpDir, "count")
+		writeScript(filepath.Join(jobsDir, "fake-job", "bin", "drain"), "echo x >> "+counter+"\necho 0\n")
+
+		Expect(runner.Run("fake-job")).To(Succeed())
+		Expect(runner.Run("fake-job")).To(Succeed())
+		Expect(ioutil.ReadFile(counter)).To(Equal([]byte("x\n")))
+	})
+
+	It("drains the job again, after a container has been restarted", func() {
+		counter := filepath.Join(tmpDir, "count")
+		writeScript(filepath.Join(jobsDir, "fake-job", "bin", "drain"), "echo x >> "+counter+"\necho 0\n")
+
+		Expect(runner.Run("fake-job")).To(Succeed())
+		Expect(runner.Reset("fake-job")).To(Succeed())
+		Expect(filepath.Join(lockDir, "fake-job")).ToNot(BeADirectory())
+
+		Expect(runner.Run("fake-job")).To(Succeed())
+		Expect(ioutil.ReadFile(counter)).To(Equal([]byte("x\nx\n")))
+	})
+
+	It("resets jobs, which haven't been drained", func() {
+		Expect(runner.Reset("fake-job")).To(Succeed())
+	})
+})
@@ -0,0 +1,31 @@
+package errand
+
+import (
+	"io"
+
+	corev1 "k8s.io/api/core/v1"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+// LogStreamer streams the logs of a container
+type LogStreamer interface {
+	Stream(namespace, podName, containerName string) (io.ReadCloser, error)
+}
+
+// NewLogStreamer returns a LogStreamer, which follows the logs of a container until it terminates
+func NewLogStreamer(client corev1client.CoreV1Interface) LogStreamer {
+	return &logStreamer{client: client}
+}
+
+type logStreamer struct {
+	client corev1client.CoreV1Interface
+}
+
+// Stream follows the logs of a container
+func (s *logStreamer) Stream(namespace, podName, containerName string) (io.ReadCloser, error) {
+	options := corev1.PodLogOptions{
+		Container: containerName,
+		Follow:    true,
+	}
+	return s.client.Pods(namespace).GetLogs(podName, &options).Stream()
+}
@@ -0,0 +1,278 @@
+// Package errand runs the errands of BOSH deployments, which are converted to
+// manually triggered ExtendedJobs, and streams their logs.
+package errand
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	ejv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/extendedjob/v1alpha1"
+)
+
+// Runner triggers an errand, streams the logs of its containers and waits for
+// the result of the run, which the ExtendedJob controllers record in the
+// ExtendedJob's status.
+type Runner struct {
+	log          *zap.SugaredLogger
+	client       client.Client
+	logs         LogStreamer
+	out          io.Writer
+	pollInterval time.Duration
+}
+
+// NewRunner returns a new errand runner, which writes the logs of the errand to out
+func NewRunner(log *zap.SugaredLogger, client client.Client, logs LogStreamer, out io.Writer, pollInterval time.Duration) *Runner {
+	return &Runner{
+		log:          log,
+		client:       client,
+		logs:         logs,
+		out:          out,
+		pollInterval: pollInterval,
+	}
+}
+
+// Run runs the errand of a deployment and returns its exit code
+func (r *Runner) Run(ctx context.Context, namespace, deploymentName, errandName string) (int, error) {
+	key := types.NamespacedName{Namespace: namespace, Name: fmt.Sprintf("%s-%s", deploymentName, errandName)}
+
+	previousJob, err := r.trigger(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+
+	run, err := r.waitForRun(ctx, key, func(run *ejv1.ExtendedJobRun) bool {
+		return run.Job != previousJob
+	})
+	if err != nil {
+		return 0, err
+	}
+	r.log.Infof("Running errand '%s' of deployment '%s' in job '%s'", errandName, deploymentName, run.Job)
+
+	pod, err := r.waitForPod(ctx, key, run.Job)
+	if err != nil {
+		return 0, err
+	}
+	if pod != nil {
+		r.streamLogs(ctx, run.Job, pod)
+	} else {
+		r.log.Infof("Not streaming logs, the pod of job '%s' is gone", run.Job)
+	}
+
+	job := run.Job
+	run, err = r.waitForRun(ctx, key, func(run *ejv1.ExtendedJobRun) bool {
+		return run.Job == job && run.Done()
+	})
+	if err != nil {
+		return 0, err
+	}
+	r.log.Infof("Errand '%s' of deployment '%s' %s", errandName, deploymentName, run.State)
+
+	if run.ExitCode != nil {
+		return int(*run.ExitCode), nil
+	}
+	if run.State == ejv1.RunStateFailed {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// trigger sets the trigger strategy of the errand's ExtendedJob to now. It
+// returns the job of the previous run, if there is one.
+func (r *Runner) trigger(ctx context.Context, key types.NamespacedName) (string, error) {
+	previousJob := ""
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		eJob := &ejv1.ExtendedJob{}
+		err := r.client.Get(ctx, key, eJob)
+		if apierrors.IsNotFound(err) {
+			return fmt.Errorf("errand ExtendedJob '%s' not found", key)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "getting errand ExtendedJob '%s'", key)
+		}
+
+		if eJob.Spec.Trigger.Strategy != ejv1.TriggerManual {
+			return fmt.Errorf("ExtendedJob '%s' is not a manual errand or has been triggered already, its trigger strategy is '%s'", key, eJob.Spec.Trigger.Strategy)
+		}
+
+		if eJob.Status.LastRun != nil {
+			previousJob = eJob.Status.LastRun.Job
+		}
+
+		eJob.Spec.Trigger.Strategy = ejv1.TriggerNow
+		return r.client.Update(ctx, eJob)
+	})
+	if err != nil {
+		return "", errors.Wrapf(err, "triggering errand")
+	}
+	return previousJob, nil
+}
+
+// waitForRun polls the errand's ExtendedJob until its last run matches
+func (r *Runner) waitForRun(ctx context.Context, key types.NamespacedName, match func(*ejv1.ExtendedJobRun) bool) (*ejv1.ExtendedJobRun, error) {
+	for {
+		eJob := &ejv1.ExtendedJob{}
+		err := r.client.Get(ctx, key, eJob)
+		if err != nil {
+			return nil, errors.Wrapf(err, "getting errand ExtendedJob '%s'", key)
+		}
+		if eJob.Status.LastRun != nil && match(eJob.Status.LastRun) {
+			return eJob.Status.LastRun, nil
+		}
+
+		if err := r.sleep(ctx); err != nil {
+			return nil, errors.Wrapf(err, "waiting for errand ExtendedJob '%s'", key)
+		}
+	}
+}
+
+// waitForPod polls the pods of the run's job, until one of them has been created.
+// It returns nil if the run is done without a pod, e.g. when its pod has been
+// deleted already.
+func (r *Runner) waitForPod(ctx context.Context, key types.NamespacedName, jobName string) (*corev1.Pod, error) {
+	for {
+		pods, err := r.jobPods(ctx, key.Namespace, jobName)
+		if err != nil {
+			return nil, err
+		}
+		if len(pods) > 0 {
+			return &pods[0], nil
+		}
+
+		eJob := &ejv1.ExtendedJob{}
+		err = r.client.Get(ctx, key, eJob)
+		if err != nil {
+			return nil, errors.Wrapf(err, "getting errand ExtendedJob '%s'", key)
+		}
+		if run := eJob.Status.LastRun; run != nil && run.Job == jobName && run.Done() {
+			return nil, nil
+		}
+
+		if err := r.sleep(ctx); err != nil {
+			return nil, errors.Wrapf(err, "waiting for pod of job '%s'", jobName)
+		}
+	}
+}
+
+// streamLogs writes the logs of all containers of the pod to out, until they
+// terminated. Each line is prefixed with the name of its container. The init
+// containers run one after the other, so are their logs.
+func (r *Runner) streamLogs(ctx context.Context, jobName string, pod *corev1.Pod) {
+	var mu sync.Mutex
+	for _, container := range pod.Spec.InitContainers {
+		if !r.streamContainerLogs(ctx, jobName, pod, container.Name, &mu) {
+			return
+		}
+	}
+
+	var wg sync.WaitGroup
+	for _, container := range pod.Spec.Containers {
+		wg.Add(1)
+		go func(containerName string) {
+			defer wg.Done()
+			r.streamContainerLogs(ctx, jobName, pod, containerName, &mu)
+		}(container.Name)
+	}
+	wg.Wait()
+}
+
+// streamContainerLogs waits for a container to start and writes its logs to out.
+// It returns false if the container didn't start, e.g. because an init container failed.
+func (r *Runner) streamContainerLogs(ctx context.Context, jobName string, pod *corev1.Pod, containerName string, mu *sync.Mutex) bool {
+	started, err := r.waitForContainer(ctx, jobName, pod, containerName)
+	if err != nil {
+		r.log.Errorf("Failed to wait for container '%s' of pod '%s': %v", containerName, pod.Name, err)
+		return false
+	}
+	if !started {
+		r.log.Infof("Not streaming logs, container '%s' of pod '%s' didn't start", containerName, pod.Name)
+		return false
+	}
+
+	stream, err := r.logs.Stream(pod.Namespace, pod.Name, containerName)
+	if err != nil {
+		r.log.Errorf("Failed to stream logs of container '%s' of pod '%s': %v", containerName, pod.Name, err)
+		return true
+	}
+	defer stream.Close()
+
+	scanner := bufio.NewScanner(stream)
+	for scanner.Scan() {
+		mu.Lock()
+		fmt.Fprintf(r.out, "[%s] %s\n", containerName, scanner.Text())
+		mu.Unlock()
+	}
+	if err := scanner.Err(); err != nil {
+		r.log.Errorf("Failed to read logs of container '%s' of pod '%s': %v", containerName, pod.Name, err)
+	}
+	return true
+}
+
+// waitForContainer polls the pod until the container is running or terminated. It
+// returns false if the pod is gone or has finished without starting the container.
+func (r *Runner) waitForContainer(ctx context.Context, jobName string, pod *corev1.Pod, containerName string) (bool, error) {
+	for {
+		pods, err := r.jobPods(ctx, pod.Namespace, jobName)
+		if err != nil {
+			return false, err
+		}
+
+		var current *corev1.Pod
+		for i := range pods {
+			if pods[i].Name == pod.Name {
+				current = &pods[i]
+			}
+		}
+		if current == nil {
+			return false, nil
+		}
+
+		statuses := append(current.Status.InitContainerStatuses, current.Status.ContainerStatuses...)
+		for _, status := range statuses {
+			if status.Name == containerName && (status.State.Running != nil || status.State.Terminated != nil) {
+				return true, nil
+			}
+		}
+		if current.Status.Phase == corev1.PodSucceeded || current.Status.Phase == corev1.PodFailed {
+			return false, nil
+		}
+
+		if err := r.sleep(ctx); err != nil {
+			return false, errors.Wrapf(err, "waiting for container '%s' of pod '%s'", containerName, pod.Name)
+		}
+	}
+}
+
+// jobPods lists the pods of a job
+func (r *Runner) jobPods(ctx context.Context, namespace, jobName string) ([]corev1.Pod, error) {
+	pods := &corev1.PodList{}
+	err := r.client.List(ctx, &client.ListOptions{
+		Namespace:     namespace,
+		LabelSelector: labels.SelectorFromSet(labels.Set{"job-name": jobName}),
+	}, pods)
+	if err != nil {
+		return nil, errors.Wrapf(err, "listing pods of job '%s'", jobName)
+	}
+	return pods.Items, nil
+}
+
+func (r *Runner) sleep(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(r.pollInterval):
+		return nil
+	}
+}
@@ -0,0 +1,183 @@
+package errand_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	crc "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"code.cloudfoundry.org/cf-operator/pkg/bosh/errand"
+	ejv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/extendedjob/v1alpha1"
+	cfakes "code.cloudfoundry.org/cf-operator/pkg/kube/controllers/fakes"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util"
+	helper "code.cloudfoundry.org/cf-operator/pkg/testhelper"
+)
+
+type fakeLogs struct {
+	logs     map[string]string
+	mu       sync.Mutex
+	streamed int
+}
+
+func (l *fakeLogs) Stream(namespace, podName, containerName string) (io.ReadCloser, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.streamed++
+	return ioutil.NopCloser(strings.NewReader(l.logs[containerName])), nil
+}
+
+var _ = Describe("Runner", func() {
+	var (
+		client *cfakes.FakeClient
+		logs   *fakeLogs
+		out    *bytes.Buffer
+		runner *errand.Runner
+		eJob   *ejv1.ExtendedJob
+		pods   []corev1.Pod
+		result ejv1.ExtendedJobRun
+	)
+
+	act := func(ctx context.Context) (int, error) {
+		return runner.Run(ctx, "default", "foo", "smoke-tests")
+	}
+
+	BeforeEach(func() {
+		eJob = &ejv1.ExtendedJob{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo-smoke-tests", Namespace: "default"},
+			Spec: ejv1.ExtendedJobSpec{
+				Trigger: ejv1.Trigger{Strategy: ejv1.TriggerManual},
+			},
+			Status: ejv1.ExtendedJobStatus{
+				LastRun: &ejv1.ExtendedJobRun{Job: "foo-smoke-tests-old", State: ejv1.RunStateSucceeded},
+			},
+		}
+		pods = []corev1.Pod{
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo-smoke-tests-new-abcde", Namespace: "default"},
+				Spec: corev1.PodSpec{
+					InitContainers: []corev1.Container{{Name: "setup"}},
+					Containers:     []corev1.Container{{Name: "smoke"}, {Name: "cleanup"}},
+				},
+				Status: corev1.PodStatus{
+					Phase: corev1.PodRunning,
+					InitContainerStatuses: []corev1.ContainerStatus{
+						{Name: "setup", State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{}}},
+					},
+					ContainerStatuses: []corev1.ContainerStatus{
+						{Name: "smoke", State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}},
+						{Name: "cleanup", State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}},
+					},
+				},
+			},
+		}
+		result = ejv1.ExtendedJobRun{Job: "foo-smoke-tests-new", State: ejv1.RunStateFailed, ExitCode: util.Int32(3)}
+		logs = &fakeLogs{logs: map[string]string{"setup": "setting up\n", "smoke": "running smoke tests\n", "cleanup": "cleaned up\n"}}
+		out = &bytes.Buffer{}
+
+		client = &cfakes.FakeClient{}
+		client.GetCalls(func(ctx context.Context, nn types.NamespacedName, object runtime.Object) error {
+			// The run is done once all logs have been streamed, or right away without pods
+			if eJob.Status.LastRun.Job == result.Job && (logs.streamed == 3 || len(pods) == 0 || pods[0].Status.Phase == corev1.PodFailed) {
+				eJob.Status.LastRun = result.DeepCopy()
+			}
+			eJob.DeepCopyInto(object.(*ejv1.ExtendedJob))
+			return nil
+		})
+		client.UpdateCalls(func(ctx context.Context, object runtime.Object) error {
+			// The errand reconciler creates the job and records the run
+			Expect(object.(*ejv1.ExtendedJob).Spec.Trigger.Strategy).To(Equal(ejv1.TriggerNow))
+			eJob.Status.LastRun = &ejv1.ExtendedJobRun{Job: "foo-smoke-tests-new", State: ejv1.RunStateRunning}
+			return nil
+		})
+		client.ListCalls(func(ctx context.Context, options *crc.ListOptions, object runtime.Object) error {
+			Expect(options.LabelSelector.String()).To(Equal("job-name=foo-smoke-tests-new"))
+			podList := corev1.PodList{Items: pods}
+			podList.DeepCopyInto(object.(*corev1.PodList))
+			return nil
+		})
+
+		_, log := helper.NewTestLogger()
+		runner = errand.NewRunner(log, client, logs, out, time.Millisecond)
+	})
+
+	It("triggers the errand, streams its logs and returns its exit code", func() {
+		exitCode, err := act(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(exitCode).To(Equal(3))
+
+		Expect(client.UpdateCallCount()).To(Equal(1))
+		Expect(out.String()).To(HavePrefix("[setup] setting up\n"))
+		Expect(out.String()).To(ContainSubstring("[smoke] running smoke tests\n"))
+		Expect(out.String()).To(ContainSubstring("[cleanup] cleaned up\n"))
+	})
+
+	It("only streams the logs of containers, which started", func() {
+		pods[0].Status = corev1.PodStatus{
+			Phase: corev1.PodFailed,
+			InitContainerStatuses: []corev1.ContainerStatus{
+				{Name: "setup", State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 3}}},
+			},
+		}
+
+		exitCode, err := act(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(exitCode).To(Equal(3))
+		Expect(out.String()).To(Equal("[setup] setting up\n"))
+	})
+
+	It("returns 0 when the errand succeeded", func() {
+		result = ejv1.ExtendedJobRun{Job: "foo-smoke-tests-new", State: ejv1.RunStateSucceeded, ExitCode: util.Int32(0)}
+
+		exitCode, err := act(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(exitCode).To(Equal(0))
+	})
+
+	It("returns the result when the pod of the errand is gone", func() {
+		pods = []corev1.Pod{}
+		result = ejv1.ExtendedJobRun{Job: "foo-smoke-tests-new", State: ejv1.RunStateFailed}
+
+		exitCode, err := act(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(exitCode).To(Equal(1))
+		Expect(out.String()).To(BeEmpty())
+	})
+
+	It("fails for ExtendedJobs, which are not manual errands", func() {
+		eJob.Spec.Trigger.Strategy = ejv1.TriggerOnce
+
+		_, err := act(context.Background())
+		Expect(err).To(MatchError(ContainSubstring("ExtendedJob 'default/foo-smoke-tests' is not a manual errand")))
+		Expect(client.UpdateCallCount()).To(Equal(0))
+	})
+
+	It("fails for unknown errands", func() {
+		client.GetReturns(apierrors.NewNotFound(schema.GroupResource{}, "foo-smoke-tests"))
+		client.GetCalls(nil)
+
+		_, err := act(context.Background())
+		Expect(err).To(MatchError(ContainSubstring("errand ExtendedJob 'default/foo-smoke-tests' not found")))
+	})
+
+	It("stops waiting for the run when the context is done", func() {
+		client.UpdateCalls(func(ctx context.Context, object runtime.Object) error { return nil })
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		_, err := act(ctx)
+		Expect(err).To(MatchError(ContainSubstring("waiting for errand ExtendedJob 'default/foo-smoke-tests'")))
+	})
+})
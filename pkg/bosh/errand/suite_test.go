@@ -0,0 +1,13 @@
+package errand_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestErrand(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Errand Suite")
+}
@@ -6,12 +6,21 @@ import (
 	"github.com/pkg/errors"
 )
 
+const (
+	// LifecycleService is the lifecycle of instance groups, which run continuously
+	LifecycleService = "service"
+	// LifecycleErrand is the lifecycle of instance groups, which run once
+	LifecycleErrand = "errand"
+)
+
+// matcher returns true if a placement rule matches the instance group. Rules,
+// which are not specified, match all instance groups.
 type matcher func(*InstanceGroup, *AddOnPlacementRules) (bool, error)
 
-// jobMatch matches stemcell rules for addon placement
+// stemcellMatch matches stemcell rules for addon placement
 func (m *Manifest) stemcellMatch(instanceGroup *InstanceGroup, rules *AddOnPlacementRules) (bool, error) {
-	if instanceGroup == nil || rules == nil {
-		return false, nil
+	if len(rules.Stemcell) == 0 {
+		return true, nil
 	}
 
 	osList := map[string]struct{}{}
@@ -36,8 +45,8 @@ func (m *Manifest) stemcellMatch(instanceGroup *InstanceGroup, rules *AddOnPlace
 
 // jobMatch matches job rules for addon placement
 func (m *Manifest) jobMatch(instanceGroup *InstanceGroup, rules *AddOnPlacementRules) (bool, error) {
-	if instanceGroup == nil || rules == nil {
-		return false, nil
+	if len(rules.Jobs) == 0 {
+		return true, nil
 	}
 
 	jobList := map[string]struct{}{}
@@ -59,12 +68,30 @@ func (m *Manifest) jobMatch(instanceGroup *InstanceGroup, rules *AddOnPlacementR
 
 // instanceGroupMatch matches instance group rules for addon placement
 func (m *Manifest) instanceGroupMatch(instanceGroup *InstanceGroup, rules *AddOnPlacementRules) (bool, error) {
-	if instanceGroup == nil || rules == nil {
-		return false, nil
+	if len(rules.InstanceGroup) == 0 {
+		return true, nil
+	}
+
+	return contains(rules.InstanceGroup, instanceGroup.Name), nil
+}
+
+// deploymentMatch matches deployment rules for addon placement
+func (m *Manifest) deploymentMatch(instanceGroup *InstanceGroup, rules *AddOnPlacementRules) (bool, error) {
+	if len(rules.Deployments) == 0 {
+		return true, nil
 	}
 
-	for _, ig := range rules.InstanceGroup {
-		if ig == instanceGroup.Name {
+	return contains(rules.Deployments, m.Name), nil
+}
+
+// networkMatch matches network rules for addon placement
+func (m *Manifest) networkMatch(instanceGroup *InstanceGroup, rules *AddOnPlacementRules) (bool, error) {
+	if len(rules.Networks) == 0 {
+		return true, nil
+	}
+
+	for _, network := range instanceGroup.Networks {
+		if contains(rules.Networks, network.Name) {
 			return true, nil
 		}
 	}
@@ -72,24 +99,75 @@ func (m *Manifest) instanceGroupMatch(instanceGroup *InstanceGroup, rules *AddOn
 	return false, nil
 }
 
-// addOnPlacementMatch returns true if any placement rule of the addon matches the instance group
+// lifecycleMatch matches lifecycle rules for addon placement. Instance groups
+// without a lifecycle are services.
+func (m *Manifest) lifecycleMatch(instanceGroup *InstanceGroup, rules *AddOnPlacementRules) (bool, error) {
+	if rules.Lifecycle == "" {
+		return true, nil
+	}
+
+	if rules.Lifecycle != LifecycleService && rules.Lifecycle != LifecycleErrand {
+		return false, fmt.Errorf("invalid lifecycle '%s', must be '%s' or '%s'", rules.Lifecycle, LifecycleService, LifecycleErrand)
+	}
+
+	lifecycle := instanceGroup.LifeCycle
+	if lifecycle == "" {
+		lifecycle = LifecycleService
+	}
+
+	return lifecycle == rules.Lifecycle, nil
+}
+
+// teamMatch skips team rules, BOSHDeployments don't belong to teams
+func (m *Manifest) teamMatch(instanceGroup *InstanceGroup, rules *AddOnPlacementRules) (bool, error) {
+	return true, nil
+}
+
+// isEmpty returns true if no placement rule is specified. Team rules are
+// skipped, so they don't count.
+func (r *AddOnPlacementRules) isEmpty() bool {
+	return len(r.Stemcell) == 0 &&
+		len(r.Deployments) == 0 &&
+		len(r.Jobs) == 0 &&
+		len(r.InstanceGroup) == 0 &&
+		len(r.Networks) == 0 &&
+		r.Lifecycle == ""
+}
+
+// onlyTeams returns true if team rules are the only placement rules specified.
+// They can't match, as BOSHDeployments don't belong to teams.
+func (r *AddOnPlacementRules) onlyTeams() bool {
+	return len(r.Teams) > 0 && r.isEmpty()
+}
+
+// addOnPlacementMatch returns true if all placement rules of the addon, which
+// are specified, match the instance group. Like in BOSH, a rule matches if any
+// of its values matches. Returns false if no rule is specified.
 func (m *Manifest) addOnPlacementMatch(instanceGroup *InstanceGroup, rules *AddOnPlacementRules) (bool, error) {
+	if instanceGroup == nil || rules == nil || rules.isEmpty() {
+		return false, nil
+	}
+
 	matchers := []matcher{
-		m.stemcellMatch,
-		m.jobMatch,
+		m.teamMatch,
+		m.lifecycleMatch,
+		m.deploymentMatch,
 		m.instanceGroupMatch,
+		m.networkMatch,
+		m.jobMatch,
+		m.stemcellMatch,
 	}
 
-	matchResult := false
-
 	for _, matcher := range matchers {
 		matched, err := matcher(instanceGroup, rules)
 		if err != nil {
 			return false, errors.Wrap(err, "failed to process match")
 		}
 
-		matchResult = matchResult || matched
+		if !matched {
+			return false, nil
+		}
 	}
 
-	return matchResult, nil
+	return true, nil
 }
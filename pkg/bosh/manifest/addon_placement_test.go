@@ -2,6 +2,7 @@ package manifest_test
 
 import (
 	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
 	. "github.com/onsi/gomega"
 
 	. "code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
@@ -26,4 +27,130 @@ var _ = Describe("Addons", func() {
 		Expect(manifest.InstanceGroups[1].Jobs[0].Name).To(Equal("cflinuxfs3-rootfs-setup"))
 		Expect(manifest.InstanceGroups[1].Jobs[1].Name).To(Equal("addon-job"))
 	})
+
+	It("should add addons without include rules to all instance groups, unless the deployment is excluded", func() {
+		manifest, err := LoadYAML([]byte(boshmanifest.WithAddons))
+		Expect(err).NotTo(HaveOccurred())
+
+		manifest.AddOns = []*AddOn{{
+			Name: "everywhere",
+			Jobs: []AddOnJob{{Name: "everywhere-job", Release: "redis"}},
+		}}
+		Expect(manifest.ApplyAddons()).To(Succeed())
+		Expect(manifest.InstanceGroups[0].Jobs).To(HaveLen(2))
+		Expect(manifest.InstanceGroups[1].Jobs).To(HaveLen(2))
+
+		manifest, err = LoadYAML([]byte(boshmanifest.WithAddons))
+		Expect(err).NotTo(HaveOccurred())
+
+		manifest.AddOns = []*AddOn{{
+			Name:    "everywhere",
+			Jobs:    []AddOnJob{{Name: "everywhere-job", Release: "redis"}},
+			Exclude: &AddOnPlacementRules{Deployments: []string{manifest.Name}},
+		}}
+		Expect(manifest.ApplyAddons()).To(Succeed())
+		Expect(manifest.InstanceGroups[0].Jobs).To(HaveLen(1))
+		Expect(manifest.InstanceGroups[1].Jobs).To(HaveLen(1))
+	})
+
+	Describe("placement rules", func() {
+		// matchedInstanceGroups returns the names of the instance groups, which get the addon job
+		matchedInstanceGroups := func(include *AddOnPlacementRules, exclude *AddOnPlacementRules) ([]string, error) {
+			manifest, err := LoadYAML([]byte(boshmanifest.WithAddons))
+			Expect(err).NotTo(HaveOccurred())
+
+			manifest.AddOns = []*AddOn{{
+				Name:    "placed",
+				Jobs:    []AddOnJob{{Name: "placed-job", Release: "redis"}},
+				Include: include,
+				Exclude: exclude,
+			}}
+			err = manifest.ApplyAddons()
+			if err != nil {
+				return nil, err
+			}
+
+			matched := []string{}
+			for _, ig := range manifest.InstanceGroups {
+				if ig.Jobs[len(ig.Jobs)-1].Name == "placed-job" {
+					matched = append(matched, ig.Name)
+				}
+			}
+			return matched, nil
+		}
+
+		DescribeTable("matches instance groups",
+			func(include *AddOnPlacementRules, exclude *AddOnPlacementRules, expected []string) {
+				matched, err := matchedInstanceGroups(include, exclude)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(matched).To(Equal(expected))
+			},
+			Entry("without rules", nil, nil, []string{"redis-slave", "diego-cell"}),
+			Entry("with empty rules", &AddOnPlacementRules{}, &AddOnPlacementRules{}, []string{"redis-slave", "diego-cell"}),
+			Entry("by deployment",
+				&AddOnPlacementRules{Deployments: []string{"bar-deployment", "foo-deployment"}}, nil,
+				[]string{"redis-slave", "diego-cell"}),
+			Entry("by excluded deployment",
+				nil, &AddOnPlacementRules{Deployments: []string{"foo-deployment"}},
+				[]string{}),
+			Entry("by network",
+				&AddOnPlacementRules{Networks: []string{"default"}}, nil,
+				[]string{"redis-slave", "diego-cell"}),
+			Entry("by other network",
+				&AddOnPlacementRules{Networks: []string{"private"}}, nil,
+				[]string{}),
+			Entry("by errand lifecycle",
+				&AddOnPlacementRules{Lifecycle: LifecycleErrand}, nil,
+				[]string{"redis-slave"}),
+			Entry("by excluded service lifecycle",
+				nil, &AddOnPlacementRules{Lifecycle: LifecycleService},
+				[]string{"redis-slave"}),
+			Entry("by all rules of include",
+				&AddOnPlacementRules{
+					Stemcell: []*AddOnStemcell{{OS: "opensuse-42.3"}},
+					Jobs:     []*AddOnPlacementJob{{Name: "redis-server", Release: "redis"}},
+				}, nil,
+				[]string{"redis-slave"}),
+			Entry("by all rules of exclude",
+				nil, &AddOnPlacementRules{
+					Deployments:   []string{"foo-deployment"},
+					InstanceGroup: []string{"diego-cell"},
+				},
+				[]string{"redis-slave"}),
+			Entry("by any value of a rule",
+				&AddOnPlacementRules{
+					Jobs: []*AddOnPlacementJob{
+						{Name: "redis-server", Release: "redis"},
+						{Name: "cflinuxfs3-rootfs-setup", Release: "cflinuxfs3"},
+					},
+				}, nil,
+				[]string{"redis-slave", "diego-cell"}),
+		)
+
+		DescribeTable("skips team rules, which can't be evaluated",
+			func(include *AddOnPlacementRules, exclude *AddOnPlacementRules, expected []string) {
+				matched, err := matchedInstanceGroups(include, exclude)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(matched).To(Equal(expected))
+			},
+			Entry("next to other include rules",
+				&AddOnPlacementRules{Teams: []string{"platform"}, InstanceGroup: []string{"diego-cell"}}, nil,
+				[]string{"diego-cell"}),
+			Entry("in exclude rules",
+				nil, &AddOnPlacementRules{Teams: []string{"platform"}},
+				[]string{"redis-slave", "diego-cell"}),
+		)
+
+		It("doesn't add addons, whose include rules only have team rules", func() {
+			matched, err := matchedInstanceGroups(&AddOnPlacementRules{Teams: []string{"platform"}}, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(matched).To(BeEmpty())
+		})
+
+		It("reports invalid lifecycles", func() {
+			_, err := matchedInstanceGroups(nil, &AddOnPlacementRules{Lifecycle: "daemon"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("invalid lifecycle 'daemon'"))
+		})
+	})
 })
@@ -0,0 +1,263 @@
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+)
+
+// CloudConfig is a BOSH cloud config. Its cloud properties describe how the
+// vm types, vm extensions and disk types of instance groups map to kube resources.
+type CloudConfig struct {
+	VMTypes      []*VMType      `json:"vm_types,omitempty" yaml:"vm_types,omitempty"`
+	VMExtensions []*VMExtension `json:"vm_extensions,omitempty" yaml:"vm_extensions,omitempty"`
+	DiskTypes    []*DiskType    `json:"disk_types,omitempty" yaml:"disk_types,omitempty"`
+}
+
+// VMType from BOSH cloud config
+type VMType struct {
+	Name            string                `json:"name" yaml:"name"`
+	CloudProperties VMTypeCloudProperties `json:"cloud_properties,omitempty" yaml:"cloud_properties,omitempty"`
+}
+
+// VMTypeCloudProperties are the resources of an instance group's pods and
+// the nodes they are scheduled to. The resources are used like vm_resources.
+type VMTypeCloudProperties struct {
+	CPU               int                 `json:"cpu,omitempty" yaml:"cpu,omitempty"`
+	RAM               int                 `json:"ram,omitempty" yaml:"ram,omitempty"`
+	EphemeralDiskSize int                 `json:"ephemeral_disk_size,omitempty" yaml:"ephemeral_disk_size,omitempty"`
+	NodeSelector      map[string]string   `json:"node_selector,omitempty" yaml:"node_selector,omitempty"`
+	Tolerations       []corev1.Toleration `json:"tolerations,omitempty" yaml:"tolerations,omitempty"`
+}
+
+// VMExtension from BOSH cloud config
+type VMExtension struct {
+	Name            string                     `json:"name" yaml:"name"`
+	CloudProperties VMExtensionCloudProperties `json:"cloud_properties,omitempty" yaml:"cloud_properties,omitempty"`
+}
+
+// VMExtensionCloudProperties are strategic merge patches. Pod patches the pod
+// template of an instance group. Service patches an additional service, which
+// exposes the ports of all instances of the instance group.
+type VMExtensionCloudProperties struct {
+	Pod     map[string]interface{} `json:"pod,omitempty" yaml:"pod,omitempty"`
+	Service map[string]interface{} `json:"service,omitempty" yaml:"service,omitempty"`
+}
+
+// DiskType from BOSH cloud config
+type DiskType struct {
+	Name            string                  `json:"name" yaml:"name"`
+	DiskSize        int                     `json:"disk_size" yaml:"disk_size"`
+	CloudProperties DiskTypeCloudProperties `json:"cloud_properties,omitempty" yaml:"cloud_properties,omitempty"`
+}
+
+// DiskTypeCloudProperties contains the storage class of persistent volume claims
+type DiskTypeCloudProperties struct {
+	StorageClass string `json:"storage_class,omitempty" yaml:"storage_class,omitempty"`
+}
+
+// LoadCloudConfigYAML returns a new BOSH cloud config from a yaml representation.
+// Like the kube specific parts of the manifest, the cloud properties use
+// the field names of their k8s structures.
+func LoadCloudConfigYAML(data []byte) (*CloudConfig, error) {
+	cc := &CloudConfig{}
+	err := yaml.Unmarshal(data, cc)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal BOSH cloud config %s", string(data))
+	}
+	return cc, nil
+}
+
+// ApplyCloudConfig adds the vm types, vm extensions and disk types of the
+// cloud config to the manifest. Like in BOSH, names have to be unique
+// across cloud configs.
+func (m *Manifest) ApplyCloudConfig(cc *CloudConfig) error {
+	if m.CloudConfig == nil {
+		m.CloudConfig = &CloudConfig{}
+	}
+
+	for _, vmType := range cc.VMTypes {
+		if m.CloudConfig.vmType(vmType.Name) != nil {
+			return fmt.Errorf("vm type '%s' is defined more than once", vmType.Name)
+		}
+		m.CloudConfig.VMTypes = append(m.CloudConfig.VMTypes, vmType)
+	}
+
+	for _, vmExtension := range cc.VMExtensions {
+		if m.CloudConfig.vmExtension(vmExtension.Name) != nil {
+			return fmt.Errorf("vm extension '%s' is defined more than once", vmExtension.Name)
+		}
+		m.CloudConfig.VMExtensions = append(m.CloudConfig.VMExtensions, vmExtension)
+	}
+
+	for _, diskType := range cc.DiskTypes {
+		if m.CloudConfig.diskType(diskType.Name) != nil {
+			return fmt.Errorf("disk type '%s' is defined more than once", diskType.Name)
+		}
+		m.CloudConfig.DiskTypes = append(m.CloudConfig.DiskTypes, diskType)
+	}
+
+	return nil
+}
+
+func (cc *CloudConfig) vmType(name string) *VMType {
+	for _, vmType := range cc.VMTypes {
+		if vmType.Name == name {
+			return vmType
+		}
+	}
+	return nil
+}
+
+func (cc *CloudConfig) vmExtension(name string) *VMExtension {
+	for _, vmExtension := range cc.VMExtensions {
+		if vmExtension.Name == name {
+			return vmExtension
+		}
+	}
+	return nil
+}
+
+func (cc *CloudConfig) diskType(name string) *DiskType {
+	for _, diskType := range cc.DiskTypes {
+		if diskType.Name == name {
+			return diskType
+		}
+	}
+	return nil
+}
+
+// instanceGroupCloudConfig contains the entries of the cloud config, which
+// an instance group references
+type instanceGroupCloudConfig struct {
+	vmType       *VMType
+	vmExtensions []*VMExtension
+	diskType     *DiskType
+}
+
+// instanceGroupCloudConfig looks up the entries referenced by the instance group.
+// Without a cloud config the references are ignored, except for the persistent
+// disk type, which is used as storage class.
+func (cc *CloudConfig) instanceGroupCloudConfig(instanceGroup *InstanceGroup) (*instanceGroupCloudConfig, error) {
+	igcc := &instanceGroupCloudConfig{}
+	if cc == nil {
+		return igcc, nil
+	}
+
+	if instanceGroup.VMType != "" {
+		igcc.vmType = cc.vmType(instanceGroup.VMType)
+		if igcc.vmType == nil {
+			return nil, fmt.Errorf("vm type '%s' not found in cloud config", instanceGroup.VMType)
+		}
+	}
+
+	for _, name := range instanceGroup.VMExtensions {
+		vmExtension := cc.vmExtension(name)
+		if vmExtension == nil {
+			return nil, fmt.Errorf("vm extension '%s' not found in cloud config", name)
+		}
+		igcc.vmExtensions = append(igcc.vmExtensions, vmExtension)
+	}
+
+	if instanceGroup.PersistentDiskType != "" {
+		igcc.diskType = cc.diskType(instanceGroup.PersistentDiskType)
+		if igcc.diskType == nil {
+			return nil, fmt.Errorf("disk type '%s' not found in cloud config", instanceGroup.PersistentDiskType)
+		}
+	}
+
+	return igcc, nil
+}
+
+// apply sets the vm resources and the persistent disk of the instance group,
+// unless the instance group specifies them itself
+func (igcc *instanceGroupCloudConfig) apply(instanceGroup *InstanceGroup) {
+	if vmType := igcc.vmType; vmType != nil && instanceGroup.VMResources == nil {
+		props := vmType.CloudProperties
+		if props.CPU > 0 || props.RAM > 0 || props.EphemeralDiskSize > 0 {
+			instanceGroup.VMResources = &VMResource{
+				CPU:               props.CPU,
+				RAM:               props.RAM,
+				EphemeralDiskSize: props.EphemeralDiskSize,
+			}
+		}
+	}
+
+	if diskType := igcc.diskType; diskType != nil && instanceGroup.PersistentDisk == nil && diskType.DiskSize > 0 {
+		size := diskType.DiskSize
+		instanceGroup.PersistentDisk = &size
+	}
+}
+
+// storageClass returns the storage class for the persistent disk of the instance group
+func (igcc *instanceGroupCloudConfig) storageClass(instanceGroup *InstanceGroup) string {
+	if igcc.diskType != nil {
+		return igcc.diskType.CloudProperties.StorageClass
+	}
+	return instanceGroup.PersistentDiskType
+}
+
+// patchPodTemplate adds the node selector and tolerations of the vm type and
+// applies the pod patches of the vm extensions
+func (igcc *instanceGroupCloudConfig) patchPodTemplate(template *corev1.PodTemplateSpec) error {
+	if vmType := igcc.vmType; vmType != nil {
+		if len(vmType.CloudProperties.NodeSelector) > 0 {
+			if template.Spec.NodeSelector == nil {
+				template.Spec.NodeSelector = map[string]string{}
+			}
+			for key, value := range vmType.CloudProperties.NodeSelector {
+				template.Spec.NodeSelector[key] = value
+			}
+		}
+		template.Spec.Tolerations = append(template.Spec.Tolerations, vmType.CloudProperties.Tolerations...)
+	}
+
+	for _, vmExtension := range igcc.vmExtensions {
+		if len(vmExtension.CloudProperties.Pod) == 0 {
+			continue
+		}
+
+		patched := corev1.PodTemplateSpec{}
+		if err := strategicMergePatch(template, vmExtension.CloudProperties.Pod, &patched); err != nil {
+			return errors.Wrapf(err, "failed to apply pod patch of vm extension '%s'", vmExtension.Name)
+		}
+		*template = patched
+	}
+
+	return nil
+}
+
+// patchService applies the service patch of a vm extension
+func patchService(service *corev1.Service, vmExtension *VMExtension) error {
+	patched := corev1.Service{}
+	if err := strategicMergePatch(service, vmExtension.CloudProperties.Service, &patched); err != nil {
+		return errors.Wrapf(err, "failed to apply service patch of vm extension '%s'", vmExtension.Name)
+	}
+	*service = patched
+	return nil
+}
+
+// strategicMergePatch applies a patch to the original kube object and stores
+// the result in patched, which has to be a pointer to the same type
+func strategicMergePatch(original interface{}, patch map[string]interface{}, patched interface{}) error {
+	originalJSON, err := json.Marshal(original)
+	if err != nil {
+		return err
+	}
+
+	patchJSON, err := json.Marshal(patch)
+	if err != nil {
+		return err
+	}
+
+	patchedJSON, err := strategicpatch.StrategicMergePatch(originalJSON, patchJSON, patched)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(patchedJSON, patched)
+}
@@ -0,0 +1,183 @@
+package manifest
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SupportLevel describes how the operator supports a BOSH manifest feature
+type SupportLevel string
+
+const (
+	// SupportIgnored is reported for fields, which are parsed but have no effect
+	SupportIgnored SupportLevel = "ignored"
+	// SupportPartial is reported for fields, which only have some of their BOSH effects
+	SupportPartial SupportLevel = "partial"
+	// SupportEmulated is reported for fields, which are implemented with kube
+	// resources and behave differently than on BOSH VMs
+	SupportEmulated SupportLevel = "emulated"
+)
+
+// CompatibilityFinding is a field of the manifest, which doesn't behave like it does on BOSH.
+// The path of the field uses the syntax of ops files.
+type CompatibilityFinding struct {
+	Path    string       `yaml:"path" json:"path"`
+	Support SupportLevel `yaml:"support" json:"support"`
+	Message string       `yaml:"message" json:"message"`
+}
+
+// String returns a human readable description of the finding
+func (f CompatibilityFinding) String() string {
+	return fmt.Sprintf("%s is %s: %s", f.Path, f.Support, f.Message)
+}
+
+// CompatibilityReport lists the fields of a manifest, which are not fully supported
+type CompatibilityReport []CompatibilityFinding
+
+// String returns the descriptions of all findings
+func (report CompatibilityReport) String() string {
+	descriptions := make([]string, len(report))
+	for i, f := range report {
+		descriptions[i] = f.String()
+	}
+	return strings.Join(descriptions, "; ")
+}
+
+func (report *CompatibilityReport) add(support SupportLevel, message string, path ...string) {
+	*report = append(*report, CompatibilityFinding{
+		Path:    "/" + strings.Join(path, "/"),
+		Support: support,
+		Message: message,
+	})
+}
+
+// Compatibility returns the fields of the manifest, which are ignored, only partially
+// supported or emulated, in manifest order. Settings of the update block, which
+// instance groups inherit, are only reported for the update block.
+func (m *Manifest) Compatibility() CompatibilityReport {
+	report := CompatibilityReport{}
+
+	if m.DirectorUUID != "" {
+		report.add(SupportIgnored, "deployments are not managed by a BOSH director", "director_uuid")
+	}
+	if m.Features != nil {
+		if m.Features.RandomizeAzPlacement != nil {
+			report.add(SupportIgnored, "instances are always placed round robin across the AZs", "features", "randomize_az_placement")
+		}
+		if m.Features.ConvergeVariables {
+			report.add(SupportIgnored, "changed variables are applied to all instance groups", "features", "converge_variables")
+		}
+		if m.Features.UseTmpfsJobConfig != nil {
+			report.add(SupportIgnored, "rendered job templates are stored in volumes of the pods", "features", "use_tmpfs_job_config")
+		}
+	}
+	if len(m.Tags) > 0 {
+		report.add(SupportIgnored, "tags are not added to the kube resources, use env.bosh.agent.settings.labels of the instance groups", "tags")
+	}
+	if len(m.Properties) > 0 {
+		report.add(SupportIgnored, "global properties are not merged into job properties", "properties")
+	}
+
+	for _, release := range m.Releases {
+		if release.SHA1 != "" {
+			report.add(SupportIgnored, "release images are not verified, use digest to pin the image", "releases", "name="+release.Name, "sha1")
+		}
+	}
+	for _, stemcell := range m.Stemcells {
+		if stemcell.Name != "" {
+			report.add(SupportIgnored, "the image tags of releases are built from the os and version of the stemcell", "stemcells", "alias="+stemcell.Alias, "name")
+		}
+	}
+
+	for _, addon := range m.AddOns {
+		if addon.Include != nil && addon.Include.onlyTeams() {
+			report.add(SupportIgnored, "deployments have no teams, the addon is not added to any instance group", "addons", "name="+addon.Name, "include", "teams")
+		} else if addon.Include != nil && len(addon.Include.Teams) > 0 {
+			report.add(SupportIgnored, "deployments have no teams, the rule is skipped", "addons", "name="+addon.Name, "include", "teams")
+		}
+		if addon.Exclude != nil && len(addon.Exclude.Teams) > 0 {
+			report.add(SupportIgnored, "deployments have no teams, the rule is skipped", "addons", "name="+addon.Name, "exclude", "teams")
+		}
+	}
+
+	if m.Update != nil && m.Update.VMStrategy != nil {
+		report.add(SupportIgnored, "pods are always recreated", "update", "vm_strategy")
+	}
+
+	for _, ig := range m.InstanceGroups {
+		report = append(report, m.instanceGroupCompatibility(ig)...)
+	}
+
+	return report
+}
+
+func (m *Manifest) instanceGroupCompatibility(ig *InstanceGroup) CompatibilityReport {
+	report := CompatibilityReport{}
+	igPath := []string{"instance_groups", "name=" + ig.Name}
+	add := func(support SupportLevel, message string, path ...string) {
+		report.add(support, message, append(append([]string{}, igPath...), path...)...)
+	}
+
+	if len(ig.AZs) > 0 {
+		add(SupportEmulated, "each AZ is a StatefulSet, whose pods are scheduled to nodes with the zone label of the AZ", "azs")
+	}
+	if ig.LifeCycle == LifecycleErrand {
+		add(SupportEmulated, "errands are ExtendedJobs, which only run when triggered", "lifecycle")
+	}
+	if len(ig.MigratedFrom) > 0 {
+		add(SupportIgnored, "instance groups are not renamed, the migrated instances are deleted", "migrated_from")
+	}
+	if len(ig.Properties) > 0 {
+		add(SupportIgnored, "instance group properties are not merged into job properties", "properties")
+	}
+
+	for _, network := range ig.Networks {
+		networkPath := "name=" + network.Name
+		if len(network.StaticIps) > 0 {
+			add(SupportIgnored, "pods get their IPs from the cluster network, use the service names of the instances", "networks", networkPath, "static_ips")
+		}
+		if len(network.Default) > 0 {
+			add(SupportIgnored, "pods only have the cluster network", "networks", networkPath, "default")
+		}
+	}
+
+	if ig.Update != nil && ig.Update.VMStrategy != nil && (m.Update == nil || ig.Update.VMStrategy != m.Update.VMStrategy) {
+		add(SupportIgnored, "pods are always recreated", "update", "vm_strategy")
+	}
+
+	env := ig.Env
+	if env.PersistentDiskFS != "" {
+		add(SupportIgnored, "the file system of persistent disks is chosen by the storage class", "env", "persistent_disk_fs")
+	}
+	if len(env.PersistentDiskMountOptions) > 0 {
+		add(SupportIgnored, "the mount options of persistent disks are chosen by the storage class", "env", "persistent_disk_mount_options")
+	}
+
+	boshEnv := env.AgentEnvBoshConfig
+	if boshEnv.Password != "" {
+		add(SupportIgnored, "containers have no login password", "env", "bosh", "password")
+	}
+	if boshEnv.KeepRootPassword != "" {
+		add(SupportIgnored, "containers have no login password", "env", "bosh", "keep_root_password")
+	}
+	if boshEnv.RemoveDevTools != nil {
+		add(SupportIgnored, "the contents of the release images are not changed", "env", "bosh", "remove_dev_tools")
+	}
+	if boshEnv.RemoveStaticLibraries != nil {
+		add(SupportIgnored, "the contents of the release images are not changed", "env", "bosh", "remove_static_libraries")
+	}
+	if boshEnv.SwapSize != nil {
+		add(SupportIgnored, "swap is configured on the nodes", "env", "bosh", "swap_size")
+	}
+	if boshEnv.IPv6.Enable {
+		add(SupportIgnored, "IPv6 is configured on the cluster network", "env", "bosh", "ipv6")
+	}
+	if boshEnv.JobDir != nil {
+		add(SupportIgnored, "job directories are volumes of the pods", "env", "bosh", "job_dir")
+	}
+	if boshEnv.Agent.Tmpfs != nil {
+		add(SupportIgnored, "job directories are volumes of the pods", "env", "bosh", "agent", "tmpfs")
+	}
+
+	return report
+}
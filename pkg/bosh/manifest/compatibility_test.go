@@ -0,0 +1,118 @@
+package manifest_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
+)
+
+var _ = Describe("Compatibility", func() {
+	var m *Manifest
+
+	BeforeEach(func() {
+		var err error
+		m, err = LoadYAML([]byte(`
+name: foo
+director_uuid: some-uuid
+features:
+  randomize_az_placement: true
+tags:
+  team: foo
+releases:
+- name: bar
+  version: "1.0"
+  sha1: some-sha1
+addons:
+- name: addon
+  jobs:
+  - name: baz
+    release: bar
+  include:
+    teams: [platform]
+- name: scoped
+  jobs:
+  - name: baz
+    release: bar
+  include:
+    teams: [platform]
+    instance_groups: [web]
+update:
+  canaries: 1
+  max_in_flight: 1
+  vm_strategy: create-swap-delete
+instance_groups:
+- name: web
+  instances: 2
+  azs: [z1, z2]
+  migrated_from:
+  - name: old-web
+  networks:
+  - name: default
+    static_ips: [10.0.0.1, 10.0.0.2]
+  jobs:
+  - name: baz
+    release: bar
+  env:
+    bosh:
+      swap_size: 0
+      remove_dev_tools: true
+- name: smoke-tests
+  instances: 1
+  lifecycle: errand
+  update:
+    vm_strategy: delete-create
+  jobs:
+  - name: baz
+    release: bar
+`))
+		Expect(err).ToNot(HaveOccurred())
+		m.ApplyUpdateBlock()
+	})
+
+	It("reports unsupported fields in manifest order", func() {
+		Expect(m.Compatibility()).To(Equal(CompatibilityReport{
+			{Path: "/director_uuid", Support: SupportIgnored, Message: "deployments are not managed by a BOSH director"},
+			{Path: "/features/randomize_az_placement", Support: SupportIgnored, Message: "instances are always placed round robin across the AZs"},
+			{Path: "/tags", Support: SupportIgnored, Message: "tags are not added to the kube resources, use env.bosh.agent.settings.labels of the instance groups"},
+			{Path: "/releases/name=bar/sha1", Support: SupportIgnored, Message: "release images are not verified, use digest to pin the image"},
+			{Path: "/addons/name=addon/include/teams", Support: SupportIgnored, Message: "deployments have no teams, the addon is not added to any instance group"},
+			{Path: "/addons/name=scoped/include/teams", Support: SupportIgnored, Message: "deployments have no teams, the rule is skipped"},
+			{Path: "/update/vm_strategy", Support: SupportIgnored, Message: "pods are always recreated"},
+			{Path: "/instance_groups/name=web/azs", Support: SupportEmulated, Message: "each AZ is a StatefulSet, whose pods are scheduled to nodes with the zone label of the AZ"},
+			{Path: "/instance_groups/name=web/migrated_from", Support: SupportIgnored, Message: "instance groups are not renamed, the migrated instances are deleted"},
+			{Path: "/instance_groups/name=web/networks/name=default/static_ips", Support: SupportIgnored, Message: "pods get their IPs from the cluster network, use the service names of the instances"},
+			{Path: "/instance_groups/name=web/env/bosh/remove_dev_tools", Support: SupportIgnored, Message: "the contents of the release images are not changed"},
+			{Path: "/instance_groups/name=web/env/bosh/swap_size", Support: SupportIgnored, Message: "swap is configured on the nodes"},
+			{Path: "/instance_groups/name=smoke-tests/lifecycle", Support: SupportEmulated, Message: "errands are ExtendedJobs, which only run when triggered"},
+			{Path: "/instance_groups/name=smoke-tests/update/vm_strategy", Support: SupportIgnored, Message: "pods are always recreated"},
+		}))
+	})
+
+	It("has no findings for supported fields", func() {
+		m, err := LoadYAML([]byte(`
+name: foo
+releases:
+- name: bar
+  version: "1.0"
+update:
+  canaries: 1
+  max_in_flight: 1
+instance_groups:
+- name: web
+  instances: 2
+  networks:
+  - name: default
+  jobs:
+  - name: baz
+    release: bar
+`))
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(m.Compatibility()).To(BeEmpty())
+	})
+
+	It("describes a finding with its path", func() {
+		Expect(m.Compatibility()[0].String()).To(Equal("/director_uuid is ignored: deployments are not managed by a BOSH director"))
+	})
+})
@@ -3,19 +3,32 @@ package manifest
 import (
 	"fmt"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"unicode"
 
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/intstr"
 
 	"code.cloudfoundry.org/cf-operator/pkg/bosh/bpm"
 	bc "code.cloudfoundry.org/cf-operator/pkg/bosh/manifest/containerization"
+	"code.cloudfoundry.org/cf-operator/pkg/bosh/monit"
 	"code.cloudfoundry.org/cf-operator/pkg/kube/util/names"
 )
 
 const (
 	// EnvJobsDir is a key for the container Env used to lookup the jobs dir
 	EnvJobsDir = "JOBS_DIR"
+	// DrainRunnerPath is where the operator binary is copied to, so the BPM
+	// process containers can run it to drain their jobs
+	DrainRunnerPath = VolumeSysDirMountPath + "/bin/cf-operator"
+	// bpmPidDir is where BPM writes the pidfiles of the processes on a VM
+	bpmPidDir = VolumeSysDirMountPath + "/run/bpm"
+	// linkAddressesWatchInterval is the interval to check the addresses of
+	// link instances at, if DNS addresses are not used
+	linkAddressesWatchInterval = "10s"
 )
 
 // ContainerFactory builds Kubernetes containers from BOSH jobs
@@ -44,24 +57,20 @@ func (c *ContainerFactory) JobsToInitContainers(
 	defaultVolumeMounts []corev1.VolumeMount,
 	bpmDisks BPMResourceDisks,
 ) ([]corev1.Container, error) {
-	copyingSpecsInitContainers := make([]corev1.Container, 0)
+	renderingInitContainers, err := c.JobsToRenderingContainers(jobs)
+	if err != nil {
+		return []corev1.Container{}, err
+	}
+
 	boshPreStartInitContainers := make([]corev1.Container, 0)
 	bpmPreStartInitContainers := make([]corev1.Container, 0)
 
-	copyingSpecsUniq := map[string]struct{}{}
 	for _, job := range jobs {
 		jobImage, err := c.releaseImageProvider.GetReleaseImage(c.instanceGroupName, job.Name)
 		if err != nil {
 			return []corev1.Container{}, err
 		}
 
-		// One copying specs init container for each release.
-		if _, done := copyingSpecsUniq[job.Release]; !done {
-			copyingSpecsUniq[job.Release] = struct{}{}
-			copyingSpecsInitContainer := jobSpecCopierContainer(job.Release, jobImage, VolumeRenderingDataName)
-			copyingSpecsInitContainers = append(copyingSpecsInitContainers, copyingSpecsInitContainer)
-		}
-
 		// Setup the BPM pre-start init containers before the BOSH pre-start init container in order to
 		// collect all the extra BPM volumes and pass them to the BOSH pre-start init container.
 		bpmConfig, ok := c.bpmConfigs[job.Name]
@@ -116,6 +125,35 @@ func (c *ContainerFactory) JobsToInitContainers(
 		boshPreStartInitContainers = append(boshPreStartInitContainers, *boshPreStartInitContainer.DeepCopy())
 	}
 
+	initContainers := flattenContainers(
+		renderingInitContainers,
+		boshPreStartInitContainers,
+		bpmPreStartInitContainers,
+	)
+
+	return initContainers, nil
+}
+
+// JobsToRenderingContainers creates the init containers, which copy the job specs,
+// render the job templates and create the job directories
+func (c *ContainerFactory) JobsToRenderingContainers(jobs []Job) ([]corev1.Container, error) {
+	copyingSpecsInitContainers := make([]corev1.Container, 0)
+
+	copyingSpecsUniq := map[string]struct{}{}
+	for _, job := range jobs {
+		jobImage, err := c.releaseImageProvider.GetReleaseImage(c.instanceGroupName, job.Name)
+		if err != nil {
+			return []corev1.Container{}, err
+		}
+
+		// One copying specs init container for each release.
+		if _, done := copyingSpecsUniq[job.Release]; !done {
+			copyingSpecsUniq[job.Release] = struct{}{}
+			copyingSpecsInitContainer := jobSpecCopierContainer(job.Release, jobImage, VolumeRenderingDataName)
+			copyingSpecsInitContainers = append(copyingSpecsInitContainers, copyingSpecsInitContainer)
+		}
+	}
+
 	resolvedPropertiesSecretName := names.CalculateIGSecretName(
 		names.DeploymentSecretTypeInstanceGroupResolvedProperties, // ig-resolved
 		c.manifestName,
@@ -123,22 +161,40 @@ func (c *ContainerFactory) JobsToInitContainers(
 		c.version,
 	)
 
-	initContainers := flattenContainers(
+	return flattenContainers(
 		copyingSpecsInitContainers,
 		templateRenderingContainer(c.instanceGroupName, resolvedPropertiesSecretName),
 		createDirContainer(jobs),
-		boshPreStartInitContainers,
-		bpmPreStartInitContainers,
+	), nil
+}
+
+// TemplateWatchingContainer creates the container, which renders the job templates
+// again, whenever the addresses of the consumed link instances change. It's only
+// needed without DNS addresses, when the addresses are the IPs of the pods.
+func (c *ContainerFactory) TemplateWatchingContainer() corev1.Container {
+	resolvedPropertiesSecretName := names.CalculateIGSecretName(
+		names.DeploymentSecretTypeInstanceGroupResolvedProperties, // ig-resolved
+		c.manifestName,
+		c.instanceGroupName,
+		c.version,
 	)
 
-	return initContainers, nil
+	container := templateRenderingContainer(c.instanceGroupName, resolvedPropertiesSecretName)
+	container.Name = "template-watch"
+	container.Env = append(container.Env, corev1.EnvVar{
+		Name:  "WATCH_INTERVAL",
+		Value: linkAddressesWatchInterval,
+	})
+	return container
 }
 
-// JobsToContainers creates a list of Containers for corev1.PodSpec Containers field
+// JobsToContainers creates a list of Containers for corev1.PodSpec Containers field.
+// The vm resources of the instance group are split evenly across the BPM processes.
 func (c *ContainerFactory) JobsToContainers(
 	jobs []Job,
 	defaultVolumeMounts []corev1.VolumeMount,
 	bpmDisks BPMResourceDisks,
+	vmResources *VMResource,
 ) ([]corev1.Container, error) {
 	var containers []corev1.Container
 
@@ -146,6 +202,11 @@ func (c *ContainerFactory) JobsToContainers(
 		return nil, fmt.Errorf("instance group %s has no jobs defined", c.instanceGroupName)
 	}
 
+	processCount := 0
+	for _, job := range jobs {
+		processCount += len(c.bpmConfigs[job.Name].Processes)
+	}
+
 	for _, job := range jobs {
 		jobImage, err := c.releaseImageProvider.GetReleaseImage(c.instanceGroupName, job.Name)
 		if err != nil {
@@ -189,9 +250,16 @@ func (c *ContainerFactory) JobsToContainers(
 				jobImage,
 				process,
 				processVolumeMounts,
+				bpmConfig.Monit.Process(process.Name, len(bpmConfig.Processes) == 1),
 				job.Properties.BOSHContainerization.Run.HealthChecks,
 			)
 
+			resources, err := processResources(vmResources, processCount, process.Limits)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to calculate resources of process '%s' of bosh job '%s'", process.Name, job.Name)
+			}
+			container.Resources = resources
+
 			containers = append(containers, *container.DeepCopy())
 		}
 	}
@@ -202,6 +270,156 @@ func (c *ContainerFactory) JobsToContainers(
 	return containers, nil
 }
 
+// PostStartContainers returns the names of the containers, whose postStart
+// hook runs the post-start script of a job
+func (c *ContainerFactory) PostStartContainers(jobs []Job) []string {
+	containers := []string{}
+	for _, job := range jobs {
+		if name, ok := c.postStartContainer(job); ok {
+			containers = append(containers, name)
+		}
+	}
+	return containers
+}
+
+// AddPostStartHooks runs the post-start script of each job in the postStart
+// hook of the job's last BPM process container. Kubernetes starts the containers
+// in order, so the script runs once all processes of the job have been started.
+// The container isn't running before the script succeeded, which keeps the pod
+// from becoming ready, and a failing script restarts the container.
+func (c *ContainerFactory) AddPostStartHooks(jobs []Job, containers []corev1.Container) {
+	for _, job := range jobs {
+		name, ok := c.postStartContainer(job)
+		if !ok {
+			continue
+		}
+
+		for i := range containers {
+			if containers[i].Name == name {
+				containers[i].Lifecycle.PostStart = postStartHook(job.Name, job.Properties.BOSHContainerization.Debug)
+			}
+		}
+	}
+}
+
+// postStartContainer returns the name of the container, which runs the
+// post-start script of a job. Jobs without BPM processes have none.
+func (c *ContainerFactory) postStartContainer(job Job) (string, bool) {
+	bpmConfig := c.bpmConfigs[job.Name]
+	if !bpmConfig.PostStart || len(bpmConfig.Processes) == 0 {
+		return "", false
+	}
+	return processContainerName(job.Name, bpmConfig.Processes[len(bpmConfig.Processes)-1].Name), true
+}
+
+// JobsToPostDeployContainers creates a container for each job with a
+// post-deploy script, which runs the script
+func (c *ContainerFactory) JobsToPostDeployContainers(
+	jobs []Job,
+	defaultVolumeMounts []corev1.VolumeMount,
+	bpmDisks BPMResourceDisks,
+) ([]corev1.Container, error) {
+	containers := []corev1.Container{}
+
+	for _, job := range jobs {
+		if !c.bpmConfigs[job.Name].PostDeploy {
+			continue
+		}
+
+		jobImage, err := c.releaseImageProvider.GetReleaseImage(c.instanceGroupName, job.Name)
+		if err != nil {
+			return []corev1.Container{}, err
+		}
+
+		container := postDeployContainer(
+			job.Name,
+			jobImage,
+			append(defaultVolumeMounts, bpmDisks.VolumeMounts()...),
+		)
+		containers = append(containers, *container.DeepCopy())
+	}
+
+	return containers, nil
+}
+
+// processResources returns the requests and limits of a process container. The
+// cpu and ram of the vm resources are the process' share of the instance group's
+// vm. The BPM memory limit takes precedence over the memory share.
+func processResources(vmResources *VMResource, processCount int, limits bpm.Limits) (corev1.ResourceRequirements, error) {
+	resources := corev1.ResourceRequirements{}
+	share := corev1.ResourceList{}
+
+	if vmResources != nil && processCount > 0 {
+		if vmResources.CPU > 0 {
+			share[corev1.ResourceCPU] = *resource.NewMilliQuantity(int64(vmResources.CPU)*1000/int64(processCount), resource.DecimalSI)
+		}
+		if vmResources.RAM > 0 {
+			share[corev1.ResourceMemory] = *resource.NewQuantity(int64(vmResources.RAM)*1024*1024/int64(processCount), resource.BinarySI)
+		}
+	}
+
+	if len(share) > 0 {
+		resources.Requests = share
+		resources.Limits = corev1.ResourceList{}
+		for name, quantity := range share {
+			resources.Limits[name] = quantity
+		}
+	}
+
+	if limits.Memory != "" {
+		memory, err := bpmMemory(limits.Memory)
+		if err != nil {
+			return resources, err
+		}
+
+		if resources.Limits == nil {
+			resources.Limits = corev1.ResourceList{}
+		}
+		resources.Limits[corev1.ResourceMemory] = memory
+
+		// The request must not exceed the limit
+		if request, ok := resources.Requests[corev1.ResourceMemory]; ok && request.Cmp(memory) > 0 {
+			resources.Requests[corev1.ResourceMemory] = memory
+		}
+	}
+
+	return resources, nil
+}
+
+// bpmMemoryUnits are the units of BPM memory limits, which are powers of 1024
+var bpmMemoryUnits = map[string]float64{
+	"B": 1,
+	"K": 1 << 10, "KB": 1 << 10, "KIB": 1 << 10,
+	"M": 1 << 20, "MB": 1 << 20, "MIB": 1 << 20,
+	"G": 1 << 30, "GB": 1 << 30, "GIB": 1 << 30,
+	"T": 1 << 40, "TB": 1 << 40, "TIB": 1 << 40,
+	"P": 1 << 50, "PB": 1 << 50, "PIB": 1 << 50,
+	"E": 1 << 60, "EB": 1 << 60, "EIB": 1 << 60,
+}
+
+// bpmMemory converts a BPM memory limit to a quantity. It accepts the same limits as BPM,
+// which parses them with bytefmt, e.g. 1G, 512MB, 1.5G or 1GiB.
+func bpmMemory(limit string) (resource.Quantity, error) {
+	s := strings.ToUpper(strings.TrimSpace(limit))
+
+	i := strings.IndexFunc(s, unicode.IsLetter)
+	if i == -1 {
+		return resource.Quantity{}, fmt.Errorf("invalid bpm memory limit '%s', a unit is required", limit)
+	}
+
+	value, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil || value < 0 {
+		return resource.Quantity{}, fmt.Errorf("invalid bpm memory limit '%s'", limit)
+	}
+
+	unit, ok := bpmMemoryUnits[s[i:]]
+	if !ok {
+		return resource.Quantity{}, fmt.Errorf("invalid bpm memory limit '%s', unknown unit '%s'", limit, s[i:])
+	}
+
+	return *resource.NewQuantity(int64(value*unit), resource.BinarySI), nil
+}
+
 // logsTailerContainer is a container that tails all logs in /var/vcap/sys/log
 func logsTailerContainer(instanceGroupName string) corev1.Container {
 
@@ -310,12 +528,15 @@ func templateRenderingContainer(instanceGroupName string, secretName string) cor
 	}
 }
 
+// createDirContainer creates the directories of the jobs and copies the
+// operator binary, which drains the jobs, to the sys dir.
 func createDirContainer(jobs []Job) corev1.Container {
 	dirs := []string{}
 	for _, job := range jobs {
 		jobDirs := append(job.dataDirs(job.Name), job.sysDirs(job.Name)...)
 		dirs = append(dirs, jobDirs...)
 	}
+	dirs = append(dirs, filepath.Dir(DrainRunnerPath))
 
 	return corev1.Container{
 		Name:  "create-dirs",
@@ -336,7 +557,7 @@ func createDirContainer(jobs []Job) corev1.Container {
 		},
 		Args: []string{
 			"-xc",
-			fmt.Sprintf("mkdir -p %s", strings.Join(dirs, " ")),
+			fmt.Sprintf("mkdir -p %s && cp \"$(command -v cf-operator)\" %s", strings.Join(dirs, " "), DrainRunnerPath),
 		},
 		SecurityContext: &corev1.SecurityContext{
 			RunAsUser: &vcapUserID,
@@ -373,6 +594,47 @@ func boshPreStartInitContainer(
 	}
 }
 
+// postStartHook resets the drain lock of a job, like the postStart hook of
+// the other process containers, and runs the job's post-start script
+func postStartHook(jobName string, debug bool) *corev1.Handler {
+	postStart := filepath.Join(VolumeJobsDirMountPath, jobName, PostStartScript)
+
+	failure := "exit 1"
+	if debug {
+		failure = `echo "Debug window 1hr" ; sleep 3600 ; exit 1`
+	}
+
+	script := fmt.Sprintf(`"%[1]s" util drain --job-name "%[2]s" --reset && { "%[3]s" || { %[4]s ; } ; }`, DrainRunnerPath, jobName, postStart, failure)
+
+	return &corev1.Handler{
+		Exec: &corev1.ExecAction{
+			Command: []string{"/bin/sh", "-c", script},
+		},
+	}
+}
+
+// postDeployContainer runs the post-deploy script of a job
+func postDeployContainer(
+	jobName string,
+	jobImage string,
+	volumeMounts []corev1.VolumeMount,
+) corev1.Container {
+	postDeploy := filepath.Join(VolumeJobsDirMountPath, jobName, PostDeployScript)
+
+	return corev1.Container{
+		Name:         names.Sanitize(fmt.Sprintf("post-deploy-%s", jobName)),
+		Image:        jobImage,
+		VolumeMounts: volumeMounts,
+		Command: []string{
+			"/bin/sh",
+		},
+		Args: []string{
+			"-xc",
+			fmt.Sprintf(`"%s"`, postDeploy),
+		},
+	}
+}
+
 func bpmPreStartInitContainer(
 	process bpm.Process,
 	jobImage string,
@@ -410,11 +672,11 @@ func bpmProcessContainer(
 	jobImage string,
 	process bpm.Process,
 	volumeMounts []corev1.VolumeMount,
+	monitChecks monit.Checks,
 	healthchecks map[string]bc.HealthCheck,
 ) corev1.Container {
-	name := names.Sanitize(fmt.Sprintf("%s-%s", jobName, processName))
 	container := corev1.Container{
-		Name:         names.Sanitize(name),
+		Name:         processContainerName(jobName, processName),
 		Image:        jobImage,
 		VolumeMounts: volumeMounts,
 		Command:      []string{process.Executable},
@@ -429,42 +691,16 @@ func bpmProcessContainer(
 		Lifecycle: &corev1.Lifecycle{},
 	}
 
-	// Setup the job drain script handler.
-	drainGlob := filepath.Join(VolumeJobsDirMountPath, jobName, "bin", "drain", "*")
+	// Drain the job once per pod, before its processes are stopped. Starting
+	// a container resets the drain lock, restarts run the preStop hook too.
 	container.Lifecycle.PreStop = &corev1.Handler{
 		Exec: &corev1.ExecAction{
-			Command: []string{
-				"sh",
-				"-c",
-				`for s in $(ls ` + drainGlob + `); do
-					(
-						echo "Running drain script $s"
-						while true; do
-							out=$($s)
-							status=$?
-
-							if [ "$status" -ne "0" ]; then
-								echo "$s FAILED with exit code $status"
-								exit $status
-							fi
-
-							if [ "$out" -lt "0" ]; then
-								echo "Sleeping dynamic draining wait time for $s..."
-								sleep ${out:1}
-								echo "Running $s again"
-							else
-								echo "Sleeping static draining wait time for $s..."
-								sleep $out
-								echo "$s done"
-								exit 0
-							fi
-						done
-					)&
-				done
-				echo "Waiting..."
-				wait
-				echo "Done"`,
-			},
+			Command: []string{DrainRunnerPath, "util", "drain", "--job-name", jobName},
+		},
+	}
+	container.Lifecycle.PostStart = &corev1.Handler{
+		Exec: &corev1.ExecAction{
+			Command: []string{DrainRunnerPath, "util", "drain", "--job-name", jobName, "--reset"},
 		},
 	}
 
@@ -472,6 +708,13 @@ func bpmProcessContainer(
 		container.Env = append(container.Env, corev1.EnvVar{Name: name, Value: value})
 	}
 
+	// Default probes are derived from the monit checks, explicit healthchecks take precedence
+	if probe := monitProbe(monitChecks); probe != nil {
+		container.ReadinessProbe = probe
+		container.LivenessProbe = probe.DeepCopy()
+		container.LivenessProbe.InitialDelaySeconds = monitStartTimeout(monitChecks)
+	}
+
 	for name, hc := range healthchecks {
 		if name == process.Name {
 			if hc.ReadinessProbe != nil {
@@ -485,6 +728,71 @@ func bpmProcessContainer(
 	return container
 }
 
+// processContainerName returns the name of the container of a BPM process
+func processContainerName(jobName string, processName string) string {
+	return names.Sanitize(fmt.Sprintf("%s-%s", jobName, processName))
+}
+
+// monitProbe converts the first monit check of a program to an exec probe, or
+// else the first checked port to a TCP probe, or else the first pidfile to an
+// exec probe, which tests that the process of the pidfile is alive.
+func monitProbe(checks monit.Checks) *corev1.Probe {
+	for _, check := range checks {
+		if check.Program != "" {
+			return &corev1.Probe{
+				Handler: corev1.Handler{
+					Exec: &corev1.ExecAction{
+						Command: []string{"sh", "-c", check.Program},
+					},
+				},
+			}
+		}
+	}
+
+	for _, check := range checks {
+		if len(check.Ports) > 0 {
+			return &corev1.Probe{
+				Handler: corev1.Handler{
+					TCPSocket: &corev1.TCPSocketAction{
+						Port: intstr.FromInt(check.Ports[0]),
+					},
+				},
+			}
+		}
+	}
+
+	for _, check := range checks {
+		// BPM doesn't run in the containers and doesn't write its pidfiles, the
+		// container of a BPM process stops with the process instead
+		if check.Pidfile != "" && !strings.HasPrefix(check.Pidfile, bpmPidDir+"/") {
+			return &corev1.Probe{
+				Handler: corev1.Handler{
+					Exec: &corev1.ExecAction{
+						Command: []string{"sh", "-c", fmt.Sprintf(`test -f "%[1]s" && kill -0 "$(cat "%[1]s")"`, check.Pidfile)},
+					},
+				},
+			}
+		}
+	}
+
+	return nil
+}
+
+// monitStartTimeout returns the longest start timeout of the checks. Like in
+// monit, it defaults to 30 seconds.
+func monitStartTimeout(checks monit.Checks) int32 {
+	timeout := 0
+	for _, check := range checks {
+		if check.StartTimeout > timeout {
+			timeout = check.StartTimeout
+		}
+	}
+	if timeout == 0 {
+		timeout = 30
+	}
+	return int32(timeout)
+}
+
 // capability converts string slice into Capability slice of kubernetes
 func capability(s []string) []corev1.Capability {
 	capabilities := make([]corev1.Capability, len(s))
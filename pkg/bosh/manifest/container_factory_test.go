@@ -5,6 +5,7 @@ import (
 	"path"
 
 	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
 	. "github.com/onsi/gomega"
 	corev1 "k8s.io/api/core/v1"
 
@@ -21,9 +22,11 @@ var _ = Describe("ContainerFactory", func() {
 		jobs                 []Job
 		defaultVolumeMounts  []corev1.VolumeMount
 		bpmDisks             BPMResourceDisks
+		vmResources          *VMResource
 	)
 
 	BeforeEach(func() {
+		vmResources = nil
 		releaseImageProvider = &fakes.FakeReleaseImageProvider{}
 		releaseImageProvider.GetReleaseImageReturns("", nil)
 
@@ -225,7 +228,7 @@ var _ = Describe("ContainerFactory", func() {
 		})
 
 		act := func() ([]corev1.Container, error) {
-			return containerFactory.JobsToContainers(jobs, defaultVolumeMounts, bpmDisks)
+			return containerFactory.JobsToContainers(jobs, defaultVolumeMounts, bpmDisks, vmResources)
 		}
 
 		It("adds the default volume mounts passed", func() {
@@ -322,7 +325,7 @@ var _ = Describe("ContainerFactory", func() {
 			}
 			containerFactory = NewContainerFactory("fake-manifest", "fake-ig", "v1", releaseImageProvider, bpmConfigsWithError)
 			actWithError := func() ([]corev1.Container, error) {
-				return containerFactory.JobsToContainers(jobs, []corev1.VolumeMount{}, BPMResourceDisks{})
+				return containerFactory.JobsToContainers(jobs, []corev1.VolumeMount{}, BPMResourceDisks{}, nil)
 			}
 			_, err := actWithError()
 			Expect(err).To(HaveOccurred())
@@ -368,6 +371,96 @@ var _ = Describe("ContainerFactory", func() {
 			Expect(containers[1].Env).To(HaveLen(2))
 		})
 
+		Context("with vm resources", func() {
+			BeforeEach(func() {
+				vmResources = &VMResource{CPU: 1, RAM: 1024}
+			})
+
+			It("splits the vm resources across the processes", func() {
+				containers, err := act()
+				Expect(err).ToNot(HaveOccurred())
+
+				for _, container := range containers[0:2] {
+					Expect(container.Resources.Requests.Cpu().String()).To(Equal("500m"))
+					Expect(container.Resources.Requests.Memory().String()).To(Equal("512Mi"))
+					Expect(container.Resources.Limits.Cpu().String()).To(Equal("500m"))
+					Expect(container.Resources.Limits.Memory().String()).To(Equal("512Mi"))
+				}
+				Expect(containers[2].Resources.Requests).To(BeEmpty())
+			})
+
+			It("uses the bpm memory limit as the container's memory limit", func() {
+				process := bpmConfigs["fake-job"].Processes[0]
+				process.Limits.Memory = "256M"
+				bpmConfigs["fake-job"] = bpm.Config{Processes: []bpm.Process{process}}
+
+				containers, err := act()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(containers[0].Resources.Limits.Memory().String()).To(Equal("256Mi"))
+				Expect(containers[0].Resources.Requests.Memory().String()).To(Equal("256Mi"))
+				Expect(containers[0].Resources.Limits.Cpu().String()).To(Equal("500m"))
+				Expect(containers[1].Resources.Limits.Memory().String()).To(Equal("512Mi"))
+			})
+
+			It("fails for invalid bpm memory limits", func() {
+				process := bpmConfigs["fake-job"].Processes[0]
+				process.Limits.Memory = "lots"
+				bpmConfigs["fake-job"] = bpm.Config{Processes: []bpm.Process{process}}
+
+				_, err := act()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("invalid bpm memory limit 'lots'"))
+			})
+		})
+
+		It("sets bpm memory limits without vm resources", func() {
+			process := bpmConfigs["other-job"].Processes[0]
+			process.Limits.Memory = "1GB"
+			bpmConfigs["other-job"] = bpm.Config{Processes: []bpm.Process{process}}
+
+			containers, err := act()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(containers[0].Resources.Limits).To(BeEmpty())
+			Expect(containers[1].Resources.Requests).To(BeEmpty())
+			Expect(containers[1].Resources.Limits.Memory().String()).To(Equal("1Gi"))
+		})
+
+		DescribeTable("accepts the bpm memory limits, which bpm accepts",
+			func(limit string, expected string) {
+				process := bpmConfigs["other-job"].Processes[0]
+				process.Limits.Memory = limit
+				bpmConfigs["other-job"] = bpm.Config{Processes: []bpm.Process{process}}
+
+				containers, err := act()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(containers[1].Resources.Limits.Memory().String()).To(Equal(expected))
+			},
+			Entry("bytes", "1024B", "1Ki"),
+			Entry("single letter units", "512M", "512Mi"),
+			Entry("lower case units", "2g", "2Gi"),
+			Entry("decimal units", "1GB", "1Gi"),
+			Entry("IEC units", "512MiB", "512Mi"),
+			Entry("IEC units in lower case", "1gib", "1Gi"),
+			Entry("fractions", "1.5G", "1536Mi"),
+			Entry("surrounding spaces", " 1T ", "1Ti"),
+		)
+
+		DescribeTable("rejects the bpm memory limits, which bpm rejects",
+			func(limit string) {
+				process := bpmConfigs["other-job"].Processes[0]
+				process.Limits.Memory = limit
+				bpmConfigs["other-job"] = bpm.Config{Processes: []bpm.Process{process}}
+
+				_, err := act()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring(fmt.Sprintf("invalid bpm memory limit '%s'", limit)))
+			},
+			Entry("without a unit", "1024"),
+			Entry("with an unknown unit", "1X"),
+			Entry("with a space before the unit", "512 MB"),
+			Entry("with a negative value", "-1G"),
+		)
+
 		Context("with lifecycle events", func() {
 			It("creates a preStop handler per job", func() {
 				containers, err := act()
@@ -375,11 +468,12 @@ var _ = Describe("ContainerFactory", func() {
 
 				Expect(containers[0].Lifecycle).ToNot(BeNil())
 				Expect(containers[0].Lifecycle.PreStop).ToNot(BeNil())
-				Expect(containers[0].Lifecycle.PreStop.Exec.Command).To(ContainElement(ContainSubstring("/var/vcap/jobs/fake-job/bin/drain/")))
+				Expect(containers[0].Lifecycle.PreStop.Exec.Command).To(Equal([]string{"/var/vcap/sys/bin/cf-operator", "util", "drain", "--job-name", "fake-job"}))
+				Expect(containers[0].Lifecycle.PostStart.Exec.Command).To(Equal([]string{"/var/vcap/sys/bin/cf-operator", "util", "drain", "--job-name", "fake-job", "--reset"}))
 
 				Expect(containers[1].Lifecycle).ToNot(BeNil())
 				Expect(containers[1].Lifecycle.PreStop).ToNot(BeNil())
-				Expect(containers[1].Lifecycle.PreStop.Exec.Command).To(ContainElement(ContainSubstring("/var/vcap/jobs/other-job/bin/drain/")))
+				Expect(containers[1].Lifecycle.PreStop.Exec.Command).To(Equal([]string{"/var/vcap/sys/bin/cf-operator", "util", "drain", "--job-name", "other-job"}))
 			})
 		})
 	})
@@ -402,7 +496,7 @@ var _ = Describe("ContainerFactory", func() {
 				Expect(err).ToNot(HaveOccurred())
 				Expect(containers).To(HaveLen(5))
 				Expect(containers[2].Name).To(Equal("create-dirs"))
-				Expect(containers[2].Args).To(ContainElement("mkdir -p /var/vcap/data/fake-job /var/vcap/data/sys/log/fake-job /var/vcap/data/sys/run/fake-job /var/vcap/sys/log/fake-job /var/vcap/sys/run/fake-job /var/vcap/data/other-job /var/vcap/data/sys/log/other-job /var/vcap/data/sys/run/other-job /var/vcap/sys/log/other-job /var/vcap/sys/run/other-job"))
+				Expect(containers[2].Args).To(ContainElement("mkdir -p /var/vcap/data/fake-job /var/vcap/data/sys/log/fake-job /var/vcap/data/sys/run/fake-job /var/vcap/sys/log/fake-job /var/vcap/sys/run/fake-job /var/vcap/data/other-job /var/vcap/data/sys/log/other-job /var/vcap/data/sys/run/other-job /var/vcap/sys/log/other-job /var/vcap/sys/run/other-job /var/vcap/sys/bin && cp \"$(command -v cf-operator)\" /var/vcap/sys/bin/cf-operator"))
 				Expect(containers[2].VolumeMounts).To(HaveLen(2))
 			})
 
@@ -523,4 +617,76 @@ var _ = Describe("ContainerFactory", func() {
 			})
 		})
 	})
+
+	Context("AddPostStartHooks", func() {
+		var containers []corev1.Container
+
+		BeforeEach(func() {
+			bpmConfigs = bpm.Configs{
+				"fake-job": bpm.Config{
+					PostStart: true,
+					Processes: []bpm.Process{{Name: "fake-process"}, {Name: "last-process"}},
+				},
+				"other-job": bpm.Config{
+					Processes: []bpm.Process{{Name: "fake-process"}},
+				},
+			}
+		})
+
+		JustBeforeEach(func() {
+			var err error
+			containers, err = containerFactory.JobsToContainers(jobs, defaultVolumeMounts, bpmDisks, vmResources)
+			Expect(err).ToNot(HaveOccurred())
+			containerFactory.AddPostStartHooks(jobs, containers)
+		})
+
+		It("runs the post-start script in the hook of the job's last process container", func() {
+			Expect(containerFactory.PostStartContainers(jobs)).To(Equal([]string{"fake-job-last-process"}))
+
+			Expect(containers[1].Name).To(Equal("fake-job-last-process"))
+			Expect(containers[1].Lifecycle.PostStart.Exec.Command).To(Equal([]string{
+				"/bin/sh",
+				"-c",
+				`"/var/vcap/sys/bin/cf-operator" util drain --job-name "fake-job" --reset && { "/var/vcap/jobs/fake-job/bin/post-start" || { exit 1 ; } ; }`,
+			}))
+		})
+
+		It("only resets the drain lock in the hooks of the other containers", func() {
+			Expect(containers[0].Lifecycle.PostStart.Exec.Command).To(Equal([]string{"/var/vcap/sys/bin/cf-operator", "util", "drain", "--job-name", "fake-job", "--reset"}))
+			Expect(containers[2].Lifecycle.PostStart.Exec.Command).To(Equal([]string{"/var/vcap/sys/bin/cf-operator", "util", "drain", "--job-name", "other-job", "--reset"}))
+		})
+
+		Context("when a job with a post-start script has no processes", func() {
+			BeforeEach(func() {
+				bpmConfigs["other-job"] = bpm.Config{PostStart: true}
+				jobs = []Job{{Name: "other-job"}, {Name: "fake-job"}}
+				bpmConfigs["fake-job"] = bpm.Config{Processes: []bpm.Process{{Name: "fake-process"}}}
+			})
+
+			It("has no container to run the script", func() {
+				Expect(containerFactory.PostStartContainers(jobs)).To(BeEmpty())
+			})
+		})
+	})
+
+	Context("JobsToPostDeployContainers", func() {
+		act := func() ([]corev1.Container, error) {
+			return containerFactory.JobsToPostDeployContainers(jobs, defaultVolumeMounts, bpmDisks)
+		}
+
+		BeforeEach(func() {
+			bpmConfigs = bpm.Configs{
+				"fake-job":  bpm.Config{},
+				"other-job": bpm.Config{PostDeploy: true},
+			}
+		})
+
+		It("creates a container for each job with a post-deploy script", func() {
+			containers, err := act()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(containers).To(HaveLen(1))
+			Expect(containers[0].Name).To(Equal("post-deploy-other-job"))
+			Expect(containers[0].Args).To(Equal([]string{"-xc", `"/var/vcap/jobs/other-job/bin/post-deploy"`}))
+		})
+	})
 })
@@ -8,6 +8,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 
 	"code.cloudfoundry.org/cf-operator/pkg/bosh/bpm"
+	"code.cloudfoundry.org/cf-operator/pkg/bosh/monit"
 )
 
 // Manifest is a BOSH deployment manifest
@@ -59,6 +60,7 @@ type BOSHContainerization struct {
 	Instances        []JobInstance      `json:"instances"`
 	Release          string             `json:"release"`
 	BPM              *bpm.Config        `json:"bpm,omitempty" yaml:"bpm,omitempty"`
+	Monit            monit.Checks       `json:"monit,omitempty" yaml:"monit,omitempty"`
 	Ports            []Port             `json:"ports"`
 	Run              RunConfig          `json:"run"`
 	PreRenderScripts []string           `json:"pre_render_scripts" yaml:"pre_render_scripts"`
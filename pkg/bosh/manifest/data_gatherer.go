@@ -16,6 +16,7 @@ import (
 
 	"code.cloudfoundry.org/cf-operator/pkg/bosh/bpm"
 	bc "code.cloudfoundry.org/cf-operator/pkg/bosh/manifest/containerization"
+	"code.cloudfoundry.org/cf-operator/pkg/bosh/monit"
 )
 
 // JobProviderLinks provides links to other jobs, indexed by provider type and name
@@ -48,24 +49,11 @@ func (jpl JobProviderLinks) Add(job Job, spec JobSpec, jobsInstances []bc.JobIns
 		for _, propertyName := range link.Properties {
 			mergeNestedExplicitProperty(properties, job, propertyName)
 		}
-		linkName := link.Name
-		linkType := link.Type
-
-		// instance_group.job can override the link name through the
-		// instance_group.job.provides, via the "as" key
-		if job.Provides != nil {
-			if value, ok := job.Provides[linkName]; ok {
-				switch value := value.(type) {
-				case map[interface{}]interface{}:
-					if overrideLinkName, ok := value["as"]; ok {
-						linkName = fmt.Sprintf("%v", overrideLinkName)
-					}
-				default:
-					return fmt.Errorf("unexpected type detected: %T, should have been a map", value)
-				}
-
-			}
+		linkName, _, err := job.providedLink(link.Name)
+		if err != nil {
+			return err
 		}
+		linkType := link.Type
 
 		if providers, ok := jpl[linkType]; ok {
 			if _, ok := providers[linkName]; ok {
@@ -94,14 +82,19 @@ type DataGatherer struct {
 	baseDir       string
 	manifest      Manifest
 	namespace     string
+	clusterDomain string
 	instanceGroup *InstanceGroup
+	linksDir      string
 
 	jobReleaseSpecs  map[string]map[string]JobSpec
 	jobProviderLinks JobProviderLinks
+	sharedLinks      map[string]JobProviderLinks
 }
 
-// NewDataGatherer returns a data gatherer with logging for a given input manifest and instance group
-func NewDataGatherer(log *zap.SugaredLogger, basedir, namespace string, manifest Manifest, instanceGroupName string) (*DataGatherer, error) {
+// NewDataGatherer returns a data gatherer with logging for a given input manifest and instance group.
+// Links shared by other deployments are read from linksDir. The addresses of
+// instances are in the cluster's DNS domain.
+func NewDataGatherer(log *zap.SugaredLogger, basedir, linksDir, namespace, clusterDomain string, manifest Manifest, instanceGroupName string) (*DataGatherer, error) {
 	ig, err := (&manifest).InstanceGroupByName(instanceGroupName)
 	if err != nil {
 		return nil, err
@@ -112,15 +105,19 @@ func NewDataGatherer(log *zap.SugaredLogger, basedir, namespace string, manifest
 		baseDir:          basedir,
 		manifest:         manifest,
 		namespace:        namespace,
+		clusterDomain:    clusterDomain,
 		instanceGroup:    ig,
+		linksDir:         linksDir,
 		jobReleaseSpecs:  map[string]map[string]JobSpec{},
 		jobProviderLinks: JobProviderLinks{},
+		sharedLinks:      map[string]JobProviderLinks{},
 	}, nil
 }
 
 // BPMConfigs returns a map of all BOSH jobs in the instance group
 // The output will be persisted by ExtendedJob as 'bpm.yaml' in the
 // `<deployment-name>.bpm.<instance-group>-v<version>` secret.
+// The monit checks and lifecycle scripts of the jobs are passed on with them.
 func (dg *DataGatherer) BPMConfigs() (bpm.Configs, error) {
 	bpm := bpm.Configs{}
 
@@ -130,7 +127,13 @@ func (dg *DataGatherer) BPMConfigs() (bpm.Configs, error) {
 	}
 
 	for _, job := range dg.instanceGroup.Jobs {
-		bpm[job.Name] = *job.Properties.BOSHContainerization.BPM
+		config := *job.Properties.BOSHContainerization.BPM
+		config.Monit = job.Properties.BOSHContainerization.Monit
+
+		spec := dg.jobReleaseSpecs[job.Release][job.Name]
+		config.PostStart = spec.HasTemplate(PostStartScript)
+		config.PostDeploy = spec.HasTemplate(PostDeployScript)
+		bpm[job.Name] = config
 	}
 
 	return bpm, nil
@@ -156,6 +159,7 @@ func (dg *DataGatherer) ResolvedProperties() (Manifest, error) {
 // * job properties
 // * bosh links
 // * bpm yaml file data
+// * monit checks
 func (dg *DataGatherer) gatherData() error {
 	err := dg.collectReleaseSpecsAndProviderLinks()
 	if err != nil {
@@ -172,6 +176,8 @@ func (dg *DataGatherer) gatherData() error {
 		return err
 	}
 
+	dg.renderMonit()
+
 	return nil
 }
 
@@ -199,7 +205,7 @@ func (dg *DataGatherer) collectReleaseSpecsAndProviderLinks() error {
 			// Generate instance spec for each ig instance
 			// This will be stored inside the current job under
 			// job.properties.bosh_containerization
-			jobsInstances := instanceGroup.jobInstances(dg.namespace, dg.manifest.Name, job.Name, spec)
+			jobsInstances := instanceGroup.jobInstances(dg.namespace, dg.clusterDomain, dg.manifest.Name, job.Name, spec)
 
 			// set jobs.properties.bosh_containerization.instances with the ig instances
 			instanceGroup.Jobs[jobIdx].Properties.BOSHContainerization.Instances = jobsInstances
@@ -228,7 +234,7 @@ func (dg *DataGatherer) processConsumers() error {
 			job.Properties.BOSHContainerization.Release = job.Release
 		}
 
-		err := generateJobConsumersData(job, dg.jobReleaseSpecs, dg.jobProviderLinks)
+		err := generateJobConsumersData(job, dg.jobReleaseSpecs, dg.jobProviderLinks, dg.deploymentLinks)
 		if err != nil {
 			return err
 		}
@@ -371,13 +377,79 @@ func (dg *DataGatherer) renderJobBPM(currentJob *Job, baseDir string) error {
 	return nil
 }
 
+// renderMonit adds the checks of the jobs' monit files to their
+// bosh_containerization. A monit file, which fails to render, is skipped, so
+// no default probes are derived from it.
+func (dg *DataGatherer) renderMonit() {
+	for i := range dg.instanceGroup.Jobs {
+		job := &dg.instanceGroup.Jobs[i]
+
+		checks, err := dg.renderJobMonit(job, dg.baseDir)
+		if err != nil {
+			dg.log.Warnf("Skipping monit file of job '%s': %v", job.Name, err)
+			continue
+		}
+		job.Properties.BOSHContainerization.Monit = checks
+	}
+}
+
+// renderJobMonit renders the monit file of a job for its first instance and
+// parses its checks.
+func (dg *DataGatherer) renderJobMonit(currentJob *Job, baseDir string) (monit.Checks, error) {
+	monitFile := filepath.Join(currentJob.specDir(baseDir), MonitFilename)
+	if _, err := os.Stat(monitFile); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	jobInstances := currentJob.Properties.BOSHContainerization.Instances
+	if len(jobInstances) == 0 {
+		return nil, nil
+	}
+	jobInstance := jobInstances[0]
+
+	renderer := btg.NewERBRenderer(
+		&btg.EvaluationContext{
+			Properties: currentJob.Properties.ToMap(),
+		},
+		&btg.InstanceInfo{
+			Address:    jobInstance.Address,
+			AZ:         jobInstance.AZ,
+			Bootstrap:  jobInstance.Index == 0,
+			ID:         jobInstance.ID,
+			Index:      jobInstance.Index,
+			Deployment: dg.manifest.Name,
+			Name:       jobInstance.Name,
+		},
+		filepath.Join(currentJob.specDir(baseDir), JobSpecFilename),
+	)
+
+	tmpfile, err := ioutil.TempFile("", "rendered.*.monit")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmpfile.Name())
+
+	if err := renderer.Render(monitFile, tmpfile.Name()); err != nil {
+		return nil, errors.Wrap(err, "failed to render monit file")
+	}
+
+	monitBytes, err := ioutil.ReadFile(tmpfile.Name())
+	if err != nil {
+		return nil, err
+	}
+
+	return monit.NewChecks(monitBytes), nil
+}
+
 // generateJobConsumersData will populate a job with its corresponding provider links
-// under properties.bosh_containerization.consumes
-func generateJobConsumersData(currentJob *Job, jobReleaseSpecs map[string]map[string]JobSpec, jobProviderLinks JobProviderLinks) error {
+// under properties.bosh_containerization.consumes. Links consumed from other deployments
+// are looked up with deploymentLinks.
+func generateJobConsumersData(currentJob *Job, jobReleaseSpecs map[string]map[string]JobSpec, jobProviderLinks JobProviderLinks, deploymentLinks func(string) (JobProviderLinks, error)) error {
 	currentJobSpecData := jobReleaseSpecs[currentJob.Release][currentJob.Name]
 	for _, provider := range currentJobSpecData.Consumes {
 
 		providerName := provider.Name
+		deployment := ""
 
 		if currentJob.Consumes != nil {
 			// Deployment manifest can intentionally prevent link resolution as long as the link is optional
@@ -396,10 +468,24 @@ func generateJobConsumersData(currentJob *Job, jobReleaseSpecs map[string]map[st
 					providerName = value.(string)
 				}
 			}
+			deployment = currentJob.consumedDeployment(provider.Name)
 		}
 
-		link, hasLink := jobProviderLinks.Lookup(&provider)
+		// Links are looked up by the name they are provided as, which is the one
+		// given in "from", and stored under the same name.
+		consumed := &JobSpecProvider{Name: providerName, Type: provider.Type}
+		link, hasLink := jobProviderLinks.Lookup(consumed)
+		if deployment != "" {
+			links, err := deploymentLinks(deployment)
+			if err != nil {
+				return err
+			}
+			link, hasLink = links.Lookup(consumed)
+		}
 		if !hasLink && !provider.Optional {
+			if deployment != "" {
+				return fmt.Errorf("cannot resolve non-optional link for provider %s of deployment %s", providerName, deployment)
+			}
 			return fmt.Errorf("cannot resolve non-optional link for provider %s", providerName)
 		}
 
@@ -71,7 +71,7 @@ var _ = Describe("DataGatherer", func() {
 
 		JustBeforeEach(func() {
 			var err error
-			dg, err = manifest.NewDataGatherer(log, assetPath, "default", *m, ig)
+			dg, err = manifest.NewDataGatherer(log, assetPath, "", "default", "cluster.local", *m, ig)
 			Expect(err).ToNot(HaveOccurred())
 		})
 
@@ -147,8 +147,6 @@ var _ = Describe("DataGatherer", func() {
 				compareToFakeRedis := []bc.JobInstance{
 					{Address: "foo-deployment-redis-slave-0.default.svc.cluster.local", AZ: "z1", ID: "redis-slave-0-redis-server", Index: 0, Instance: 0, Name: "redis-slave-redis-server"},
 					{Address: "foo-deployment-redis-slave-1.default.svc.cluster.local", AZ: "z2", ID: "redis-slave-1-redis-server", Index: 1, Instance: 0, Name: "redis-slave-redis-server"},
-					{Address: "foo-deployment-redis-slave-2.default.svc.cluster.local", AZ: "z1", ID: "redis-slave-2-redis-server", Index: 2, Instance: 1, Name: "redis-slave-redis-server"},
-					{Address: "foo-deployment-redis-slave-3.default.svc.cluster.local", AZ: "z2", ID: "redis-slave-3-redis-server", Index: 3, Instance: 1, Name: "redis-slave-redis-server"},
 				}
 				Expect(jobInstancesRedis).To(BeEquivalentTo(compareToFakeRedis))
 			})
@@ -182,6 +180,27 @@ var _ = Describe("DataGatherer", func() {
 					Expect(deep.Equal(jobConsumesFromDoppler.Properties, expectedProperties)).To(HaveLen(0))
 				})
 
+				Context("when the link is consumed from an alias", func() {
+					BeforeEach(func() {
+						m.InstanceGroups[0].Jobs[0].Provides = map[string]interface{}{
+							"doppler": map[interface{}]interface{}{"as": "doppler-alias"},
+						}
+						m.InstanceGroups[1].Jobs[0].Consumes = map[string]interface{}{
+							"doppler": map[interface{}]interface{}{"from": "doppler-alias"},
+						}
+					})
+
+					It("keys the consumed link by the alias", func() {
+						manifest, err := dg.ResolvedProperties()
+						Expect(err).ToNot(HaveOccurred())
+
+						jobBoshContainerizationConsumes := manifest.InstanceGroups[1].Jobs[0].Properties.BOSHContainerization.Consumes
+						Expect(jobBoshContainerizationConsumes).To(HaveKey("doppler-alias"))
+						Expect(jobBoshContainerizationConsumes).ToNot(HaveKey("doppler"))
+						Expect(jobBoshContainerizationConsumes["doppler-alias"].Instances).To(HaveLen(4))
+					})
+				})
+
 				It("has an empty consumes list if the job does not consume a link", func() {
 					manifest, err := dg.ResolvedProperties()
 					Expect(err).ToNot(HaveOccurred())
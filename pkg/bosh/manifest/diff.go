@@ -0,0 +1,299 @@
+package manifest
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+
+	ejv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/extendedjob/v1alpha1"
+	essv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/extendedstatefulset/v1alpha1"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/names"
+)
+
+const (
+	// ActionCreate means the resource would be created
+	ActionCreate = "create"
+	// ActionUpdate means the resource would be updated, for instance groups this creates a new version
+	ActionUpdate = "update"
+	// ActionDelete means the resource would be deleted
+	ActionDelete = "delete"
+)
+
+// ResourceChange describes how a kube resource would change
+type ResourceChange struct {
+	Kind   string `yaml:"kind"`
+	Name   string `yaml:"name"`
+	Action string `yaml:"action"`
+	Reason string `yaml:"reason,omitempty"`
+}
+
+// PropertyChange describes a changed property of a BOSH job
+type PropertyChange struct {
+	InstanceGroup string      `yaml:"instance_group"`
+	Job           string      `yaml:"job"`
+	Property      string      `yaml:"property"`
+	Old           interface{} `yaml:"old,omitempty"`
+	New           interface{} `yaml:"new,omitempty"`
+}
+
+// DeploymentDiff is a preview of the changes a new manifest would cause
+type DeploymentDiff struct {
+	Resources  []ResourceChange `yaml:"resources"`
+	Properties []PropertyChange `yaml:"properties"`
+}
+
+// previewResource is the part of a kube resource, which is compared for the diff
+type previewResource struct {
+	kind    string
+	name    string
+	spec    interface{}
+	details func(other previewResource) (string, error)
+}
+
+// Diff compares the kube resources generated for the current manifest with the ones
+// generated for the desired manifest. The current manifest is nil, if nothing has been
+// deployed yet. Nothing is applied.
+func (kc *KubeConverter) Diff(current *Manifest, desired *Manifest, clusterDomain string) (*DeploymentDiff, error) {
+	currentResources, err := kc.previewResources(current, clusterDomain)
+	if err != nil {
+		return nil, errors.Wrap(err, "converting current manifest")
+	}
+	desiredResources, err := kc.previewResources(desired, clusterDomain)
+	if err != nil {
+		return nil, errors.Wrap(err, "converting desired manifest")
+	}
+
+	diff := &DeploymentDiff{
+		Resources:  []ResourceChange{},
+		Properties: diffProperties(current, desired),
+	}
+
+	for key, desiredResource := range desiredResources {
+		currentResource, ok := currentResources[key]
+		switch {
+		case !ok:
+			diff.Resources = append(diff.Resources, ResourceChange{Kind: desiredResource.kind, Name: desiredResource.name, Action: ActionCreate})
+		case !reflect.DeepEqual(currentResource.spec, desiredResource.spec):
+			change := ResourceChange{Kind: desiredResource.kind, Name: desiredResource.name, Action: ActionUpdate}
+			if desiredResource.details != nil {
+				change.Reason, err = desiredResource.details(currentResource)
+				if err != nil {
+					return nil, errors.Wrapf(err, "comparing %s '%s'", desiredResource.kind, desiredResource.name)
+				}
+			}
+			diff.Resources = append(diff.Resources, change)
+		}
+	}
+	for key, currentResource := range currentResources {
+		if _, ok := desiredResources[key]; !ok {
+			diff.Resources = append(diff.Resources, ResourceChange{Kind: currentResource.kind, Name: currentResource.name, Action: ActionDelete})
+		}
+	}
+
+	sort.Slice(diff.Resources, func(i, j int) bool {
+		if diff.Resources[i].Kind != diff.Resources[j].Kind {
+			return diff.Resources[i].Kind < diff.Resources[j].Kind
+		}
+		return diff.Resources[i].Name < diff.Resources[j].Name
+	})
+
+	return diff, nil
+}
+
+// previewResources returns the resources, which can be generated from the manifest alone, by kind and name
+func (kc *KubeConverter) previewResources(m *Manifest, clusterDomain string) (map[string]previewResource, error) {
+	resources := map[string]previewResource{}
+	if m == nil {
+		return resources, nil
+	}
+
+	add := func(r previewResource) {
+		resources[r.kind+"/"+r.name] = r
+	}
+
+	for _, secret := range kc.Variables(m.Name, m.Variables) {
+		add(previewResource{kind: "ExtendedSecret", name: secret.Name, spec: secret.Spec})
+	}
+
+	// The jobs, which interpolate the variables and gather the data of the instance groups
+	jobFactory := NewJobFactory(*m, kc.namespace, clusterDomain)
+	jobs := []func() (*ejv1.ExtendedJob, error){
+		jobFactory.VariableInterpolationJob,
+		jobFactory.DataGatheringJob,
+		jobFactory.BPMConfigsJob,
+	}
+	if m.ProvidesSharedLinks() {
+		jobs = append(jobs, jobFactory.LinksJob)
+	}
+	for _, job := range jobs {
+		eJob, err := job()
+		if err != nil {
+			return nil, errors.Wrap(err, "building jobs")
+		}
+		add(previewResource{kind: "ExtendedJob", name: eJob.Name, spec: eJob.Spec})
+	}
+
+	for _, ig := range m.InstanceGroups {
+		igYAML, err := yaml.Marshal(ig)
+		if err != nil {
+			return nil, errors.Wrapf(err, "marshalling instance group '%s'", ig.Name)
+		}
+		resource := previewResource{
+			name: fmt.Sprintf("%s-%s", m.Name, names.Sanitize(ig.Name)),
+			spec: string(igYAML),
+			details: func(other previewResource) (string, error) {
+				changed, err := changedFields(other.spec.(string), string(igYAML))
+				if err != nil {
+					return "", err
+				}
+				return fmt.Sprintf("changed %s", strings.Join(changed, ", ")), nil
+			},
+		}
+
+		if ig.LifeCycle == "errand" {
+			resource.kind = "ExtendedJob"
+			add(resource)
+			continue
+		}
+
+		resource.kind = "ExtendedStatefulSet"
+		add(resource)
+
+		igCloudConfig, err := m.CloudConfig.instanceGroupCloudConfig(ig)
+		if err != nil {
+			return nil, errors.Wrapf(err, "looking up cloud config of instance group '%s'", ig.Name)
+		}
+
+		services, err := kc.serviceToKubeServices(m.Name, ig, &essv1.ExtendedStatefulSet{}, igCloudConfig.vmExtensions, m.UsesDNSAddresses())
+		if err != nil {
+			return nil, errors.Wrapf(err, "converting services of instance group '%s'", ig.Name)
+		}
+		for _, service := range services {
+			add(previewResource{kind: "Service", name: service.Name, spec: service.Spec})
+		}
+	}
+
+	return resources, nil
+}
+
+// changedFields returns the top level keys, which differ between two YAML documents
+func changedFields(oldYAML string, newYAML string) ([]string, error) {
+	oldFields := map[string]interface{}{}
+	newFields := map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(oldYAML), &oldFields); err != nil {
+		return nil, errors.Wrap(err, "unmarshalling current YAML")
+	}
+	if err := yaml.Unmarshal([]byte(newYAML), &newFields); err != nil {
+		return nil, errors.Wrap(err, "unmarshalling desired YAML")
+	}
+
+	changed := []string{}
+	for key, value := range newFields {
+		if !reflect.DeepEqual(oldFields[key], value) {
+			changed = append(changed, key)
+		}
+	}
+	for key := range oldFields {
+		if _, ok := newFields[key]; !ok {
+			changed = append(changed, key)
+		}
+	}
+	sort.Strings(changed)
+
+	return changed, nil
+}
+
+// diffProperties compares the properties of all jobs, which exist in either manifest
+func diffProperties(current *Manifest, desired *Manifest) []PropertyChange {
+	changes := []PropertyChange{}
+
+	type jobKey struct{ instanceGroup, job string }
+	currentProperties := map[jobKey]map[string]interface{}{}
+	desiredProperties := map[jobKey]map[string]interface{}{}
+	keys := []jobKey{}
+
+	collect := func(m *Manifest, properties map[jobKey]map[string]interface{}) {
+		if m == nil {
+			return
+		}
+		for _, ig := range m.InstanceGroups {
+			for _, job := range ig.Jobs {
+				key := jobKey{ig.Name, job.Name}
+				if _, seen := currentProperties[key]; !seen {
+					if _, seen := desiredProperties[key]; !seen {
+						keys = append(keys, key)
+					}
+				}
+				properties[key] = flattenProperties("", job.Properties.Properties)
+			}
+		}
+	}
+	collect(current, currentProperties)
+	collect(desired, desiredProperties)
+
+	for _, key := range keys {
+		oldProperties := currentProperties[key]
+		newProperties := desiredProperties[key]
+
+		paths := []string{}
+		for path := range oldProperties {
+			paths = append(paths, path)
+		}
+		for path := range newProperties {
+			if _, ok := oldProperties[path]; !ok {
+				paths = append(paths, path)
+			}
+		}
+		sort.Strings(paths)
+
+		for _, path := range paths {
+			if reflect.DeepEqual(oldProperties[path], newProperties[path]) {
+				continue
+			}
+			changes = append(changes, PropertyChange{
+				InstanceGroup: key.instanceGroup,
+				Job:           key.job,
+				Property:      path,
+				Old:           oldProperties[path],
+				New:           newProperties[path],
+			})
+		}
+	}
+
+	return changes
+}
+
+// flattenProperties maps the dotted path of each leaf property to its value
+func flattenProperties(prefix string, properties map[string]interface{}) map[string]interface{} {
+	flat := map[string]interface{}{}
+
+	for key, value := range properties {
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+
+		switch value := value.(type) {
+		case map[string]interface{}:
+			for k, v := range flattenProperties(path, value) {
+				flat[k] = v
+			}
+		case map[interface{}]interface{}:
+			nested := map[string]interface{}{}
+			for k, v := range value {
+				nested[fmt.Sprintf("%v", k)] = v
+			}
+			for k, v := range flattenProperties(path, nested) {
+				flat[k] = v
+			}
+		default:
+			flat[path] = value
+		}
+	}
+
+	return flat
+}
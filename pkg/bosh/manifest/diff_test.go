@@ -0,0 +1,88 @@
+package manifest_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
+	"code.cloudfoundry.org/cf-operator/testing"
+)
+
+var _ = Describe("Diff", func() {
+	var (
+		env           testing.Catalog
+		current       manifest.Manifest
+		desired       manifest.Manifest
+		kubeConverter *manifest.KubeConverter
+	)
+
+	BeforeEach(func() {
+		current = env.DefaultBOSHManifest()
+		desired = env.DefaultBOSHManifest()
+		kubeConverter = manifest.NewKubeConverter("foo")
+	})
+
+	act := func() *manifest.DeploymentDiff {
+		diff, err := kubeConverter.Diff(&current, &desired, "cluster.local")
+		Expect(err).ToNot(HaveOccurred())
+		return diff
+	}
+
+	It("is empty when the manifests are equal", func() {
+		diff := act()
+		Expect(diff.Resources).To(BeEmpty())
+		Expect(diff.Properties).To(BeEmpty())
+	})
+
+	It("reports a new version for changed instance groups and their new services", func() {
+		desired.InstanceGroups[1].Instances = 3
+
+		diff := act()
+		Expect(diff.Resources).To(ConsistOf(
+			manifest.ResourceChange{Kind: "ExtendedJob", Name: "dm-foo-deployment", Action: manifest.ActionUpdate},
+			manifest.ResourceChange{Kind: "ExtendedStatefulSet", Name: "foo-deployment-diego-cell", Action: manifest.ActionUpdate, Reason: "changed instances"},
+			manifest.ResourceChange{Kind: "Service", Name: "foo-deployment-diego-cell-2", Action: manifest.ActionCreate},
+		))
+	})
+
+	It("reports created and deleted resources", func() {
+		desired.InstanceGroups = desired.InstanceGroups[1:]
+		desired.Variables = append(desired.Variables, manifest.Variable{Name: "router_password", Type: "password"})
+
+		diff := act()
+		Expect(diff.Resources).To(ConsistOf(
+			manifest.ResourceChange{Kind: "ExtendedJob", Name: "bpm-foo-deployment", Action: manifest.ActionUpdate},
+			manifest.ResourceChange{Kind: "ExtendedJob", Name: "dg-foo-deployment", Action: manifest.ActionUpdate},
+			manifest.ResourceChange{Kind: "ExtendedJob", Name: "dm-foo-deployment", Action: manifest.ActionUpdate},
+			manifest.ResourceChange{Kind: "ExtendedJob", Name: "foo-deployment-redis-slave", Action: manifest.ActionDelete},
+			manifest.ResourceChange{Kind: "ExtendedSecret", Name: "foo-deployment.var-router-password", Action: manifest.ActionCreate},
+		))
+		Expect(diff.Properties).To(BeEmpty())
+	})
+
+	It("reports changed job properties", func() {
+		desired.InstanceGroups[1].Jobs[0].Properties.Properties["foo"] = map[interface{}]interface{}{
+			"domain": "example.com",
+			"port":   8080,
+		}
+
+		diff := act()
+		Expect(diff.Properties).To(Equal([]manifest.PropertyChange{
+			{InstanceGroup: "diego-cell", Job: "cflinuxfs3-rootfs-setup", Property: "foo.domain", Old: "((system_domain))", New: "example.com"},
+			{InstanceGroup: "diego-cell", Job: "cflinuxfs3-rootfs-setup", Property: "foo.port", New: 8080},
+		}))
+	})
+
+	It("reports all resources as created, if nothing has been deployed", func() {
+		diff, err := kubeConverter.Diff(nil, &desired, "cluster.local")
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(diff.Resources).To(ContainElement(
+			manifest.ResourceChange{Kind: "ExtendedJob", Name: "dm-foo-deployment", Action: manifest.ActionCreate},
+		))
+		for _, change := range diff.Resources {
+			Expect(change.Action).To(Equal(manifest.ActionCreate))
+		}
+		Expect(diff.Properties).ToNot(BeEmpty())
+	})
+})
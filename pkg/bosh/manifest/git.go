@@ -0,0 +1,159 @@
+package manifest
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// gitTimeout bounds each git command, e.g. fetching a remote repository
+	gitTimeout = 2 * time.Minute
+	// gitRemoteHead is the ref, which stores the fetched HEAD of the remote repository
+	gitRemoteHead = "refs/remote/HEAD"
+)
+
+var (
+	commitRegex = regexp.MustCompile(`^[0-9a-f]{40}$`)
+	// revisionRegex matches branches, tags and commits. They must not start with a dash,
+	// so they are never mistaken for options of git commands.
+	revisionRegex = regexp.MustCompile(`^[0-9A-Za-z_][0-9A-Za-z_./-]*$`)
+	// gitSchemes are the transports repositories can be fetched with. Others, like 'ext::'
+	// or 'file://', would run commands or read files of the operator.
+	gitSchemes = map[string]bool{"https": true, "ssh": true, "git": true}
+)
+
+// gitCache reads files from git repositories. Each repository is fetched into a
+// bare repository below dir. Pinned commits, which have been fetched before, are
+// read from the cache without contacting the remote again.
+type gitCache struct {
+	dir   string
+	mutex sync.Mutex
+}
+
+func newGitCache(dir string) *gitCache {
+	return &gitCache{dir: dir}
+}
+
+// Read returns the content of path at revision in repo and the commit revision
+// has been resolved to. An empty revision means the remote HEAD.
+func (g *gitCache) Read(repo string, revision string, path string) (string, string, error) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if repo == "" || path == "" {
+		return "", "", fmt.Errorf("git ref needs a repository and a path")
+	}
+	if err := validateGitRef(repo, revision); err != nil {
+		return "", "", err
+	}
+
+	dir := filepath.Join(g.dir, fmt.Sprintf("%x", sha256.Sum256([]byte(repo))))
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		if err := os.MkdirAll(g.dir, 0700); err != nil {
+			return "", "", errors.Wrapf(err, "failed to create git cache directory '%s'", g.dir)
+		}
+		if _, err := git("", "init", "--quiet", "--bare", "--", dir); err != nil {
+			return "", "", err
+		}
+	}
+
+	commit, err := g.resolve(dir, repo, revision)
+	if err != nil {
+		return "", "", err
+	}
+
+	data, err := git(dir, "show", fmt.Sprintf("%s:%s", commit, strings.TrimPrefix(path, "/")))
+	if err != nil {
+		return "", "", errors.Wrapf(err, "failed to read '%s' from commit %s of git repository '%s'", path, commit, repo)
+	}
+
+	return data, commit, nil
+}
+
+// resolve returns the commit of revision. Commits are looked up in the cache
+// first, everything else is fetched from the remote repository.
+func (g *gitCache) resolve(dir string, repo string, revision string) (string, error) {
+	if commitRegex.MatchString(revision) {
+		if commit, err := revParse(dir, revision); err == nil {
+			return commit, nil
+		}
+	}
+
+	_, err := git(dir, "fetch", "--quiet", "--force", "--prune", "--tags", "--", repo,
+		"+refs/heads/*:refs/heads/*",
+		"+HEAD:"+gitRemoteHead,
+	)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to fetch git repository '%s'", repo)
+	}
+
+	if revision == "" || revision == "HEAD" {
+		revision = gitRemoteHead
+	}
+	commit, err := revParse(dir, revision)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to resolve revision '%s' of git repository '%s'", revision, repo)
+	}
+
+	return commit, nil
+}
+
+// validateGitRef checks the repository and revision of a git ref, before they are passed to git
+func validateGitRef(repo string, revision string) error {
+	if strings.HasPrefix(repo, "-") {
+		return fmt.Errorf("invalid git repository '%s'", repo)
+	}
+	u, err := url.Parse(repo)
+	if err != nil {
+		return errors.Wrapf(err, "invalid git repository '%s'", repo)
+	}
+	if !gitSchemes[u.Scheme] || u.Host == "" {
+		return fmt.Errorf("invalid git repository '%s': only https, ssh and git urls are supported", repo)
+	}
+
+	if revision != "" && (!revisionRegex.MatchString(revision) || strings.Contains(revision, "..")) {
+		return fmt.Errorf("invalid git revision '%s'", revision)
+	}
+
+	return nil
+}
+
+func revParse(dir string, revision string) (string, error) {
+	commit, err := git(dir, "rev-parse", "--verify", "--quiet", revision+"^{commit}")
+	return strings.TrimSpace(commit), err
+}
+
+// git runs a git command in dir and returns its output
+func git(dir string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), gitTimeout)
+	defer cancel()
+
+	command := args[0]
+	if dir != "" {
+		args = append([]string{"--git-dir", dir}, args...)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", errors.Wrapf(err, "git %s failed: %s", command, strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.String(), nil
+}
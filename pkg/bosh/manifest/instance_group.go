@@ -2,6 +2,7 @@ package manifest
 
 import (
 	"fmt"
+	"sort"
 
 	corev1 "k8s.io/api/core/v1"
 
@@ -27,37 +28,200 @@ type InstanceGroup struct {
 	LifeCycle          string                 `yaml:"lifecycle,omitempty"`
 	Properties         map[string]interface{} `yaml:"properties,omitempty"`
 	Env                AgentEnv               `yaml:"env,omitempty"`
+	// Placements are recorded by the operator, see PlaceInstances
+	Placements []InstancePlacement `yaml:"placements,omitempty"`
 }
 
-func (ig *InstanceGroup) jobInstances(namespace string, deploymentName string, jobName string, spec JobSpec) []bc.JobInstance {
-	var jobsInstances []bc.JobInstance
-	for i := 0; i < ig.Instances; i++ {
+// InstancePlacement is the AZ of an instance and the ordinal of its pod in the
+// StatefulSet of that AZ
+type InstancePlacement struct {
+	AZ      string `yaml:"az,omitempty"`
+	Ordinal int    `yaml:"ordinal"`
+}
 
-		// TODO: Understand whether there are negative side-effects to using this
-		// default
-		azs := []string{""}
-		if len(ig.AZs) > 0 {
-			azs = ig.AZs
+// azNames returns the AZs of the instance group, which is a single unnamed AZ without AZs
+func (ig *InstanceGroup) azNames() []string {
+	if len(ig.AZs) == 0 {
+		return []string{""}
+	}
+	return ig.AZs
+}
+
+// PlaceInstances records the AZ and pod ordinal of each instance. Like BOSH,
+// instances are balanced across the AZs and new instances are placed round
+// robin. Instances keep their placement from the previous instance group, so
+// changing the AZs or the number of instances only moves the instances, which
+// have to move to keep the AZs balanced.
+func (ig *InstanceGroup) PlaceInstances(previous *InstanceGroup) {
+	var placements []InstancePlacement
+	if previous != nil {
+		placements = previous.Placements
+	}
+	ig.Placements = ig.placeInstances(placements)
+}
+
+// placements returns the recorded placements, or places the instances round
+// robin, if none are recorded for the current instances and AZs
+func (ig *InstanceGroup) placements() []InstancePlacement {
+	if len(ig.Placements) != ig.Instances {
+		return ig.placeInstances(nil)
+	}
+	for _, placement := range ig.Placements {
+		if !contains(ig.azNames(), placement.AZ) {
+			return ig.placeInstances(nil)
+		}
+	}
+	return ig.Placements
+}
+
+// instancePlacement returns the AZ index and the pod ordinal of the instance
+// with the given index
+func (ig *InstanceGroup) instancePlacement(index int) (int, int) {
+	placement := ig.placements()[index]
+	for azIndex, az := range ig.azNames() {
+		if az == placement.AZ {
+			return azIndex, placement.Ordinal
+		}
+	}
+	return 0, placement.Ordinal
+}
+
+// InstanceIndex returns the index of the instance, which runs as the pod with
+// the given ordinal in the StatefulSet of the AZ with the given index
+func (ig *InstanceGroup) InstanceIndex(azIndex int, podOrdinal int) (int, error) {
+	for index := 0; index < ig.Instances; index++ {
+		instanceAZIndex, instanceOrdinal := ig.instancePlacement(index)
+		if instanceAZIndex == azIndex && instanceOrdinal == podOrdinal {
+			return index, nil
+		}
+	}
+	return 0, fmt.Errorf("no instance of instance group '%s' runs as pod %d in AZ %d", ig.Name, podOrdinal, azIndex)
+}
+
+// azReplicas returns the number of instances in each AZ
+func (ig *InstanceGroup) azReplicas() []int32 {
+	replicas := make([]int32, len(ig.azNames()))
+	for index := 0; index < ig.Instances; index++ {
+		azIndex, _ := ig.instancePlacement(index)
+		replicas[azIndex]++
+	}
+	return replicas
+}
+
+// placeInstances places the instances, keeping the previous placements where possible.
+// The pod ordinals of each AZ are kept consecutive, since they belong to a StatefulSet.
+func (ig *InstanceGroup) placeInstances(previous []InstancePlacement) []InstancePlacement {
+	azs := ig.azNames()
+	placements := make([]InstancePlacement, ig.Instances)
+	members := map[string][]int{}
+
+	// Keep the instances in AZs, which still exist
+	for index := 0; index < ig.Instances && index < len(previous); index++ {
+		if contains(azs, previous[index].AZ) {
+			placements[index] = previous[index]
+			members[previous[index].AZ] = append(members[previous[index].AZ], index)
+		}
+	}
+
+	// Balance the AZs, the AZs with the most instances keep the remainder
+	byCount := append([]string{}, azs...)
+	sort.SliceStable(byCount, func(i, j int) bool {
+		return len(members[byCount[i]]) > len(members[byCount[j]])
+	})
+	target := map[string]int{}
+	for i, az := range byCount {
+		target[az] = ig.Instances / len(azs)
+		if i < ig.Instances%len(azs) {
+			target[az]++
+		}
+	}
+
+	placed := make([]bool, ig.Instances)
+	for _, az := range azs {
+		// Remove the instances with the highest ordinals from AZs with too many instances
+		indices := members[az]
+		sort.Slice(indices, func(i, j int) bool {
+			return placements[indices[i]].Ordinal < placements[indices[j]].Ordinal
+		})
+		if len(indices) > target[az] {
+			indices = indices[:target[az]]
+		}
+		members[az] = indices
+
+		// Close gaps left by removed instances, so the ordinals are consecutive
+		for ordinal, index := range indices {
+			placements[index].Ordinal = ordinal
+			placed[index] = true
+		}
+	}
+
+	// Place new and removed instances in the AZ with the fewest instances
+	for index := range placements {
+		if placed[index] {
+			continue
+		}
+
+		best := -1
+		for i, az := range azs {
+			if len(members[az]) >= target[az] {
+				continue
+			}
+			if best < 0 || len(members[az]) < len(members[azs[best]]) {
+				best = i
+			}
+		}
+
+		az := azs[best]
+		placements[index] = InstancePlacement{AZ: az, Ordinal: len(members[az])}
+		members[az] = append(members[az], index)
+	}
+
+	return placements
+}
+
+// terminationGracePeriod returns the time in seconds the pods of the instance
+// group are given to drain their jobs and to stop their processes.
+func (ig *InstanceGroup) terminationGracePeriod() int64 {
+	maxDrainTime := DefaultMaxDrainTime
+	if ig.Env.AgentEnvBoshConfig.Agent.Settings.MaxDrainTime != nil {
+		maxDrainTime = *ig.Env.AgentEnvBoshConfig.Agent.Settings.MaxDrainTime
+	}
+	return int64(maxDrainTime + processStopTime)
+}
+
+// consumesLinks returns true if a job of the instance group consumes links
+func (ig *InstanceGroup) consumesLinks() bool {
+	for _, job := range ig.Jobs {
+		if len(job.Properties.BOSHContainerization.Consumes) > 0 {
+			return true
 		}
+	}
+	return false
+}
 
-		for _, az := range azs {
-			index := len(jobsInstances)
-			name := fmt.Sprintf("%s-%s", ig.Name, jobName)
-			id := fmt.Sprintf("%s-%d-%s", ig.Name, index, jobName)
-			// All jobs in same instance group will use same service
-			serviceName := fmt.Sprintf("%s-%s-%d", deploymentName, ig.Name, index)
-			// TODO: not allowed to hardcode svc.cluster.local
-			address := fmt.Sprintf("%s.%s.svc.cluster.local", serviceName, namespace)
-
-			jobsInstances = append(jobsInstances, bc.JobInstance{
-				Address:  address,
-				AZ:       az,
-				ID:       id,
-				Index:    index,
-				Instance: i,
-				Name:     name,
-			})
+func (ig *InstanceGroup) jobInstances(namespace string, clusterDomain string, deploymentName string, jobName string, spec JobSpec) []bc.JobInstance {
+	var jobsInstances []bc.JobInstance
+	for index := 0; index < ig.Instances; index++ {
+		azIndex, podOrdinal := ig.instancePlacement(index)
+		az := ""
+		if len(ig.AZs) > 0 {
+			az = ig.AZs[azIndex]
 		}
+
+		name := fmt.Sprintf("%s-%s", ig.Name, jobName)
+		id := fmt.Sprintf("%s-%d-%s", ig.Name, index, jobName)
+		// All jobs in same instance group will use same service
+		serviceName := fmt.Sprintf("%s-%s-%d", deploymentName, ig.Name, index)
+		address := fmt.Sprintf("%s.%s.svc.%s", serviceName, namespace, clusterDomain)
+
+		jobsInstances = append(jobsInstances, bc.JobInstance{
+			Address:  address,
+			AZ:       az,
+			ID:       id,
+			Index:    index,
+			Instance: podOrdinal,
+			Name:     name,
+		})
 	}
 	return jobsInstances
 }
@@ -78,11 +242,11 @@ type Network struct {
 
 // Update from BOSH deployment manifest
 type Update struct {
-	Canaries        int     `yaml:"canaries"`
-	MaxInFlight     string  `yaml:"max_in_flight"`
-	CanaryWatchTime string  `yaml:"canary_watch_time"`
-	UpdateWatchTime string  `yaml:"update_watch_time"`
-	Serial          bool    `yaml:"serial,omitempty"`
+	Canaries        *int    `yaml:"canaries"`
+	MaxInFlight     *string `yaml:"max_in_flight"`
+	CanaryWatchTime *string `yaml:"canary_watch_time"`
+	UpdateWatchTime *string `yaml:"update_watch_time"`
+	Serial          *bool   `yaml:"serial,omitempty"`
 	VMStrategy      *string `yaml:"vm_strategy,omitempty"`
 }
 
@@ -103,6 +267,14 @@ type JobDir struct {
 	TmpfsSize string `yaml:"tmpfs_size,omitempty"`
 }
 
+const (
+	// DefaultMaxDrainTime is the time in seconds jobs are given to drain, unless
+	// the instance group sets env.bosh.agent.settings.max_drain_time
+	DefaultMaxDrainTime = 30
+	// processStopTime is the time in seconds processes are given to stop after draining
+	processStopTime = 10
+)
+
 var (
 	// LabelDeploymentName is the name of a label for the deployment name
 	LabelDeploymentName = fmt.Sprintf("%s/deployment-name", apis.GroupName)
@@ -110,15 +282,21 @@ var (
 	LabelInstanceGroupName = fmt.Sprintf("%s/instance-group-name", apis.GroupName)
 	// LabelDeploymentVersion is the name of a label for the deployment's version
 	LabelDeploymentVersion = fmt.Sprintf("%s/deployment-version", apis.GroupName)
+	// LabelPostDeploy is the name of a label for the ExtendedJobs, which run the post-deploy scripts of an instance group
+	LabelPostDeploy = fmt.Sprintf("%s/post-deploy", apis.GroupName)
+	// LabelErrand is the name of a label for the ExtendedJobs, which run the errands of a deployment
+	LabelErrand = fmt.Sprintf("%s/errand", apis.GroupName)
 )
 
 // AgentSettings from BOSH deployment manifest.
 // These annotations and labels are added to kube resources.
 // Affinity is added into the pod's definition.
+// MaxDrainTime is the time in seconds the jobs of a pod are given to drain.
 type AgentSettings struct {
-	Annotations map[string]string `yaml:"annotations,omitempty"`
-	Labels      map[string]string `yaml:"labels,omitempty"`
-	Affinity    *corev1.Affinity  `json:"affinity,omitempty" yaml:"affinity,omitempty"`
+	Annotations  map[string]string `yaml:"annotations,omitempty"`
+	Labels       map[string]string `yaml:"labels,omitempty"`
+	Affinity     *corev1.Affinity  `json:"affinity,omitempty" yaml:"affinity,omitempty"`
+	MaxDrainTime *int              `yaml:"max_drain_time,omitempty"`
 }
 
 // Set overrides labels and annotations with operator-owned metadata
@@ -0,0 +1,146 @@
+package manifest_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
+)
+
+var _ = Describe("InstanceGroup", func() {
+	Describe("PlaceInstances", func() {
+		var (
+			ig       *InstanceGroup
+			previous *InstanceGroup
+		)
+
+		BeforeEach(func() {
+			ig = &InstanceGroup{Name: "diego-cell", Instances: 4, AZs: []string{"z1", "z2"}}
+			previous = nil
+		})
+
+		JustBeforeEach(func() {
+			ig.PlaceInstances(previous)
+		})
+
+		It("places new instances round robin", func() {
+			Expect(ig.Placements).To(Equal([]InstancePlacement{
+				{AZ: "z1", Ordinal: 0},
+				{AZ: "z2", Ordinal: 0},
+				{AZ: "z1", Ordinal: 1},
+				{AZ: "z2", Ordinal: 1},
+			}))
+		})
+
+		Context("when the instance group has no AZs", func() {
+			BeforeEach(func() {
+				ig.AZs = nil
+			})
+
+			It("places all instances in a single unnamed AZ", func() {
+				Expect(ig.Placements).To(Equal([]InstancePlacement{
+					{Ordinal: 0},
+					{Ordinal: 1},
+					{Ordinal: 2},
+					{Ordinal: 3},
+				}))
+			})
+		})
+
+		Context("when an AZ is added", func() {
+			BeforeEach(func() {
+				ig.Instances = 6
+				ig.AZs = []string{"z1", "z2", "z3"}
+				previous = &InstanceGroup{Name: "diego-cell", Instances: 6, AZs: []string{"z1", "z2"}}
+				previous.PlaceInstances(nil)
+			})
+
+			It("only moves the instances, which are needed to balance the AZs", func() {
+				Expect(ig.Placements).To(Equal([]InstancePlacement{
+					{AZ: "z1", Ordinal: 0},
+					{AZ: "z2", Ordinal: 0},
+					{AZ: "z1", Ordinal: 1},
+					{AZ: "z2", Ordinal: 1},
+					{AZ: "z3", Ordinal: 0},
+					{AZ: "z3", Ordinal: 1},
+				}))
+			})
+
+			It("looks up the instances by their new placement", func() {
+				index, err := ig.InstanceIndex(2, 1)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(index).To(Equal(5))
+
+				index, err = ig.InstanceIndex(1, 1)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(index).To(Equal(3))
+
+				_, err = ig.InstanceIndex(0, 2)
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("when an AZ is removed", func() {
+			BeforeEach(func() {
+				ig.Instances = 6
+				previous = &InstanceGroup{Name: "diego-cell", Instances: 6, AZs: []string{"z1", "z2", "z3"}}
+				previous.PlaceInstances(nil)
+			})
+
+			It("keeps the instances of the remaining AZs and moves the others", func() {
+				Expect(ig.Placements).To(Equal([]InstancePlacement{
+					{AZ: "z1", Ordinal: 0},
+					{AZ: "z2", Ordinal: 0},
+					{AZ: "z1", Ordinal: 2},
+					{AZ: "z1", Ordinal: 1},
+					{AZ: "z2", Ordinal: 1},
+					{AZ: "z2", Ordinal: 2},
+				}))
+			})
+		})
+
+		Context("when the instance group is scaled up", func() {
+			BeforeEach(func() {
+				ig.Instances = 5
+				previous = &InstanceGroup{Name: "diego-cell", Instances: 4, AZs: []string{"z1", "z2"}}
+				previous.PlaceInstances(nil)
+			})
+
+			It("keeps the existing instances and places the new ones", func() {
+				Expect(ig.Placements).To(Equal([]InstancePlacement{
+					{AZ: "z1", Ordinal: 0},
+					{AZ: "z2", Ordinal: 0},
+					{AZ: "z1", Ordinal: 1},
+					{AZ: "z2", Ordinal: 1},
+					{AZ: "z1", Ordinal: 2},
+				}))
+			})
+		})
+
+		Context("when the instance group is scaled down", func() {
+			BeforeEach(func() {
+				ig.Instances = 3
+				previous = &InstanceGroup{Name: "diego-cell", Instances: 4, AZs: []string{"z1", "z2"}}
+				previous.PlaceInstances(nil)
+			})
+
+			It("keeps the remaining instances", func() {
+				Expect(ig.Placements).To(Equal([]InstancePlacement{
+					{AZ: "z1", Ordinal: 0},
+					{AZ: "z2", Ordinal: 0},
+					{AZ: "z1", Ordinal: 1},
+				}))
+			})
+		})
+	})
+
+	Describe("InstanceIndex", func() {
+		It("uses round robin placements, if none are recorded", func() {
+			ig := &InstanceGroup{Name: "diego-cell", Instances: 4, AZs: []string{"z1", "z2"}}
+
+			index, err := ig.InstanceIndex(1, 1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(index).To(Equal(3))
+		})
+	})
+})
@@ -13,19 +13,42 @@ import (
 // JobSpecFilename is the name of the job spec manifest in an unpacked BOSH release
 const JobSpecFilename = "job.MF"
 
+// MonitFilename is the name of the monit file in an unpacked BOSH release
+const MonitFilename = "monit"
+
+const (
+	// PostStartScript is the destination of a job's post-start template
+	PostStartScript = "bin/post-start"
+	// PostDeployScript is the destination of a job's post-deploy template
+	PostDeployScript = "bin/post-deploy"
+)
+
 // JobSpec describes the contents of "job.MF" files
 type JobSpec struct {
 	Name        string
 	Description string
 	Packages    []string
 	Templates   map[string]string
-	Properties  map[string]struct {
-		Description string
-		Default     interface{}
-		Example     interface{}
+	Properties  map[string]JobSpecProperty
+	Consumes    []JobSpecProvider
+	Provides    []JobSpecLink
+}
+
+// JobSpecProperty is the definition of a property in a job spec
+type JobSpecProperty struct {
+	Description string
+	Default     interface{}
+	Example     interface{}
+}
+
+// HasTemplate returns true if one of the job's templates is rendered to the destination
+func (spec JobSpec) HasTemplate(destination string) bool {
+	for _, dst := range spec.Templates {
+		if dst == destination {
+			return true
+		}
 	}
-	Consumes []JobSpecProvider
-	Provides []JobSpecLink
+	return false
 }
 
 // JobSpecProvider represents a provider in the job spec Consumes field.
@@ -23,6 +23,9 @@ const (
 	// EnvCFONamespace is a key for the container Env used to lookup the
 	// namespace CF operator is running in
 	EnvCFONamespace = "CF_OPERATOR_NAMESPACE"
+	// EnvClusterDomain is a key for the container Env used to lookup the
+	// DNS domain of the cluster
+	EnvClusterDomain = "CLUSTER_DOMAIN"
 	// EnvBaseDir is a key for the container Env used to lookup the base dir
 	EnvBaseDir = "BASE_DIR"
 	// EnvVariablesDir is a key for the container Env used to lookup the variables dir
@@ -39,14 +42,16 @@ const (
 type JobFactory struct {
 	Manifest            Manifest
 	Namespace           string
+	ClusterDomain       string
 	desiredManifestName string
 }
 
 // NewJobFactory returns a new JobFactory
-func NewJobFactory(manifest Manifest, namespace string) *JobFactory {
+func NewJobFactory(manifest Manifest, namespace string, clusterDomain string) *JobFactory {
 	return &JobFactory{
-		Manifest:  manifest,
-		Namespace: namespace,
+		Manifest:      manifest,
+		Namespace:     namespace,
+		ClusterDomain: clusterDomain,
 		// ExtendedJob will always pick the latest version for versioned secrets
 		desiredManifestName: names.DesiredManifestName(manifest.Name, "1"),
 	}
@@ -167,7 +172,8 @@ func (f *JobFactory) VariableInterpolationJob() (*ejv1.ExtendedJob, error) {
 					},
 				},
 				Spec: corev1.PodSpec{
-					RestartPolicy: corev1.RestartPolicyOnFailure,
+					RestartPolicy:    corev1.RestartPolicyOnFailure,
+					ImagePullSecrets: f.Manifest.ImagePullSecrets,
 					Containers: []corev1.Container{
 						{
 							Name:         VarInterpolationContainerName,
@@ -205,7 +211,12 @@ func (f *JobFactory) DataGatheringJob() (*ejv1.ExtendedJob, error) {
 	}
 
 	eJobName := fmt.Sprintf("dg-%s", f.Manifest.Name)
-	return f.gatheringJob(eJobName, names.DeploymentSecretTypeInstanceGroupResolvedProperties, containers)
+	job, err := f.gatheringJob(eJobName, names.DeploymentSecretTypeInstanceGroupResolvedProperties, containers)
+	if err != nil {
+		return nil, err
+	}
+	f.mountConsumedLinks(job)
+	return job, nil
 }
 
 // BPMConfigsJob returns an extended job to calculate BPM information
@@ -219,11 +230,60 @@ func (f *JobFactory) BPMConfigsJob() (*ejv1.ExtendedJob, error) {
 	}
 
 	eJobName := fmt.Sprintf("bpm-%s", f.Manifest.Name)
-	return f.gatheringJob(eJobName, names.DeploymentSecretBpmInformation, containers)
+	job, err := f.gatheringJob(eJobName, names.DeploymentSecretBpmInformation, containers)
+	if err != nil {
+		return nil, err
+	}
+	f.mountConsumedLinks(job)
+	return job, nil
+}
+
+// LinksJob returns an extended job to collect the links, which the deployment
+// shares with other deployments
+func (f *JobFactory) LinksJob() (*ejv1.ExtendedJob, error) {
+	container := f.gatheringContainer("shared-links", "")
+	container.Name = LinksContainerName
+
+	eJobName := fmt.Sprintf("lnk-%s", f.Manifest.Name)
+	job, err := f.gatheringJob(eJobName, names.DeploymentSecretTypeLinks, []corev1.Container{container})
+	if err != nil {
+		return nil, err
+	}
+	// The output secret is named '<deployment-name>.links-v<version>'
+	job.Spec.Output.NamePrefix = names.DesiredManifestPrefix(f.Manifest.Name)
+	return job, nil
+}
+
+// mountConsumedLinks mounts the latest links shared by the deployments, whose links are
+// consumed. When a deployment shares new links, the job runs again.
+func (f *JobFactory) mountConsumedLinks(job *ejv1.ExtendedJob) {
+	podSpec := &job.Spec.Template.Spec
+	for _, deployment := range f.Manifest.ConsumedDeployments() {
+		// ExtendedJob will always pick the latest version for versioned secrets
+		secretName := names.LinksSecretName(deployment, "1")
+		volumeName := names.Sanitize("links-" + deployment)
+
+		podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+			Name: volumeName,
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName: secretName,
+				},
+			},
+		})
+
+		for i := range podSpec.Containers {
+			podSpec.Containers[i].VolumeMounts = append(podSpec.Containers[i].VolumeMounts, corev1.VolumeMount{
+				Name:      volumeName,
+				MountPath: filepath.Join(VolumeLinksMountPath, deployment),
+				ReadOnly:  true,
+			})
+		}
+	}
 }
 
 func (f *JobFactory) gatheringContainer(cmd, instanceGroupName string) corev1.Container {
-	return corev1.Container{
+	container := corev1.Container{
 		Name:  names.Sanitize(instanceGroupName),
 		Image: GetOperatorDockerImage(),
 		Args:  []string{"util", cmd},
@@ -247,16 +307,27 @@ func (f *JobFactory) gatheringContainer(cmd, instanceGroupName string) corev1.Co
 				Name:  EnvCFONamespace,
 				Value: f.Namespace,
 			},
+			{
+				Name:  EnvClusterDomain,
+				Value: f.ClusterDomain,
+			},
 			{
 				Name:  EnvBaseDir,
 				Value: VolumeRenderingDataMountPath,
 			},
 			{
-				Name:  EnvInstanceGroupName,
-				Value: instanceGroupName,
+				Name:  EnvLinksDir,
+				Value: VolumeLinksMountPath,
 			},
 		},
 	}
+	if instanceGroupName != "" {
+		container.Env = append(container.Env, corev1.EnvVar{
+			Name:  EnvInstanceGroupName,
+			Value: instanceGroupName,
+		})
+	}
+	return container
 }
 
 func (f *JobFactory) gatheringJob(name string, secretType names.DeploymentSecretType, containers []corev1.Container) (*ejv1.ExtendedJob, error) {
@@ -316,7 +387,8 @@ func (f *JobFactory) gatheringJob(name string, secretType names.DeploymentSecret
 					},
 				},
 				Spec: corev1.PodSpec{
-					RestartPolicy: corev1.RestartPolicyOnFailure,
+					RestartPolicy:    corev1.RestartPolicyOnFailure,
+					ImagePullSecrets: f.Manifest.ImagePullSecrets,
 					// Init Container to copy contents
 					InitContainers: initContainers,
 					// Container to run data gathering
@@ -19,7 +19,7 @@ var _ = Describe("JobFactory", func() {
 
 	BeforeEach(func() {
 		m = env.DefaultBOSHManifest()
-		factory = manifest.NewJobFactory(m, "namespace")
+		factory = manifest.NewJobFactory(m, "namespace", "cluster.local")
 	})
 
 	Describe("DataGatheringJob", func() {
@@ -33,6 +33,57 @@ var _ = Describe("JobFactory", func() {
 			Expect(jobDG.InitContainers[0].VolumeMounts[0].MountPath).To(Equal("/var/vcap/all-releases"))
 			Expect(jobDG.InitContainers[1].VolumeMounts[0].MountPath).To(Equal("/var/vcap/all-releases"))
 		})
+
+		It("mounts the links shared by consumed deployments", func() {
+			m.InstanceGroups[0].Jobs[0].Consumes = map[string]interface{}{
+				"database": map[interface{}]interface{}{"from": "db", "deployment": "mysql"},
+			}
+			factory = manifest.NewJobFactory(m, "namespace", "cluster.local")
+
+			job, err := factory.DataGatheringJob()
+			Expect(err).ToNot(HaveOccurred())
+			podSpec := job.Spec.Template.Spec
+
+			Expect(podSpec.Volumes).To(ContainElement(corev1.Volume{
+				Name: "links-mysql",
+				VolumeSource: corev1.VolumeSource{
+					Secret: &corev1.SecretVolumeSource{SecretName: "mysql.links-v1"},
+				},
+			}))
+			for _, container := range podSpec.Containers {
+				Expect(container.VolumeMounts).To(ContainElement(corev1.VolumeMount{
+					Name:      "links-mysql",
+					MountPath: "/var/run/secrets/links/mysql",
+					ReadOnly:  true,
+				}))
+			}
+		})
+
+		It("uses the image pull secrets of the manifest", func() {
+			m.ImagePullSecrets = []corev1.LocalObjectReference{{Name: "registry-credentials"}}
+			factory = manifest.NewJobFactory(m, "namespace", "cluster.local")
+
+			job, err := factory.DataGatheringJob()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(job.Spec.Template.Spec.ImagePullSecrets).To(Equal(m.ImagePullSecrets))
+		})
+	})
+
+	Describe("LinksJob", func() {
+		It("has a single container, whose output is the links secret of the deployment", func() {
+			job, err := factory.LinksJob()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(job.Name).To(Equal("lnk-" + m.Name))
+			Expect(job.Spec.Output.NamePrefix + job.Spec.Template.Spec.Containers[0].Name).To(Equal(m.Name + ".links"))
+			Expect(job.Spec.Output.Versioned).To(BeTrue())
+
+			Expect(job.Spec.Template.Spec.Containers).To(HaveLen(1))
+			container := job.Spec.Template.Spec.Containers[0]
+			Expect(container.Args).To(Equal([]string{"util", "shared-links"}))
+			for _, env := range container.Env {
+				Expect(env.Name).ToNot(Equal(manifest.EnvInstanceGroupName))
+			}
+		})
 	})
 
 	Describe("BPMConfigsJob", func() {
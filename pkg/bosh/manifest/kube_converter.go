@@ -1,6 +1,8 @@
 package manifest
 
 import (
+	"time"
+
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	esv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/extendedsecret/v1alpha1"
@@ -45,6 +47,9 @@ func (kc *KubeConverter) Variables(manifestName string, variables []Variable) []
 				AlternativeNames: v.Options.AlternativeNames,
 				IsCA:             v.Options.IsCA,
 			}
+			if v.Options.Duration > 0 {
+				certRequest.Duration = &metav1.Duration{Duration: time.Duration(v.Options.Duration) * 24 * time.Hour}
+			}
 			if v.Options.CA != "" {
 				certRequest.CARef = esv1.SecretReference{
 					Name: names.CalculateSecretName(names.DeploymentSecretTypeVariable, manifestName, v.Options.CA),
@@ -1,6 +1,8 @@
 package manifest_test
 
 import (
+	"time"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"github.com/onsi/gomega/format"
@@ -110,6 +112,24 @@ var _ = Describe("kube converter", func() {
 				Expect(request.IsCA).To(Equal(true))
 				Expect(request.CARef.Name).To(Equal("foo-deployment.var-theca"))
 				Expect(request.CARef.Key).To(Equal("certificate"))
+				Expect(request.Duration).To(BeNil())
+			})
+
+			It("converts the duration of certificate variables from days", func() {
+				m.Variables[0] = manifest.Variable{
+					Name: "foo-cert",
+					Type: "certificate",
+					Options: &manifest.VariableOptions{
+						CommonName: "example.com",
+						IsCA:       true,
+						Duration:   30,
+					},
+				}
+				variables := act()
+				Expect(variables).To(HaveLen(1))
+
+				request := variables[0].Spec.Request.CertificateRequest
+				Expect(request.Duration.Duration).To(Equal(30 * 24 * time.Hour))
 			})
 		})
 
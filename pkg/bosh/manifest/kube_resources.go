@@ -4,9 +4,11 @@ import (
 	"fmt"
 	"strconv"
 
+	"github.com/pkg/errors"
 	"k8s.io/api/apps/v1beta2"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 
 	"code.cloudfoundry.org/cf-operator/pkg/bosh/bpm"
 	ejv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/extendedjob/v1alpha1"
@@ -31,8 +33,11 @@ type ReleaseImageProvider interface {
 type BPMResources struct {
 	InstanceGroups []essv1.ExtendedStatefulSet
 	Errands        []ejv1.ExtendedJob
+	PostDeploys    []ejv1.ExtendedJob
 	Services       []corev1.Service
 	Disks          BPMResourceDisks
+	// PostStartContainers are the containers of the instance group, which run post-start scripts
+	PostStartContainers []string
 }
 
 // BPMResourceDisk represents a converted BPM disk to k8s resources.
@@ -91,12 +96,24 @@ func (disks BPMResourceDisks) Volumes() []corev1.Volume {
 
 // BPMResources uses BOSH Process Manager information to create k8s container specs from single BOSH instance group.
 // It returns extended stateful sets, services and extended jobs.
-func (kc *KubeConverter) BPMResources(manifestName string, version string, instanceGroup *InstanceGroup, releaseImageProvider ReleaseImageProvider, bpmConfigs bpm.Configs) (*BPMResources, error) {
+// The vm type, vm extensions and persistent disk type of the instance group are looked up in the cloud config.
+// Without DNS addresses, the services of the instances are headless, so their names resolve to the pod IPs,
+// and the pods of an instance group are started in parallel.
+// The image pull secrets are added to the pods of the instance group.
+// The post-deploy scripts of a service instance group are run by an ExtendedJob, which is triggered once
+// all instance groups of the deployment are ready.
+func (kc *KubeConverter) BPMResources(manifestName string, version string, instanceGroup *InstanceGroup, releaseImageProvider ReleaseImageProvider, bpmConfigs bpm.Configs, cloudConfig *CloudConfig, useDNSAddresses bool, imagePullSecrets []corev1.LocalObjectReference) (*BPMResources, error) {
 	instanceGroup.Env.AgentEnvBoshConfig.Agent.Settings.Set(manifestName, instanceGroup.Name, version)
 
+	igCloudConfig, err := cloudConfig.instanceGroupCloudConfig(instanceGroup)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to look up cloud config of instance group '%s'", instanceGroup.Name)
+	}
+	igCloudConfig.apply(instanceGroup)
+
 	defaultDisks := generateDefaultDisks(manifestName, instanceGroup, version, kc.namespace)
 
-	bpmDisks, err := generateBPMDisks(manifestName, instanceGroup, bpmConfigs, kc.namespace)
+	bpmDisks, err := generateBPMDisks(manifestName, instanceGroup, bpmConfigs, igCloudConfig.storageClass(instanceGroup), kc.namespace)
 	if err != nil {
 		return nil, err
 	}
@@ -116,7 +133,26 @@ func (kc *KubeConverter) BPMResources(manifestName string, version string, insta
 			return nil, err
 		}
 
-		services, err := kc.serviceToKubeServices(manifestName, instanceGroup, &convertedExtStatefulSet)
+		convertedExtStatefulSet.Spec.Template.Spec.Template.Spec.ImagePullSecrets = imagePullSecrets
+
+		// Without DNS addresses, templates are rendered with the link instances,
+		// whose pods have an IP. All pods start at once, so instances consuming
+		// their own links don't wait for each other, and render again once the
+		// addresses change.
+		if !useDNSAddresses {
+			convertedExtStatefulSet.Spec.Template.Spec.PodManagementPolicy = v1beta2.ParallelPodManagement
+			if instanceGroup.consumesLinks() {
+				podSpec := &convertedExtStatefulSet.Spec.Template.Spec.Template.Spec
+				podSpec.Containers = append(podSpec.Containers, cfac.TemplateWatchingContainer())
+			}
+		}
+
+		err = igCloudConfig.patchPodTemplate(&convertedExtStatefulSet.Spec.Template.Spec.Template)
+		if err != nil {
+			return nil, err
+		}
+
+		services, err := kc.serviceToKubeServices(manifestName, instanceGroup, &convertedExtStatefulSet, igCloudConfig.vmExtensions, useDNSAddresses)
 		if err != nil {
 			return nil, err
 		}
@@ -125,12 +161,35 @@ func (kc *KubeConverter) BPMResources(manifestName string, version string, insta
 		}
 
 		res.InstanceGroups = append(res.InstanceGroups, convertedExtStatefulSet)
+		res.PostStartContainers = cfac.PostStartContainers(instanceGroup.Jobs)
+
+		postDeployEJob, err := kc.postDeployToExtendedJob(cfac, manifestName, instanceGroup, defaultDisks, bpmDisks)
+		if err != nil {
+			return nil, err
+		}
+		if postDeployEJob != nil {
+			postDeployEJob.Spec.Template.Spec.ImagePullSecrets = imagePullSecrets
+
+			err = igCloudConfig.patchPodTemplate(&postDeployEJob.Spec.Template)
+			if err != nil {
+				return nil, err
+			}
+
+			res.PostDeploys = append(res.PostDeploys, *postDeployEJob)
+		}
 	case "errand":
 		convertedEJob, err := kc.errandToExtendedJob(cfac, manifestName, instanceGroup, defaultDisks, bpmDisks)
 		if err != nil {
 			return nil, err
 		}
 
+		convertedEJob.Spec.Template.Spec.ImagePullSecrets = imagePullSecrets
+
+		err = igCloudConfig.patchPodTemplate(&convertedEJob.Spec.Template)
+		if err != nil {
+			return nil, err
+		}
+
 		res.Errands = append(res.Errands, convertedEJob)
 	}
 
@@ -151,17 +210,39 @@ func (kc *KubeConverter) serviceToExtendedSts(
 		return essv1.ExtendedStatefulSet{}, err
 	}
 
-	containers, err := cfac.JobsToContainers(instanceGroup.Jobs, defaultVolumeMounts, bpmDisks)
+	containers, err := cfac.JobsToContainers(instanceGroup.Jobs, defaultVolumeMounts, bpmDisks, instanceGroup.VMResources)
 	if err != nil {
 		return essv1.ExtendedStatefulSet{}, err
 	}
 
+	cfac.AddPostStartHooks(instanceGroup.Jobs, containers)
+
 	defaultVolumes := defaultDisks.Volumes()
 	bpmVolumes := bpmDisks.Volumes()
 	volumes := make([]corev1.Volume, 0, len(defaultVolumes)+len(bpmVolumes))
 	volumes = append(volumes, defaultVolumes...)
 	volumes = append(volumes, bpmVolumes...)
 
+	terminationGracePeriod := instanceGroup.terminationGracePeriod()
+
+	var update *essv1.UpdateStrategy
+	if instanceGroup.Update != nil {
+		update = &essv1.UpdateStrategy{}
+		if instanceGroup.Update.Canaries != nil {
+			update.Canaries = int32(*instanceGroup.Update.Canaries)
+		}
+		if instanceGroup.Update.MaxInFlight != nil {
+			maxInFlight := intstr.Parse(*instanceGroup.Update.MaxInFlight)
+			update.MaxInFlight = &maxInFlight
+		}
+		if instanceGroup.Update.CanaryWatchTime != nil {
+			update.CanaryWatchTime = *instanceGroup.Update.CanaryWatchTime
+		}
+		if instanceGroup.Update.UpdateWatchTime != nil {
+			update.UpdateWatchTime = *instanceGroup.Update.UpdateWatchTime
+		}
+	}
+
 	extSts := essv1.ExtendedStatefulSet{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:        fmt.Sprintf("%s-%s", manifestName, names.Sanitize(instanceGroup.Name)),
@@ -171,6 +252,10 @@ func (kc *KubeConverter) serviceToExtendedSts(
 		},
 		Spec: essv1.ExtendedStatefulSetSpec{
 			UpdateOnConfigChange: true,
+			Update:               update,
+			Zones:                instanceGroup.AZs,
+			Replicas:             util.Int32(int32(instanceGroup.Instances)),
+			ZoneReplicas:         instanceGroup.azReplicas(),
 			Template: v1beta2.StatefulSet{
 				ObjectMeta: metav1.ObjectMeta{
 					Name:        instanceGroup.Name,
@@ -178,7 +263,6 @@ func (kc *KubeConverter) serviceToExtendedSts(
 					Annotations: instanceGroup.Env.AgentEnvBoshConfig.Agent.Settings.Annotations,
 				},
 				Spec: v1beta2.StatefulSetSpec{
-					Replicas: util.Int32(int32(instanceGroup.Instances)),
 					Selector: &metav1.LabelSelector{
 						MatchLabels: instanceGroup.Env.AgentEnvBoshConfig.Agent.Settings.Labels,
 					},
@@ -189,10 +273,11 @@ func (kc *KubeConverter) serviceToExtendedSts(
 							Annotations: instanceGroup.Env.AgentEnvBoshConfig.Agent.Settings.Annotations,
 						},
 						Spec: corev1.PodSpec{
-							Affinity:       instanceGroup.Env.AgentEnvBoshConfig.Agent.Settings.Affinity,
-							Volumes:        volumes,
-							InitContainers: initContainers,
-							Containers:     containers,
+							Affinity:                      instanceGroup.Env.AgentEnvBoshConfig.Agent.Settings.Affinity,
+							Volumes:                       volumes,
+							InitContainers:                initContainers,
+							Containers:                    containers,
+							TerminationGracePeriodSeconds: &terminationGracePeriod,
 							SecurityContext: &corev1.PodSecurityContext{
 								FSGroup: &admGroupID,
 							},
@@ -206,8 +291,11 @@ func (kc *KubeConverter) serviceToExtendedSts(
 	return extSts, nil
 }
 
-// serviceToKubeServices will generate Services which expose ports for InstanceGroup's jobs
-func (kc *KubeConverter) serviceToKubeServices(manifestName string, instanceGroup *InstanceGroup, eSts *essv1.ExtendedStatefulSet) ([]corev1.Service, error) {
+// serviceToKubeServices will generate Services which expose ports for InstanceGroup's jobs.
+// Each vm extension with a service patch adds a Service for all instances.
+// Without DNS addresses, the Services of the instances are headless and publish
+// the addresses of pods which are not ready yet, so they can be resolved while rendering.
+func (kc *KubeConverter) serviceToKubeServices(manifestName string, instanceGroup *InstanceGroup, eSts *essv1.ExtendedStatefulSet, vmExtensions []*VMExtension, useDNSAddresses bool) ([]corev1.Service, error) {
 	var services []corev1.Service
 	// Collect ports to be exposed for each job
 	ports := []corev1.ServicePort{}
@@ -226,49 +314,35 @@ func (kc *KubeConverter) serviceToKubeServices(manifestName string, instanceGrou
 		return services, nil
 	}
 
-	for i := 0; i < instanceGroup.Instances; i++ {
-		if len(instanceGroup.AZs) == 0 {
-			services = append(services, corev1.Service{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      names.ServiceName(manifestName, instanceGroup.Name, len(services)),
-					Namespace: kc.namespace,
-					Labels: map[string]string{
-						LabelDeploymentName:    manifestName,
-						LabelInstanceGroupName: instanceGroup.Name,
-						essv1.LabelAZIndex:     strconv.Itoa(0),
-						essv1.LabelPodOrdinal:  strconv.Itoa(i),
-					},
-				},
-				Spec: corev1.ServiceSpec{
-					Ports: ports,
-					Selector: map[string]string{
-						LabelInstanceGroupName: instanceGroup.Name,
-						essv1.LabelAZIndex:     strconv.Itoa(0),
-						essv1.LabelPodOrdinal:  strconv.Itoa(i),
-					},
-				},
-			})
-		}
-		for azIndex := range instanceGroup.AZs {
-			services = append(services, corev1.Service{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      names.ServiceName(manifestName, instanceGroup.Name, len(services)),
-					Namespace: kc.namespace,
-					Labels: map[string]string{
-						LabelInstanceGroupName: instanceGroup.Name,
-						essv1.LabelAZIndex:     strconv.Itoa(azIndex),
-						essv1.LabelPodOrdinal:  strconv.Itoa(i),
-					},
+	// Each instance has a service, which selects its pod in the StatefulSet of its AZ
+	for index := 0; index < instanceGroup.Instances; index++ {
+		azIndex, podOrdinal := instanceGroup.instancePlacement(index)
+		services = append(services, corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      names.ServiceName(manifestName, instanceGroup.Name, index),
+				Namespace: kc.namespace,
+				Labels: map[string]string{
+					LabelDeploymentName:    manifestName,
+					LabelInstanceGroupName: instanceGroup.Name,
+					essv1.LabelAZIndex:     strconv.Itoa(azIndex),
+					essv1.LabelPodOrdinal:  strconv.Itoa(podOrdinal),
 				},
-				Spec: corev1.ServiceSpec{
-					Ports: ports,
-					Selector: map[string]string{
-						LabelInstanceGroupName: instanceGroup.Name,
-						essv1.LabelAZIndex:     strconv.Itoa(azIndex),
-						essv1.LabelPodOrdinal:  strconv.Itoa(i),
-					},
+			},
+			Spec: corev1.ServiceSpec{
+				Ports: ports,
+				Selector: map[string]string{
+					LabelInstanceGroupName: instanceGroup.Name,
+					essv1.LabelAZIndex:     strconv.Itoa(azIndex),
+					essv1.LabelPodOrdinal:  strconv.Itoa(podOrdinal),
 				},
-			})
+			},
+		})
+	}
+
+	if !useDNSAddresses {
+		for i := range services {
+			services[i].Spec.ClusterIP = "None"
+			services[i].Spec.PublishNotReadyAddresses = true
 		}
 	}
 
@@ -290,6 +364,35 @@ func (kc *KubeConverter) serviceToKubeServices(manifestName string, instanceGrou
 
 	services = append(services, headlessService)
 
+	for _, vmExtension := range vmExtensions {
+		if len(vmExtension.CloudProperties.Service) == 0 {
+			continue
+		}
+
+		extensionService := corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      names.ServiceName(manifestName, fmt.Sprintf("%s-%s", instanceGroup.Name, vmExtension.Name), -1),
+				Namespace: kc.namespace,
+				Labels: map[string]string{
+					LabelDeploymentName:    manifestName,
+					LabelInstanceGroupName: instanceGroup.Name,
+				},
+			},
+			Spec: corev1.ServiceSpec{
+				Ports: ports,
+				Selector: map[string]string{
+					LabelInstanceGroupName: instanceGroup.Name,
+				},
+			},
+		}
+
+		if err := patchService(&extensionService, vmExtension); err != nil {
+			return nil, err
+		}
+
+		services = append(services, extensionService)
+	}
+
 	// Set headlessService to govern StatefulSet
 	eSts.Spec.Template.Spec.ServiceName = names.ServiceName(manifestName, instanceGroup.Name, -1)
 
@@ -310,7 +413,7 @@ func (kc *KubeConverter) errandToExtendedJob(
 		return ejv1.ExtendedJob{}, err
 	}
 
-	containers, err := cfac.JobsToContainers(instanceGroup.Jobs, defaultVolumeMounts, bpmDisks)
+	containers, err := cfac.JobsToContainers(instanceGroup.Jobs, defaultVolumeMounts, bpmDisks, instanceGroup.VMResources)
 	if err != nil {
 		return ejv1.ExtendedJob{}, err
 	}
@@ -325,12 +428,17 @@ func (kc *KubeConverter) errandToExtendedJob(
 	volumes = append(volumes, defaultVolumes...)
 	volumes = append(volumes, bpmVolumes...)
 
+	eJobLabels := map[string]string{LabelErrand: "true"}
+	for name, value := range instanceGroup.Env.AgentEnvBoshConfig.Agent.Settings.Labels {
+		eJobLabels[name] = value
+	}
+
 	// Errand EJob
 	eJob := ejv1.ExtendedJob{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:        fmt.Sprintf("%s-%s", manifestName, instanceGroup.Name),
 			Namespace:   kc.namespace,
-			Labels:      instanceGroup.Env.AgentEnvBoshConfig.Agent.Settings.Labels,
+			Labels:      eJobLabels,
 			Annotations: instanceGroup.Env.AgentEnvBoshConfig.Agent.Settings.Annotations,
 		},
 		Spec: ejv1.ExtendedJobSpec{
@@ -360,3 +468,81 @@ func (kc *KubeConverter) errandToExtendedJob(
 	}
 	return eJob, nil
 }
+
+// postDeployToExtendedJob will generate an ExtendedJob, which runs the post-deploy scripts of
+// an instance group's jobs. It is created with a manual trigger. It returns nil if no job has a
+// post-deploy script.
+func (kc *KubeConverter) postDeployToExtendedJob(
+	cfac *ContainerFactory,
+	manifestName string,
+	instanceGroup *InstanceGroup,
+	defaultDisks BPMResourceDisks,
+	bpmDisks BPMResourceDisks,
+) (*ejv1.ExtendedJob, error) {
+	defaultVolumeMounts := defaultDisks.VolumeMounts()
+	containers, err := cfac.JobsToPostDeployContainers(instanceGroup.Jobs, defaultVolumeMounts, bpmDisks)
+	if err != nil {
+		return nil, err
+	}
+	if len(containers) == 0 {
+		return nil, nil
+	}
+
+	// The post-deploy pod doesn't run the pre-start scripts again
+	initContainers, err := cfac.JobsToRenderingContainers(instanceGroup.Jobs)
+	if err != nil {
+		return nil, err
+	}
+
+	// The post-deploy pod is not part of the StatefulSet, it renders the templates of the first instance
+	for i := range initContainers {
+		if initContainers[i].Name == "template-render" {
+			initContainers[i].Env = append(initContainers[i].Env, corev1.EnvVar{Name: "SPEC_INDEX", Value: "0"})
+		}
+	}
+
+	labels := map[string]string{LabelPostDeploy: "true"}
+	for k, v := range instanceGroup.Env.AgentEnvBoshConfig.Agent.Settings.Labels {
+		labels[k] = v
+	}
+
+	defaultVolumes := defaultDisks.Volumes()
+	bpmVolumes := bpmDisks.Volumes()
+	volumes := make([]corev1.Volume, 0, len(defaultVolumes)+len(bpmVolumes))
+	volumes = append(volumes, defaultVolumes...)
+	volumes = append(volumes, bpmVolumes...)
+
+	return &ejv1.ExtendedJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        fmt.Sprintf("%s-%s-post-deploy", manifestName, names.Sanitize(instanceGroup.Name)),
+			Namespace:   kc.namespace,
+			Labels:      labels,
+			Annotations: instanceGroup.Env.AgentEnvBoshConfig.Agent.Settings.Annotations,
+		},
+		Spec: ejv1.ExtendedJobSpec{
+			Trigger: ejv1.Trigger{
+				Strategy: ejv1.TriggerManual,
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: fmt.Sprintf("%s-post-deploy", instanceGroup.Name),
+					// Not labeled with the instance group, the services of the instance group must not select the pod
+					Labels: map[string]string{
+						LabelDeploymentName: manifestName,
+					},
+					Annotations: instanceGroup.Env.AgentEnvBoshConfig.Agent.Settings.Annotations,
+				},
+				Spec: corev1.PodSpec{
+					Affinity:       instanceGroup.Env.AgentEnvBoshConfig.Agent.Settings.Affinity,
+					Containers:     containers,
+					InitContainers: initContainers,
+					Volumes:        volumes,
+					RestartPolicy:  corev1.RestartPolicyNever,
+					SecurityContext: &corev1.PodSecurityContext{
+						FSGroup: &admGroupID,
+					},
+				},
+			},
+		},
+	}, nil
+}
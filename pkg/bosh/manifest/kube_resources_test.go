@@ -5,11 +5,16 @@ import (
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"k8s.io/api/apps/v1beta2"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 
 	"code.cloudfoundry.org/cf-operator/pkg/bosh/bpm"
 	"code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
+	bc "code.cloudfoundry.org/cf-operator/pkg/bosh/manifest/containerization"
+	"code.cloudfoundry.org/cf-operator/pkg/bosh/monit"
+	ejv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/extendedjob/v1alpha1"
 	essv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/extendedstatefulset/v1alpha1"
 	"code.cloudfoundry.org/cf-operator/testing"
 	"code.cloudfoundry.org/cf-operator/testing/boshreleases"
@@ -24,7 +29,7 @@ var _ = Describe("kube converter", func() {
 	Context("BPMResources", func() {
 		act := func(bpmConfigs bpm.Configs, instanceGroup *manifest.InstanceGroup) (*manifest.BPMResources, error) {
 			kubeConverter := manifest.NewKubeConverter("foo")
-			resources, err := kubeConverter.BPMResources(m.Name, "1", instanceGroup, &m, bpmConfigs)
+			resources, err := kubeConverter.BPMResources(m.Name, "1", instanceGroup, &m, bpmConfigs, m.CloudConfig, m.UsesDNSAddresses(), m.ImagePullSecrets)
 			return resources, err
 		}
 
@@ -66,6 +71,7 @@ var _ = Describe("kube converter", func() {
 					Expect(eJob.Name).To(Equal("foo-deployment-redis-slave"))
 					Expect(eJob.GetLabels()).To(HaveKeyWithValue(manifest.LabelDeploymentName, m.Name))
 					Expect(eJob.GetLabels()).To(HaveKeyWithValue(manifest.LabelInstanceGroupName, m.InstanceGroups[0].Name))
+					Expect(eJob.GetLabels()).To(HaveKeyWithValue(manifest.LabelErrand, "true"))
 					Expect(eJob.GetLabels()).To(HaveKeyWithValue(manifest.LabelDeploymentVersion, "1"))
 					Expect(eJob.GetLabels()).To(HaveKeyWithValue("custom-label", "foo"))
 					Expect(eJob.GetAnnotations()).To(HaveKeyWithValue("custom-annotation", "bar"))
@@ -115,6 +121,10 @@ var _ = Describe("kube converter", func() {
 
 					// Test labels and annotation in the extended statefulSet
 					extStS := resources.InstanceGroups[0]
+					Expect(extStS.Spec.Update).To(BeNil())
+					Expect(extStS.Spec.Zones).To(Equal([]string{"z1", "z2"}))
+					Expect(*extStS.Spec.Replicas).To(Equal(int32(2)))
+					Expect(extStS.Spec.ZoneReplicas).To(Equal([]int32{1, 1}))
 					Expect(extStS.Name).To(Equal(fmt.Sprintf("%s-%s", m.Name, "diego-cell")))
 					Expect(extStS.GetLabels()).To(HaveKeyWithValue(manifest.LabelDeploymentName, m.Name))
 					Expect(extStS.GetLabels()).To(HaveKeyWithValue(manifest.LabelInstanceGroupName, "diego-cell"))
@@ -223,37 +233,7 @@ var _ = Describe("kube converter", func() {
 						},
 					}))
 
-					service2 := resources.Services[2]
-					Expect(service2.Name).To(Equal(fmt.Sprintf("%s-%s-2", m.Name, stS.Name)))
-					Expect(service2.Spec.Selector).To(Equal(map[string]string{
-						manifest.LabelInstanceGroupName: stS.Name,
-						essv1.LabelAZIndex:              "0",
-						essv1.LabelPodOrdinal:           "1",
-					}))
-					Expect(service2.Spec.Ports).To(Equal([]corev1.ServicePort{
-						{
-							Name:     "rep-server",
-							Protocol: corev1.ProtocolTCP,
-							Port:     1801,
-						},
-					}))
-
-					service3 := resources.Services[3]
-					Expect(service3.Name).To(Equal(fmt.Sprintf("%s-%s-3", m.Name, stS.Name)))
-					Expect(service3.Spec.Selector).To(Equal(map[string]string{
-						manifest.LabelInstanceGroupName: stS.Name,
-						essv1.LabelAZIndex:              "1",
-						essv1.LabelPodOrdinal:           "1",
-					}))
-					Expect(service3.Spec.Ports).To(Equal([]corev1.ServicePort{
-						{
-							Name:     "rep-server",
-							Protocol: corev1.ProtocolTCP,
-							Port:     1801,
-						},
-					}))
-
-					headlessService := resources.Services[4]
+					headlessService := resources.Services[2]
 					Expect(headlessService.Name).To(Equal(fmt.Sprintf("%s-%s", m.Name, stS.Name)))
 					Expect(headlessService.Spec.Selector).To(Equal(map[string]string{
 						manifest.LabelInstanceGroupName: stS.Name,
@@ -270,6 +250,230 @@ var _ = Describe("kube converter", func() {
 					// Test affinity
 					Expect(stS.Spec.Affinity).To(BeNil())
 				})
+
+				It("converts the update block to an update strategy", func() {
+					canaries := 2
+					maxInFlight := "25%"
+					canaryWatchTime := "1000-30000"
+					updateWatchTime := "60000"
+					m.InstanceGroups[1].Update = &manifest.Update{
+						Canaries:        &canaries,
+						MaxInFlight:     &maxInFlight,
+						CanaryWatchTime: &canaryWatchTime,
+						UpdateWatchTime: &updateWatchTime,
+					}
+					resources, err := act(bpmConfigs[1], m.InstanceGroups[1])
+					Expect(err).ShouldNot(HaveOccurred())
+
+					expectedMaxInFlight := intstr.FromString("25%")
+					Expect(resources.InstanceGroups[0].Spec.Update).To(Equal(&essv1.UpdateStrategy{
+						Canaries:        2,
+						MaxInFlight:     &expectedMaxInFlight,
+						CanaryWatchTime: "1000-30000",
+						UpdateWatchTime: "60000",
+					}))
+				})
+
+				It("derives the termination grace period from the max drain time", func() {
+					resources, err := act(bpmConfigs[1], m.InstanceGroups[1])
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(*resources.InstanceGroups[0].Spec.Template.Spec.Template.Spec.TerminationGracePeriodSeconds).To(Equal(int64(40)))
+
+					maxDrainTime := 600
+					m.InstanceGroups[1].Env.AgentEnvBoshConfig.Agent.Settings.MaxDrainTime = &maxDrainTime
+					resources, err = act(bpmConfigs[1], m.InstanceGroups[1])
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(*resources.InstanceGroups[0].Spec.Template.Spec.Template.Spec.TerminationGracePeriodSeconds).To(Equal(int64(610)))
+				})
+
+				It("converts the vm resources to container resources and the ephemeral disk size limit", func() {
+					m.InstanceGroups[1].VMResources = &manifest.VMResource{CPU: 2, RAM: 2048, EphemeralDiskSize: 4096}
+					resources, err := act(bpmConfigs[1], m.InstanceGroups[1])
+					Expect(err).ShouldNot(HaveOccurred())
+
+					podSpec := resources.InstanceGroups[0].Spec.Template.Spec.Template.Spec
+					Expect(podSpec.Containers[0].Resources.Requests.Cpu().String()).To(Equal("2"))
+					// The bpm memory limit of 1G takes precedence
+					Expect(podSpec.Containers[0].Resources.Requests.Memory().String()).To(Equal("1Gi"))
+					Expect(podSpec.Containers[0].Resources.Limits.Memory().String()).To(Equal("1Gi"))
+
+					limited := 0
+					for _, volume := range podSpec.Volumes {
+						if volume.Name == manifest.VolumeDataDirName || volume.Name == manifest.VolumeEphemeralDirName {
+							Expect(volume.EmptyDir.SizeLimit.String()).To(Equal("4Gi"))
+							limited++
+						}
+					}
+					Expect(limited).To(Equal(2))
+				})
+
+				It("makes the services of the instances headless without DNS addresses", func() {
+					useDNSAddresses := false
+					m.Features = &manifest.Feature{UseDNSAddresses: &useDNSAddresses}
+					resources, err := act(bpmConfigs[1], m.InstanceGroups[1])
+					Expect(err).ShouldNot(HaveOccurred())
+
+					Expect(resources.Services).To(HaveLen(3))
+					for _, service := range resources.Services[:2] {
+						Expect(service.Spec.ClusterIP).To(Equal("None"))
+						Expect(service.Spec.PublishNotReadyAddresses).To(BeTrue())
+					}
+				})
+
+				It("starts the pods in parallel without DNS addresses", func() {
+					resources, err := act(bpmConfigs[1], m.InstanceGroups[1])
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(resources.InstanceGroups[0].Spec.Template.Spec.PodManagementPolicy).To(BeEmpty())
+
+					useDNSAddresses := false
+					m.Features = &manifest.Feature{UseDNSAddresses: &useDNSAddresses}
+					resources, err = act(bpmConfigs[1], m.InstanceGroups[1])
+					Expect(err).ShouldNot(HaveOccurred())
+
+					stsSpec := resources.InstanceGroups[0].Spec.Template.Spec
+					Expect(stsSpec.PodManagementPolicy).To(BeEquivalentTo(v1beta2.ParallelPodManagement))
+					for _, container := range stsSpec.Template.Spec.Containers {
+						Expect(container.Name).ToNot(Equal("template-watch"))
+					}
+				})
+
+				It("renders the templates again when the link addresses change without DNS addresses", func() {
+					useDNSAddresses := false
+					m.Features = &manifest.Feature{UseDNSAddresses: &useDNSAddresses}
+					m.InstanceGroups[1].Jobs[0].Properties.BOSHContainerization.Consumes = map[string]bc.JobLink{
+						"peers": {Instances: []bc.JobInstance{{ID: "diego-cell-0"}}},
+					}
+					resources, err := act(bpmConfigs[1], m.InstanceGroups[1])
+					Expect(err).ShouldNot(HaveOccurred())
+
+					containers := resources.InstanceGroups[0].Spec.Template.Spec.Template.Spec.Containers
+					watcher := containers[len(containers)-1]
+					Expect(watcher.Name).To(Equal("template-watch"))
+					Expect(watcher.Args).To(Equal([]string{"-xc", "cf-operator util template-render"}))
+					Expect(watcher.Env).To(ContainElement(corev1.EnvVar{Name: "WATCH_INTERVAL", Value: "10s"}))
+					Expect(watcher.VolumeMounts).To(ContainElement(corev1.VolumeMount{Name: manifest.VolumeJobsDirName, MountPath: manifest.VolumeJobsDirMountPath}))
+				})
+
+				It("adds the image pull secrets to the pods", func() {
+					m.ImagePullSecrets = []corev1.LocalObjectReference{{Name: "registry-credentials"}}
+					resources, err := act(bpmConfigs[1], m.InstanceGroups[1])
+					Expect(err).ShouldNot(HaveOccurred())
+
+					podSpec := resources.InstanceGroups[0].Spec.Template.Spec.Template.Spec
+					Expect(podSpec.ImagePullSecrets).To(Equal(m.ImagePullSecrets))
+				})
+
+				It("derives default probes from the monit checks", func() {
+					config := bpmConfigs[1]["cflinuxfs3-rootfs-setup"]
+					config.Monit = monit.Checks{
+						{Name: "cflinuxfs3-rootfs-setup", Ports: []int{8080}, StartTimeout: 60},
+					}
+					bpmConfigs[1]["cflinuxfs3-rootfs-setup"] = config
+					job := &m.InstanceGroups[1].Jobs[0]
+					healthCheck := job.Properties.BOSHContainerization.Run.HealthChecks["test-server"]
+					healthCheck.LivenessProbe = nil
+					job.Properties.BOSHContainerization.Run.HealthChecks["test-server"] = healthCheck
+
+					resources, err := act(bpmConfigs[1], m.InstanceGroups[1])
+					Expect(err).ShouldNot(HaveOccurred())
+
+					container := resources.InstanceGroups[0].Spec.Template.Spec.Template.Spec.Containers[0]
+					Expect(container.ReadinessProbe.Exec.Command[0]).To(Equal("curl --silent --fail --head http://${HOSTNAME}:8080/health"))
+					Expect(container.LivenessProbe.TCPSocket.Port).To(Equal(intstr.FromInt(8080)))
+					Expect(container.LivenessProbe.InitialDelaySeconds).To(Equal(int32(60)))
+				})
+
+				It("runs the program of a monit check as exec probe", func() {
+					config := bpmConfigs[1]["cflinuxfs3-rootfs-setup"]
+					config.Monit = monit.Checks{
+						{Name: "health", Program: "/var/vcap/jobs/cflinuxfs3-rootfs-setup/bin/health_check"},
+					}
+					bpmConfigs[1]["cflinuxfs3-rootfs-setup"] = config
+					job := &m.InstanceGroups[1].Jobs[0]
+					job.Properties.BOSHContainerization.Run.HealthChecks = nil
+
+					resources, err := act(bpmConfigs[1], m.InstanceGroups[1])
+					Expect(err).ShouldNot(HaveOccurred())
+
+					container := resources.InstanceGroups[0].Spec.Template.Spec.Template.Spec.Containers[0]
+					command := []string{"sh", "-c", "/var/vcap/jobs/cflinuxfs3-rootfs-setup/bin/health_check"}
+					Expect(container.ReadinessProbe.Exec.Command).To(Equal(command))
+					Expect(container.LivenessProbe.Exec.Command).To(Equal(command))
+					Expect(container.LivenessProbe.InitialDelaySeconds).To(Equal(int32(30)))
+				})
+
+				It("tests the pidfile of a monit check, which has no program or port", func() {
+					config := bpmConfigs[1]["cflinuxfs3-rootfs-setup"]
+					config.Monit = monit.Checks{
+						{Name: "cflinuxfs3-rootfs-setup", Pidfile: "/var/vcap/sys/run/cflinuxfs3-rootfs-setup/setup.pid"},
+					}
+					bpmConfigs[1]["cflinuxfs3-rootfs-setup"] = config
+					job := &m.InstanceGroups[1].Jobs[0]
+					job.Properties.BOSHContainerization.Run.HealthChecks = nil
+
+					resources, err := act(bpmConfigs[1], m.InstanceGroups[1])
+					Expect(err).ShouldNot(HaveOccurred())
+
+					container := resources.InstanceGroups[0].Spec.Template.Spec.Template.Spec.Containers[0]
+					command := []string{"sh", "-c", `test -f "/var/vcap/sys/run/cflinuxfs3-rootfs-setup/setup.pid" && kill -0 "$(cat "/var/vcap/sys/run/cflinuxfs3-rootfs-setup/setup.pid")"`}
+					Expect(container.ReadinessProbe.Exec.Command).To(Equal(command))
+					Expect(container.LivenessProbe.Exec.Command).To(Equal(command))
+				})
+
+				It("ignores the pidfiles of BPM", func() {
+					config := bpmConfigs[1]["cflinuxfs3-rootfs-setup"]
+					config.Monit = monit.Checks{
+						{Name: "cflinuxfs3-rootfs-setup", Pidfile: "/var/vcap/sys/run/bpm/cflinuxfs3-rootfs-setup/cflinuxfs3-rootfs-setup.pid"},
+					}
+					bpmConfigs[1]["cflinuxfs3-rootfs-setup"] = config
+					job := &m.InstanceGroups[1].Jobs[0]
+					job.Properties.BOSHContainerization.Run.HealthChecks = nil
+
+					resources, err := act(bpmConfigs[1], m.InstanceGroups[1])
+					Expect(err).ShouldNot(HaveOccurred())
+
+					container := resources.InstanceGroups[0].Spec.Template.Spec.Template.Spec.Containers[0]
+					Expect(container.ReadinessProbe).To(BeNil())
+					Expect(container.LivenessProbe).To(BeNil())
+				})
+
+				It("runs the post-start scripts of the jobs in postStart hooks", func() {
+					config := bpmConfigs[1]["cflinuxfs3-rootfs-setup"]
+					config.PostStart = true
+					bpmConfigs[1]["cflinuxfs3-rootfs-setup"] = config
+
+					resources, err := act(bpmConfigs[1], m.InstanceGroups[1])
+					Expect(err).ShouldNot(HaveOccurred())
+
+					containers := resources.InstanceGroups[0].Spec.Template.Spec.Template.Spec.Containers
+					Expect(resources.PostStartContainers).To(Equal([]string{containers[0].Name}))
+					Expect(containers[0].Lifecycle.PostStart.Exec.Command[2]).To(ContainSubstring(`"/var/vcap/jobs/cflinuxfs3-rootfs-setup/bin/post-start"`))
+					Expect(resources.PostDeploys).To(BeEmpty())
+				})
+
+				It("converts the post-deploy scripts of the jobs to an ExtendedJob", func() {
+					config := bpmConfigs[1]["cflinuxfs3-rootfs-setup"]
+					config.PostDeploy = true
+					bpmConfigs[1]["cflinuxfs3-rootfs-setup"] = config
+
+					resources, err := act(bpmConfigs[1], m.InstanceGroups[1])
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(resources.PostDeploys).To(HaveLen(1))
+
+					eJob := resources.PostDeploys[0]
+					Expect(eJob.Name).To(Equal(fmt.Sprintf("%s-%s", m.Name, "diego-cell-post-deploy")))
+					Expect(eJob.GetLabels()).To(HaveKeyWithValue(manifest.LabelPostDeploy, "true"))
+					Expect(eJob.Spec.Trigger.Strategy).To(Equal(ejv1.TriggerManual))
+
+					template := eJob.Spec.Template
+					Expect(template.GetLabels()).To(HaveKeyWithValue(manifest.LabelDeploymentName, m.Name))
+					Expect(template.GetLabels()).ToNot(HaveKey(manifest.LabelInstanceGroupName))
+					Expect(template.Spec.RestartPolicy).To(Equal(corev1.RestartPolicyNever))
+					Expect(template.Spec.Containers).To(HaveLen(1))
+					Expect(template.Spec.Containers[0].Name).To(Equal("post-deploy-cflinuxfs3-rootfs-setup"))
+					Expect(template.Spec.InitContainers[1].Name).To(Equal("template-render"))
+					Expect(template.Spec.InitContainers[1].Env).To(ContainElement(corev1.EnvVar{Name: "SPEC_INDEX", Value: "0"}))
+				})
 			})
 		})
 
@@ -624,5 +828,104 @@ var _ = Describe("kube converter", func() {
 				}))
 			})
 		})
+
+		Context("when a cloud config is provided", func() {
+			var bpmConfigs []bpm.Configs
+
+			BeforeEach(func() {
+				m = *env.BOSHManifestWithBPMRelease()
+
+				cloudConfig, err := manifest.LoadCloudConfigYAML([]byte(`---
+vm_types:
+- name: small
+  cloud_properties:
+    cpu: 2
+    node_selector:
+      node-pool: small
+    tolerations:
+    - key: dedicated
+      operator: Equal
+      value: bosh
+      effect: NoSchedule
+vm_extensions:
+- name: lb
+  cloud_properties:
+    pod:
+      metadata:
+        annotations:
+          prometheus.io/scrape: "true"
+      spec:
+        priorityClassName: high
+    service:
+      metadata:
+        annotations:
+          external-dns.alpha.kubernetes.io/hostname: bpm.example.com
+      spec:
+        type: LoadBalancer
+disk_types:
+- name: fast
+  disk_size: 2048
+  cloud_properties:
+    storage_class: ssd
+`))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(m.ApplyCloudConfig(cloudConfig)).To(Succeed())
+
+				ig := m.InstanceGroups[0]
+				ig.VMType = "small"
+				ig.VMExtensions = []string{"lb"}
+				ig.PersistentDisk = nil
+				ig.PersistentDiskType = "fast"
+
+				// The cloud config is stored in the with-ops manifest
+				manifestBytes, err := m.Marshal()
+				Expect(err).ShouldNot(HaveOccurred())
+				reloaded, err := manifest.LoadYAML(manifestBytes)
+				Expect(err).ShouldNot(HaveOccurred())
+				m = *reloaded
+
+				c, err := bpm.NewConfig([]byte(boshreleases.EnablePersistentDiskBPMConfig))
+				Expect(err).ShouldNot(HaveOccurred())
+
+				bpmConfigs = []bpm.Configs{
+					{"test-server": c},
+				}
+			})
+
+			It("applies the vm type, vm extensions and disk type", func() {
+				resources, err := act(bpmConfigs[0], m.InstanceGroups[0])
+				Expect(err).ShouldNot(HaveOccurred())
+
+				template := resources.InstanceGroups[0].Spec.Template.Spec.Template
+				Expect(template.Spec.Containers[0].Resources.Requests.Cpu().String()).To(Equal("2"))
+				Expect(template.Spec.NodeSelector).To(Equal(map[string]string{"node-pool": "small"}))
+				Expect(template.Spec.Tolerations).To(Equal([]corev1.Toleration{
+					{Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "bosh", Effect: corev1.TaintEffectNoSchedule},
+				}))
+				Expect(template.Spec.PriorityClassName).To(Equal("high"))
+				Expect(template.Annotations).To(HaveKeyWithValue("prometheus.io/scrape", "true"))
+
+				Expect(resources.Services).To(HaveLen(3))
+				lbService := resources.Services[2]
+				Expect(lbService.Name).To(Equal("bpm-bpm-lb"))
+				Expect(lbService.Annotations).To(HaveKeyWithValue("external-dns.alpha.kubernetes.io/hostname", "bpm.example.com"))
+				Expect(lbService.Spec.Type).To(Equal(corev1.ServiceTypeLoadBalancer))
+				Expect(lbService.Spec.Selector).To(Equal(map[string]string{manifest.LabelInstanceGroupName: "bpm"}))
+				Expect(lbService.Spec.Ports).To(HaveLen(2))
+
+				pvc := resources.Disks.Filter("persistent", "true")[0].PersistentVolumeClaim
+				Expect(*pvc.Spec.StorageClassName).To(Equal("ssd"))
+				storage := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+				Expect(storage.String()).To(Equal("2Gi"))
+			})
+
+			It("fails if the vm type is not in the cloud config", func() {
+				m.InstanceGroups[0].VMType = "large"
+
+				_, err := act(bpmConfigs[0], m.InstanceGroups[0])
+				Expect(err).Should(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("failed to look up cloud config of instance group 'bpm': vm type 'large' not found in cloud config"))
+			})
+		})
 	})
 })
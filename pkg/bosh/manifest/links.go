@@ -0,0 +1,170 @@
+package manifest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	yaml "gopkg.in/yaml.v2"
+
+	bc "code.cloudfoundry.org/cf-operator/pkg/bosh/manifest/containerization"
+)
+
+const (
+	// LinksKeyName is the name of the key in the secret of links, which a deployment shares
+	LinksKeyName = "links.yaml"
+	// LinksContainerName is the name of the container, which collects the shared links
+	// of a deployment. It's also part of the output secret's name
+	LinksContainerName = "links"
+	// EnvLinksDir is a key for the container Env used to lookup the directory
+	// with the shared links of other deployments
+	EnvLinksDir = "LINKS_DIR"
+	// VolumeLinksMountPath is the mount path for the shared links of other deployments.
+	// The links of each deployment are mounted into a sub directory named after the deployment.
+	VolumeLinksMountPath = "/var/run/secrets/links/"
+)
+
+// providedLink returns the name a link of the job's spec is provided as, and
+// whether it is shared with other deployments
+func (j *Job) providedLink(linkName string) (string, bool, error) {
+	value, ok := j.Provides[linkName]
+	if !ok || value == nil {
+		return linkName, false, nil
+	}
+
+	provides, ok := value.(map[interface{}]interface{})
+	if !ok {
+		return "", false, fmt.Errorf("unexpected type detected: %T, should have been a map", value)
+	}
+
+	// instance_group.job can override the link name through the
+	// instance_group.job.provides, via the "as" key
+	if as, ok := provides["as"]; ok {
+		linkName = fmt.Sprintf("%v", as)
+	}
+	shared, _ := provides["shared"].(bool)
+
+	return linkName, shared, nil
+}
+
+// consumedDeployment returns the deployment a link is consumed from, if it's
+// not the job's own deployment
+func (j *Job) consumedDeployment(linkName string) string {
+	consumes, ok := j.Consumes[linkName].(map[interface{}]interface{})
+	if !ok {
+		return ""
+	}
+
+	deployment, ok := consumes["deployment"]
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("%v", deployment)
+}
+
+// ProvidesSharedLinks returns true if any job shares a link with other deployments
+func (m *Manifest) ProvidesSharedLinks() bool {
+	for _, ig := range m.InstanceGroups {
+		for _, job := range ig.Jobs {
+			for linkName := range job.Provides {
+				if _, shared, _ := job.providedLink(linkName); shared {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// ConsumedDeployments returns the sorted names of other deployments, whose links
+// are consumed by any job
+func (m *Manifest) ConsumedDeployments() []string {
+	deployments := map[string]bool{}
+	for _, ig := range m.InstanceGroups {
+		for _, job := range ig.Jobs {
+			for linkName := range job.Consumes {
+				if deployment := job.consumedDeployment(linkName); deployment != "" && deployment != m.Name {
+					deployments[deployment] = true
+				}
+			}
+		}
+	}
+
+	result := make([]string, 0, len(deployments))
+	for deployment := range deployments {
+		result = append(result, deployment)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// SharedLinks returns the links of a manifest, which are shared with other deployments.
+// The output will be persisted by ExtendedJob as 'links.yaml' in the
+// `<deployment-name>.links-v<version>` secret.
+func SharedLinks(log *zap.SugaredLogger, basedir, namespace, clusterDomain string, manifest Manifest) (JobProviderLinks, error) {
+	dg := &DataGatherer{
+		log:              log,
+		baseDir:          basedir,
+		manifest:         manifest,
+		namespace:        namespace,
+		clusterDomain:    clusterDomain,
+		jobReleaseSpecs:  map[string]map[string]JobSpec{},
+		jobProviderLinks: JobProviderLinks{},
+	}
+
+	err := dg.collectReleaseSpecsAndProviderLinks()
+	if err != nil {
+		return nil, err
+	}
+
+	shared := JobProviderLinks{}
+	for _, ig := range dg.manifest.InstanceGroups {
+		for _, job := range ig.Jobs {
+			spec := dg.jobReleaseSpecs[job.Release][job.Name]
+			for _, link := range spec.Provides {
+				linkName, isShared, err := job.providedLink(link.Name)
+				if err != nil {
+					return nil, err
+				}
+				if !isShared {
+					continue
+				}
+
+				if _, ok := shared[link.Type]; !ok {
+					shared[link.Type] = map[string]bc.JobLink{}
+				}
+				shared[link.Type][linkName] = dg.jobProviderLinks[link.Type][linkName]
+			}
+		}
+	}
+
+	return shared, nil
+}
+
+// deploymentLinks returns the links shared by another deployment, which have
+// been mounted into the links directory
+func (dg *DataGatherer) deploymentLinks(deployment string) (JobProviderLinks, error) {
+	if deployment == dg.manifest.Name {
+		return dg.jobProviderLinks, nil
+	}
+	if links, ok := dg.sharedLinks[deployment]; ok {
+		return links, nil
+	}
+
+	path := filepath.Join(dg.linksDir, deployment, LinksKeyName)
+	linksBytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read links shared by deployment '%s'", deployment)
+	}
+
+	links := JobProviderLinks{}
+	if err := yaml.Unmarshal(linksBytes, &links); err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal links shared by deployment '%s'", deployment)
+	}
+
+	dg.sharedLinks[deployment] = links
+	return links, nil
+}
@@ -0,0 +1,161 @@
+package manifest_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+	yaml "gopkg.in/yaml.v2"
+
+	"code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
+	helper "code.cloudfoundry.org/cf-operator/pkg/testhelper"
+	"code.cloudfoundry.org/cf-operator/testing"
+)
+
+var _ = Describe("Links", func() {
+	var (
+		m   *manifest.Manifest
+		env testing.Catalog
+		log *zap.SugaredLogger
+	)
+
+	BeforeEach(func() {
+		_, log = helper.NewTestLogger()
+		m = env.BOSHManifestWithProviderAndConsumer()
+	})
+
+	Describe("ProvidesSharedLinks", func() {
+		It("is true if a job provides a shared link", func() {
+			Expect(m.ProvidesSharedLinks()).To(BeTrue())
+		})
+
+		It("is false if no link is shared", func() {
+			m.InstanceGroups[0].Jobs[0].Provides = map[string]interface{}{
+				"doppler": map[interface{}]interface{}{"as": "doppler"},
+			}
+			Expect(m.ProvidesSharedLinks()).To(BeFalse())
+		})
+	})
+
+	Describe("ConsumedDeployments", func() {
+		It("lists the other deployments links are consumed from", func() {
+			Expect(m.ConsumedDeployments()).To(BeEmpty())
+
+			m.InstanceGroups[1].Jobs[0].Consumes = map[string]interface{}{
+				"doppler": map[interface{}]interface{}{"from": "doppler", "deployment": "loggregator"},
+				"other":   map[interface{}]interface{}{"from": "other", "deployment": m.Name},
+			}
+			Expect(m.ConsumedDeployments()).To(Equal([]string{"loggregator"}))
+		})
+	})
+
+	Describe("SharedLinks", func() {
+		It("returns the shared links with their instances and properties", func() {
+			links, err := manifest.SharedLinks(log, assetPath, "default", "cluster.local", *m)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(links).To(HaveKey("doppler"))
+			Expect(links).ToNot(HaveKey("loggregator"))
+			link := links["doppler"]["doppler"]
+			Expect(link.Instances).To(HaveLen(4))
+			Expect(link.Properties).To(HaveKeyWithValue("doppler", HaveKeyWithValue("grpc_port", 7765)))
+		})
+
+		It("distributes the instances round robin across the azs", func() {
+			m.InstanceGroups[0].Instances = 3
+			links, err := manifest.SharedLinks(log, assetPath, "default", "cluster.local", *m)
+			Expect(err).ToNot(HaveOccurred())
+
+			instances := links["doppler"]["doppler"].Instances
+			Expect(instances).To(HaveLen(3))
+			for i, expected := range []struct {
+				az       string
+				instance int
+			}{{"z1", 0}, {"z2", 0}, {"z1", 1}} {
+				Expect(instances[i].Index).To(Equal(i))
+				Expect(instances[i].AZ).To(Equal(expected.az))
+				Expect(instances[i].Instance).To(Equal(expected.instance))
+				Expect(instances[i].Address).To(Equal(fmt.Sprintf("cf-doppler-%d.default.svc.cluster.local", i)))
+			}
+		})
+
+		It("uses the cluster domain for the addresses of the instances", func() {
+			links, err := manifest.SharedLinks(log, assetPath, "default", "example.org", *m)
+			Expect(err).ToNot(HaveOccurred())
+
+			for _, instance := range links["doppler"]["doppler"].Instances {
+				Expect(instance.Address).To(HaveSuffix(".default.svc.example.org"))
+			}
+		})
+	})
+
+	Describe("consuming links of other deployments", func() {
+		var linksDir string
+
+		BeforeEach(func() {
+			var err error
+			linksDir, err = ioutil.TempDir("", "links")
+			Expect(err).ToNot(HaveOccurred())
+
+			// The other deployment runs three doppler instances, this one four
+			provider := env.BOSHManifestWithProviderAndConsumer()
+			provider.Name = "loggregator"
+			provider.InstanceGroups[0].Instances = 3
+			links, err := manifest.SharedLinks(log, assetPath, "default", "cluster.local", *provider)
+			Expect(err).ToNot(HaveOccurred())
+			linksBytes, err := yaml.Marshal(links)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(os.MkdirAll(filepath.Join(linksDir, "loggregator"), 0755)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(linksDir, "loggregator", manifest.LinksKeyName), linksBytes, 0644)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(linksDir)).To(Succeed())
+		})
+
+		act := func(consumes map[interface{}]interface{}) (manifest.Manifest, error) {
+			m.InstanceGroups[1].Jobs[0].Consumes = map[string]interface{}{"doppler": consumes}
+			dg, err := manifest.NewDataGatherer(log, assetPath, linksDir, "default", "cluster.local", *m, "log-api")
+			Expect(err).ToNot(HaveOccurred())
+			return dg.ResolvedProperties()
+		}
+
+		It("resolves the instances and properties of the link shared by the other deployment", func() {
+			resolved, err := act(map[interface{}]interface{}{"from": "doppler", "deployment": "loggregator"})
+			Expect(err).ToNot(HaveOccurred())
+
+			ig, err := resolved.InstanceGroupByName("log-api")
+			Expect(err).ToNot(HaveOccurred())
+			link, ok := ig.Jobs[0].Properties.BOSHContainerization.Consumes["doppler"]
+			Expect(ok).To(BeTrue())
+			Expect(link.Properties).To(HaveKeyWithValue("doppler", HaveKeyWithValue("grpc_port", 7765)))
+			Expect(link.Instances).To(HaveLen(3))
+			for i, instance := range link.Instances {
+				Expect(instance.Index).To(Equal(i))
+				Expect(instance.Address).To(Equal(fmt.Sprintf("loggregator-doppler-%d.default.svc.cluster.local", i)))
+			}
+		})
+
+		It("resolves the link of its own deployment without the deployment", func() {
+			resolved, err := act(map[interface{}]interface{}{"from": "doppler"})
+			Expect(err).ToNot(HaveOccurred())
+
+			ig, err := resolved.InstanceGroupByName("log-api")
+			Expect(err).ToNot(HaveOccurred())
+			link := ig.Jobs[0].Properties.BOSHContainerization.Consumes["doppler"]
+			Expect(link.Instances).To(HaveLen(4))
+			Expect(link.Instances[0].Address).To(Equal("cf-doppler-0.default.svc.cluster.local"))
+		})
+
+		It("fails if the other deployment doesn't share links", func() {
+			_, err := act(map[interface{}]interface{}{"from": "doppler", "deployment": "unknown"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("failed to read links shared by deployment 'unknown'"))
+		})
+	})
+})
@@ -8,8 +8,10 @@ import (
 	"regexp"
 	"strings"
 
+	kubeyaml "github.com/ghodss/yaml"
 	"github.com/pkg/errors"
 	yaml "gopkg.in/yaml.v2"
+	corev1 "k8s.io/api/core/v1"
 
 	bc "code.cloudfoundry.org/cf-operator/pkg/bosh/manifest/containerization"
 )
@@ -38,6 +40,8 @@ type VariableOptions struct {
 	IsCA             bool       `yaml:"is_ca"`
 	CA               string     `yaml:"ca,omitempty"`
 	ExtendedKeyUsage []AuthType `yaml:"extended_key_usage,omitempty"`
+	// Duration is the validity of a certificate in days
+	Duration int `yaml:"duration,omitempty"`
 }
 
 // Variable from BOSH deployment manifest
@@ -68,6 +72,10 @@ type Release struct {
 	URL      string           `yaml:"url,omitempty"`
 	SHA1     string           `yaml:"sha1,omitempty"`
 	Stemcell *ReleaseStemcell `yaml:"stemcell,omitempty"`
+	// Image replaces the image built from the url, stemcell and version
+	Image string `yaml:"image,omitempty"`
+	// Digest pins the image of the release
+	Digest string `yaml:"digest,omitempty"`
 }
 
 // AddOnJob from BOSH deployment manifest
@@ -92,10 +100,11 @@ type AddOnPlacementJob struct {
 type AddOnPlacementRules struct {
 	Stemcell      []*AddOnStemcell     `yaml:"stemcell,omitempty"`
 	Deployments   []string             `yaml:"deployments,omitempty"`
-	Jobs          []*AddOnPlacementJob `yaml:"release,omitempty"`
+	Jobs          []*AddOnPlacementJob `yaml:"jobs,omitempty"`
 	InstanceGroup []string             `yaml:"instance_groups,omitempty"`
 	Networks      []string             `yaml:"networks,omitempty"`
 	Teams         []string             `yaml:"teams,omitempty"`
+	Lifecycle     string               `yaml:"lifecycle,omitempty"`
 }
 
 // AddOn from BOSH deployment manifest
@@ -119,6 +128,9 @@ type Manifest struct {
 	Properties     []map[string]interface{} `yaml:"properties,omitempty"`
 	Variables      []Variable               `yaml:"variables,omitempty"`
 	Update         *Update                  `yaml:"update,omitempty"`
+	CloudConfig    *CloudConfig             `yaml:"cloud_config,omitempty"`
+	// ImagePullSecrets are added to all pods of the deployment
+	ImagePullSecrets []corev1.LocalObjectReference `yaml:"image_pull_secrets,omitempty"`
 }
 
 // LoadYAML returns a new BOSH deployment manifest from a yaml representation
@@ -141,6 +153,16 @@ func LoadYAML(data []byte) (*Manifest, error) {
 		m.InstanceGroups[i].Env.AgentEnvBoshConfig.Agent.Settings.Affinity = ig.Env.BOSH.Agent.Settings.Affinity
 	}
 
+	// The cloud properties of the cloud config contain k8s structures, too
+	cc := &struct {
+		CloudConfig *CloudConfig `json:"cloud_config,omitempty"`
+	}{}
+	err = kubeyaml.Unmarshal(data, cc)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal cloud config from deployment manifest")
+	}
+	m.CloudConfig = cc.CloudConfig
+
 	return m, nil
 }
 
@@ -159,7 +181,8 @@ func (m *Manifest) SHA1() (string, error) {
 	return fmt.Sprintf("%x", sha1.Sum(manifestBytes)), nil
 }
 
-// GetReleaseImage returns the release image location for a given instance group/job
+// GetReleaseImage returns the release image location for a given instance group/job.
+// An image set on the release takes precedence over the one built from its url.
 func (m *Manifest) GetReleaseImage(instanceGroupName, jobName string) (string, error) {
 	var instanceGroup *InstanceGroup
 	for i := range m.InstanceGroups {
@@ -193,6 +216,9 @@ func (m *Manifest) GetReleaseImage(instanceGroupName, jobName string) (string, e
 	for i := range m.Releases {
 		if m.Releases[i].Name == job.Release {
 			release := m.Releases[i]
+			if release.Image != "" {
+				return release.pinnedImage(release.Image), nil
+			}
 			name := strings.TrimRight(release.URL, "/")
 
 			var stemcellVersion string
@@ -205,7 +231,7 @@ func (m *Manifest) GetReleaseImage(instanceGroupName, jobName string) (string, e
 				}
 				stemcellVersion = stemcell.OS + "-" + stemcell.Version
 			}
-			return fmt.Sprintf("%s/%s:%s-%s", name, release.Name, stemcellVersion, release.Version), nil
+			return release.pinnedImage(fmt.Sprintf("%s/%s:%s-%s", name, release.Name, stemcellVersion, release.Version)), nil
 		}
 	}
 	return "", fmt.Errorf("release '%s' not found", job.Release)
@@ -274,6 +300,15 @@ func (m *Manifest) InstanceGroupByName(name string) (*InstanceGroup, error) {
 	return nil, errors.Errorf("can't find instance group '%s' in manifest", name)
 }
 
+// UsesDNSAddresses returns true, unless the use_dns_addresses feature is
+// explicitly disabled. Without DNS addresses, links use the IPs of the pods.
+func (m *Manifest) UsesDNSAddresses() bool {
+	if m.Features == nil || m.Features.UseDNSAddresses == nil {
+		return true
+	}
+	return *m.Features.UseDNSAddresses
+}
+
 // ImplicitVariables returns a list of all implicit variables in a manifest
 func (m *Manifest) ImplicitVariables() ([]string, error) {
 	varMap := make(map[string]bool)
@@ -310,17 +345,115 @@ func (m *Manifest) ImplicitVariables() ([]string, error) {
 	return names, nil
 }
 
-// ApplyAddons goes through all defined addons and adds jobs to matched instance groups
+// ApplyUpdateBlock merges the manifest's update block into the update blocks of all instance groups.
+// Settings of an instance group take precedence, unset settings are inherited. Zero values,
+// e.g. `canaries: 0`, are settings, too.
+func (m *Manifest) ApplyUpdateBlock() {
+	if m.Update == nil {
+		return
+	}
+
+	for _, ig := range m.InstanceGroups {
+		if ig.Update == nil {
+			update := *m.Update
+			ig.Update = &update
+			continue
+		}
+
+		if ig.Update.Canaries == nil {
+			ig.Update.Canaries = m.Update.Canaries
+		}
+		if ig.Update.MaxInFlight == nil {
+			ig.Update.MaxInFlight = m.Update.MaxInFlight
+		}
+		if ig.Update.CanaryWatchTime == nil {
+			ig.Update.CanaryWatchTime = m.Update.CanaryWatchTime
+		}
+		if ig.Update.UpdateWatchTime == nil {
+			ig.Update.UpdateWatchTime = m.Update.UpdateWatchTime
+		}
+		if ig.Update.Serial == nil {
+			ig.Update.Serial = m.Update.Serial
+		}
+		if ig.Update.VMStrategy == nil {
+			ig.Update.VMStrategy = m.Update.VMStrategy
+		}
+	}
+}
+
+// InstanceGroupsBefore returns the instance groups which have to be ready, before the named
+// instance group is deployed. Instance groups are deployed in manifest order. Consecutive
+// instance groups, which are not serial, are deployed in parallel. Errands are not deployed
+// in order, as they only run on demand.
+func (m *Manifest) InstanceGroupsBefore(name string) ([]*InstanceGroup, error) {
+	deployed := []*InstanceGroup{}
+	parallelStart := 0
+
+	for _, ig := range m.InstanceGroups {
+		if ig.LifeCycle == "errand" {
+			if ig.Name == name {
+				return []*InstanceGroup{}, nil
+			}
+			continue
+		}
+
+		if len(deployed) > 0 && (m.isSerial(ig) || m.isSerial(deployed[len(deployed)-1])) {
+			parallelStart = len(deployed)
+		}
+
+		if ig.Name == name {
+			return deployed[:parallelStart], nil
+		}
+		deployed = append(deployed, ig)
+	}
+
+	return nil, errors.Errorf("can't find instance group '%s' in manifest", name)
+}
+
+// isSerial returns whether the instance group is deployed serially, which is the default
+func (m *Manifest) isSerial(ig *InstanceGroup) bool {
+	if ig.Update != nil && ig.Update.Serial != nil {
+		return *ig.Update.Serial
+	}
+	if m.Update != nil && m.Update.Serial != nil {
+		return *m.Update.Serial
+	}
+	return true
+}
+
+// PlaceInstances records the placements of the instances of all instance groups.
+// Instances keep their placements from the previous manifest, see InstanceGroup.PlaceInstances.
+func (m *Manifest) PlaceInstances(previous *Manifest) {
+	for _, ig := range m.InstanceGroups {
+		var previousIG *InstanceGroup
+		if previous != nil {
+			previousIG, _ = previous.InstanceGroupByName(ig.Name)
+		}
+		ig.PlaceInstances(previousIG)
+	}
+}
+
+// ApplyAddons goes through all defined addons and adds jobs to matched instance groups.
+// Addons without include rules are added to all instance groups. Addons, whose
+// include rules only have team rules, are not added to any instance group.
 func (m *Manifest) ApplyAddons() error {
 	for _, addon := range m.AddOns {
+		if addon.Include != nil && addon.Include.onlyTeams() {
+			continue
+		}
+
 		for _, ig := range m.InstanceGroups {
-			include, err := m.addOnPlacementMatch(ig, addon.Include)
-			if err != nil {
-				return errors.Wrap(err, "failed to process include placement matches")
+			include := true
+			if addon.Include != nil && !addon.Include.isEmpty() {
+				var err error
+				include, err = m.addOnPlacementMatch(ig, addon.Include)
+				if err != nil {
+					return errors.Wrapf(err, "failed to process include placement matches of addon '%s'", addon.Name)
+				}
 			}
 			exclude, err := m.addOnPlacementMatch(ig, addon.Exclude)
 			if err != nil {
-				return errors.Wrap(err, "failed to process exclude placement matches")
+				return errors.Wrapf(err, "failed to process exclude placement matches of addon '%s'", addon.Name)
 			}
 
 			if exclude || !include {
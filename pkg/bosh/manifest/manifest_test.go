@@ -6,8 +6,10 @@ import (
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
 
 	. "code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
+	bdc "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
 	t "code.cloudfoundry.org/cf-operator/testing"
 	"code.cloudfoundry.org/cf-operator/testing/boshmanifest"
 )
@@ -185,7 +187,7 @@ var _ = Describe("Manifest", func() {
 			Describe("Jobs", func() {
 				It("contains desired values", func() {
 					Expect(getStructTagForName("Jobs", addOnPlacementRule)).To(Equal(
-						`yaml:"release,omitempty"`,
+						`yaml:"jobs,omitempty"`,
 					))
 				})
 			})
@@ -213,6 +215,14 @@ var _ = Describe("Manifest", func() {
 					))
 				})
 			})
+
+			Describe("Lifecycle", func() {
+				It("contains desired values", func() {
+					Expect(getStructTagForName("Lifecycle", addOnPlacementRule)).To(Equal(
+						`yaml:"lifecycle,omitempty"`,
+					))
+				})
+			})
 		})
 
 		Describe("AddOnPlacementJob", func() {
@@ -1226,6 +1236,70 @@ var _ = Describe("Manifest", func() {
 				Expect(err).ToNot(HaveOccurred())
 				Expect(releaseImage).To(Equal("hub.docker.com/cfcontainerization/cflinuxfs3:opensuse-15.0-28.g837c5b3-30.263-7.0.0_233.gde0accd0-0.62.0"))
 			})
+
+			It("uses the image of the release if it is set", func() {
+				manifest.Releases[1].Image = "registry.example.com/redis:custom"
+				releaseImage, err := manifest.GetReleaseImage("redis-slave", "redis-server")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(releaseImage).To(Equal("registry.example.com/redis:custom"))
+			})
+
+			It("pins the image to the digest of the release", func() {
+				manifest.Releases[1].Digest = "sha256:0123"
+				releaseImage, err := manifest.GetReleaseImage("redis-slave", "redis-server")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(releaseImage).To(Equal("hub.docker.com/cfcontainerization/redis:opensuse-42.3-28.g837c5b3-30.263-7.0.0_234.gcd7d1132-36.15.0@sha256:0123"))
+
+				manifest.Releases[1].Image = "registry.example.com/redis@sha256:4567"
+				releaseImage, err = manifest.GetReleaseImage("redis-slave", "redis-server")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(releaseImage).To(Equal("registry.example.com/redis@sha256:0123"))
+			})
+		})
+
+		Describe("ApplyImages", func() {
+			BeforeEach(func() {
+				*manifest = env.DefaultBOSHManifest()
+			})
+
+			It("sets the image overrides and pull secrets", func() {
+				err := manifest.ApplyImages(
+					[]bdc.ReleaseImage{{Release: "redis", Image: "registry.example.com/redis:custom", Digest: "sha256:0123"}},
+					[]corev1.LocalObjectReference{{Name: "registry-credentials"}},
+				)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(manifest.Releases[0].Image).To(BeEmpty())
+				Expect(manifest.Releases[1].Image).To(Equal("registry.example.com/redis:custom"))
+				Expect(manifest.Releases[1].Digest).To(Equal("sha256:0123"))
+				Expect(manifest.ImagePullSecrets).To(ConsistOf(corev1.LocalObjectReference{Name: "registry-credentials"}))
+			})
+
+			It("reports an error if the release is not part of the manifest", func() {
+				err := manifest.ApplyImages([]bdc.ReleaseImage{{Release: "unknown", Image: "foo"}}, nil)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("image override for unknown release 'unknown'"))
+			})
+		})
+
+		Describe("ApplyRegistryMirror", func() {
+			BeforeEach(func() {
+				*manifest = env.DefaultBOSHManifest()
+			})
+
+			It("replaces the registry of the release images", func() {
+				manifest.Releases[0].URL = "cfcontainerization"
+				manifest.Releases[1].Image = "localhost:5000/redis:custom"
+				manifest.ApplyRegistryMirror("mirror.example.com/")
+
+				Expect(manifest.Releases[0].URL).To(Equal("mirror.example.com/cfcontainerization"))
+				Expect(manifest.Releases[1].URL).To(Equal("mirror.example.com/cfcontainerization"))
+				Expect(manifest.Releases[1].Image).To(Equal("mirror.example.com/redis:custom"))
+			})
+
+			It("does nothing without a mirror", func() {
+				manifest.ApplyRegistryMirror("")
+				Expect(manifest.Releases[1].URL).To(Equal("hub.docker.com/cfcontainerization"))
+			})
 		})
 
 		Describe("InstanceGroupByName", func() {
@@ -1244,5 +1318,132 @@ var _ = Describe("Manifest", func() {
 				Expect(ig.Name).To(Equal("redis-slave"))
 			})
 		})
+
+		Describe("UsesDNSAddresses", func() {
+			It("uses DNS addresses, unless the feature is disabled", func() {
+				*manifest = Manifest{}
+				Expect(manifest.UsesDNSAddresses()).To(BeTrue())
+
+				useDNSAddresses := true
+				manifest.Features = &Feature{UseDNSAddresses: &useDNSAddresses}
+				Expect(manifest.UsesDNSAddresses()).To(BeTrue())
+
+				useDNSAddresses = false
+				Expect(manifest.UsesDNSAddresses()).To(BeFalse())
+			})
+		})
+
+		Describe("InstanceGroupsBefore", func() {
+			parallel := false
+
+			BeforeEach(func() {
+				*manifest = Manifest{
+					InstanceGroups: []*InstanceGroup{
+						{Name: "db"},
+						{Name: "smoke-tests", LifeCycle: "errand"},
+						{Name: "api"},
+						{Name: "router", Update: &Update{Serial: &parallel}},
+						{Name: "worker", Update: &Update{Serial: &parallel}},
+					},
+				}
+			})
+
+			It("returns an error if the instance group does not exist", func() {
+				_, err := manifest.InstanceGroupsBefore("foo")
+				Expect(err).To(HaveOccurred())
+			})
+
+			It("returns all previous instance groups of a serial instance group", func() {
+				igs, err := manifest.InstanceGroupsBefore("api")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(igs).To(HaveLen(1))
+				Expect(igs[0].Name).To(Equal("db"))
+			})
+
+			It("doesn't wait for consecutive parallel instance groups", func() {
+				igs, err := manifest.InstanceGroupsBefore("worker")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(igs).To(HaveLen(2))
+				Expect(igs[1].Name).To(Equal("api"))
+			})
+
+			It("deploys errands without waiting", func() {
+				igs, err := manifest.InstanceGroupsBefore("smoke-tests")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(igs).To(BeEmpty())
+			})
+
+			It("uses the manifest's update block as default", func() {
+				manifest.Update = &Update{Serial: &parallel}
+				igs, err := manifest.InstanceGroupsBefore("api")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(igs).To(BeEmpty())
+			})
+		})
+
+		Describe("ApplyUpdateBlock", func() {
+			var (
+				canaries        int
+				maxInFlight     string
+				canaryWatchTime string
+				updateWatchTime string
+			)
+
+			BeforeEach(func() {
+				canaries = 1
+				maxInFlight = "2"
+				canaryWatchTime = "1000-30000"
+				updateWatchTime = "5000-60000"
+
+				*manifest = env.DefaultBOSHManifest()
+				manifest.Update = &Update{
+					Canaries:        &canaries,
+					MaxInFlight:     &maxInFlight,
+					CanaryWatchTime: &canaryWatchTime,
+					UpdateWatchTime: &updateWatchTime,
+				}
+			})
+
+			It("copies the update block to instance groups without one", func() {
+				manifest.ApplyUpdateBlock()
+				Expect(manifest.InstanceGroups[0].Update).To(Equal(manifest.Update))
+				Expect(manifest.InstanceGroups[0].Update).ToNot(BeIdenticalTo(manifest.Update))
+			})
+
+			It("keeps the settings of an instance group's update block", func() {
+				igMaxInFlight := "50%"
+				manifest.InstanceGroups[0].Update = &Update{MaxInFlight: &igMaxInFlight}
+				manifest.ApplyUpdateBlock()
+				Expect(manifest.InstanceGroups[0].Update).To(Equal(&Update{
+					Canaries:        &canaries,
+					MaxInFlight:     &igMaxInFlight,
+					CanaryWatchTime: &canaryWatchTime,
+					UpdateWatchTime: &updateWatchTime,
+				}))
+			})
+
+			It("keeps zero values of an instance group's update block", func() {
+				igCanaries := 0
+				manifest.InstanceGroups[0].Update = &Update{Canaries: &igCanaries}
+				manifest.ApplyUpdateBlock()
+				Expect(*manifest.InstanceGroups[0].Update.Canaries).To(Equal(0))
+				Expect(*manifest.InstanceGroups[0].Update.MaxInFlight).To(Equal("2"))
+			})
+
+			It("reads zero values from the manifest", func() {
+				m, err := LoadYAML([]byte(`
+update:
+  canaries: 2
+instance_groups:
+- name: web
+  update:
+    canaries: 0
+`))
+				Expect(err).ToNot(HaveOccurred())
+				m.ApplyUpdateBlock()
+				Expect(m.InstanceGroups[0].Update.Canaries).ToNot(BeNil())
+				Expect(*m.InstanceGroups[0].Update.Canaries).To(Equal(0))
+			})
+		})
 	})
 })
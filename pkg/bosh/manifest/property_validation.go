@@ -0,0 +1,309 @@
+package manifest
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// variableRegexp matches property values, which are a single variable. Their kind
+// is only known once the variable is interpolated.
+var variableRegexp = regexp.MustCompile(`^\(\((!?[-/\.\w\pL]+)\)\)$`)
+
+// FindingSeverity is the severity of a property validation finding
+type FindingSeverity string
+
+const (
+	// FindingWarning is reported for properties, which might not be a problem, e.g.
+	// unknown properties, which are ignored by the job's templates
+	FindingWarning FindingSeverity = "warning"
+	// FindingError is reported for properties, which the job's templates can't render
+	FindingError FindingSeverity = "error"
+)
+
+// PropertyFinding is a problem with a job property of the manifest, found by
+// validating it against the job's release spec
+type PropertyFinding struct {
+	InstanceGroup string          `yaml:"instance_group" json:"instanceGroup"`
+	Job           string          `yaml:"job" json:"job"`
+	Property      string          `yaml:"property" json:"property"`
+	Severity      FindingSeverity `yaml:"severity" json:"severity"`
+	Message       string          `yaml:"message" json:"message"`
+}
+
+// String returns a human readable description of the finding
+func (f PropertyFinding) String() string {
+	return fmt.Sprintf("job '%s' of instance group '%s': property '%s' %s", f.Job, f.InstanceGroup, f.Property, f.Message)
+}
+
+// PropertyFindings is a list of property validation findings
+type PropertyFindings []PropertyFinding
+
+// String returns the descriptions of all findings
+func (findings PropertyFindings) String() string {
+	descriptions := make([]string, len(findings))
+	for i, f := range findings {
+		descriptions[i] = f.String()
+	}
+	return strings.Join(descriptions, "; ")
+}
+
+// Summary returns the descriptions of the first max findings and the number
+// of the remaining ones
+func (findings PropertyFindings) Summary(max int) string {
+	if len(findings) <= max {
+		return findings.String()
+	}
+	return fmt.Sprintf("%s; and %d more", findings[:max].String(), len(findings)-max)
+}
+
+// Errors returns the findings with error severity
+func (findings PropertyFindings) Errors() PropertyFindings {
+	return findings.withSeverity(FindingError)
+}
+
+// Warnings returns the findings with warning severity
+func (findings PropertyFindings) Warnings() PropertyFindings {
+	return findings.withSeverity(FindingWarning)
+}
+
+// Without returns the findings, which are not in the given findings
+func (findings PropertyFindings) Without(other PropertyFindings) PropertyFindings {
+	known := map[PropertyFinding]bool{}
+	for _, f := range other {
+		known[f] = true
+	}
+	result := PropertyFindings{}
+	for _, f := range findings {
+		if !known[f] {
+			result = append(result, f)
+		}
+	}
+	return result
+}
+
+func (findings PropertyFindings) withSeverity(severity FindingSeverity) PropertyFindings {
+	result := PropertyFindings{}
+	for _, f := range findings {
+		if f.Severity == severity {
+			result = append(result, f)
+		}
+	}
+	return result
+}
+
+// ValidateProperties checks the properties of a manifest job against the
+// property definitions of its spec. Unknown properties and properties without
+// a default, which are not set, are reported as warnings, since templates might
+// ignore them or only use them with 'if_p'. Properties, whose value is of another
+// kind (hash, array or scalar) than their default, are reported as errors.
+// Values, which are a single variable, are not checked, as their kind is only
+// known once they are interpolated.
+func (spec JobSpec) ValidateProperties(job Job) PropertyFindings {
+	findings := PropertyFindings{}
+	report := func(property string, severity FindingSeverity, format string, v ...interface{}) {
+		findings = append(findings, PropertyFinding{
+			Job:      job.Name,
+			Property: property,
+			Severity: severity,
+			Message:  fmt.Sprintf(format, v...),
+		})
+	}
+
+	var walk func(prefix string, properties map[string]interface{})
+	walk = func(prefix string, properties map[string]interface{}) {
+		for _, name := range sortedKeys(properties) {
+			path := prefix + name
+			value := properties[name]
+
+			if property, ok := spec.Properties[path]; ok {
+				if isVariable(value) {
+					continue
+				}
+				expected, actual := propertyKind(property.Default), propertyKind(value)
+				if expected != "" && actual != "" && expected != actual {
+					report(path, FindingError, "must be of kind '%s', but is of kind '%s'", expected, actual)
+				}
+				continue
+			}
+
+			if !spec.hasPropertiesUnder(path) {
+				report(path, FindingWarning, "is not defined in the spec of the job")
+				continue
+			}
+
+			nested, ok := toStringMap(value)
+			if !ok {
+				if value != nil && !isVariable(value) {
+					report(path, FindingError, "must be of kind 'hash', but is of kind '%s'", propertyKind(value))
+				}
+				continue
+			}
+			walk(path+".", nested)
+		}
+	}
+	walk("", job.Properties.Properties)
+
+	names := make([]string, 0, len(spec.Properties))
+	for name := range spec.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if spec.Properties[name].Default != nil {
+			continue
+		}
+		if _, ok := job.Property(name); !ok {
+			report(name, FindingWarning, "has no default and is not set")
+		}
+	}
+
+	return findings
+}
+
+// isVariable returns true if the value is a single variable, e.g. '((password))'
+func isVariable(value interface{}) bool {
+	s, ok := value.(string)
+	return ok && variableRegexp.MatchString(s)
+}
+
+// hasPropertiesUnder returns true if the spec defines properties nested under path
+func (spec JobSpec) hasPropertiesUnder(path string) bool {
+	for name := range spec.Properties {
+		if strings.HasPrefix(name, path+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// PropertyFindings validates the job properties of the instance group against
+// the specs of their release jobs.
+// The output will be persisted by ExtendedJob as 'validation.yaml' in the
+// `<deployment-name>.bpm.<instance-group>-v<version>` secret.
+func (dg *DataGatherer) PropertyFindings() (PropertyFindings, error) {
+	findings := PropertyFindings{}
+	for _, job := range dg.instanceGroup.Jobs {
+		if _, ok := dg.jobReleaseSpecs[job.Release]; !ok {
+			dg.jobReleaseSpecs[job.Release] = map[string]JobSpec{}
+		}
+		spec, ok := dg.jobReleaseSpecs[job.Release][job.Name]
+		if !ok {
+			jobSpec, err := job.loadSpec(dg.baseDir)
+			if err != nil {
+				return findings, err
+			}
+			spec = *jobSpec
+			dg.jobReleaseSpecs[job.Release][job.Name] = spec
+		}
+
+		for _, f := range spec.ValidateProperties(job) {
+			f.InstanceGroup = dg.instanceGroup.Name
+			findings = append(findings, f)
+		}
+	}
+	return findings, nil
+}
+
+// ValidatedJobSpec is the spec of a release job, whose properties have been
+// validated by the data gathering job. Only the property definitions are kept.
+// The specs are persisted as 'specs.yaml' next to the findings, so the validating
+// webhook can validate later changes of the properties without the release images.
+type ValidatedJobSpec struct {
+	Release string  `yaml:"release"`
+	Version string  `yaml:"version"`
+	Spec    JobSpec `yaml:"spec"`
+}
+
+// ValidatedJobSpecs are the specs of the jobs of an instance group
+type ValidatedJobSpecs []ValidatedJobSpec
+
+// ValidatedJobSpecs returns the property definitions of the specs, which the
+// properties of the instance group's jobs have been validated against.
+// The output will be persisted by ExtendedJob as 'specs.yaml' in the
+// `<deployment-name>.bpm.<instance-group>-v<version>` secret.
+func (dg *DataGatherer) ValidatedJobSpecs() ValidatedJobSpecs {
+	specs := ValidatedJobSpecs{}
+	for _, job := range dg.instanceGroup.Jobs {
+		spec, ok := dg.jobReleaseSpecs[job.Release][job.Name]
+		if !ok {
+			continue
+		}
+		release := dg.manifest.release(job.Release)
+		if release == nil {
+			continue
+		}
+
+		validated := ValidatedJobSpec{Release: release.Name, Version: release.Version}
+		validated.Spec.Name = spec.Name
+		validated.Spec.Properties = make(map[string]JobSpecProperty, len(spec.Properties))
+		for name, property := range spec.Properties {
+			validated.Spec.Properties[name] = JobSpecProperty{Default: property.Default}
+		}
+		specs = append(specs, validated)
+	}
+	return specs
+}
+
+// ValidateProperties validates the job properties of the instance group against
+// the specs of their release jobs. Jobs, whose release version has no spec, are
+// skipped.
+func (specs ValidatedJobSpecs) ValidateProperties(m *Manifest, instanceGroup *InstanceGroup) PropertyFindings {
+	findings := PropertyFindings{}
+	for _, job := range instanceGroup.Jobs {
+		release := m.release(job.Release)
+		if release == nil {
+			continue
+		}
+		for _, validated := range specs {
+			if validated.Release != release.Name || validated.Version != release.Version || validated.Spec.Name != job.Name {
+				continue
+			}
+			for _, f := range validated.Spec.ValidateProperties(job) {
+				f.InstanceGroup = instanceGroup.Name
+				findings = append(findings, f)
+			}
+		}
+	}
+	return findings
+}
+
+// propertyKind returns the kind of a property value, or an empty string for nil
+func propertyKind(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return ""
+	case map[string]interface{}, map[interface{}]interface{}:
+		return "hash"
+	case []interface{}:
+		return "array"
+	default:
+		return "scalar"
+	}
+}
+
+// toStringMap returns the value as a map with string keys, if it is a hash
+func toStringMap(value interface{}) (map[string]interface{}, bool) {
+	switch m := value.(type) {
+	case map[string]interface{}:
+		return m, true
+	case map[interface{}]interface{}:
+		result := make(map[string]interface{}, len(m))
+		for k, v := range m {
+			result[fmt.Sprintf("%v", k)] = v
+		}
+		return result, true
+	default:
+		return nil, false
+	}
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
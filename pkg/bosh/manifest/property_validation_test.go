@@ -0,0 +1,215 @@
+package manifest_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	yaml "gopkg.in/yaml.v2"
+
+	. "code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
+	helper "code.cloudfoundry.org/cf-operator/pkg/testhelper"
+	"code.cloudfoundry.org/cf-operator/testing"
+)
+
+var _ = Describe("ValidateProperties", func() {
+	var (
+		spec JobSpec
+		job  Job
+	)
+
+	BeforeEach(func() {
+		err := yaml.Unmarshal([]byte(`
+name: fake-job
+properties:
+  fake.port:
+    default: 8080
+  fake.tls.cert:
+    description: certificate, used by templates with if_p
+  fake.users:
+    default: []
+  fake.limits:
+    default: {}
+`), &spec)
+		Expect(err).ToNot(HaveOccurred())
+
+		job = Job{Name: "fake-job"}
+		err = yaml.Unmarshal([]byte(`
+fake:
+  port: 9090
+  tls:
+    cert: some-cert
+  users:
+  - admin
+  limits:
+    cpu: 2
+`), &job.Properties.Properties)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	act := func() PropertyFindings {
+		return spec.ValidateProperties(job)
+	}
+
+	It("has no findings for properties matching the spec", func() {
+		Expect(act()).To(BeEmpty())
+	})
+
+	It("warns about properties which are not in the spec", func() {
+		job.Properties.Properties["fake"].(map[interface{}]interface{})["prot"] = 9090
+		job.Properties.Properties["other"] = map[interface{}]interface{}{"port": 1}
+
+		findings := act()
+		Expect(findings.Errors()).To(BeEmpty())
+		Expect(findings.Warnings()).To(ConsistOf(
+			PropertyFinding{Job: "fake-job", Property: "fake.prot", Severity: FindingWarning, Message: "is not defined in the spec of the job"},
+			PropertyFinding{Job: "fake-job", Property: "other", Severity: FindingWarning, Message: "is not defined in the spec of the job"},
+		))
+	})
+
+	It("warns about properties without a default which are not set", func() {
+		delete(job.Properties.Properties["fake"].(map[interface{}]interface{}), "tls")
+
+		Expect(act()).To(ConsistOf(
+			PropertyFinding{Job: "fake-job", Property: "fake.tls.cert", Severity: FindingWarning, Message: "has no default and is not set"},
+		))
+	})
+
+	It("doesn't check the kind of properties, which are a single variable", func() {
+		fake := job.Properties.Properties["fake"].(map[interface{}]interface{})
+		fake["users"] = "((users))"
+		fake["tls"] = "((tls))"
+
+		Expect(act().Errors()).To(BeEmpty())
+	})
+
+	It("fails for properties of another kind than their default", func() {
+		fake := job.Properties.Properties["fake"].(map[interface{}]interface{})
+		fake["port"] = map[interface{}]interface{}{"http": 9090}
+		fake["users"] = "admin"
+
+		findings := act()
+		Expect(findings.Warnings()).To(BeEmpty())
+		Expect(findings.Errors()).To(ConsistOf(
+			PropertyFinding{Job: "fake-job", Property: "fake.port", Severity: FindingError, Message: "must be of kind 'scalar', but is of kind 'hash'"},
+			PropertyFinding{Job: "fake-job", Property: "fake.users", Severity: FindingError, Message: "must be of kind 'array', but is of kind 'scalar'"},
+		))
+	})
+
+	It("fails for scalars in place of nested properties", func() {
+		job.Properties.Properties["fake"].(map[interface{}]interface{})["tls"] = "some-cert"
+
+		Expect(act().Errors()).To(ConsistOf(
+			PropertyFinding{Job: "fake-job", Property: "fake.tls", Severity: FindingError, Message: "must be of kind 'hash', but is of kind 'scalar'"},
+		))
+	})
+
+	Describe("Summary", func() {
+		findings := PropertyFindings{
+			{Job: "fake-job", Property: "a", Message: "is not defined in the spec of the job"},
+			{Job: "fake-job", Property: "b", Message: "is not defined in the spec of the job"},
+			{Job: "fake-job", Property: "c", Message: "is not defined in the spec of the job"},
+		}
+
+		It("describes all findings, if there are no more than max", func() {
+			Expect(findings.Summary(3)).To(Equal(findings.String()))
+		})
+
+		It("counts the findings after the first max", func() {
+			Expect(findings.Summary(2)).To(Equal(
+				"job 'fake-job' of instance group '': property 'a' is not defined in the spec of the job; " +
+					"job 'fake-job' of instance group '': property 'b' is not defined in the spec of the job; " +
+					"and 1 more",
+			))
+		})
+	})
+
+	Describe("Without", func() {
+		It("leaves out the given findings", func() {
+			reported := PropertyFinding{Job: "fake-job", Property: "a", Severity: FindingWarning, Message: "is not defined in the spec of the job"}
+			added := PropertyFinding{Job: "fake-job", Property: "b", Severity: FindingWarning, Message: "is not defined in the spec of the job"}
+
+			Expect(PropertyFindings{reported, added}.Without(PropertyFindings{reported})).To(Equal(PropertyFindings{added}))
+		})
+	})
+
+	Describe("ValidatedJobSpecs", func() {
+		var (
+			m     *Manifest
+			specs ValidatedJobSpecs
+		)
+
+		BeforeEach(func() {
+			m = &Manifest{
+				Releases:       []*Release{{Name: "fake-release", Version: "1.0"}},
+				InstanceGroups: []*InstanceGroup{{Name: "fake-ig", Jobs: []Job{job}}},
+			}
+			m.InstanceGroups[0].Jobs[0].Release = "fake-release"
+			m.InstanceGroups[0].Jobs[0].Properties.Properties["unknown"] = "foo"
+			specs = ValidatedJobSpecs{{Release: "fake-release", Version: "1.0", Spec: spec}}
+		})
+
+		It("validates the jobs of the instance group against the specs of their release version", func() {
+			Expect(specs.ValidateProperties(m, m.InstanceGroups[0])).To(ConsistOf(
+				PropertyFinding{InstanceGroup: "fake-ig", Job: "fake-job", Property: "unknown", Severity: FindingWarning, Message: "is not defined in the spec of the job"},
+			))
+		})
+
+		It("skips jobs of other release versions", func() {
+			m.Releases[0].Version = "2.0"
+
+			Expect(specs.ValidateProperties(m, m.InstanceGroups[0])).To(BeEmpty())
+		})
+
+		It("keeps the property definitions when they are persisted", func() {
+			specsBytes, err := yaml.Marshal(specs)
+			Expect(err).ToNot(HaveOccurred())
+
+			persisted := ValidatedJobSpecs{}
+			Expect(yaml.Unmarshal(specsBytes, &persisted)).To(Succeed())
+			Expect(persisted.ValidateProperties(m, m.InstanceGroups[0])).To(Equal(specs.ValidateProperties(m, m.InstanceGroups[0])))
+		})
+	})
+
+	Context("when validating the jobs of an instance group", func() {
+		It("reports the findings with the instance group", func() {
+			env := testing.Catalog{}
+			m := env.BOSHManifestWithProviderAndConsumer()
+			m.InstanceGroups[0].Jobs[0].Properties.Properties["unknown_property"] = "foo"
+
+			_, log := helper.NewTestLogger()
+			dg, err := NewDataGatherer(log, assetPath, "", "default", "cluster.local", *m, m.InstanceGroups[0].Name)
+			Expect(err).ToNot(HaveOccurred())
+
+			findings, err := dg.PropertyFindings()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(findings).To(ContainElement(PropertyFinding{
+				InstanceGroup: m.InstanceGroups[0].Name,
+				Job:           m.InstanceGroups[0].Jobs[0].Name,
+				Property:      "unknown_property",
+				Severity:      FindingWarning,
+				Message:       "is not defined in the spec of the job",
+			}))
+		})
+
+		It("returns the property definitions of the specs with their release version", func() {
+			env := testing.Catalog{}
+			m := env.BOSHManifestWithProviderAndConsumer()
+			job := m.InstanceGroups[0].Jobs[0]
+
+			_, log := helper.NewTestLogger()
+			dg, err := NewDataGatherer(log, assetPath, "", "default", "cluster.local", *m, m.InstanceGroups[0].Name)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = dg.PropertyFindings()
+			Expect(err).ToNot(HaveOccurred())
+
+			specs := dg.ValidatedJobSpecs()
+			Expect(specs).ToNot(BeEmpty())
+			Expect(specs[0].Release).To(Equal(job.Release))
+			Expect(specs[0].Version).To(Equal(m.Releases[0].Version))
+			Expect(specs[0].Spec.Name).To(Equal(job.Name))
+			Expect(specs[0].Spec.Properties).ToNot(BeEmpty())
+			for _, property := range specs[0].Spec.Properties {
+				Expect(property.Description).To(BeEmpty())
+			}
+		})
+	})
+})
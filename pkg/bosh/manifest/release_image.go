@@ -0,0 +1,83 @@
+package manifest
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	bdc "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+)
+
+// ApplyImages sets the image overrides and pull secrets of a BOSHDeployment
+// on the manifest. Overrides for releases, which are not part of the
+// manifest, are an error.
+func (m *Manifest) ApplyImages(images []bdc.ReleaseImage, pullSecrets []corev1.LocalObjectReference) error {
+	for _, image := range images {
+		release := m.release(image.Release)
+		if release == nil {
+			return fmt.Errorf("image override for unknown release '%s'", image.Release)
+		}
+		if image.Image != "" {
+			release.Image = image.Image
+		}
+		if image.Digest != "" {
+			release.Digest = image.Digest
+		}
+	}
+
+	m.ImagePullSecrets = append(m.ImagePullSecrets, pullSecrets...)
+
+	return nil
+}
+
+// ApplyRegistryMirror replaces the registry of all release images with the
+// mirror. Images without a registry are pulled from Docker Hub, so the mirror
+// is prepended to them.
+func (m *Manifest) ApplyRegistryMirror(mirror string) {
+	mirror = strings.TrimRight(mirror, "/")
+	if mirror == "" {
+		return
+	}
+
+	for _, release := range m.Releases {
+		if release.URL != "" {
+			release.URL = mirrorImage(mirror, release.URL)
+		}
+		if release.Image != "" {
+			release.Image = mirrorImage(mirror, release.Image)
+		}
+	}
+}
+
+func (m *Manifest) release(name string) *Release {
+	for _, release := range m.Releases {
+		if release.Name == name {
+			return release
+		}
+	}
+	return nil
+}
+
+// pinnedImage appends the digest of the release to the image, replacing any
+// digest the image already has.
+func (r *Release) pinnedImage(image string) string {
+	if r.Digest == "" {
+		return image
+	}
+	if i := strings.Index(image, "@"); i != -1 {
+		image = image[:i]
+	}
+	return image + "@" + r.Digest
+}
+
+// mirrorImage replaces the registry of the image. Like docker, the first
+// component of the image is taken to be a registry, if it contains a '.' or a
+// ':' or is 'localhost'.
+func mirrorImage(mirror, image string) string {
+	parts := strings.SplitN(image, "/", 2)
+	if len(parts) == 2 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		return mirror + "/" + parts[1]
+	}
+	return mirror + "/" + image
+}
@@ -0,0 +1,297 @@
+package manifest
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	yamlv2 "gopkg.in/yaml.v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"code.cloudfoundry.org/cf-operator/pkg/bosh/bpm"
+	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	ejv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/extendedjob/v1alpha1"
+	esv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/extendedsecret/v1alpha1"
+	essv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/extendedstatefulset/v1alpha1"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/names"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/versionedsecretstore"
+)
+
+// renderedVersion is the version of the versioned secrets and instance groups of a
+// rendered deployment, which is the version of a deployment's first rollout
+const renderedVersion = "1"
+
+// GatheredData is the output of the data gathering for one instance group
+type GatheredData struct {
+	ResolvedProperties Manifest
+	BPMConfigs         bpm.Configs
+	PropertyFindings   PropertyFindings
+	ValidatedJobSpecs  ValidatedJobSpecs
+}
+
+// GatherData runs the data gathering for all instance groups of the manifest in-process,
+// like the data gathering ExtendedJobs do in the cluster. The jobs of the unpacked
+// releases are read from baseDir.
+func GatherData(log *zap.SugaredLogger, m *Manifest, baseDir, linksDir, namespace, clusterDomain string) (map[string]GatheredData, error) {
+	manifestBytes, err := m.Marshal()
+	if err != nil {
+		return nil, errors.Wrap(err, "marshalling manifest")
+	}
+
+	// Each container of the data gathering jobs loads the manifest on its own
+	newDataGatherer := func(instanceGroupName string) (*DataGatherer, error) {
+		m, err := LoadYAML(manifestBytes)
+		if err != nil {
+			return nil, err
+		}
+		return NewDataGatherer(log, baseDir, linksDir, namespace, clusterDomain, *m, instanceGroupName)
+	}
+
+	gathered := map[string]GatheredData{}
+	for _, ig := range m.InstanceGroups {
+		data := GatheredData{}
+
+		dg, err := newDataGatherer(ig.Name)
+		if err != nil {
+			return nil, err
+		}
+		data.ResolvedProperties, err = dg.ResolvedProperties()
+		if err != nil {
+			return nil, errors.Wrapf(err, "gathering data of instance group '%s'", ig.Name)
+		}
+
+		dg, err = newDataGatherer(ig.Name)
+		if err != nil {
+			return nil, err
+		}
+		data.PropertyFindings, err = dg.PropertyFindings()
+		if err != nil {
+			return nil, errors.Wrapf(err, "validating properties of instance group '%s'", ig.Name)
+		}
+		data.ValidatedJobSpecs = dg.ValidatedJobSpecs()
+		data.BPMConfigs, err = dg.BPMConfigs()
+		if err != nil {
+			return nil, errors.Wrapf(err, "rendering BPM configs of instance group '%s'", ig.Name)
+		}
+
+		gathered[ig.Name] = data
+	}
+
+	return gathered, nil
+}
+
+// RenderedResources are the kube resources, which the operator creates for a deployment
+type RenderedResources struct {
+	ExtendedSecrets        []esv1.ExtendedSecret
+	ExtendedJobs           []ejv1.ExtendedJob
+	ExtendedStatefulSets   []essv1.ExtendedStatefulSet
+	Services               []corev1.Service
+	PersistentVolumeClaims []corev1.PersistentVolumeClaim
+	Secrets                []corev1.Secret
+}
+
+// RenderAll returns all kube resources, which the operator creates for a with-ops manifest,
+// using the gathered data of its instance groups. Nothing is applied.
+// Explicit variables are not interpolated, since they are generated in the cluster.
+func (kc *KubeConverter) RenderAll(m *Manifest, gathered map[string]GatheredData, clusterDomain string) (*RenderedResources, error) {
+	resources := &RenderedResources{
+		ExtendedSecrets: kc.Variables(m.Name, m.Variables),
+	}
+
+	manifestBytes, err := m.Marshal()
+	if err != nil {
+		return nil, errors.Wrap(err, "marshalling with-ops manifest")
+	}
+	resources.Secrets = append(resources.Secrets, corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      names.CalculateSecretName(names.DeploymentSecretTypeManifestWithOps, m.Name, ""),
+			Namespace: kc.namespace,
+		},
+		StringData: map[string]string{"manifest.yaml": string(manifestBytes)},
+	})
+
+	jobFactory := NewJobFactory(*m, kc.namespace, clusterDomain)
+	jobs := []func() (*ejv1.ExtendedJob, error){
+		jobFactory.VariableInterpolationJob,
+		jobFactory.DataGatheringJob,
+		jobFactory.BPMConfigsJob,
+	}
+	if m.ProvidesSharedLinks() {
+		jobs = append(jobs, jobFactory.LinksJob)
+	}
+	for _, job := range jobs {
+		eJob, err := job()
+		if err != nil {
+			return nil, err
+		}
+		resources.ExtendedJobs = append(resources.ExtendedJobs, *eJob)
+	}
+
+	for _, ig := range m.InstanceGroups {
+		data, ok := gathered[ig.Name]
+		if !ok {
+			return nil, errors.Errorf("missing gathered data for instance group '%s'", ig.Name)
+		}
+
+		secrets, err := kc.gatheredDataSecrets(m.Name, ig.Name, data)
+		if err != nil {
+			return nil, err
+		}
+		resources.Secrets = append(resources.Secrets, secrets...)
+
+		bpmResources, err := kc.BPMResources(m.Name, renderedVersion, ig, m, data.BPMConfigs, m.CloudConfig, m.UsesDNSAddresses(), m.ImagePullSecrets)
+		if err != nil {
+			return nil, errors.Wrapf(err, "converting instance group '%s'", ig.Name)
+		}
+		resources.ExtendedStatefulSets = append(resources.ExtendedStatefulSets, bpmResources.InstanceGroups...)
+		resources.ExtendedJobs = append(resources.ExtendedJobs, bpmResources.Errands...)
+		resources.ExtendedJobs = append(resources.ExtendedJobs, bpmResources.PostDeploys...)
+		resources.Services = append(resources.Services, bpmResources.Services...)
+		for _, disk := range bpmResources.Disks {
+			if disk.PersistentVolumeClaim != nil {
+				resources.PersistentVolumeClaims = append(resources.PersistentVolumeClaims, *disk.PersistentVolumeClaim)
+			}
+		}
+	}
+
+	resources.setTypeMeta()
+	return resources, nil
+}
+
+// gatheredDataSecrets returns the versioned secrets, which the data gathering jobs write for an instance group
+func (kc *KubeConverter) gatheredDataSecrets(manifestName string, instanceGroupName string, data GatheredData) ([]corev1.Secret, error) {
+	propertiesBytes, err := yamlv2.Marshal(data.ResolvedProperties)
+	if err != nil {
+		return nil, errors.Wrapf(err, "marshalling resolved properties of instance group '%s'", instanceGroupName)
+	}
+	bpmBytes, err := yamlv2.Marshal(data.BPMConfigs)
+	if err != nil {
+		return nil, errors.Wrapf(err, "marshalling BPM configs of instance group '%s'", instanceGroupName)
+	}
+	findingsBytes, err := yamlv2.Marshal(data.PropertyFindings)
+	if err != nil {
+		return nil, errors.Wrapf(err, "marshalling property findings of instance group '%s'", instanceGroupName)
+	}
+	specsBytes, err := yamlv2.Marshal(data.ValidatedJobSpecs)
+	if err != nil {
+		return nil, errors.Wrapf(err, "marshalling job specs of instance group '%s'", instanceGroupName)
+	}
+
+	secret := func(secretType names.DeploymentSecretType, data map[string]string) corev1.Secret {
+		return corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      names.CalculateIGSecretName(secretType, manifestName, instanceGroupName, renderedVersion),
+				Namespace: kc.namespace,
+				Labels: map[string]string{
+					bdv1.LabelDeploymentName:             manifestName,
+					bdv1.LabelDeploymentSecretType:       secretType.String(),
+					ejv1.LabelInstanceGroup:              instanceGroupName,
+					versionedsecretstore.LabelSecretKind: "versionedSecret",
+					versionedsecretstore.LabelVersion:    renderedVersion,
+				},
+			},
+			StringData: data,
+		}
+	}
+
+	return []corev1.Secret{
+		secret(names.DeploymentSecretTypeInstanceGroupResolvedProperties, map[string]string{
+			"properties.yaml": string(propertiesBytes),
+		}),
+		secret(names.DeploymentSecretBpmInformation, map[string]string{
+			"bpm.yaml":        string(bpmBytes),
+			"validation.yaml": string(findingsBytes),
+			"specs.yaml":      string(specsBytes),
+		}),
+	}, nil
+}
+
+// setTypeMeta sets the kind and API version of all resources, so they can be applied
+func (r *RenderedResources) setTypeMeta() {
+	for i := range r.ExtendedSecrets {
+		r.ExtendedSecrets[i].TypeMeta = metav1.TypeMeta{Kind: "ExtendedSecret", APIVersion: esv1.SchemeGroupVersion.String()}
+	}
+	for i := range r.ExtendedJobs {
+		r.ExtendedJobs[i].TypeMeta = metav1.TypeMeta{Kind: "ExtendedJob", APIVersion: ejv1.SchemeGroupVersion.String()}
+	}
+	for i := range r.ExtendedStatefulSets {
+		r.ExtendedStatefulSets[i].TypeMeta = metav1.TypeMeta{Kind: "ExtendedStatefulSet", APIVersion: essv1.SchemeGroupVersion.String()}
+	}
+	for i := range r.Services {
+		r.Services[i].TypeMeta = metav1.TypeMeta{Kind: "Service", APIVersion: "v1"}
+	}
+	for i := range r.PersistentVolumeClaims {
+		r.PersistentVolumeClaims[i].TypeMeta = metav1.TypeMeta{Kind: "PersistentVolumeClaim", APIVersion: "v1"}
+	}
+	for i := range r.Secrets {
+		r.Secrets[i].TypeMeta = metav1.TypeMeta{Kind: "Secret", APIVersion: "v1"}
+	}
+}
+
+// Objects returns all resources, sorted by kind and name
+func (r *RenderedResources) Objects() []runtime.Object {
+	objects := []runtime.Object{}
+	for i := range r.ExtendedSecrets {
+		objects = append(objects, &r.ExtendedSecrets[i])
+	}
+	for i := range r.ExtendedJobs {
+		objects = append(objects, &r.ExtendedJobs[i])
+	}
+	for i := range r.ExtendedStatefulSets {
+		objects = append(objects, &r.ExtendedStatefulSets[i])
+	}
+	for i := range r.Services {
+		objects = append(objects, &r.Services[i])
+	}
+	for i := range r.PersistentVolumeClaims {
+		objects = append(objects, &r.PersistentVolumeClaims[i])
+	}
+	for i := range r.Secrets {
+		objects = append(objects, &r.Secrets[i])
+	}
+
+	key := func(object runtime.Object) string {
+		accessor, _ := meta.Accessor(object)
+		return object.GetObjectKind().GroupVersionKind().Kind + "/" + accessor.GetName()
+	}
+	sort.SliceStable(objects, func(i, j int) bool {
+		return key(objects[i]) < key(objects[j])
+	})
+	return objects
+}
+
+// Write writes each resource as YAML to '<dir>/<kind>/<name>.yaml'. The output
+// only depends on the resources, so it can be compared between revisions of a
+// manifest.
+func (r *RenderedResources) Write(dir string) error {
+	for _, object := range r.Objects() {
+		accessor, err := meta.Accessor(object)
+		if err != nil {
+			return err
+		}
+		kind := strings.ToLower(object.GetObjectKind().GroupVersionKind().Kind)
+
+		objectBytes, err := yaml.Marshal(object)
+		if err != nil {
+			return errors.Wrapf(err, "marshalling %s '%s'", kind, accessor.GetName())
+		}
+
+		kindDir := filepath.Join(dir, kind)
+		if err := os.MkdirAll(kindDir, 0755); err != nil {
+			return errors.Wrapf(err, "creating directory '%s'", kindDir)
+		}
+		path := filepath.Join(kindDir, accessor.GetName()+".yaml")
+		if err := ioutil.WriteFile(path, objectBytes, 0644); err != nil {
+			return errors.Wrapf(err, "writing '%s'", path)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package manifest_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+
+	"code.cloudfoundry.org/cf-operator/pkg/bosh/bpm"
+	"code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
+	"code.cloudfoundry.org/cf-operator/testing"
+	"code.cloudfoundry.org/cf-operator/testing/boshreleases"
+)
+
+var _ = Describe("RenderAll", func() {
+	var (
+		m        manifest.Manifest
+		env      testing.Catalog
+		gathered map[string]manifest.GatheredData
+	)
+
+	BeforeEach(func() {
+		m = env.DefaultBOSHManifest()
+
+		c, err := bpm.NewConfig([]byte(boshreleases.DefaultBPMConfig))
+		Expect(err).ShouldNot(HaveOccurred())
+		gathered = map[string]manifest.GatheredData{
+			"redis-slave": {
+				BPMConfigs: bpm.Configs{"redis-server": c},
+			},
+			"diego-cell": {
+				BPMConfigs: bpm.Configs{"cflinuxfs3-rootfs-setup": c},
+				PropertyFindings: manifest.PropertyFindings{
+					{InstanceGroup: "diego-cell", Job: "cflinuxfs3-rootfs-setup", Property: "foo", Severity: manifest.FindingWarning, Message: "is not defined in the spec of the job"},
+				},
+				ValidatedJobSpecs: manifest.ValidatedJobSpecs{
+					{Release: "cflinuxfs3", Version: "0.62.0", Spec: manifest.JobSpec{Name: "cflinuxfs3-rootfs-setup"}},
+				},
+			},
+		}
+	})
+
+	act := func() (*manifest.RenderedResources, error) {
+		return manifest.NewKubeConverter("default").RenderAll(&m, gathered, "cluster.local")
+	}
+
+	objectNames := func(resources *manifest.RenderedResources) []string {
+		result := []string{}
+		for _, object := range resources.Objects() {
+			result = append(result, object.GetObjectKind().GroupVersionKind().Kind+"/"+object.(interface{ GetName() string }).GetName())
+		}
+		return result
+	}
+
+	It("renders all resources the operator creates for the deployment", func() {
+		resources, err := act()
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(objectNames(resources)).To(Equal([]string{
+			"ExtendedJob/bpm-foo-deployment",
+			"ExtendedJob/dg-foo-deployment",
+			"ExtendedJob/dm-foo-deployment",
+			"ExtendedJob/foo-deployment-redis-slave",
+			"ExtendedSecret/foo-deployment.var-adminpass",
+			"ExtendedStatefulSet/foo-deployment-diego-cell",
+			"Secret/foo-deployment.bpm.diego-cell-v1",
+			"Secret/foo-deployment.bpm.redis-slave-v1",
+			"Secret/foo-deployment.ig-resolved.diego-cell-v1",
+			"Secret/foo-deployment.ig-resolved.redis-slave-v1",
+			"Secret/foo-deployment.with-ops",
+			"Service/foo-deployment-diego-cell",
+			"Service/foo-deployment-diego-cell-0",
+			"Service/foo-deployment-diego-cell-1",
+		}))
+		Expect(resources.ExtendedStatefulSets[0].APIVersion).To(Equal("fissile.cloudfoundry.org/v1alpha1"))
+	})
+
+	It("renders the gathered data as versioned secrets", func() {
+		resources, err := act()
+		Expect(err).ToNot(HaveOccurred())
+
+		var secret corev1.Secret
+		for _, s := range resources.Secrets {
+			if s.Name == "foo-deployment.bpm.diego-cell-v1" {
+				secret = s
+			}
+		}
+		Expect(secret.Labels).To(HaveKeyWithValue("fissile.cloudfoundry.org/instance-group", "diego-cell"))
+		Expect(secret.StringData["bpm.yaml"]).To(ContainSubstring("cflinuxfs3-rootfs-setup:"))
+		Expect(secret.StringData["validation.yaml"]).To(ContainSubstring("property: foo"))
+		Expect(secret.StringData["specs.yaml"]).To(ContainSubstring("release: cflinuxfs3"))
+	})
+
+	It("fails when the data of an instance group hasn't been gathered", func() {
+		delete(gathered, "diego-cell")
+
+		_, err := act()
+		Expect(err).To(MatchError("missing gathered data for instance group 'diego-cell'"))
+	})
+
+	Context("when writing the resources", func() {
+		var dir string
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "render-all")
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(dir)
+		})
+
+		It("writes each resource to a file by kind and name", func() {
+			resources, err := act()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resources.Write(dir)).To(Succeed())
+
+			eStsBytes, err := ioutil.ReadFile(filepath.Join(dir, "extendedstatefulset", "foo-deployment-diego-cell.yaml"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(eStsBytes)).To(ContainSubstring("kind: ExtendedStatefulSet"))
+			Expect(string(eStsBytes)).To(ContainSubstring("name: foo-deployment-diego-cell"))
+
+			files, err := filepath.Glob(filepath.Join(dir, "*", "*.yaml"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(files).To(HaveLen(len(resources.Objects())))
+		})
+
+		It("writes the same output for the same manifest", func() {
+			resources, err := act()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resources.Write(dir)).To(Succeed())
+			firstBytes, err := ioutil.ReadFile(filepath.Join(dir, "extendedjob", "foo-deployment-redis-slave.yaml"))
+			Expect(err).ToNot(HaveOccurred())
+
+			m = env.DefaultBOSHManifest()
+			resources, err = act()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resources.Write(dir)).To(Succeed())
+			secondBytes, err := ioutil.ReadFile(filepath.Join(dir, "extendedjob", "foo-deployment-redis-slave.yaml"))
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(secondBytes).To(Equal(firstBytes))
+		})
+	})
+})
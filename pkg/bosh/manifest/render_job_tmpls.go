@@ -8,17 +8,24 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"reflect"
+	"sort"
+	"time"
 
 	"github.com/pkg/errors"
 	btg "github.com/viovanov/bosh-template-go"
+	"go.uber.org/zap"
 
 	bc "code.cloudfoundry.org/cf-operator/pkg/bosh/manifest/containerization"
 )
 
 // RenderJobTemplates will render templates for all jobs of the instance group
 // https://bosh.io/docs/create-release/#job-specs
-// boshManifest is a resolved manifest for a single instance group
+// boshManifest is a resolved manifest for a single instance group.
+// Without DNS addresses, the templates are rendered with the consumed link
+// instances, whose addresses resolve so far, see ResolveLinkAddresses.
 func RenderJobTemplates(
+	log *zap.SugaredLogger,
 	boshManifestPath string,
 	jobsDir string,
 	jobsOutputDir string,
@@ -26,21 +33,155 @@ func RenderJobTemplates(
 	specIndex int,
 	podIP net.IP,
 ) error {
+	boshManifest, err := loadResolvedManifest(boshManifestPath, podIP)
+	if err != nil {
+		return err
+	}
+
+	if _, err := boshManifest.ResolveLinkAddresses(log, instanceGroupName, specIndex, podIP); err != nil {
+		return err
+	}
+
+	return renderJobTemplates(boshManifest, jobsDir, jobsOutputDir, instanceGroupName, specIndex, podIP, true)
+}
+
+// WatchJobTemplates renders the templates for all jobs of the instance group
+// again, whenever the resolved addresses of the consumed link instances change.
+// Without DNS addresses, these are the IPs of pods, which might start after
+// this one or get recreated. The pre-render scripts already ran, when the
+// templates were rendered first, so they are not run again.
+// It checks the addresses every interval and only returns on errors.
+func WatchJobTemplates(
+	log *zap.SugaredLogger,
+	boshManifestPath string,
+	jobsDir string,
+	jobsOutputDir string,
+	instanceGroupName string,
+	specIndex int,
+	podIP net.IP,
+	interval time.Duration,
+) error {
+	// The addresses might have changed since the templates were rendered first,
+	// so the first check always renders them
+	var rendered []string
+	first := true
+	for {
+		boshManifest, err := loadResolvedManifest(boshManifestPath, podIP)
+		if err != nil {
+			return err
+		}
+
+		addresses, err := boshManifest.ResolveLinkAddresses(log, instanceGroupName, specIndex, podIP)
+		if err != nil {
+			return err
+		}
+
+		if first || !reflect.DeepEqual(addresses, rendered) {
+			log.Infof("Rendering job templates with link addresses %v", addresses)
+			err := renderJobTemplates(boshManifest, jobsDir, jobsOutputDir, instanceGroupName, specIndex, podIP, false)
+			if err != nil {
+				return err
+			}
+			rendered = addresses
+			first = false
+		}
+
+		time.Sleep(interval)
+	}
+}
+
+// ResolveLinkAddresses replaces the DNS addresses of the link instances, which
+// the jobs of the instance group consume, with their IPs, unless the manifest
+// uses DNS addresses. The per-instance services are headless in this case, so
+// their names resolve to the pod IPs. All jobs of an instance share its
+// service, so the current instance's own address is replaced by the pod IP.
+// Pods of other instances might not have an IP yet, e.g. if they start after
+// this one. Instances, whose address doesn't resolve, are left out of the
+// links instead of waiting for them, so instance groups, which consume their
+// own links, can start. It returns the resolved addresses of the link
+// instances, sorted by link and instance ID.
+func (m *Manifest) ResolveLinkAddresses(log *zap.SugaredLogger, instanceGroupName string, specIndex int, podIP net.IP) ([]string, error) {
+	addresses := []string{}
+	if m.UsesDNSAddresses() {
+		return addresses, nil
+	}
 
+	instanceGroup, err := m.InstanceGroupByName(instanceGroupName)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, job := range instanceGroup.Jobs {
+		currentJobInstance, err := job.instance(specIndex)
+		if err != nil {
+			return nil, err
+		}
+
+		consumes := job.Properties.BOSHContainerization.Consumes
+		for name, link := range consumes {
+			instances := []bc.JobInstance{}
+			for _, instance := range link.Instances {
+				if instance.Address == currentJobInstance.Address {
+					instance.Address = podIP.String()
+				} else {
+					ips, err := net.LookupIP(instance.Address)
+					if err != nil {
+						log.Infof("Leaving out instance '%s' of link '%s' of job '%s', its address '%s' doesn't resolve yet: %v", instance.ID, name, job.Name, instance.Address, err)
+						continue
+					}
+					instance.Address = ips[0].String()
+				}
+				instances = append(instances, instance)
+				addresses = append(addresses, fmt.Sprintf("%s/%s=%s", name, instance.ID, instance.Address))
+			}
+			link.Instances = instances
+			consumes[name] = link
+		}
+
+		currentJobInstance.Address = podIP.String()
+	}
+
+	sort.Strings(addresses)
+	return addresses, nil
+}
+
+// instance returns the instance of the job with the spec index
+func (job *Job) instance(specIndex int) (*bc.JobInstance, error) {
+	instances := job.Properties.BOSHContainerization.Instances
+	for i := range instances {
+		if instances[i].Index == specIndex {
+			return &instances[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no instance found for spec index '%d'", specIndex)
+}
+
+func loadResolvedManifest(boshManifestPath string, podIP net.IP) (*Manifest, error) {
 	if podIP == nil {
-		return fmt.Errorf("the pod IP is empty")
+		return nil, fmt.Errorf("the pod IP is empty")
 	}
 
 	// Loading deployment manifest file
 	resolvedYML, err := ioutil.ReadFile(boshManifestPath)
 	if err != nil {
-		return errors.Wrapf(err, "couldn't read manifest file %s", boshManifestPath)
+		return nil, errors.Wrapf(err, "couldn't read manifest file %s", boshManifestPath)
 	}
 	boshManifest, err := LoadYAML(resolvedYML)
 	if err != nil {
-		return errors.Wrapf(err, "failed to load BOSH deployment manifest %s", boshManifestPath)
+		return nil, errors.Wrapf(err, "failed to load BOSH deployment manifest %s", boshManifestPath)
 	}
+	return boshManifest, nil
+}
 
+func renderJobTemplates(
+	boshManifest *Manifest,
+	jobsDir string,
+	jobsOutputDir string,
+	instanceGroupName string,
+	specIndex int,
+	podIP net.IP,
+	preRender bool,
+) error {
 	// Loop over instancegroups
 	for _, instanceGroup := range boshManifest.InstanceGroups {
 
@@ -58,22 +199,18 @@ func RenderJobTemplates(
 			}
 
 			// Run pre-render scripts for the current job.
-			for idx, script := range job.Properties.BOSHContainerization.PreRenderScripts {
-				if err := runPreRenderScript(script, idx, false); err != nil {
-					return errors.Wrapf(err, "failed to run pre-render script %d for job %s", idx, job.Name)
+			if preRender {
+				for idx, script := range job.Properties.BOSHContainerization.PreRenderScripts {
+					if err := runPreRenderScript(script, idx, false); err != nil {
+						return errors.Wrapf(err, "failed to run pre-render script %d for job %s", idx, job.Name)
+					}
 				}
 			}
 
 			// Find job instance that's being rendered
-			var currentJobInstance *bc.JobInstance
-			for _, instance := range job.Properties.BOSHContainerization.Instances {
-				if instance.Index == specIndex {
-					currentJobInstance = &instance
-					break
-				}
-			}
-			if currentJobInstance == nil {
-				return fmt.Errorf("no instance found for spec index '%d'", specIndex)
+			currentJobInstance, err := job.instance(specIndex)
+			if err != nil {
+				return err
 			}
 
 			// Loop over templates for rendering files
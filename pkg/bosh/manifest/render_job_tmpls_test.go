@@ -7,8 +7,11 @@ import (
 	"path/filepath"
 
 	"code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
+	bc "code.cloudfoundry.org/cf-operator/pkg/bosh/manifest/containerization"
+	helper "code.cloudfoundry.org/cf-operator/pkg/testhelper"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
 	yaml "gopkg.in/yaml.v2"
 )
 
@@ -19,8 +22,13 @@ var _ = Describe("Trender", func() {
 		instanceGroupName  string
 		index              int
 		podIP              net.IP
+		log                *zap.SugaredLogger
 	)
 
+	BeforeEach(func() {
+		_, log = helper.NewTestLogger()
+	})
+
 	Context("when podIP is nil", func() {
 		BeforeEach(func() {
 			deploymentManifest = "../../../testing/assets/ig-resolved.mysql-v1.yml"
@@ -31,7 +39,7 @@ var _ = Describe("Trender", func() {
 		})
 
 		act := func() error {
-			return manifest.RenderJobTemplates(deploymentManifest, jobsDir, jobsDir, instanceGroupName, index, podIP)
+			return manifest.RenderJobTemplates(log, deploymentManifest, jobsDir, jobsDir, instanceGroupName, index, podIP)
 		}
 
 		It("fails", func() {
@@ -50,7 +58,7 @@ var _ = Describe("Trender", func() {
 		})
 
 		act := func() error {
-			return manifest.RenderJobTemplates(deploymentManifest, jobsDir, jobsDir, instanceGroupName, index, podIP)
+			return manifest.RenderJobTemplates(log, deploymentManifest, jobsDir, jobsDir, instanceGroupName, index, podIP)
 		}
 
 		Context("with an invalid instance index", func() {
@@ -99,6 +107,93 @@ var _ = Describe("Trender", func() {
 		})
 	})
 
+	Context("when the deployment doesn't use DNS addresses", func() {
+		var m *manifest.Manifest
+
+		BeforeEach(func() {
+			instanceGroupName = "log-api"
+			index = 0
+			podIP = net.ParseIP("172.17.0.13")
+
+			manifestBytes, err := ioutil.ReadFile("../../../testing/assets/gatherManifest.yml")
+			Expect(err).ToNot(HaveOccurred())
+			m, err = manifest.LoadYAML(manifestBytes)
+			Expect(err).ToNot(HaveOccurred())
+
+			useDNSAddresses := false
+			m.Features = &manifest.Feature{UseDNSAddresses: &useDNSAddresses}
+		})
+
+		act := func() ([]string, *manifest.Job) {
+			addresses, err := m.ResolveLinkAddresses(log, instanceGroupName, index, podIP)
+			Expect(err).ToNot(HaveOccurred())
+			ig, err := m.InstanceGroupByName(instanceGroupName)
+			Expect(err).ToNot(HaveOccurred())
+			return addresses, &ig.Jobs[0]
+		}
+
+		It("leaves out the link instances, whose addresses don't resolve yet", func() {
+			ig, err := m.InstanceGroupByName(instanceGroupName)
+			Expect(err).ToNot(HaveOccurred())
+			for _, link := range ig.Jobs[0].Properties.BOSHContainerization.Consumes {
+				Expect(link.Instances).ToNot(BeEmpty())
+				for i := range link.Instances {
+					link.Instances[i].Address = "unresolvable.invalid"
+				}
+			}
+
+			addresses, job := act()
+			Expect(addresses).To(BeEmpty())
+			for _, link := range job.Properties.BOSHContainerization.Consumes {
+				Expect(link.Instances).To(BeEmpty())
+			}
+			Expect(job.Properties.BOSHContainerization.Instances[0].Address).To(Equal("172.17.0.13"))
+		})
+
+		Context("when the instance group consumes its own link", func() {
+			BeforeEach(func() {
+				ig, err := m.InstanceGroupByName(instanceGroupName)
+				Expect(err).ToNot(HaveOccurred())
+				containerization := &ig.Jobs[0].Properties.BOSHContainerization
+				own := containerization.Instances[0]
+				containerization.Consumes = map[string]bc.JobLink{
+					"peers": {
+						Instances: []bc.JobInstance{
+							own,
+							{ID: "log-api-1-loggregator_trafficcontroller", Address: "10.0.0.2"},
+							{ID: "log-api-2-loggregator_trafficcontroller", Address: "log-api-2.unresolvable.invalid"},
+						},
+						Properties: map[string]interface{}{"port": 8081},
+					},
+				}
+			})
+
+			It("renders with the pod IP and the addresses of the instances, which exist so far", func() {
+				addresses, job := act()
+				Expect(addresses).To(Equal([]string{
+					"peers/log-api-0-loggregator_trafficcontroller=172.17.0.13",
+					"peers/log-api-1-loggregator_trafficcontroller=10.0.0.2",
+				}))
+
+				peers := job.Properties.BOSHContainerization.Consumes["peers"]
+				Expect(peers.Instances).To(HaveLen(2))
+				Expect(peers.Instances[0].Address).To(Equal("172.17.0.13"))
+				Expect(peers.Instances[1].Address).To(Equal("10.0.0.2"))
+				Expect(peers.Properties).To(Equal(map[string]interface{}{"port": 8081}))
+				Expect(job.Properties.BOSHContainerization.Instances[0].Address).To(Equal("172.17.0.13"))
+			})
+
+			It("keeps the DNS addresses if the deployment uses them", func() {
+				useDNSAddresses := true
+				m.Features.UseDNSAddresses = &useDNSAddresses
+
+				addresses, job := act()
+				Expect(addresses).To(BeEmpty())
+				Expect(job.Properties.BOSHContainerization.Consumes["peers"].Instances).To(HaveLen(3))
+			})
+		})
+	})
+
 	Context("with an empty instances array in consumes", func() {
 		BeforeEach(func() {
 			deploymentManifest = "../../../testing/assets/ig-resolved.mysql-v1.yml"
@@ -114,7 +209,7 @@ var _ = Describe("Trender", func() {
 		})
 
 		It("renders the job erb files correctly", func() {
-			err := manifest.RenderJobTemplates(deploymentManifest, jobsDir, jobsDir, instanceGroupName, index, podIP)
+			err := manifest.RenderJobTemplates(log, deploymentManifest, jobsDir, jobsDir, instanceGroupName, index, podIP)
 			Expect(err).ToNot(HaveOccurred())
 
 			drainFile := filepath.Join(jobsDir, "pxc-mysql", "bin/drain")
@@ -3,11 +3,15 @@ package manifest
 import (
 	"context"
 	"fmt"
-	"io/ioutil"
-	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 
+	boshtpl "github.com/cloudfoundry/bosh-cli/director/template"
+	"github.com/cppforlife/go-patch/patch"
 	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -17,11 +21,19 @@ import (
 	"code.cloudfoundry.org/cf-operator/pkg/kube/util/versionedsecretstore"
 )
 
+// sharedGitCache is used by all resolvers, so repositories are fetched only once
+var sharedGitCache = newGitCache(filepath.Join(os.TempDir(), "cf-operator", "git"))
+
+// sharedURLCache is used by all resolvers, so unchanged files are downloaded only once
+var sharedURLCache = newURLCache()
+
 // Resolver resolves references from bdpl CRD to a BOSH manifest
 type Resolver struct {
 	client               client.Client
 	versionedSecretStore versionedsecretstore.VersionedSecretStore
 	newInterpolatorFunc  func() Interpolator
+	gitCache             *gitCache
+	urlCache             *urlCache
 }
 
 // NewInterpolatorFunc returns a fresh Interpolator
@@ -33,6 +45,8 @@ func NewResolver(client client.Client, f NewInterpolatorFunc) *Resolver {
 		client:               client,
 		newInterpolatorFunc:  f,
 		versionedSecretStore: versionedsecretstore.NewVersionedSecretStore(client),
+		gitCache:             sharedGitCache,
+		urlCache:             sharedURLCache,
 	}
 }
 
@@ -59,38 +73,41 @@ func (r *Resolver) DesiredManifest(ctx context.Context, boshDeploymentName, name
 
 // WithOpsManifest returns manifest referenced by our bdpl CRD
 // The resulting manifest has variables interpolated and ops files applied.
-// It is the 'with-ops' manifest.
-func (r *Resolver) WithOpsManifest(instance *bdc.BOSHDeployment, namespace string) (*Manifest, error) {
+// It is the 'with-ops' manifest. The commits of git refs are returned as well.
+func (r *Resolver) WithOpsManifest(instance *bdc.BOSHDeployment, namespace string) (*Manifest, []bdc.ResolvedCommit, error) {
 	interpolator := r.newInterpolatorFunc()
 	spec := instance.Spec
 	manifest := &Manifest{}
-	var (
-		m   string
-		err error
-	)
-
-	m, err = r.resourceData(namespace, spec.Manifest.Type, spec.Manifest.Ref, bdc.ManifestSpecName)
+	var commits []bdc.ResolvedCommit
+	m, commit, err := r.refData(namespace, spec.Manifest, bdc.ManifestSpecName)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	if commit != nil {
+		commits = append(commits, *commit)
 	}
 
 	// Get the deployment name from the manifest
 	manifest, err = LoadYAML([]byte(m))
 	if err != nil {
-		return nil, errors.Wrapf(err, "failed to unmarshal manifest")
+		return nil, nil, errors.Wrapf(err, "failed to unmarshal manifest")
 	}
 
 	// Interpolate manifest with ops
 	ops := spec.Ops
 
 	for _, op := range ops {
-		opsData, err := r.resourceData(namespace, op.Type, op.Ref, bdc.OpsSpecName)
+		// Ops and manifest refs share the same fields
+		opsData, commit, err := r.refData(namespace, bdc.Manifest(op), bdc.OpsSpecName)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
+		}
+		if commit != nil {
+			commits = append(commits, *commit)
 		}
 		err = interpolator.BuildOps([]byte(opsData))
 		if err != nil {
-			return nil, errors.Wrapf(err, "failed to build ops with: %#v", opsData)
+			return nil, nil, errors.Wrapf(err, "failed to build ops with: %#v", opsData)
 		}
 	}
 
@@ -98,27 +115,96 @@ func (r *Resolver) WithOpsManifest(instance *bdc.BOSHDeployment, namespace strin
 	if len(ops) != 0 {
 		bytes, err = interpolator.Interpolate([]byte(m))
 		if err != nil {
-			return nil, errors.Wrapf(err, "failed to interpolate %#v", m)
+			return nil, nil, errors.Wrapf(err, "failed to interpolate %#v", m)
+		}
+	}
+
+	// User-supplied variables are interpolated right away, so they take
+	// precedence over the variables generated by the operator
+	userVars, err := r.userVars(namespace, spec.Vars)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(userVars) != 0 {
+		bytes, err = boshtpl.NewTemplate(bytes).Evaluate(userVars, patch.Ops{}, boshtpl.EvaluateOpts{})
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "failed to interpolate user-supplied variables")
 		}
 	}
 
 	// Reload the manifest after interpolation, and apply implicit variables
 	manifest, err = LoadYAML(bytes)
 	if err != nil {
-		return nil, errors.Wrapf(err, "failed to load yaml after applying ops %#v", m)
+		return nil, nil, errors.Wrapf(err, "failed to load yaml after applying ops %#v", m)
+	}
+
+	// Don't generate variables, which have been supplied by the user
+	if len(userVars) != 0 {
+		variables := []Variable{}
+		for _, v := range manifest.Variables {
+			if _, ok := userVars[v.Name]; !ok {
+				variables = append(variables, v)
+			}
+		}
+		manifest.Variables = variables
+	}
+
+	// Merge the addons of the runtime configs, which include this deployment
+	runtimeConfigs, err := r.runtimeConfigs()
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, rc := range runtimeConfigs {
+		runtimeConfig, err := LoadRuntimeConfigYAML([]byte(rc.Spec.Config))
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "failed to load runtime config '%s'", rc.Name)
+		}
+		err = manifest.ApplyRuntimeConfig(runtimeConfig)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "failed to apply runtime config '%s'", rc.Name)
+		}
+	}
+
+	// Add the vm types, vm extensions and disk types of the namespace's cloud configs
+	cloudConfigs, err := r.cloudConfigs(namespace)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, cc := range cloudConfigs {
+		cloudConfig, err := LoadCloudConfigYAML([]byte(cc.Spec.Config))
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "failed to load cloud config '%s'", cc.Name)
+		}
+		err = manifest.ApplyCloudConfig(cloudConfig)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "failed to apply cloud config '%s'", cc.Name)
+		}
+	}
+
+	// Override the images of releases and add the pull secrets
+	err = manifest.ApplyImages(spec.Images, spec.ImagePullSecrets)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to apply images")
+	}
+
+	if len(userVars) != 0 || len(runtimeConfigs) != 0 || len(cloudConfigs) != 0 || len(spec.Images) != 0 || len(spec.ImagePullSecrets) != 0 {
+		bytes, err = manifest.Marshal()
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "failed to marshal manifest after interpolating user-supplied variables and applying runtime configs, cloud configs and images")
+		}
 	}
 	m = string(bytes)
 
 	// Interpolate implicit variables
 	vars, err := manifest.ImplicitVariables()
 	if err != nil {
-		return nil, errors.Wrapf(err, "failed to list implicit variables")
+		return nil, nil, errors.Wrapf(err, "failed to list implicit variables")
 	}
 
 	for _, v := range vars {
 		varData, err := r.resourceData(namespace, bdc.SecretType, names.CalculateSecretName(names.DeploymentSecretTypeVariable, instance.GetName(), v), bdc.ImplicitVariableKeyName)
 		if err != nil {
-			return nil, errors.Wrapf(err, "failed to load secret for variable '%s'", v)
+			return nil, nil, errors.Wrapf(err, "failed to load secret for variable '%s'", v)
 		}
 
 		m = strings.Replace(m, fmt.Sprintf("((%s))", v), varData, -1)
@@ -126,16 +212,140 @@ func (r *Resolver) WithOpsManifest(instance *bdc.BOSHDeployment, namespace strin
 
 	manifest, err = LoadYAML([]byte(m))
 	if err != nil {
-		return nil, errors.Wrapf(err, "failed to load yaml after interpolating implicit variables %#v", m)
+		return nil, nil, errors.Wrapf(err, "failed to load yaml after interpolating implicit variables %#v", m)
 	}
 
 	// Apply addons
 	err = manifest.ApplyAddons()
 	if err != nil {
-		return nil, errors.Wrapf(err, "failed to apply addons")
+		return nil, nil, errors.Wrapf(err, "failed to apply addons")
 	}
 
-	return manifest, err
+	manifest.ApplyUpdateBlock()
+
+	return manifest, commits, err
+}
+
+// userVars reads the user-supplied variables. Later variables override earlier ones.
+func (r *Resolver) userVars(namespace string, vars []bdc.Var) (boshtpl.StaticVariables, error) {
+	userVars := boshtpl.StaticVariables{}
+
+	for _, v := range vars {
+		switch {
+		case v.Name != "" && v.Ref == "":
+			userVars[v.Name] = v.Value
+		case v.Name != "":
+			key := v.Key
+			if key == "" {
+				key = bdc.ImplicitVariableKeyName
+			}
+			data, err := r.resourceData(namespace, v.Type, v.Ref, key)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to load variable '%s'", v.Name)
+			}
+			userVars[v.Name] = data
+		case v.Ref != "":
+			key := v.Key
+			if key == "" {
+				key = bdc.VarsSpecName
+			}
+			data, err := r.resourceData(namespace, v.Type, v.Ref, key)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to load variables from '%s'", v.Ref)
+			}
+			fileVars := boshtpl.StaticVariables{}
+			if err := yaml.Unmarshal([]byte(data), &fileVars); err != nil {
+				return nil, errors.Wrapf(err, "failed to unmarshal variables from '%s'", v.Ref)
+			}
+			for name, value := range fileVars {
+				userVars[name] = value
+			}
+		default:
+			return nil, fmt.Errorf("variable needs a name or a ref")
+		}
+	}
+
+	return userVars, nil
+}
+
+// runtimeConfigs returns all runtime configs of the cluster, sorted by name
+func (r *Resolver) runtimeConfigs() ([]bdc.RuntimeConfig, error) {
+	list := &bdc.RuntimeConfigList{}
+	err := r.client.List(context.TODO(), &client.ListOptions{}, list)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list runtime configs via client.List")
+	}
+
+	sort.Slice(list.Items, func(i, j int) bool {
+		return list.Items[i].Name < list.Items[j].Name
+	})
+	return list.Items, nil
+}
+
+// cloudConfigs returns the cloud configs of the namespace, sorted by name
+func (r *Resolver) cloudConfigs(namespace string) ([]bdc.CloudConfig, error) {
+	list := &bdc.CloudConfigList{}
+	err := r.client.List(context.TODO(), &client.ListOptions{Namespace: namespace}, list)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list cloud configs via client.List")
+	}
+
+	sort.Slice(list.Items, func(i, j int) bool {
+		return list.Items[i].Name < list.Items[j].Name
+	})
+	return list.Items, nil
+}
+
+// refData resolves a manifest or ops ref and returns its data. For git refs the
+// resolved commit is returned as well.
+func (r *Resolver) refData(namespace string, ref bdc.Manifest, key string) (string, *bdc.ResolvedCommit, error) {
+	switch ref.Type {
+	case bdc.GitType:
+		return r.gitData(ref.Ref, ref.Revision, ref.Path, key)
+	case bdc.URLType:
+		data, err := r.urlData(namespace, ref.Ref, ref.SHA256, ref.SecretRef, key)
+		return data, nil, err
+	default:
+		data, err := r.resourceData(namespace, ref.Type, ref.Ref, key)
+		return data, nil, err
+	}
+}
+
+// urlData downloads a manifest or ops file. The optional secret provides a CA
+// certificate and HTTP headers, e.g. for authorization.
+func (r *Resolver) urlData(namespace string, url string, sha256 string, secretRef string, key string) (string, error) {
+	var secretData map[string][]byte
+	if secretRef != "" {
+		secret := &corev1.Secret{}
+		err := r.client.Get(context.TODO(), types.NamespacedName{Name: secretRef, Namespace: namespace}, secret)
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to retrieve secret '%s/%s' for %s url '%s' via client.Get", namespace, secretRef, key, url)
+		}
+		secretData = secret.Data
+	}
+
+	data, err := r.urlCache.Get(url, sha256, secretData)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to resolve %s from url '%s'", key, url)
+	}
+
+	return data, nil
+}
+
+// gitData reads a manifest or ops file from a git repository and returns it with the commit it was read from
+func (r *Resolver) gitData(repo string, revision string, path string, key string) (string, *bdc.ResolvedCommit, error) {
+	data, commit, err := r.gitCache.Read(repo, revision, path)
+	if err != nil {
+		return "", nil, errors.Wrapf(err, "failed to resolve %s from git repository '%s'", key, repo)
+	}
+
+	return data, &bdc.ResolvedCommit{
+		Spec:     key,
+		Repo:     repo,
+		Revision: revision,
+		Path:     path,
+		Commit:   commit,
+	}, nil
 }
 
 // resourceData resolves different manifest reference types and returns the resource's data
@@ -168,15 +378,7 @@ func (r *Resolver) resourceData(namespace string, resType string, name string, k
 		}
 		data = string(encodedData)
 	case bdc.URLType:
-		httpResponse, err := http.Get(name)
-		if err != nil {
-			return data, errors.Wrapf(err, "failed to resolve %s from url '%s' via http.Get", key, name)
-		}
-		body, err := ioutil.ReadAll(httpResponse.Body)
-		if err != nil {
-			return data, errors.Wrapf(err, "failed to read %s response body '%s' via ioutil", key, name)
-		}
-		data = string(body)
+		return r.urlData(namespace, name, "", "", key)
 	default:
 		return data, fmt.Errorf("unrecognized %s ref type %s", key, name)
 	}
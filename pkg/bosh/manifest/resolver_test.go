@@ -1,7 +1,17 @@
 package manifest_test
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
 
 	bdm "code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
 	"code.cloudfoundry.org/cf-operator/pkg/bosh/manifest/fakes"
@@ -10,6 +20,8 @@ import (
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	fakeClient "sigs.k8s.io/controller-runtime/pkg/client/fake"
 
@@ -60,6 +72,7 @@ var _ = Describe("Resolver", func() {
   path: /instance_groups/name=component1?/instances
   value: 4`
 
+		Expect(bdc.AddToScheme(scheme.Scheme)).To(Succeed())
 		client = fakeClient.NewFakeClient(
 			&corev1.ConfigMap{
 				ObjectMeta: metav1.ObjectMeta{
@@ -233,7 +246,7 @@ instance_groups:
 				},
 			}
 
-			manifest, err := resolver.WithOpsManifest(deployment, "default")
+			manifest, _, err := resolver.WithOpsManifest(deployment, "default")
 
 			Expect(err).ToNot(HaveOccurred())
 			Expect(manifest).ToNot(Equal(nil))
@@ -263,7 +276,7 @@ instance_groups:
 				},
 			}
 
-			manifest, err := resolver.WithOpsManifest(deployment, "default")
+			manifest, _, err := resolver.WithOpsManifest(deployment, "default")
 
 			Expect(err).ToNot(HaveOccurred())
 			Expect(manifest).ToNot(Equal(nil))
@@ -289,7 +302,7 @@ instance_groups:
 				},
 			}
 
-			manifest, err := resolver.WithOpsManifest(deployment, "default")
+			manifest, _, err := resolver.WithOpsManifest(deployment, "default")
 
 			Expect(err).ToNot(HaveOccurred())
 			Expect(manifest).ToNot(Equal(nil))
@@ -333,7 +346,7 @@ instance_groups:
 				},
 			}
 
-			manifest, err := resolver.WithOpsManifest(deployment, "default")
+			manifest, _, err := resolver.WithOpsManifest(deployment, "default")
 
 			Expect(err).ToNot(HaveOccurred())
 			Expect(manifest).ToNot(Equal(nil))
@@ -387,7 +400,7 @@ instance_groups:
 				},
 			}
 
-			manifest, err := resolver.WithOpsManifest(deployment, "default")
+			manifest, _, err := resolver.WithOpsManifest(deployment, "default")
 
 			Expect(err).ToNot(HaveOccurred())
 			Expect(manifest).ToNot(Equal(nil))
@@ -414,7 +427,7 @@ instance_groups:
 					},
 				},
 			}
-			_, err := resolver.WithOpsManifest(deployment, "default")
+			_, _, err := resolver.WithOpsManifest(deployment, "default")
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("failed to retrieve manifest"))
 		})
@@ -428,7 +441,7 @@ instance_groups:
 					},
 				},
 			}
-			_, err := resolver.WithOpsManifest(deployment, "default")
+			_, _, err := resolver.WithOpsManifest(deployment, "default")
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("doesn't contain key manifest"))
 		})
@@ -442,7 +455,7 @@ instance_groups:
 					},
 				},
 			}
-			_, err := resolver.WithOpsManifest(deployment, "default")
+			_, _, err := resolver.WithOpsManifest(deployment, "default")
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("yaml: unmarshal errors"))
 		})
@@ -457,7 +470,7 @@ instance_groups:
 					},
 				},
 			}
-			_, err := resolver.WithOpsManifest(deployment, "default")
+			_, _, err := resolver.WithOpsManifest(deployment, "default")
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("unrecognized manifest ref type"))
 		})
@@ -477,7 +490,7 @@ instance_groups:
 					},
 				},
 			}
-			_, err := resolver.WithOpsManifest(deployment, "default")
+			_, _, err := resolver.WithOpsManifest(deployment, "default")
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("failed to retrieve ops from configmap"))
 		})
@@ -497,7 +510,7 @@ instance_groups:
 					},
 				},
 			}
-			_, err := resolver.WithOpsManifest(deployment, "default")
+			_, _, err := resolver.WithOpsManifest(deployment, "default")
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("doesn't contain key ops"))
 		})
@@ -519,7 +532,7 @@ instance_groups:
 					},
 				},
 			}
-			_, err := resolver.WithOpsManifest(deployment, "default")
+			_, _, err := resolver.WithOpsManifest(deployment, "default")
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("failed to build ops"))
 		})
@@ -540,7 +553,7 @@ instance_groups:
 					},
 				},
 			}
-			_, err := resolver.WithOpsManifest(deployment, "default")
+			_, _, err := resolver.WithOpsManifest(deployment, "default")
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("failed to interpolate"))
 		})
@@ -561,7 +574,7 @@ instance_groups:
 					},
 				},
 			}
-			_, err := resolver.WithOpsManifest(deployment, "default")
+			_, _, err := resolver.WithOpsManifest(deployment, "default")
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("unrecognized ops ref type"))
 		})
@@ -585,7 +598,7 @@ instance_groups:
 					},
 				},
 			}
-			_, err := resolver.WithOpsManifest(deployment, "default")
+			_, _, err := resolver.WithOpsManifest(deployment, "default")
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("failed to retrieve ops from configmap"))
 		})
@@ -609,7 +622,7 @@ instance_groups:
 					},
 				},
 			}
-			_, err := resolver.WithOpsManifest(deployment, "default")
+			_, _, err := resolver.WithOpsManifest(deployment, "default")
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("failed to retrieve ops from secret"))
 		})
@@ -637,7 +650,7 @@ instance_groups:
 					},
 				},
 			}
-			_, err := resolver.WithOpsManifest(deployment, "default")
+			_, _, err := resolver.WithOpsManifest(deployment, "default")
 
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("failed to retrieve ops from secret"))
@@ -656,10 +669,680 @@ instance_groups:
 					Ops: []bdc.Ops{},
 				},
 			}
-			m, err := resolver.WithOpsManifest(deployment, "default")
+			m, _, err := resolver.WithOpsManifest(deployment, "default")
 
 			Expect(err).ToNot(HaveOccurred())
 			Expect(m.Variables[1].Options.CommonName).To(Equal("example.com"))
 		})
 	})
+
+	Describe("user-supplied variables", func() {
+		var deployment *bdc.BOSHDeployment
+
+		BeforeEach(func() {
+			for _, object := range []runtime.Object{
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "system-domain", Namespace: "default"},
+					Data:       map[string][]byte{"domain": []byte("secret.example.com")},
+				},
+				&corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{Name: "vars-file", Namespace: "default"},
+					Data: map[string]string{bdc.VarsSpecName: `---
+system_domain: file.example.com
+foo-pass:
+  password: from-file
+`},
+				},
+			} {
+				Expect(client.Create(context.Background(), object)).To(Succeed())
+			}
+
+			deployment = &bdc.BOSHDeployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "foo-deployment",
+				},
+				Spec: bdc.BOSHDeploymentSpec{
+					Manifest: bdc.Manifest{
+						Type: bdc.ConfigMapType,
+						Ref:  "manifest-with-vars",
+					},
+				},
+			}
+		})
+
+		It("interpolates inline variables before implicit variables", func() {
+			deployment.Spec.Vars = []bdc.Var{{Name: "system_domain", Value: "inline.example.com"}}
+
+			m, _, err := resolver.WithOpsManifest(deployment, "default")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(m.Variables[1].Options.CommonName).To(Equal("inline.example.com"))
+		})
+
+		It("reads variables from a secret", func() {
+			deployment.Spec.Vars = []bdc.Var{{Name: "system_domain", Type: bdc.SecretType, Ref: "system-domain", Key: "domain"}}
+
+			m, _, err := resolver.WithOpsManifest(deployment, "default")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(m.Variables[1].Options.CommonName).To(Equal("secret.example.com"))
+		})
+
+		It("reads a file of variables, removes them from the generated variables and lets later entries take precedence", func() {
+			deployment.Spec.Vars = []bdc.Var{
+				{Type: bdc.ConfigMapType, Ref: "vars-file"},
+				{Name: "system_domain", Value: "inline.example.com"},
+			}
+
+			m, _, err := resolver.WithOpsManifest(deployment, "default")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(m.Variables).To(HaveLen(1))
+			Expect(m.Variables[0].Name).To(Equal("router_ca"))
+			Expect(m.Variables[0].Options.CommonName).To(Equal("inline.example.com"))
+			Expect(m.InstanceGroups[1].Properties["password"]).To(Equal("from-file"))
+		})
+
+		It("fails if a referenced variable can not be found", func() {
+			deployment.Spec.Vars = []bdc.Var{{Name: "system_domain", Type: bdc.SecretType, Ref: "missing"}}
+
+			_, _, err := resolver.WithOpsManifest(deployment, "default")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("failed to load variable 'system_domain'"))
+		})
+	})
+
+	Describe("runtime configs", func() {
+		var deployment *bdc.BOSHDeployment
+
+		createRuntimeConfig := func(name string, config string) {
+			runtimeConfig := &bdc.RuntimeConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: name},
+				Spec:       bdc.RuntimeConfigSpec{Config: config},
+			}
+			Expect(client.Create(context.Background(), runtimeConfig)).To(Succeed())
+		}
+
+		BeforeEach(func() {
+			configMap := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "named-manifest", Namespace: "default"},
+				Data: map[string]string{bdc.ManifestSpecName: `---
+name: foo-deployment
+releases:
+- name: redis
+  version: 36.15.0
+instance_groups:
+- name: redis
+  instances: 1
+  jobs:
+  - name: redis-server
+    release: redis
+`},
+			}
+			Expect(client.Create(context.Background(), configMap)).To(Succeed())
+
+			deployment = &bdc.BOSHDeployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "foo-deployment",
+				},
+				Spec: bdc.BOSHDeploymentSpec{
+					Manifest: bdc.Manifest{
+						Type: bdc.ConfigMapType,
+						Ref:  "named-manifest",
+					},
+				},
+			}
+		})
+
+		It("adds the addons and their releases to included deployments", func() {
+			createRuntimeConfig("syslog", `---
+releases:
+- name: syslog
+  version: 11.4.0
+- name: unused
+  version: 1.0.0
+addons:
+- name: syslog
+  include:
+    deployments: [foo-deployment]
+  jobs:
+  - name: syslog_forwarder
+    release: syslog
+    properties:
+      syslog:
+        address: logs.example.com
+`)
+
+			m, _, err := resolver.WithOpsManifest(deployment, "default")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(m.Releases).To(HaveLen(2))
+			Expect(m.Releases[1].Name).To(Equal("syslog"))
+			Expect(m.InstanceGroups[0].Jobs).To(HaveLen(2))
+			Expect(m.InstanceGroups[0].Jobs[1].Name).To(Equal("syslog_forwarder"))
+			Expect(m.InstanceGroups[0].Jobs[1].Properties.Properties).To(HaveKey("syslog"))
+		})
+
+		It("adds addons without include rules to all deployments, in the order of the runtime config names", func() {
+			createRuntimeConfig("b-hardening", `---
+releases:
+- name: os-conf
+  version: 20.0.0
+addons:
+- name: hardening
+  jobs:
+  - name: login_banner
+    release: os-conf
+`)
+			createRuntimeConfig("a-redis", `---
+releases:
+- name: redis
+  version: 36.15.0
+addons:
+- name: redis-exporter
+  jobs:
+  - name: redis-exporter
+    release: redis
+`)
+
+			m, _, err := resolver.WithOpsManifest(deployment, "default")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(m.Releases).To(HaveLen(2))
+			Expect(m.InstanceGroups[0].Jobs).To(HaveLen(3))
+			Expect(m.InstanceGroups[0].Jobs[1].Name).To(Equal("redis-exporter"))
+			Expect(m.InstanceGroups[0].Jobs[2].Name).To(Equal("login_banner"))
+		})
+
+		It("doesn't change deployments, which are not included", func() {
+			createRuntimeConfig("syslog", `---
+releases:
+- name: syslog
+  version: 11.4.0
+addons:
+- name: syslog
+  include:
+    deployments: [bar-deployment]
+  jobs:
+  - name: syslog_forwarder
+    release: syslog
+`)
+
+			m, _, err := resolver.WithOpsManifest(deployment, "default")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(m.Releases).To(HaveLen(1))
+			Expect(m.AddOns).To(BeEmpty())
+			Expect(m.InstanceGroups[0].Jobs).To(HaveLen(1))
+		})
+
+		It("fails if the release of an addon job is missing", func() {
+			createRuntimeConfig("syslog", `---
+addons:
+- name: syslog
+  jobs:
+  - name: syslog_forwarder
+    release: syslog
+`)
+
+			_, _, err := resolver.WithOpsManifest(deployment, "default")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("failed to apply runtime config 'syslog': release 'syslog' of addon 'syslog' is missing"))
+		})
+
+		It("fails if a release conflicts with the manifest", func() {
+			createRuntimeConfig("redis", `---
+releases:
+- name: redis
+  version: 40.0.0
+addons:
+- name: redis-exporter
+  jobs:
+  - name: redis-exporter
+    release: redis
+`)
+
+			_, _, err := resolver.WithOpsManifest(deployment, "default")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("release 'redis' has version '36.15.0' in the manifest and version '40.0.0' in the runtime config"))
+		})
+	})
+
+	Describe("cloud configs", func() {
+		var deployment *bdc.BOSHDeployment
+
+		createCloudConfig := func(name string, namespace string, config string) {
+			cloudConfig := &bdc.CloudConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+				Spec:       bdc.CloudConfigSpec{Config: config},
+			}
+			Expect(client.Create(context.Background(), cloudConfig)).To(Succeed())
+		}
+
+		BeforeEach(func() {
+			configMap := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "named-manifest", Namespace: "default"},
+				Data: map[string]string{bdc.ManifestSpecName: `---
+name: foo-deployment
+releases:
+- name: redis
+  version: 36.15.0
+instance_groups:
+- name: redis
+  instances: 1
+  vm_type: small
+  persistent_disk_type: fast
+  jobs:
+  - name: redis-server
+    release: redis
+`},
+			}
+			Expect(client.Create(context.Background(), configMap)).To(Succeed())
+
+			deployment = &bdc.BOSHDeployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "foo-deployment",
+				},
+				Spec: bdc.BOSHDeploymentSpec{
+					Manifest: bdc.Manifest{
+						Type: bdc.ConfigMapType,
+						Ref:  "named-manifest",
+					},
+				},
+			}
+		})
+
+		It("adds the cloud configs of the namespace to the manifest", func() {
+			createCloudConfig("vm-types", "default", `---
+vm_types:
+- name: small
+  cloud_properties:
+    cpu: 2
+    ram: 4096
+    tolerations:
+    - key: dedicated
+      operator: Exists
+      effect: NoExecute
+      tolerationSeconds: 300
+`)
+			createCloudConfig("disk-types", "default", `---
+disk_types:
+- name: fast
+  disk_size: 2048
+  cloud_properties:
+    storage_class: ssd
+`)
+			createCloudConfig("other", "other-namespace", `---
+vm_types:
+- name: medium
+`)
+
+			m, _, err := resolver.WithOpsManifest(deployment, "default")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(m.CloudConfig.VMTypes).To(HaveLen(1))
+			Expect(m.CloudConfig.VMTypes[0].CloudProperties.CPU).To(Equal(2))
+			Expect(*m.CloudConfig.VMTypes[0].CloudProperties.Tolerations[0].TolerationSeconds).To(Equal(int64(300)))
+			Expect(m.CloudConfig.DiskTypes).To(HaveLen(1))
+			Expect(m.CloudConfig.DiskTypes[0].CloudProperties.StorageClass).To(Equal("ssd"))
+		})
+
+		It("fails if names are defined in multiple cloud configs", func() {
+			createCloudConfig("a", "default", `---
+vm_types:
+- name: small
+`)
+			createCloudConfig("b", "default", `---
+vm_types:
+- name: small
+`)
+
+			_, _, err := resolver.WithOpsManifest(deployment, "default")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("failed to apply cloud config 'b': vm type 'small' is defined more than once"))
+		})
+	})
+
+	Describe("images", func() {
+		var deployment *bdc.BOSHDeployment
+
+		BeforeEach(func() {
+			configMap := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "manifest-with-release", Namespace: "default"},
+				Data: map[string]string{bdc.ManifestSpecName: `---
+name: foo-deployment
+releases:
+- name: redis
+  version: 36.15.0
+  url: docker.io/cfcontainerization
+instance_groups:
+- name: redis
+  instances: 1
+  jobs:
+  - name: redis-server
+    release: redis
+`},
+			}
+			Expect(client.Create(context.Background(), configMap)).To(Succeed())
+
+			deployment = &bdc.BOSHDeployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "foo-deployment",
+				},
+				Spec: bdc.BOSHDeploymentSpec{
+					Manifest: bdc.Manifest{
+						Type: bdc.ConfigMapType,
+						Ref:  "manifest-with-release",
+					},
+				},
+			}
+		})
+
+		It("adds the image overrides and pull secrets to the manifest", func() {
+			deployment.Spec.Images = []bdc.ReleaseImage{{Release: "redis", Image: "registry.example.com/redis:custom", Digest: "sha256:0123"}}
+			deployment.Spec.ImagePullSecrets = []corev1.LocalObjectReference{{Name: "registry-credentials"}}
+
+			m, _, err := resolver.WithOpsManifest(deployment, "default")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(m.Releases[0].Image).To(Equal("registry.example.com/redis:custom"))
+			Expect(m.Releases[0].Digest).To(Equal("sha256:0123"))
+			Expect(m.ImagePullSecrets).To(Equal([]corev1.LocalObjectReference{{Name: "registry-credentials"}}))
+		})
+
+		It("fails if an image overrides an unknown release", func() {
+			deployment.Spec.Images = []bdc.ReleaseImage{{Release: "nats", Image: "nats:latest"}}
+
+			_, _, err := resolver.WithOpsManifest(deployment, "default")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("image override for unknown release 'nats'"))
+		})
+	})
+
+	Describe("git refs", func() {
+		var (
+			repo       string
+			repoURL    string
+			server     *httptest.Server
+			deployment *bdc.BOSHDeployment
+		)
+
+		git := func(args ...string) string {
+			cmd := exec.Command("git", append([]string{"-C", repo}, args...)...)
+			cmd.Env = append(os.Environ(),
+				"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+				"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+			)
+			out, err := cmd.CombinedOutput()
+			Expect(err).ToNot(HaveOccurred(), string(out))
+			return strings.TrimSpace(string(out))
+		}
+
+		commit := func(path string, content string) string {
+			Expect(ioutil.WriteFile(filepath.Join(repo, path), []byte(content), 0644)).To(Succeed())
+			git("add", path)
+			git("commit", "--quiet", "-m", "update "+path)
+			git("update-server-info")
+			return git("rev-parse", "HEAD")
+		}
+
+		BeforeEach(func() {
+			var err error
+			repo, err = ioutil.TempDir("", "resolver-git")
+			Expect(err).ToNot(HaveOccurred())
+			git("init", "--quiet")
+
+			// Serve the repository with git's dumb HTTP protocol, local repositories can't be fetched
+			server = httptest.NewTLSServer(http.FileServer(http.Dir(filepath.Join(repo, ".git"))))
+			repoURL = server.URL
+			os.Setenv("GIT_SSL_NO_VERIFY", "true")
+
+			deployment = &bdc.BOSHDeployment{
+				Spec: bdc.BOSHDeploymentSpec{
+					Manifest: bdc.Manifest{
+						Type: bdc.GitType,
+						Ref:  repoURL,
+						Path: "manifest.yml",
+					},
+				},
+			}
+		})
+
+		AfterEach(func() {
+			os.Unsetenv("GIT_SSL_NO_VERIFY")
+			server.Close()
+			Expect(os.RemoveAll(repo)).To(Succeed())
+		})
+
+		It("reads the manifest and ops files from the remote HEAD and records the commits", func() {
+			commit("manifest.yml", "instance_groups:\n- name: component1\n  instances: 1\n")
+			head := commit("ops.yml", replaceOpsStr)
+			deployment.Spec.Ops = []bdc.Ops{{Type: bdc.GitType, Ref: repoURL, Path: "/ops.yml"}}
+			interpolator.InterpolateReturns([]byte("instance_groups:\n- name: component1\n  instances: 2\n"), nil)
+
+			manifest, commits, err := resolver.WithOpsManifest(deployment, "default")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(manifest.InstanceGroups[0].Instances).To(Equal(2))
+			Expect(string(interpolator.BuildOpsArgsForCall(0))).To(Equal(replaceOpsStr))
+			Expect(commits).To(Equal([]bdc.ResolvedCommit{
+				{Spec: bdc.ManifestSpecName, Repo: repoURL, Path: "manifest.yml", Commit: head},
+				{Spec: bdc.OpsSpecName, Repo: repoURL, Path: "/ops.yml", Commit: head},
+			}))
+		})
+
+		It("reads a pinned commit, even after the branch moved on", func() {
+			pinned := commit("manifest.yml", "instance_groups:\n- name: component1\n  instances: 1\n")
+			deployment.Spec.Manifest.Revision = pinned
+			_, _, err := resolver.WithOpsManifest(deployment, "default")
+			Expect(err).ToNot(HaveOccurred())
+
+			commit("manifest.yml", "instance_groups:\n- name: component2\n  instances: 1\n")
+
+			manifest, commits, err := resolver.WithOpsManifest(deployment, "default")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(manifest.InstanceGroups[0].Name).To(Equal("component1"))
+			Expect(commits[0].Commit).To(Equal(pinned))
+		})
+
+		It("reads a branch", func() {
+			commit("manifest.yml", "instance_groups:\n- name: component1\n  instances: 1\n")
+			git("checkout", "--quiet", "-b", "feature")
+			feature := commit("manifest.yml", "instance_groups:\n- name: component2\n  instances: 1\n")
+			deployment.Spec.Manifest.Revision = "feature"
+
+			manifest, commits, err := resolver.WithOpsManifest(deployment, "default")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(manifest.InstanceGroups[0].Name).To(Equal("component2"))
+			Expect(commits[0].Commit).To(Equal(feature))
+		})
+
+		It("fails for an unknown revision", func() {
+			commit("manifest.yml", "instance_groups: []\n")
+			deployment.Spec.Manifest.Revision = "unknown"
+
+			_, _, err := resolver.WithOpsManifest(deployment, "default")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("failed to resolve revision 'unknown'"))
+		})
+
+		Context("when the ref is hostile", func() {
+			var marker string
+
+			BeforeEach(func() {
+				commit("manifest.yml", "instance_groups: []\n")
+				marker = filepath.Join(repo, "pwned")
+			})
+
+			It("rejects options as repository", func() {
+				deployment.Spec.Manifest.Ref = "--upload-pack=touch " + marker
+
+				_, _, err := resolver.WithOpsManifest(deployment, "default")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("invalid git repository"))
+				Expect(marker).ToNot(BeAnExistingFile())
+			})
+
+			It("rejects repositories, which are not https, ssh or git urls", func() {
+				for _, ref := range []string{
+					"ext::sh -c touch% " + marker,
+					"file://" + repo,
+					repo,
+					"http://example.com/repo.git",
+				} {
+					deployment.Spec.Manifest.Ref = ref
+
+					_, _, err := resolver.WithOpsManifest(deployment, "default")
+					Expect(err).To(HaveOccurred(), ref)
+					Expect(err.Error()).To(ContainSubstring("invalid git repository"))
+				}
+				Expect(marker).ToNot(BeAnExistingFile())
+			})
+
+			It("rejects options and ranges as revision", func() {
+				for _, revision := range []string{"--output=" + marker, "-h", "HEAD..master", "HEAD~1 --all"} {
+					deployment.Spec.Manifest.Revision = revision
+
+					_, _, err := resolver.WithOpsManifest(deployment, "default")
+					Expect(err).To(HaveOccurred(), revision)
+					Expect(err.Error()).To(ContainSubstring("invalid git revision"))
+				}
+				Expect(marker).ToNot(BeAnExistingFile())
+			})
+		})
+	})
+
+	Describe("url refs", func() {
+		var (
+			server     *ghttp.Server
+			deployment *bdc.BOSHDeployment
+			manifest   string
+		)
+
+		BeforeEach(func() {
+			server = ghttp.NewServer()
+			manifest = "instance_groups:\n- name: component1\n  instances: 1\n"
+
+			deployment = &bdc.BOSHDeployment{
+				Spec: bdc.BOSHDeploymentSpec{
+					Manifest: bdc.Manifest{
+						Type: bdc.URLType,
+						Ref:  server.URL() + "/manifest.yml",
+					},
+				},
+			}
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("reuses the cached content if it has not been modified", func() {
+			server.AppendHandlers(
+				ghttp.RespondWith(http.StatusOK, manifest, http.Header{"ETag": []string{`"v1"`}}),
+				ghttp.CombineHandlers(
+					ghttp.VerifyHeaderKV("If-None-Match", `"v1"`),
+					ghttp.RespondWith(http.StatusNotModified, ""),
+				),
+			)
+
+			_, _, err := resolver.WithOpsManifest(deployment, "default")
+			Expect(err).ToNot(HaveOccurred())
+
+			m, _, err := resolver.WithOpsManifest(deployment, "default")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(m.InstanceGroups[0].Name).To(Equal("component1"))
+			Expect(server.ReceivedRequests()).To(HaveLen(2))
+		})
+
+		It("verifies the checksum", func() {
+			server.AppendHandlers(
+				ghttp.RespondWith(http.StatusOK, manifest),
+				ghttp.RespondWith(http.StatusOK, manifest),
+			)
+
+			deployment.Spec.Manifest.SHA256 = fmt.Sprintf("%x", sha256.Sum256([]byte(manifest)))
+			_, _, err := resolver.WithOpsManifest(deployment, "default")
+			Expect(err).ToNot(HaveOccurred())
+
+			deployment.Spec.Manifest.SHA256 = "0000"
+			_, _, err = resolver.WithOpsManifest(deployment, "default")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("expected '0000'"))
+		})
+
+		It("fails for unexpected status codes", func() {
+			server.AppendHandlers(ghttp.RespondWith(http.StatusForbidden, "denied"))
+
+			_, _, err := resolver.WithOpsManifest(deployment, "default")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("unexpected status '403 Forbidden'"))
+		})
+
+		It("fails if the secret does not exist", func() {
+			deployment.Spec.Manifest.SecretRef = "missing"
+
+			_, _, err := resolver.WithOpsManifest(deployment, "default")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("failed to retrieve secret 'default/missing'"))
+		})
+
+		It("uses the CA certificate and headers of the secret", func() {
+			tlsServer := ghttp.NewTLSServer()
+			defer tlsServer.Close()
+			tlsServer.AppendHandlers(ghttp.CombineHandlers(
+				ghttp.VerifyHeaderKV("Authorization", "Bearer token"),
+				ghttp.RespondWith(http.StatusOK, manifest),
+			))
+
+			ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: tlsServer.HTTPTestServer.Certificate().Raw})
+			err := client.Create(context.Background(), &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "url-credentials", Namespace: "default"},
+				Data: map[string][]byte{
+					bdm.URLSecretCAKey: ca,
+					"Authorization":    []byte("Bearer token"),
+				},
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			deployment.Spec.Manifest.Ref = tlsServer.URL() + "/manifest.yml"
+			deployment.Spec.Manifest.SecretRef = "url-credentials"
+
+			m, _, err := resolver.WithOpsManifest(deployment, "default")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(m.InstanceGroups[0].Name).To(Equal("component1"))
+		})
+
+		It("doesn't reuse the cached content for other credentials", func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyHeaderKV("Authorization", "Bearer token"),
+					ghttp.RespondWith(http.StatusOK, manifest, http.Header{"ETag": []string{`"v1"`}}),
+				),
+				ghttp.CombineHandlers(
+					func(w http.ResponseWriter, r *http.Request) {
+						Expect(r.Header.Get("If-None-Match")).To(BeEmpty())
+					},
+					ghttp.VerifyHeaderKV("Authorization", "Bearer other-token"),
+					ghttp.RespondWith(http.StatusForbidden, "denied"),
+				),
+			)
+
+			for name, token := range map[string]string{"url-credentials": "Bearer token", "other-url-credentials": "Bearer other-token"} {
+				err := client.Create(context.Background(), &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+					Data:       map[string][]byte{"Authorization": []byte(token)},
+				})
+				Expect(err).ToNot(HaveOccurred())
+			}
+
+			deployment.Spec.Manifest.SecretRef = "url-credentials"
+			_, _, err := resolver.WithOpsManifest(deployment, "default")
+			Expect(err).ToNot(HaveOccurred())
+
+			deployment.Spec.Manifest.SecretRef = "other-url-credentials"
+			_, _, err = resolver.WithOpsManifest(deployment, "default")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("unexpected status '403 Forbidden'"))
+		})
+
+		It("fails for files which are too large", func() {
+			server.AppendHandlers(ghttp.RespondWith(http.StatusOK, strings.Repeat("#", 10<<20+1)))
+
+			_, _, err := resolver.WithOpsManifest(deployment, "default")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("exceeds"))
+		})
+	})
 })
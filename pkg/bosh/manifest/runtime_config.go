@@ -0,0 +1,79 @@
+package manifest
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// RuntimeConfig is a BOSH runtime config, which adds addons to deployments
+type RuntimeConfig struct {
+	Releases []*Release `yaml:"releases,omitempty"`
+	AddOns   []*AddOn   `yaml:"addons,omitempty"`
+}
+
+// LoadRuntimeConfigYAML returns a new BOSH runtime config from a yaml representation
+func LoadRuntimeConfigYAML(data []byte) (*RuntimeConfig, error) {
+	rc := &RuntimeConfig{}
+	err := yaml.Unmarshal(data, rc)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal BOSH runtime config %s", string(data))
+	}
+	return rc, nil
+}
+
+// ApplyRuntimeConfig adds the addons of the runtime config, which include the
+// deployment, to the manifest. The releases of their jobs are added, too.
+// The addons are applied to the instance groups by ApplyAddons.
+func (m *Manifest) ApplyRuntimeConfig(rc *RuntimeConfig) error {
+	releases := map[string]*Release{}
+	for _, release := range rc.Releases {
+		releases[release.Name] = release
+	}
+
+	for _, addon := range rc.AddOns {
+		if addon.Include != nil && len(addon.Include.Deployments) > 0 && !contains(addon.Include.Deployments, m.Name) {
+			continue
+		}
+
+		for _, job := range addon.Jobs {
+			release, ok := releases[job.Release]
+			if !ok {
+				return fmt.Errorf("release '%s' of addon '%s' is missing in the runtime config", job.Release, addon.Name)
+			}
+			if err := m.addRelease(release); err != nil {
+				return errors.Wrapf(err, "failed to add release of addon '%s'", addon.Name)
+			}
+		}
+
+		m.AddOns = append(m.AddOns, addon)
+	}
+
+	return nil
+}
+
+// addRelease adds a release, unless the manifest already contains it
+func (m *Manifest) addRelease(release *Release) error {
+	for _, r := range m.Releases {
+		if r.Name != release.Name {
+			continue
+		}
+		if r.Version != release.Version {
+			return fmt.Errorf("release '%s' has version '%s' in the manifest and version '%s' in the runtime config", r.Name, r.Version, release.Version)
+		}
+		return nil
+	}
+
+	m.Releases = append(m.Releases, release)
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,137 @@
+package manifest
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// urlTimeout bounds the download of a manifest or ops file
+	urlTimeout = 30 * time.Second
+	// urlMaxSize is the maximum size of a downloaded manifest or ops file
+	urlMaxSize = 10 << 20
+	// URLSecretCAKey is the key of the CA certificate in the secret of a url ref.
+	// All other keys of that secret are sent as HTTP headers.
+	URLSecretCAKey = "ca.crt"
+)
+
+type urlCacheEntry struct {
+	etag string
+	data string
+}
+
+// urlCache downloads manifests and ops files. Responses with an ETag are cached,
+// so unchanged files are not downloaded again. Entries are keyed by the url and the
+// secret data, so a response is only reused for the credentials it was fetched with.
+type urlCache struct {
+	mutex   sync.Mutex
+	entries map[string]urlCacheEntry
+}
+
+func newURLCache() *urlCache {
+	return &urlCache{entries: map[string]urlCacheEntry{}}
+}
+
+// Get downloads url. If expectedSHA256 is set, the content has to match it.
+// The secret data, if any, provides a CA certificate and HTTP headers.
+func (u *urlCache) Get(url string, expectedSHA256 string, secretData map[string][]byte) (string, error) {
+	request, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid url '%s'", url)
+	}
+
+	client := &http.Client{Timeout: urlTimeout}
+	for key, value := range secretData {
+		if key == URLSecretCAKey {
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(value) {
+				return "", fmt.Errorf("invalid CA certificate for url '%s'", url)
+			}
+			client.Transport = &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: &tls.Config{RootCAs: pool},
+			}
+			continue
+		}
+		request.Header.Set(key, string(value))
+	}
+
+	key := cacheKey(url, secretData)
+	u.mutex.Lock()
+	cached, isCached := u.entries[key]
+	u.mutex.Unlock()
+	if isCached {
+		request.Header.Set("If-None-Match", cached.etag)
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to get url '%s'", url)
+	}
+	defer response.Body.Close()
+
+	var data string
+	switch {
+	case response.StatusCode == http.StatusNotModified && isCached:
+		data = cached.data
+	case response.StatusCode == http.StatusOK:
+		body, err := ioutil.ReadAll(io.LimitReader(response.Body, urlMaxSize+1))
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to read response body of url '%s'", url)
+		}
+		if len(body) > urlMaxSize {
+			return "", fmt.Errorf("response body of url '%s' exceeds %d bytes", url, urlMaxSize)
+		}
+		data = string(body)
+	default:
+		return "", fmt.Errorf("failed to get url '%s': unexpected status '%s'", url, response.Status)
+	}
+
+	if expectedSHA256 != "" {
+		actual := fmt.Sprintf("%x", sha256.Sum256([]byte(data)))
+		if actual != expectedSHA256 {
+			return "", fmt.Errorf("sha256 of url '%s' is '%s', expected '%s'", url, actual, expectedSHA256)
+		}
+	}
+
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	etag := response.Header.Get("ETag")
+	if etag == "" && response.StatusCode == http.StatusNotModified {
+		etag = cached.etag
+	}
+	if etag != "" {
+		u.entries[key] = urlCacheEntry{etag: etag, data: data}
+	} else {
+		delete(u.entries, key)
+	}
+
+	return data, nil
+}
+
+// cacheKey identifies a url together with the secret data it is downloaded with
+func cacheKey(url string, secretData map[string][]byte) string {
+	keys := make([]string, 0, len(secretData))
+	for key := range secretData {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	hash := sha256.New()
+	for _, key := range keys {
+		fmt.Fprintf(hash, "%d:%s%d:", len(key), key, len(secretData[key]))
+		hash.Write(secretData[key])
+	}
+
+	return fmt.Sprintf("%s#%x", url, hash.Sum(nil))
+}
@@ -100,7 +100,7 @@ func generateDefaultDisks(manifestName string, instanceGroup *InstanceGroup, ver
 			// https://bosh.io/docs/vm-config/#jobs-and-packages
 			Volume: &corev1.Volume{
 				Name:         VolumeDataDirName,
-				VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+				VolumeSource: corev1.VolumeSource{EmptyDir: ephemeralDiskSource(instanceGroup)},
 			},
 			VolumeMount: &corev1.VolumeMount{
 				Name:      VolumeDataDirName,
@@ -148,7 +148,7 @@ func generateDefaultDisks(manifestName string, instanceGroup *InstanceGroup, ver
 // - persistent_disk (boolean)
 // - additional_volumes (list of volumes)
 // - unrestricted_volumes (list of volumes)
-func generateBPMDisks(manifestName string, instanceGroup *InstanceGroup, bpmConfigs bpm.Configs, namespace string) (BPMResourceDisks, error) {
+func generateBPMDisks(manifestName string, instanceGroup *InstanceGroup, bpmConfigs bpm.Configs, storageClass string, namespace string) (BPMResourceDisks, error) {
 	bpmDisks := make(BPMResourceDisks, 0)
 
 	rAdditionalVolumes := regexp.MustCompile(AdditionalVolumesRegex)
@@ -224,7 +224,7 @@ func generateBPMDisks(manifestName string, instanceGroup *InstanceGroup, bpmConf
 			ephemeralDisk := BPMResourceDisk{
 				Volume: &corev1.Volume{
 					Name:         VolumeEphemeralDirName,
-					VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+					VolumeSource: corev1.VolumeSource{EmptyDir: ephemeralDiskSource(instanceGroup)},
 				},
 				VolumeMount: &corev1.VolumeMount{
 					Name:      VolumeEphemeralDirName,
@@ -244,7 +244,7 @@ func generateBPMDisks(manifestName string, instanceGroup *InstanceGroup, bpmConf
 					" but instance group '%s' doesn't have any persistent disk declaration", job.Name, instanceGroup.Name)
 			}
 
-			persistentVolumeClaim := generatePersistentVolumeClaim(manifestName, instanceGroup, namespace)
+			persistentVolumeClaim := generatePersistentVolumeClaim(manifestName, instanceGroup, storageClass, namespace)
 
 			// Specify the job sub-path inside of the instance group PV
 			bpmPersistentDisk := BPMResourceDisk{
@@ -274,6 +274,17 @@ func generateBPMDisks(manifestName string, instanceGroup *InstanceGroup, bpmConf
 	return bpmDisks, nil
 }
 
+// ephemeralDiskSource returns the source of the volumes for ephemeral job data.
+// Their size is limited by the ephemeral disk size of the instance group's vm resources.
+func ephemeralDiskSource(instanceGroup *InstanceGroup) *corev1.EmptyDirVolumeSource {
+	source := &corev1.EmptyDirVolumeSource{}
+	if instanceGroup.VMResources != nil && instanceGroup.VMResources.EphemeralDiskSize > 0 {
+		sizeLimit := resource.MustParse(fmt.Sprintf("%d%s", instanceGroup.VMResources.EphemeralDiskSize, "Mi"))
+		source.SizeLimit = &sizeLimit
+	}
+	return source
+}
+
 // generateVolumeName generate volume name based on secret name
 func generateVolumeName(secretName string) string {
 	nameSlices := strings.Split(secretName, ".")
@@ -286,7 +297,7 @@ func generateVolumeName(secretName string) string {
 	return volName
 }
 
-func generatePersistentVolumeClaim(manifestName string, instanceGroup *InstanceGroup, namespace string) corev1.PersistentVolumeClaim {
+func generatePersistentVolumeClaim(manifestName string, instanceGroup *InstanceGroup, storageClass string, namespace string) corev1.PersistentVolumeClaim {
 	// Spec of a persistentVolumeClaim
 	persistentVolumeClaim := corev1.PersistentVolumeClaim{
 		ObjectMeta: metav1.ObjectMeta{
@@ -304,8 +315,8 @@ func generatePersistentVolumeClaim(manifestName string, instanceGroup *InstanceG
 	}
 
 	// add storage class if specified
-	if instanceGroup.PersistentDiskType != "" {
-		persistentVolumeClaim.Spec.StorageClassName = &instanceGroup.PersistentDiskType
+	if storageClass != "" {
+		persistentVolumeClaim.Spec.StorageClassName = &storageClass
 	}
 
 	return persistentVolumeClaim
@@ -0,0 +1,146 @@
+// Package monit parses the checks of a BOSH job's monit file, so they can be
+// converted to probes of the job's containers.
+package monit
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Check from a monit file
+type Check struct {
+	// Name of the checked service, usually the name of the BPM process
+	Name string `yaml:"name" json:"name"`
+	// Program is the command of a 'check program'
+	Program string `yaml:"program,omitempty" json:"program,omitempty"`
+	// Pidfile is the pidfile of a 'check process'
+	Pidfile string `yaml:"pidfile,omitempty" json:"pidfile,omitempty"`
+	// Ports are tested by 'if failed port' rules
+	Ports []int `yaml:"ports,omitempty" json:"ports,omitempty"`
+	// StartTimeout is the time in seconds the process is given to start
+	StartTimeout int `yaml:"start_timeout,omitempty" json:"start_timeout,omitempty"`
+}
+
+// Checks holds the checks of a monit file
+type Checks []Check
+
+// NewChecks parses the checks of processes and programs from a rendered monit
+// file. Other types of checks, like those of remote hosts, are ignored.
+func NewChecks(data []byte) Checks {
+	checks := Checks{}
+	tokens := tokenize(string(data))
+
+	// Index of the check the tokens belong to, -1 for ignored checks
+	current := -1
+	var action string
+	for i := 0; i < len(tokens); i++ {
+		switch tokens[i] {
+		case "check":
+			current = -1
+			action = ""
+			if i+2 >= len(tokens) {
+				break
+			}
+			switch tokens[i+1] {
+			case "process", "program":
+				checks = append(checks, Check{Name: tokens[i+2]})
+				current = len(checks) - 1
+			}
+			i += 2
+		case "start", "stop", "restart":
+			action = tokens[i]
+		case "timeout":
+			if current == -1 || action != "start" || i+1 >= len(tokens) {
+				break
+			}
+			if timeout, err := strconv.Atoi(tokens[i+1]); err == nil {
+				checks[current].StartTimeout = timeout
+				i++
+			}
+		case "path":
+			if current != -1 && checks[current].Program == "" && i+1 < len(tokens) {
+				checks[current].Program = tokens[i+1]
+				i++
+			}
+		case "pidfile":
+			if current != -1 && i+1 < len(tokens) {
+				checks[current].Pidfile = tokens[i+1]
+				i++
+			}
+		case "port":
+			if current == -1 || i+1 >= len(tokens) {
+				break
+			}
+			if port, err := strconv.Atoi(tokens[i+1]); err == nil {
+				checks[current].Ports = append(checks[current].Ports, port)
+				i++
+			}
+		}
+	}
+
+	return checks
+}
+
+// Process returns the checks of a BPM process. The checks of a job with a
+// single process all belong to that process, otherwise they are matched by
+// name.
+func (c Checks) Process(name string, single bool) Checks {
+	if single {
+		return c
+	}
+
+	checks := Checks{}
+	for _, check := range c {
+		if check.Name == name {
+			checks = append(checks, check)
+		}
+	}
+	return checks
+}
+
+// tokenize splits a monit file into words. Double quoted strings are a single
+// word and comments are skipped.
+func tokenize(data string) []string {
+	tokens := []string{}
+	var token strings.Builder
+	inToken, quoted, comment := false, false, false
+
+	flush := func() {
+		if inToken {
+			tokens = append(tokens, token.String())
+			token.Reset()
+			inToken = false
+		}
+	}
+
+	for _, r := range data {
+		switch {
+		case comment:
+			if r == '\n' {
+				comment = false
+			}
+		case quoted:
+			if r == '"' {
+				quoted = false
+				flush()
+			} else {
+				token.WriteRune(r)
+			}
+		case r == '"':
+			flush()
+			quoted, inToken = true, true
+		case r == '#':
+			flush()
+			comment = true
+		case unicode.IsSpace(r):
+			flush()
+		default:
+			token.WriteRune(r)
+			inToken = true
+		}
+	}
+	flush()
+
+	return tokens
+}
@@ -0,0 +1,56 @@
+package monit_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"code.cloudfoundry.org/cf-operator/pkg/bosh/monit"
+)
+
+var _ = Describe("monit Checks", func() {
+	var checks monit.Checks
+
+	BeforeEach(func() {
+		checks = monit.NewChecks([]byte(`
+check process nats
+  with pidfile /var/vcap/sys/run/bpm/nats/nats.pid
+  start program "/var/vcap/jobs/bpm/bin/bpm start nats" with timeout 60 seconds
+  stop program "/var/vcap/jobs/bpm/bin/bpm stop nats" with timeout 10 seconds
+  if failed host 127.0.0.1 port 4222 then restart
+  if failed port 8222 protocol http then restart # monitoring
+  group vcap
+
+check program nats-health
+  with path "/var/vcap/jobs/nats/bin/health_check --timeout 5"
+  if status != 0 then alert
+
+check file nats-config with path /var/vcap/jobs/nats/config/nats.conf
+  if failed permission 644 then alert
+
+check host nats-cluster with address nats.service.cf.internal
+  if failed port 4223 then alert
+`))
+	})
+
+	It("parses the checks of processes and programs", func() {
+		Expect(checks).To(Equal(monit.Checks{
+			{Name: "nats", Pidfile: "/var/vcap/sys/run/bpm/nats/nats.pid", Ports: []int{4222, 8222}, StartTimeout: 60},
+			{Name: "nats-health", Program: "/var/vcap/jobs/nats/bin/health_check --timeout 5"},
+		}))
+	})
+
+	It("returns the checks of a process by name", func() {
+		Expect(checks.Process("nats", false)).To(Equal(monit.Checks{
+			{Name: "nats", Pidfile: "/var/vcap/sys/run/bpm/nats/nats.pid", Ports: []int{4222, 8222}, StartTimeout: 60},
+		}))
+		Expect(checks.Process("other", false)).To(BeEmpty())
+	})
+
+	It("returns all checks for the only process of a job", func() {
+		Expect(checks.Process("other", true)).To(HaveLen(2))
+	})
+
+	It("is empty without checks", func() {
+		Expect(monit.NewChecks([]byte("# no checks\n"))).To(BeEmpty())
+	})
+})
@@ -0,0 +1,13 @@
+package monit_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestMonit(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "monit Suite")
+}
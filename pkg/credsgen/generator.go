@@ -1,5 +1,7 @@
 package credsgen
 
+import "time"
+
 const (
 	// DefaultPasswordLength represents the default length of a generated password
 	// (number of characters)
@@ -17,6 +19,8 @@ type CertificateGenerationRequest struct {
 	AlternativeNames []string
 	IsCA             bool
 	CA               Certificate
+	// Duration is the validity of the certificate, the generator's default is used if it's zero
+	Duration time.Duration
 }
 
 // Certificate holds the information about a certificate
@@ -75,8 +75,8 @@ func (g InMemoryGenerator) generateCertificate(request credsgen.CertificateGener
 	//Sign certificate
 	signingProfile := &config.SigningProfile{
 		Usage:        []string{"server auth", "client auth"},
-		Expiry:       time.Duration(g.Expiry*24) * time.Hour,
-		ExpiryString: fmt.Sprintf("%dh", g.Expiry*24),
+		Expiry:       g.validity(request),
+		ExpiryString: g.validity(request).String(),
 	}
 	policy := &config.Signing{
 		Profiles: map[string]*config.SigningProfile{},
@@ -100,7 +100,7 @@ func (g InMemoryGenerator) generateCertificate(request credsgen.CertificateGener
 // generateCACertificate Generate self-signed root CA certificate and private key
 func (g InMemoryGenerator) generateCACertificate(request credsgen.CertificateGenerationRequest) (credsgen.Certificate, error) {
 	req := &csr.CertificateRequest{
-		CA:         &csr.CAConfig{Expiry: fmt.Sprintf("%dh", g.Expiry*24)},
+		CA:         &csr.CAConfig{Expiry: g.validity(request).String()},
 		CN:         request.CommonName,
 		KeyRequest: &csr.BasicKeyRequest{A: g.Algorithm, S: g.Bits},
 	}
@@ -116,3 +116,11 @@ func (g InMemoryGenerator) generateCACertificate(request credsgen.CertificateGen
 
 	return cert, nil
 }
+
+// validity returns the requested validity of a certificate, or the generator's default
+func (g InMemoryGenerator) validity(request credsgen.CertificateGenerationRequest) time.Duration {
+	if request.Duration > 0 {
+		return request.Duration
+	}
+	return time.Duration(g.Expiry*24) * time.Hour
+}
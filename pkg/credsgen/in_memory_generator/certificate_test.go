@@ -108,6 +108,17 @@ var _ = Describe("InMemoryGenerator", func() {
 					Expect(parsedCert.NotAfter.Before(time.Now().AddDate(0, 0, 2))).To(BeTrue())
 					Expect(len(cert.PrivateKey)).To(Equal(227))
 				})
+
+				It("uses the requested duration", func() {
+					request.Duration = 2 * time.Hour
+
+					cert, err := generator.GenerateCertificate("foo", request)
+					Expect(err).ToNot(HaveOccurred())
+
+					parsedCert, err := parseCert(cert.Certificate)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(parsedCert.NotAfter.Sub(parsedCert.NotBefore)).To(BeNumerically("~", 2*time.Hour, time.Minute))
+				})
 			})
 		})
 
@@ -134,6 +145,18 @@ var _ = Describe("InMemoryGenerator", func() {
 				Expect(cert.PrivateKey).ToNot(BeEmpty())
 				Expect(parsedCert.Subject.CommonName).To(Equal(request.CommonName))
 			})
+
+			It("uses the requested duration", func() {
+				request.CommonName = "example.com"
+				request.Duration = 48 * time.Hour
+
+				cert, err := generator.GenerateCertificate("foo", request)
+				Expect(err).ToNot(HaveOccurred())
+
+				parsedCert, err := parseCert(cert.Certificate)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(parsedCert.NotAfter.Sub(parsedCert.NotBefore)).To(BeNumerically("~", 48*time.Hour, time.Minute))
+			})
 		})
 	})
 })
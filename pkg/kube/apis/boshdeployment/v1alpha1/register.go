@@ -36,6 +36,10 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 	scheme.AddKnownTypes(SchemeGroupVersion,
 		&BOSHDeployment{},
 		&BOSHDeploymentList{},
+		&RuntimeConfig{},
+		&RuntimeConfigList{},
+		&CloudConfig{},
+		&CloudConfigList{},
 	)
 
 	metav1.AddToGroupVersion(scheme, SchemeGroupVersion)
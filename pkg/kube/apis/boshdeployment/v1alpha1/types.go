@@ -3,6 +3,7 @@ package v1alpha1
 import (
 	"fmt"
 
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"code.cloudfoundry.org/cf-operator/pkg/kube/apis"
@@ -16,9 +17,11 @@ import (
 const (
 	ManifestSpecName        string = "manifest"
 	OpsSpecName             string = "ops"
+	VarsSpecName            string = "vars"
 	ConfigMapType           string = "configmap"
 	SecretType              string = "secret"
 	URLType                 string = "url"
+	GitType                 string = "git"
 	ImplicitVariableKeyName string = "value"
 )
 
@@ -31,30 +34,191 @@ var (
 	LabelManifestSHA1 = fmt.Sprintf("%s/manifestsha1", apis.GroupName)
 	// AnnotationManifestSHA1 is the annotation key for manifest SHA1
 	AnnotationManifestSHA1 = fmt.Sprintf("%s/manifestsha1", apis.GroupName)
+	// AnnotationRollbackVersion is the annotation key to request a rollback to a desired manifest version.
+	// It is removed once the rollback has been applied.
+	AnnotationRollbackVersion = fmt.Sprintf("%s/rollback-version", apis.GroupName)
 )
 
 // BOSHDeploymentSpec defines the desired state of BOSHDeployment
 type BOSHDeploymentSpec struct {
 	Manifest Manifest `json:"manifest"`
 	Ops      []Ops    `json:"ops,omitempty"`
+	// Vars are user-supplied variables. Later entries take precedence over
+	// earlier ones, all of them over variables generated by the operator.
+	Vars []Var `json:"vars,omitempty"`
+	// Paused stops the reconcilers from acting on the deployment. Changes made
+	// while paused are applied together when the deployment is resumed.
+	Paused bool `json:"paused,omitempty"`
+	// Images override the images built from the releases' urls
+	Images []ReleaseImage `json:"images,omitempty"`
+	// ImagePullSecrets are added to all pods of the deployment
+	ImagePullSecrets []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+}
+
+// ReleaseImage overrides the image of a release
+type ReleaseImage struct {
+	// Release is the name of the release in the manifest
+	Release string `json:"release"`
+	// Image replaces the image built from the release's url, stemcell and version
+	Image string `json:"image,omitempty"`
+	// Digest pins the image, e.g. sha256:...
+	Digest string `json:"digest,omitempty"`
 }
 
 // Manifest defines the manifest type and location
 type Manifest struct {
 	Type string `json:"type"`
 	Ref  string `json:"ref"`
+	// Revision is the branch, tag or commit to read a git ref from, defaults to HEAD
+	Revision string `json:"revision,omitempty"`
+	// Path is the file to read from a git ref
+	Path string `json:"path,omitempty"`
+	// SHA256 is the expected checksum of the content of a url ref
+	SHA256 string `json:"sha256,omitempty"`
+	// SecretRef names a secret with the CA certificate (key ca.crt) and HTTP headers for a url ref
+	SecretRef string `json:"secretRef,omitempty"`
 }
 
 // Ops defines the ops type and location
 type Ops struct {
 	Type string `json:"type"`
 	Ref  string `json:"ref"`
+	// Revision is the branch, tag or commit to read a git ref from, defaults to HEAD
+	Revision string `json:"revision,omitempty"`
+	// Path is the file to read from a git ref
+	Path string `json:"path,omitempty"`
+	// SHA256 is the expected checksum of the content of a url ref
+	SHA256 string `json:"sha256,omitempty"`
+	// SecretRef names a secret with the CA certificate (key ca.crt) and HTTP headers for a url ref
+	SecretRef string `json:"secretRef,omitempty"`
+}
+
+// Var defines a user-supplied variable, or a file of variables, like the bosh CLI's
+// -v, --var-file and -l flags
+type Var struct {
+	// Name of the variable. Without a name, Ref contains a YAML file of variables (-l).
+	Name string `json:"name,omitempty"`
+	// Value of the variable (-v)
+	Value string `json:"value,omitempty"`
+	// Type of Ref, either configmap or secret
+	Type string `json:"type,omitempty"`
+	// Ref names the configmap or secret, which contains the value of the variable (--var-file)
+	Ref string `json:"ref,omitempty"`
+	// Key in Ref, defaults to "value" for a variable and to "vars" for a file of variables
+	Key string `json:"key,omitempty"`
+}
+
+// ResolvedCommit records the commit a git manifest or ops file has been read from
+type ResolvedCommit struct {
+	// Either manifest or ops
+	Spec     string `json:"spec"`
+	Repo     string `json:"repo"`
+	Revision string `json:"revision,omitempty"`
+	Path     string `json:"path"`
+	Commit   string `json:"commit"`
+}
+
+// Phases of a BOSHDeployment, stored in BOSHDeploymentStatus.State
+const (
+	// PhasePending means no reconciler has reported progress yet
+	PhasePending = "Pending"
+	// PhaseDeploying means at least one condition is still in progress
+	PhaseDeploying = "Deploying"
+	// PhaseReady means all conditions, including every instance group, are true
+	PhaseReady = "Ready"
+	// PhaseFailed means at least one condition is false
+	PhaseFailed = "Failed"
+	// PhasePaused means the reconcilers don't act on the deployment
+	PhasePaused = "Paused"
+)
+
+// BOSHDeploymentConditionType is the type of a BOSHDeployment condition
+type BOSHDeploymentConditionType string
+
+// Valid condition types of a BOSHDeployment
+const (
+	// ConditionOpsResolved is true once the manifest and its ops files have been resolved into the with-ops secret
+	ConditionOpsResolved BOSHDeploymentConditionType = "OpsResolved"
+	// ConditionVariablesGenerated is true once ExtendedSecrets exist for all explicit variables
+	ConditionVariablesGenerated BOSHDeploymentConditionType = "VariablesGenerated"
+	// ConditionVariablesInterpolated is true once the desired manifest has been written by the variable interpolation job
+	ConditionVariablesInterpolated BOSHDeploymentConditionType = "VariablesInterpolated"
+	// ConditionDataGathered is true once resolved properties exist for all instance groups
+	ConditionDataGathered BOSHDeploymentConditionType = "DataGathered"
+	// ConditionBPMRendered is true once BPM configs exist for all instance groups
+	ConditionBPMRendered BOSHDeploymentConditionType = "BPMRendered"
+	// ConditionInstanceGroupReady is true once all instances of an instance group are ready.
+	// There is one condition of this type per instance group.
+	ConditionInstanceGroupReady BOSHDeploymentConditionType = "InstanceGroupReady"
+	// ConditionPostStart is true once the post-start scripts succeeded on all instances of an instance group.
+	// There is one condition of this type per instance group with post-start scripts.
+	ConditionPostStart BOSHDeploymentConditionType = "PostStart"
+	// ConditionPostDeploy is true once the post-deploy scripts of an instance group succeeded.
+	// There is one condition of this type per instance group with post-deploy scripts.
+	ConditionPostDeploy BOSHDeploymentConditionType = "PostDeploy"
+	// ConditionPropertiesValid is false if the job properties of an instance group don't match the specs of their release jobs.
+	// There is one condition of this type per instance group. Warnings don't block the deployment.
+	ConditionPropertiesValid BOSHDeploymentConditionType = "PropertiesValid"
+)
+
+// DeploymentConditionTypes lists the deployment wide condition types in the order they are reached
+var DeploymentConditionTypes = []BOSHDeploymentConditionType{
+	ConditionOpsResolved,
+	ConditionVariablesGenerated,
+	ConditionVariablesInterpolated,
+	ConditionDataGathered,
+	ConditionBPMRendered,
+}
+
+// BOSHDeploymentCondition describes the state of one step of a BOSHDeployment
+type BOSHDeploymentCondition struct {
+	Type BOSHDeploymentConditionType `json:"type"`
+	// Name of the instance group, only set for instance group conditions
+	InstanceGroup string `json:"instanceGroup,omitempty"`
+	// One of True, False or Unknown. Unknown means the step is still in progress.
+	Status  corev1.ConditionStatus `json:"status"`
+	Reason  string                 `json:"reason,omitempty"`
+	Message string                 `json:"message,omitempty"`
+	// The last error observed for this step, kept after the step recovers
+	LastError string `json:"lastError,omitempty"`
+	// The generation of the BOSHDeployment this condition was computed for
+	ObservedGeneration int64       `json:"observedGeneration"`
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+}
+
+// BOSHDeploymentRollback records a rollback to a previous desired manifest version
+type BOSHDeploymentRollback struct {
+	// The latest desired manifest version before the rollback
+	FromVersion int `json:"fromVersion"`
+	// The desired manifest version which has been rolled back to
+	ToVersion int `json:"toVersion"`
+	// The new desired manifest version, which is a copy of ToVersion
+	Version int         `json:"version"`
+	Time    metav1.Time `json:"time,omitempty"`
+}
+
+// BOSHDeploymentPostDeploy records the last run of the post-deploy scripts
+type BOSHDeploymentPostDeploy struct {
+	// The versions of all instance groups the scripts were run for, e.g. "api=2,nats=1"
+	Revision string      `json:"revision"`
+	Time     metav1.Time `json:"time,omitempty"`
 }
 
 // BOSHDeploymentStatus defines the observed state of BOSHDeployment
 type BOSHDeploymentStatus struct {
-	State string   `json:"state"`
-	Nodes []string `json:"nodes"`
+	// The phase of the deployment, one of Pending, Deploying, Ready, Failed or Paused
+	State              string                    `json:"state"`
+	Nodes              []string                  `json:"nodes"`
+	ObservedGeneration int64                     `json:"observedGeneration,omitempty"`
+	Conditions         []BOSHDeploymentCondition `json:"conditions,omitempty"`
+	// The last rollback of the desired manifest
+	Rollback *BOSHDeploymentRollback `json:"rollback,omitempty"`
+	// Whether the deployment reconciler has observed the deployment as paused
+	Paused bool `json:"paused,omitempty"`
+	// The commits of the git manifest and ops files of the last resolved manifest
+	ResolvedCommits []ResolvedCommit `json:"resolvedCommits,omitempty"`
+	// The last run of the post-deploy scripts, once all instance groups were ready
+	PostDeploy *BOSHDeploymentPostDeploy `json:"postDeploy,omitempty"`
 }
 
 // +genclient
@@ -79,8 +243,159 @@ type BOSHDeploymentList struct {
 	Items           []BOSHDeployment `json:"items"`
 }
 
+// RuntimeConfigSpec defines the desired state of RuntimeConfig
+type RuntimeConfigSpec struct {
+	// Config is a BOSH runtime config in YAML. Its addons are merged into all
+	// BOSHDeployments matched by their include and exclude rules, together
+	// with the releases of the addon jobs.
+	Config string `json:"config"`
+}
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// RuntimeConfig is the Schema for the cluster-wide runtimeconfigs API
+// +k8s:openapi-gen=true
+type RuntimeConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec RuntimeConfigSpec `json:"spec,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// RuntimeConfigList contains a list of RuntimeConfig
+type RuntimeConfigList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []RuntimeConfig `json:"items"`
+}
+
+// CloudConfigSpec defines the desired state of CloudConfig
+type CloudConfigSpec struct {
+	// Config is a BOSH cloud config in YAML. Its vm types, vm extensions and
+	// disk types are referenced by the instance groups of all BOSHDeployments
+	// in the same namespace.
+	Config string `json:"config"`
+}
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// CloudConfig is the Schema for the cloudconfigs API
+// +k8s:openapi-gen=true
+type CloudConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec CloudConfigSpec `json:"spec,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// CloudConfigList contains a list of CloudConfig
+type CloudConfigList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []CloudConfig `json:"items"`
+}
+
 // ToBeDeleted checks whether this BOSHDeployment has been marked for deletion
 func (e *BOSHDeployment) ToBeDeleted() bool {
 	// IsZero means that the object hasn't been marked for deletion
 	return !e.GetDeletionTimestamp().IsZero()
 }
+
+// GetCondition returns the condition of the given type and instance group, or nil if it is not set
+func (s *BOSHDeploymentStatus) GetCondition(conditionType BOSHDeploymentConditionType, instanceGroup string) *BOSHDeploymentCondition {
+	for i := range s.Conditions {
+		if s.Conditions[i].Type == conditionType && s.Conditions[i].InstanceGroup == instanceGroup {
+			return &s.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// SetCondition adds or replaces a condition and recalculates the phase.
+// The transition time only changes when the condition status changes and
+// the last error is kept when the new condition doesn't carry one.
+func (s *BOSHDeploymentStatus) SetCondition(condition BOSHDeploymentCondition) {
+	existing := s.GetCondition(condition.Type, condition.InstanceGroup)
+	if existing == nil {
+		if condition.LastTransitionTime.IsZero() {
+			condition.LastTransitionTime = metav1.Now()
+		}
+		s.Conditions = append(s.Conditions, condition)
+		s.State = s.phase()
+		return
+	}
+
+	if existing.Status != condition.Status {
+		existing.LastTransitionTime = metav1.Now()
+	}
+	if condition.LastError != "" {
+		existing.LastError = condition.LastError
+	}
+	existing.Status = condition.Status
+	existing.Reason = condition.Reason
+	existing.Message = condition.Message
+	existing.ObservedGeneration = condition.ObservedGeneration
+	s.State = s.phase()
+}
+
+// RemoveInstanceGroupConditions removes the conditions of instance groups which are not in the given list
+func (s *BOSHDeploymentStatus) RemoveInstanceGroupConditions(instanceGroups []string) {
+	keep := map[string]bool{}
+	for _, ig := range instanceGroups {
+		keep[ig] = true
+	}
+
+	conditions := []BOSHDeploymentCondition{}
+	for _, c := range s.Conditions {
+		if c.InstanceGroup == "" || keep[c.InstanceGroup] {
+			conditions = append(conditions, c)
+		}
+	}
+	s.Conditions = conditions
+	s.State = s.phase()
+}
+
+// SetPaused records whether the deployment is paused and recalculates the phase
+func (s *BOSHDeploymentStatus) SetPaused(paused bool) {
+	s.Paused = paused
+	s.State = s.phase()
+}
+
+// phase calculates the deployment phase from the conditions
+func (s *BOSHDeploymentStatus) phase() string {
+	if s.Paused {
+		return PhasePaused
+	}
+
+	if len(s.Conditions) == 0 {
+		return PhasePending
+	}
+
+	for _, c := range s.Conditions {
+		if c.Status == corev1.ConditionFalse {
+			return PhaseFailed
+		}
+	}
+
+	for _, t := range DeploymentConditionTypes {
+		c := s.GetCondition(t, "")
+		if c == nil || c.Status != corev1.ConditionTrue {
+			return PhaseDeploying
+		}
+	}
+
+	for _, c := range s.Conditions {
+		if c.Status != corev1.ConditionTrue {
+			return PhaseDeploying
+		}
+	}
+
+	return PhaseReady
+}
@@ -112,9 +112,41 @@ type Output struct {
 	Versioned      bool              `json:"versioned,omitempty"`
 }
 
+// RunState is the state of an errand run
+type RunState string
+
+const (
+	// RunStateRunning means the job of the run has been created
+	RunStateRunning RunState = "running"
+	// RunStateSucceeded means the job of the run succeeded
+	RunStateSucceeded RunState = "succeeded"
+	// RunStateFailed means the job of the run failed
+	RunStateFailed RunState = "failed"
+)
+
+// ExtendedJobRun records a run of an errand
+type ExtendedJobRun struct {
+	// Job is the name of the batchv1.Job, which runs the errand
+	Job string `json:"job"`
+	// Pod is the name of the job's pod, once the run is done
+	Pod            string       `json:"pod,omitempty"`
+	State          RunState     `json:"state"`
+	StartTime      metav1.Time  `json:"startTime"`
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+	// ExitCode is the exit code of the first failed container, or 0 if all containers succeeded
+	ExitCode *int32 `json:"exitCode,omitempty"`
+}
+
+// Done checks whether the run succeeded or failed
+func (r *ExtendedJobRun) Done() bool {
+	return r.State == RunStateSucceeded || r.State == RunStateFailed
+}
+
 // ExtendedJobStatus defines the observed state of ExtendedJob
 type ExtendedJobStatus struct {
 	Nodes []string `json:"nodes"`
+	// LastRun is the latest run of an errand
+	LastRun *ExtendedJobRun `json:"lastRun,omitempty"`
 }
 
 // +genclient
@@ -75,6 +75,32 @@ func (in *ExtendedJobList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExtendedJobRun) DeepCopyInto(out *ExtendedJobRun) {
+	*out = *in
+	in.StartTime.DeepCopyInto(&out.StartTime)
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+	if in.ExitCode != nil {
+		in, out := &in.ExitCode, &out.ExitCode
+		*out = new(int32)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExtendedJobRun.
+func (in *ExtendedJobRun) DeepCopy() *ExtendedJobRun {
+	if in == nil {
+		return nil
+	}
+	out := new(ExtendedJobRun)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ExtendedJobSpec) DeepCopyInto(out *ExtendedJobSpec) {
 	*out = *in
@@ -106,6 +132,11 @@ func (in *ExtendedJobStatus) DeepCopyInto(out *ExtendedJobStatus) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.LastRun != nil {
+		in, out := &in.LastRun, &out.LastRun
+		*out = new(ExtendedJobRun)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -26,6 +26,8 @@ const (
 var (
 	// LabelKind is the label key for secret kind
 	LabelKind = fmt.Sprintf("%s/secret-kind", apis.GroupName)
+	// AnnotationUpdateTime is the annotation key for the time the data of a generated secret changed
+	AnnotationUpdateTime = fmt.Sprintf("%s/update-time", apis.GroupName)
 )
 
 const (
@@ -39,13 +41,17 @@ type SecretReference struct {
 	Key  string
 }
 
-// CertificateRequest specifies the details for the certificate generation
+// CertificateRequest specifies the details for the certificate generation.
+// Duration is the validity of the certificate, it defaults to the generator's validity.
+// The certificate is renewed RenewBefore it expires, by default after two thirds of its validity.
 type CertificateRequest struct {
-	CommonName       string          `json:"commonName"`
-	AlternativeNames []string        `json:"alternativeNames"`
-	IsCA             bool            `json:"isCA"`
-	CARef            SecretReference `json:"CARef"`
-	CAKeyRef         SecretReference `json:"CAKeyRef"`
+	CommonName       string           `json:"commonName"`
+	AlternativeNames []string         `json:"alternativeNames"`
+	IsCA             bool             `json:"isCA"`
+	CARef            SecretReference  `json:"CARef"`
+	CAKeyRef         SecretReference  `json:"CAKeyRef"`
+	Duration         *metav1.Duration `json:"duration,omitempty"`
+	RenewBefore      *metav1.Duration `json:"renewBefore,omitempty"`
 }
 
 // Request specifies details for the secret generation
@@ -60,9 +66,27 @@ type ExtendedSecretSpec struct {
 	SecretName string  `json:"secretName"`
 }
 
-// ExtendedSecretStatus defines the observed state of ExtendedSecret
+// CARotationStage is the stage of a CA certificate's rotation
+type CARotationStage string
+
+// A rotated CA is first trusted, then used to reissue the certificates it signs, before the old CA is removed
+const (
+	// CARotationTrustNewCA means the new CA has been added to the trust bundle, certificates are still signed by the old CA
+	CARotationTrustNewCA CARotationStage = "TrustNewCA"
+	// CARotationReissueCertificates means certificates are signed by the new CA, the old CA is still trusted
+	CARotationReissueCertificates CARotationStage = "ReissueCertificates"
+)
+
+// ExtendedSecretStatus defines the observed state of ExtendedSecret.
+// NotAfter is the expiry of a generated certificate and RenewalTime the time it is renewed.
+// CARotation is the stage of a CA certificate's rotation, it is empty unless a rotation is in progress.
+// CARotationTime is the time the stage started.
 type ExtendedSecretStatus struct {
-	SecretStatus []string `json:"secretStatus"`
+	SecretStatus   []string        `json:"secretStatus"`
+	NotAfter       *metav1.Time    `json:"notAfter,omitempty"`
+	RenewalTime    *metav1.Time    `json:"renewalTime,omitempty"`
+	CARotation     CARotationStage `json:"caRotation,omitempty"`
+	CARotationTime *metav1.Time    `json:"caRotationTime,omitempty"`
 }
 
 // +genclient
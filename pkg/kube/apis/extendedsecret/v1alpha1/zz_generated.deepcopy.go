@@ -10,6 +10,7 @@ Don't alter this file, it was generated.
 package v1alpha1
 
 import (
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -23,6 +24,16 @@ func (in *CertificateRequest) DeepCopyInto(out *CertificateRequest) {
 	}
 	out.CARef = in.CARef
 	out.CAKeyRef = in.CAKeyRef
+	if in.Duration != nil {
+		in, out := &in.Duration, &out.Duration
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.RenewBefore != nil {
+		in, out := &in.RenewBefore, &out.RenewBefore
+		*out = new(v1.Duration)
+		**out = **in
+	}
 	return
 }
 
@@ -122,6 +133,18 @@ func (in *ExtendedSecretStatus) DeepCopyInto(out *ExtendedSecretStatus) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.NotAfter != nil {
+		in, out := &in.NotAfter, &out.NotAfter
+		*out = (*in).DeepCopy()
+	}
+	if in.RenewalTime != nil {
+		in, out := &in.RenewalTime, &out.RenewalTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CARotationTime != nil {
+		in, out := &in.CARotationTime, &out.CARotationTime
+		*out = (*in).DeepCopy()
+	}
 	return
 }
 
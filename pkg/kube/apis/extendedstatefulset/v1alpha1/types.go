@@ -2,9 +2,14 @@ package v1alpha1
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/pkg/errors"
 	"k8s.io/api/apps/v1beta2"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 
 	"code.cloudfoundry.org/cf-operator/pkg/kube/apis"
 )
@@ -27,6 +32,21 @@ var (
 	LabelAZName = fmt.Sprintf("%s/az-name", apis.GroupName)
 	// LabelPodOrdinal is the index of pod ordinal
 	LabelPodOrdinal = fmt.Sprintf("%s/pod-ordinal", apis.GroupName)
+	// AnnotationUpdateState is the annotation key for the rollout state of a StatefulSet version
+	AnnotationUpdateState = fmt.Sprintf("%s/update-state", apis.GroupName)
+	// AnnotationUpdateStartTime is the annotation key for the start time of the current rollout step
+	AnnotationUpdateStartTime = fmt.Sprintf("%s/update-start-time", apis.GroupName)
+)
+
+const (
+	// UpdateStateCanary means the canaries of a new version are being watched
+	UpdateStateCanary = "canary"
+	// UpdateStateUpdating means the new version is scaled up in batches
+	UpdateStateUpdating = "updating"
+	// UpdateStateDone means all replicas have been updated to the new version
+	UpdateStateDone = "done"
+	// UpdateStateFailed means the rollout has been halted, because replicas didn't become ready in time
+	UpdateStateFailed = "failed"
 )
 
 // ExtendedStatefulSetSpec defines the desired state of ExtendedStatefulSet
@@ -40,8 +60,34 @@ type ExtendedStatefulSetSpec struct {
 	// Indicates the availability zones that the ExtendedStatefulSet needs to span
 	Zones []string `json:"zones,omitempty"`
 
+	// Total number of replicas, which are distributed round robin across the zones.
+	// Without it, the StatefulSet of each zone has the replicas of the template.
+	Replicas *int32 `json:"replicas,omitempty"`
+
+	// Number of replicas of each zone, in the order of the zones. Overrides the
+	// round robin distribution of Replicas.
+	ZoneReplicas []int32 `json:"zoneReplicas,omitempty"`
+
 	// Defines a regular StatefulSet template
 	Template v1beta2.StatefulSet `json:"template"`
+
+	// Update configures a staged rollout of new versions, all replicas are replaced at once if empty
+	Update *UpdateStrategy `json:"update,omitempty"`
+}
+
+// UpdateStrategy describes a rollout like the update block of a BOSH manifest
+type UpdateStrategy struct {
+	// Number of replicas which are updated first
+	Canaries int32 `json:"canaries,omitempty"`
+
+	// Number of replicas, or percentage of all replicas, which are updated in parallel after the canaries
+	MaxInFlight *intstr.IntOrString `json:"maxInFlight,omitempty"`
+
+	// Time in milliseconds to wait for canaries to become ready, e.g. "1000-30000" or "30000"
+	CanaryWatchTime string `json:"canaryWatchTime,omitempty"`
+
+	// Time in milliseconds to wait for a batch of replicas to become ready, e.g. "1000-30000" or "30000"
+	UpdateWatchTime string `json:"updateWatchTime,omitempty"`
 }
 
 // ExtendedStatefulSetStatus defines the observed state of ExtendedStatefulSet
@@ -83,3 +129,44 @@ func (e *ExtendedStatefulSet) GetMaxAvailableVersion(versions map[int]bool) int
 	}
 	return maxAvailableVersion
 }
+
+// BatchSize returns the number of replicas to update in parallel, out of total replicas
+func (u *UpdateStrategy) BatchSize(total int32) int32 {
+	if u == nil || u.MaxInFlight == nil {
+		return total
+	}
+
+	size, err := intstr.GetValueFromIntOrPercent(u.MaxInFlight, int(total), true)
+	if err != nil || size < 1 {
+		return 1
+	}
+	return int32(size)
+}
+
+// ParseWatchTime parses a BOSH watch time, which is either a maximum or a
+// "min-max" range in milliseconds. An empty watch time has no limits, which
+// is returned as zero durations.
+func ParseWatchTime(watchTime string) (time.Duration, time.Duration, error) {
+	if watchTime == "" {
+		return 0, 0, nil
+	}
+
+	parts := strings.SplitN(watchTime, "-", 2)
+	if len(parts) == 1 {
+		parts = []string{"0", parts[0]}
+	}
+
+	limits := make([]time.Duration, 2)
+	for i, part := range parts {
+		ms, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || ms < 0 {
+			return 0, 0, errors.Errorf("invalid watch time '%s'", watchTime)
+		}
+		limits[i] = time.Duration(ms) * time.Millisecond
+	}
+
+	if limits[0] > limits[1] {
+		return 0, 0, errors.Errorf("invalid watch time '%s': minimum exceeds maximum", watchTime)
+	}
+	return limits[0], limits[1], nil
+}
@@ -17,6 +17,8 @@ import (
 type BoshdeploymentV1alpha1Interface interface {
 	RESTClient() rest.Interface
 	BOSHDeploymentsGetter
+	CloudConfigsGetter
+	RuntimeConfigsGetter
 }
 
 // BoshdeploymentV1alpha1Client is used to interact with features provided by the boshdeployment group.
@@ -28,6 +30,14 @@ func (c *BoshdeploymentV1alpha1Client) BOSHDeployments(namespace string) BOSHDep
 	return newBOSHDeployments(c, namespace)
 }
 
+func (c *BoshdeploymentV1alpha1Client) CloudConfigs(namespace string) CloudConfigInterface {
+	return newCloudConfigs(c, namespace)
+}
+
+func (c *BoshdeploymentV1alpha1Client) RuntimeConfigs() RuntimeConfigInterface {
+	return newRuntimeConfigs(c)
+}
+
 // NewForConfig creates a new BoshdeploymentV1alpha1Client for the given config.
 func NewForConfig(c *rest.Config) (*BoshdeploymentV1alpha1Client, error) {
 	config := *c
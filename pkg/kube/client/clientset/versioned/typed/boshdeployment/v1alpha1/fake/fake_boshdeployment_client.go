@@ -21,6 +21,14 @@ func (c *FakeBoshdeploymentV1alpha1) BOSHDeployments(namespace string) v1alpha1.
 	return &FakeBOSHDeployments{c, namespace}
 }
 
+func (c *FakeBoshdeploymentV1alpha1) CloudConfigs(namespace string) v1alpha1.CloudConfigInterface {
+	return &FakeCloudConfigs{c, namespace}
+}
+
+func (c *FakeBoshdeploymentV1alpha1) RuntimeConfigs() v1alpha1.RuntimeConfigInterface {
+	return &FakeRuntimeConfigs{c}
+}
+
 // RESTClient returns a RESTClient that is used to communicate
 // with API server by this client implementation.
 func (c *FakeBoshdeploymentV1alpha1) RESTClient() rest.Interface {
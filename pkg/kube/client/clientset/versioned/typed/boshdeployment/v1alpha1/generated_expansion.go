@@ -8,3 +8,7 @@ Don't alter this file, it was generated.
 package v1alpha1
 
 type BOSHDeploymentExpansion interface{}
+
+type CloudConfigExpansion interface{}
+
+type RuntimeConfigExpansion interface{}
@@ -14,3 +14,15 @@ type BOSHDeploymentListerExpansion interface{}
 // BOSHDeploymentNamespaceListerExpansion allows custom methods to be added to
 // BOSHDeploymentNamespaceLister.
 type BOSHDeploymentNamespaceListerExpansion interface{}
+
+// CloudConfigListerExpansion allows custom methods to be added to
+// CloudConfigLister.
+type CloudConfigListerExpansion interface{}
+
+// CloudConfigNamespaceListerExpansion allows custom methods to be added to
+// CloudConfigNamespaceLister.
+type CloudConfigNamespaceListerExpansion interface{}
+
+// RuntimeConfigListerExpansion allows custom methods to be added to
+// RuntimeConfigLister.
+type RuntimeConfigListerExpansion interface{}
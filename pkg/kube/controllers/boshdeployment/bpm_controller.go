@@ -4,17 +4,21 @@ import (
 	"context"
 	"fmt"
 
+	"k8s.io/api/apps/v1beta2"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	bdm "code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
 	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	estsv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/extendedstatefulset/v1alpha1"
 	"code.cloudfoundry.org/cf-operator/pkg/kube/util/config"
 	"code.cloudfoundry.org/cf-operator/pkg/kube/util/ctxlog"
 	"code.cloudfoundry.org/cf-operator/pkg/kube/util/names"
@@ -79,9 +83,67 @@ func AddBPM(ctx context.Context, config *config.Config, mgr manager.Manager) err
 		return err
 	}
 
+	// We have to watch the StatefulSets of instance groups, to report when an instance group is ready.
+	// The StatefulSets are labeled with the version of the BPM secret they were created from.
+	store := vss.NewVersionedSecretStore(mgr.GetClient())
+	statefulSetPredicates := predicate.Funcs{
+		CreateFunc:  func(e event.CreateEvent) bool { return false },
+		DeleteFunc:  func(e event.DeleteEvent) bool { return false },
+		GenericFunc: func(e event.GenericEvent) bool { return false },
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldStatefulSet := e.ObjectOld.(*v1beta2.StatefulSet)
+			newStatefulSet := e.ObjectNew.(*v1beta2.StatefulSet)
+
+			return isInstanceGroupStatefulSet(newStatefulSet) &&
+				(oldStatefulSet.Status.ReadyReplicas != newStatefulSet.Status.ReadyReplicas ||
+					oldStatefulSet.Annotations[estsv1.AnnotationUpdateState] != newStatefulSet.Annotations[estsv1.AnnotationUpdateState])
+		},
+	}
+	err = c.Watch(&source.Kind{Type: &v1beta2.StatefulSet{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(func(a handler.MapObject) []reconcile.Request {
+			statefulSetLabels := a.Meta.GetLabels()
+			deploymentName := statefulSetLabels[bdm.LabelDeploymentName]
+			instanceGroupName := statefulSetLabels[bdm.LabelInstanceGroupName]
+			version := statefulSetLabels[bdm.LabelDeploymentVersion]
+
+			// Only the latest BPM secret is reconciled, StatefulSets of older versions are being replaced
+			latest, err := store.Latest(ctx, a.Meta.GetNamespace(), names.CalculateIGSecretName(names.DeploymentSecretBpmInformation, deploymentName, instanceGroupName, ""))
+			if err != nil {
+				ctxlog.Errorf(ctx, "Failed to get latest BPM secret for StatefulSet '%s': %v", a.Meta.GetName(), err)
+				return []reconcile.Request{}
+			}
+			if latest.GetLabels()[vss.LabelVersion] != version {
+				return []reconcile.Request{}
+			}
+
+			reconciliation := reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Namespace: latest.GetNamespace(),
+					Name:      latest.GetName(),
+				},
+			}
+			ctxlog.NewMappingEvent(a.Object).Debug(ctx, reconciliation, "BPMSecret", a.Meta.GetName(), "StatefulSet")
+
+			return []reconcile.Request{reconciliation}
+		}),
+	}, statefulSetPredicates)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
+func isInstanceGroupStatefulSet(statefulSet *v1beta2.StatefulSet) bool {
+	statefulSetLabels := statefulSet.GetLabels()
+	for _, label := range []string{bdm.LabelDeploymentName, bdm.LabelInstanceGroupName, bdm.LabelDeploymentVersion} {
+		if _, ok := statefulSetLabels[label]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
 func isBPMInfoSecret(secret *corev1.Secret) bool {
 	ok := vss.IsVersionedSecret(*secret)
 	if !ok {
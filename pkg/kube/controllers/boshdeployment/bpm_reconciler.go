@@ -3,12 +3,17 @@ package boshdeployment
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/pkg/errors"
 	yaml "gopkg.in/yaml.v2"
+	"k8s.io/api/apps/v1beta2"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -23,9 +28,24 @@ import (
 	estsv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/extendedstatefulset/v1alpha1"
 	"code.cloudfoundry.org/cf-operator/pkg/kube/util/config"
 	log "code.cloudfoundry.org/cf-operator/pkg/kube/util/ctxlog"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/names"
 	vss "code.cloudfoundry.org/cf-operator/pkg/kube/util/versionedsecretstore"
 )
 
+// instanceGroupWaitInterval is the interval to check whether an instance group can be deployed,
+// while previous instance groups are not ready yet
+const instanceGroupWaitInterval = 10 * time.Second
+
+// pausedWaitInterval is the interval to check whether a paused deployment has been resumed
+const pausedWaitInterval = 30 * time.Second
+
+// postStartHookError is the reason of waiting containers, whose postStart hook failed
+const postStartHookError = "PostStartHookError"
+
+// maxConditionFindings is the number of property findings listed in the message of the
+// properties condition, the remaining ones are only counted
+const maxConditionFindings = 5
+
 type DesiredManifest interface {
 	DesiredManifest(ctx context.Context, boshDeploymentName, namespace string) (*bdm.Manifest, error)
 }
@@ -35,25 +55,27 @@ var _ reconcile.Reconciler = &ReconcileBOSHDeployment{}
 // NewBPMReconciler returns a new reconcile.Reconciler
 func NewBPMReconciler(ctx context.Context, config *config.Config, mgr manager.Manager, resolver DesiredManifest, srf setReferenceFunc, kubeConverter *bdm.KubeConverter) reconcile.Reconciler {
 	return &ReconcileBPM{
-		ctx:           ctx,
-		config:        config,
-		client:        mgr.GetClient(),
-		scheme:        mgr.GetScheme(),
-		resolver:      resolver,
-		setReference:  srf,
-		kubeConverter: kubeConverter,
+		ctx:                  ctx,
+		config:               config,
+		client:               mgr.GetClient(),
+		scheme:               mgr.GetScheme(),
+		resolver:             resolver,
+		setReference:         srf,
+		kubeConverter:        kubeConverter,
+		versionedSecretStore: vss.NewVersionedSecretStore(mgr.GetClient()),
 	}
 }
 
 // ReconcileBPM reconciles an Instance Group BPM versioned secret
 type ReconcileBPM struct {
-	ctx           context.Context
-	config        *config.Config
-	client        client.Client
-	scheme        *runtime.Scheme
-	resolver      DesiredManifest
-	setReference  setReferenceFunc
-	kubeConverter *bdm.KubeConverter
+	ctx                  context.Context
+	config               *config.Config
+	client               client.Client
+	scheme               *runtime.Scheme
+	resolver             DesiredManifest
+	setReference         setReferenceFunc
+	kubeConverter        *bdm.KubeConverter
+	versionedSecretStore vss.VersionedSecretStore
 }
 
 // Reconcile reconciles an Instance Group BPM versioned secret read the corresponding
@@ -90,49 +112,474 @@ func (r *ReconcileBPM) Reconcile(request reconcile.Request) (reconcile.Result, e
 		return reconcile.Result{},
 			log.WithEvent(bpmSecret, "GetBOSHDeploymentLabel").Errorf(ctx, "There's no label for a BOSH Deployment name on the Instance Group BPM versioned bpmSecret '%s'", request.NamespacedName)
 	}
-	manifest, err := r.resolver.DesiredManifest(ctx, boshDeploymentName, request.Namespace)
-	if err != nil {
-		return reconcile.Result{},
-			log.WithEvent(bpmSecret, "DesiredManifestReadError").Errorf(ctx, "Failed to read desired manifest '%s': %v", request.NamespacedName, err)
-	}
 
-	// Apply BPM information
 	instanceGroupName, ok := bpmSecret.Labels[ejv1.LabelInstanceGroup]
 	if !ok {
 		return reconcile.Result{},
 			log.WithEvent(bpmSecret, "LabelMissingError").Errorf(ctx, "Missing container label for bpm information bpmSecret '%s'", request.NamespacedName)
 	}
 
+	instance := &bdv1.BOSHDeployment{}
+	err = r.client.Get(ctx, types.NamespacedName{Namespace: request.Namespace, Name: boshDeploymentName}, instance)
+	if err != nil {
+		return reconcile.Result{},
+			log.WithEvent(bpmSecret, "GetBOSHDeployment").Errorf(ctx, "Failed to get BoshDeployment instance '%s': %v", boshDeploymentName, err)
+	}
+
+	// Resuming doesn't create new BPM secrets, unless the manifest changed while paused.
+	// The secret is requeued, so its instance group is deployed once the deployment is resumed.
+	if instance.Spec.Paused {
+		log.Debugf(ctx, "Delaying reconcile: BOSHDeployment '%s' is paused", boshDeploymentName)
+		return reconcile.Result{RequeueAfter: pausedWaitInterval}, nil
+	}
+
+	manifest, err := r.resolver.DesiredManifest(ctx, boshDeploymentName, request.Namespace)
+	if err != nil {
+		err = log.WithEvent(bpmSecret, "DesiredManifestReadError").Errorf(ctx, "Failed to read desired manifest '%s': %v", request.NamespacedName, err)
+		setConditions(ctx, r.client, instance, conditionFalse(bdv1.ConditionVariablesInterpolated, "", "DesiredManifestReadError", err))
+		return reconcile.Result{}, err
+	}
+
+	// Apply BPM information
 	resources, err := r.applyBPMResources(bpmSecret, manifest)
+	if err != nil {
+		err = log.WithEvent(bpmSecret, "BPMApplyingError").Errorf(ctx, "Failed to apply BPM information: %v", err)
+		setConditions(ctx, r.client, instance,
+			conditionTrue(bdv1.ConditionVariablesInterpolated, "", "Interpolated", "Desired manifest has been created"),
+			conditionFalse(bdv1.ConditionBPMRendered, "", "BPMApplyingError", err),
+		)
+		return reconcile.Result{}, err
+	}
+
+	// Job properties have been validated against the release job specs by the data gathering job
+	validation, err := r.propertiesConditions(ctx, instance, bpmSecret, instanceGroupName)
 	if err != nil {
 		return reconcile.Result{},
-			log.WithEvent(bpmSecret, "BPMApplyingError").Errorf(ctx, "Failed to apply BPM information: %v", err)
+			log.WithEvent(bpmSecret, "PropertyValidationReadError").Errorf(ctx, "Failed to read job property validation of instance group '%s': %v", instanceGroupName, err)
+	}
+	for _, condition := range validation {
+		if condition.Status == corev1.ConditionFalse {
+			log.Infof(ctx, "Not deploying instance group '%s': %s", instanceGroupName, condition.Message)
+			setConditions(ctx, r.client, instance, append(validation,
+				conditionTrue(bdv1.ConditionVariablesInterpolated, "", "Interpolated", "Desired manifest has been created"),
+				conditionFalse(bdv1.ConditionInstanceGroupReady, instanceGroupName, "InvalidProperties", errors.New("job properties don't match the release job specs")),
+			)...)
+			return reconcile.Result{}, nil
+		}
 	}
 
-	// Start the instance groups referenced by this BPM secret
-	instanceName, ok := bpmSecret.Labels[bdv1.LabelDeploymentName]
-	if !ok {
+	// Instance groups are deployed in manifest order, unless they are not serial
+	ready, message, err := r.instanceGroupsBeforeReady(ctx, request.Namespace, manifest, instanceGroupName)
+	if err != nil {
 		return reconcile.Result{},
-			log.WithEvent(bpmSecret, "LabelMissingError").Errorf(ctx, "Missing deployment mame label for bpm information bpmSecret '%s'", request.NamespacedName)
+			log.WithEvent(bpmSecret, "InstanceGroupOrderError").Errorf(ctx, "Failed to check instance groups deployed before '%s': %v", instanceGroupName, err)
+	}
+	if !ready {
+		log.Infof(ctx, "Delaying deployment of instance group '%s': %s", instanceGroupName, message)
+		setConditions(ctx, r.client, instance, append(validation,
+			conditionTrue(bdv1.ConditionVariablesInterpolated, "", "Interpolated", "Desired manifest has been created"),
+			conditionUnknown(bdv1.ConditionInstanceGroupReady, instanceGroupName, "Waiting", message),
+		)...)
+		return reconcile.Result{RequeueAfter: instanceGroupWaitInterval}, nil
 	}
 
 	// Deploy instance groups
-	instance := &bdv1.BOSHDeployment{}
-	err = r.client.Get(ctx, types.NamespacedName{Namespace: request.Namespace, Name: instanceName}, instance)
+	err = r.deployInstanceGroups(ctx, instance, instanceGroupName, resources)
+	if err != nil {
+		err = log.WithEvent(bpmSecret, "InstanceGroupStartError").Errorf(ctx, "Failed to start : %v", err)
+		setConditions(ctx, r.client, instance,
+			conditionTrue(bdv1.ConditionVariablesInterpolated, "", "Interpolated", "Desired manifest has been created"),
+			conditionFalse(bdv1.ConditionInstanceGroupReady, instanceGroupName, "InstanceGroupStartError", err),
+		)
+		return reconcile.Result{}, err
+	}
+
+	conditions, err := r.deploymentConditions(ctx, request.Namespace, manifest, instanceGroupName, bpmSecret.Labels[vss.LabelVersion], resources)
 	if err != nil {
 		return reconcile.Result{},
-			log.WithEvent(bpmSecret, "GetBOSHDeployment").Errorf(ctx, "Failed to get BoshDeployment instance '%s': %v", instanceName, err)
+			log.WithEvent(bpmSecret, "DeploymentStatusError").Errorf(ctx, "Failed to calculate status of instance group '%s': %v", instanceGroupName, err)
 	}
+	setConditions(ctx, r.client, instance, append(validation, conditions...)...)
 
-	err = r.deployInstanceGroups(ctx, instance, instanceGroupName, resources)
+	running, err := r.postDeploy(ctx, instance, manifest)
 	if err != nil {
 		return reconcile.Result{},
-			log.WithEvent(bpmSecret, "InstanceGroupStartError").Errorf(ctx, "Failed to start : %v", err)
+			log.WithEvent(bpmSecret, "PostDeployError").Errorf(ctx, "Failed to run post-deploy scripts of BOSHDeployment '%s': %v", boshDeploymentName, err)
+	}
+
+	// Pods are not watched, so the progress of lifecycle scripts is polled
+	for _, condition := range conditions {
+		if condition.Type == bdv1.ConditionPostStart && condition.Status != corev1.ConditionTrue {
+			running = true
+		}
+	}
+	if running {
+		return reconcile.Result{RequeueAfter: instanceGroupWaitInterval}, nil
 	}
 
 	return reconcile.Result{}, nil
 }
 
+// deploymentConditions calculates the conditions known to the BPM reconciler after
+// an instance group has been deployed: the desired manifest exists, whether data
+// gathering and BPM rendering produced secrets for all instance groups and whether
+// the instance group is ready.
+func (r *ReconcileBPM) deploymentConditions(ctx context.Context, namespace string, manifest *bdm.Manifest, instanceGroupName string, version string, resources *bdm.BPMResources) ([]bdv1.BOSHDeploymentCondition, error) {
+	conditions := []bdv1.BOSHDeploymentCondition{
+		conditionTrue(bdv1.ConditionVariablesInterpolated, "", "Interpolated", "Desired manifest has been created"),
+	}
+
+	for _, step := range []struct {
+		conditionType bdv1.BOSHDeploymentConditionType
+		secretType    names.DeploymentSecretType
+		reason        string
+	}{
+		{bdv1.ConditionDataGathered, names.DeploymentSecretTypeInstanceGroupResolvedProperties, "Gathered"},
+		{bdv1.ConditionBPMRendered, names.DeploymentSecretBpmInformation, "Rendered"},
+	} {
+		pending := []string{}
+		for _, ig := range manifest.InstanceGroups {
+			count, err := r.versionedSecretStore.VersionCount(ctx, namespace, names.CalculateIGSecretName(step.secretType, manifest.Name, ig.Name, ""))
+			if err != nil {
+				return conditions, errors.Wrapf(err, "listing %s secrets", step.secretType)
+			}
+			if count == 0 {
+				pending = append(pending, ig.Name)
+			}
+		}
+
+		if len(pending) == 0 {
+			conditions = append(conditions, conditionTrue(step.conditionType, "", step.reason, "All instance groups have been processed"))
+		} else {
+			conditions = append(conditions, conditionUnknown(step.conditionType, "", "Pending", fmt.Sprintf("Waiting for instance groups: %s", strings.Join(pending, ", "))))
+		}
+	}
+
+	ready, message, err := r.instanceGroupReady(ctx, namespace, manifest.Name, instanceGroupName, version, resources)
+	if err != nil {
+		return conditions, err
+	}
+	if ready {
+		conditions = append(conditions, conditionTrue(bdv1.ConditionInstanceGroupReady, instanceGroupName, "Ready", message))
+	} else {
+		conditions = append(conditions, conditionUnknown(bdv1.ConditionInstanceGroupReady, instanceGroupName, "Deploying", message))
+	}
+
+	if len(resources.PostStartContainers) > 0 {
+		condition, err := r.postStartCondition(ctx, namespace, manifest.Name, instanceGroupName, version, resources.PostStartContainers, ready)
+		if err != nil {
+			return conditions, err
+		}
+		conditions = append(conditions, condition)
+	}
+
+	return conditions, nil
+}
+
+// postStartCondition reports the post-start scripts of an instance group. They gate the
+// readiness of the instances, so they succeeded once the instance group is ready. Failed
+// scripts are found in the statuses of the containers, whose postStart hooks run them.
+func (r *ReconcileBPM) postStartCondition(ctx context.Context, namespace string, deploymentName string, instanceGroupName string, version string, containers []string, ready bool) (bdv1.BOSHDeploymentCondition, error) {
+	if ready {
+		return conditionTrue(bdv1.ConditionPostStart, instanceGroupName, "Succeeded", "Post-start scripts succeeded on all instances"), nil
+	}
+
+	pods := &corev1.PodList{}
+	err := r.client.List(ctx, &client.ListOptions{
+		Namespace: namespace,
+		LabelSelector: labels.SelectorFromSet(labels.Set{
+			bdm.LabelDeploymentName:    deploymentName,
+			bdm.LabelInstanceGroupName: instanceGroupName,
+			bdm.LabelDeploymentVersion: version,
+		}),
+	}, pods)
+	if err != nil {
+		return bdv1.BOSHDeploymentCondition{}, errors.Wrapf(err, "listing pods of instance group '%s'", instanceGroupName)
+	}
+
+	for _, pod := range pods.Items {
+		for _, status := range pod.Status.ContainerStatuses {
+			if !contains(containers, status.Name) || status.Ready {
+				continue
+			}
+
+			if waiting := status.State.Waiting; waiting != nil && waiting.Reason == postStartHookError {
+				err := fmt.Errorf("postStart hook of container '%s' of pod '%s' failed: %s", status.Name, pod.Name, waiting.Message)
+				return conditionFalse(bdv1.ConditionPostStart, instanceGroupName, "PostStartFailed", err), nil
+			}
+
+			terminated := status.State.Terminated
+			if terminated == nil {
+				terminated = status.LastTerminationState.Terminated
+			}
+			if terminated != nil && terminated.ExitCode != 0 {
+				err := fmt.Errorf("container '%s' of pod '%s' failed with exit code %d", status.Name, pod.Name, terminated.ExitCode)
+				return conditionFalse(bdv1.ConditionPostStart, instanceGroupName, "PostStartFailed", err), nil
+			}
+		}
+	}
+
+	return conditionUnknown(bdv1.ConditionPostStart, instanceGroupName, "Running", "Waiting for the post-start scripts of all instances"), nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// postDeploy triggers the post-deploy ExtendedJobs of the deployment, once all instance groups
+// are ready for a new revision, and reports their progress. It returns true while they run.
+func (r *ReconcileBPM) postDeploy(ctx context.Context, instance *bdv1.BOSHDeployment, manifest *bdm.Manifest) (bool, error) {
+	eJobs := &ejv1.ExtendedJobList{}
+	err := r.client.List(ctx, &client.ListOptions{
+		Namespace: instance.GetNamespace(),
+		LabelSelector: labels.SelectorFromSet(labels.Set{
+			bdm.LabelDeploymentName: manifest.Name,
+			bdm.LabelPostDeploy:     "true",
+		}),
+	}, eJobs)
+	if err != nil {
+		return false, errors.Wrap(err, "listing post-deploy ExtendedJobs")
+	}
+	if len(eJobs.Items) == 0 {
+		return false, nil
+	}
+
+	for _, ig := range manifest.InstanceGroups {
+		condition := instance.Status.GetCondition(bdv1.ConditionInstanceGroupReady, ig.Name)
+		if condition == nil || condition.Status != corev1.ConditionTrue {
+			conditions := []bdv1.BOSHDeploymentCondition{}
+			for _, eJob := range eJobs.Items {
+				conditions = append(conditions, conditionUnknown(bdv1.ConditionPostDeploy, eJob.Labels[bdm.LabelInstanceGroupName], "Waiting", "Waiting for all instance groups to be ready"))
+			}
+			setConditions(ctx, r.client, instance, conditions...)
+			return false, nil
+		}
+	}
+
+	revision, err := r.deployedRevision(ctx, instance.GetNamespace(), manifest)
+	if err != nil {
+		return false, err
+	}
+
+	// The revision is recorded once the scripts have been triggered, so they are triggered
+	// again if triggering fails
+	if instance.Status.PostDeploy == nil || instance.Status.PostDeploy.Revision != revision {
+		triggered := metav1.Now()
+		for i := range eJobs.Items {
+			eJob := &eJobs.Items[i]
+			err := r.triggerPostDeploy(ctx, eJob)
+			if err != nil {
+				return false, err
+			}
+			log.WithEvent(eJob, "PostDeployTriggered").Infof(ctx, "Triggered post-deploy ExtendedJob '%s' for revision '%s'", eJob.Name, revision)
+		}
+
+		err = updateStatus(ctx, r.client, instance, func(status *bdv1.BOSHDeploymentStatus) {
+			status.PostDeploy = &bdv1.BOSHDeploymentPostDeploy{Revision: revision, Time: triggered}
+		})
+		if err != nil {
+			return false, errors.Wrap(err, "recording post-deploy revision")
+		}
+	}
+
+	running := false
+	conditions := []bdv1.BOSHDeploymentCondition{}
+	for i := range eJobs.Items {
+		eJob := &eJobs.Items[i]
+		condition, err := r.postDeployCondition(ctx, eJob, instance.Status.PostDeploy.Time)
+		if err != nil {
+			return false, err
+		}
+		if condition.Status == corev1.ConditionUnknown {
+			running = true
+		}
+		conditions = append(conditions, condition)
+	}
+	setConditions(ctx, r.client, instance, conditions...)
+
+	return running, nil
+}
+
+// deployedRevision lists the latest BPM versions of all instance groups
+func (r *ReconcileBPM) deployedRevision(ctx context.Context, namespace string, manifest *bdm.Manifest) (string, error) {
+	versions := []string{}
+	for _, ig := range manifest.InstanceGroups {
+		version, err := r.latestBPMVersion(ctx, namespace, manifest.Name, ig.Name)
+		if err != nil {
+			return "", err
+		}
+		versions = append(versions, fmt.Sprintf("%s=%d", ig.Name, version))
+	}
+	return strings.Join(versions, ","), nil
+}
+
+// triggerPostDeploy removes the pods of previous runs and runs the post-deploy ExtendedJob now
+func (r *ReconcileBPM) triggerPostDeploy(ctx context.Context, eJob *ejv1.ExtendedJob) error {
+	pods := &corev1.PodList{}
+	err := r.client.List(ctx, &client.ListOptions{
+		Namespace:     eJob.GetNamespace(),
+		LabelSelector: labels.SelectorFromSet(labels.Set{ejv1.LabelEJobName: eJob.Name}),
+	}, pods)
+	if err != nil {
+		return errors.Wrapf(err, "listing pods of post-deploy ExtendedJob '%s'", eJob.Name)
+	}
+	for i := range pods.Items {
+		err := r.client.Delete(ctx, &pods.Items[i])
+		if err != nil && !apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "deleting pod '%s' of a previous post-deploy run", pods.Items[i].Name)
+		}
+	}
+
+	eJob.Spec.Trigger.Strategy = ejv1.TriggerNow
+	err = r.client.Update(ctx, eJob)
+	if err != nil {
+		return errors.Wrapf(err, "triggering post-deploy ExtendedJob '%s'", eJob.Name)
+	}
+	return nil
+}
+
+// postDeployCondition reports the state of the latest pod of a post-deploy ExtendedJob, which
+// has been created after the scripts were triggered
+func (r *ReconcileBPM) postDeployCondition(ctx context.Context, eJob *ejv1.ExtendedJob, since metav1.Time) (bdv1.BOSHDeploymentCondition, error) {
+	instanceGroupName := eJob.Labels[bdm.LabelInstanceGroupName]
+
+	pods := &corev1.PodList{}
+	err := r.client.List(ctx, &client.ListOptions{
+		Namespace:     eJob.GetNamespace(),
+		LabelSelector: labels.SelectorFromSet(labels.Set{ejv1.LabelEJobName: eJob.Name}),
+	}, pods)
+	if err != nil {
+		return bdv1.BOSHDeploymentCondition{}, errors.Wrapf(err, "listing pods of post-deploy ExtendedJob '%s'", eJob.Name)
+	}
+
+	// Timestamps are stored with a precision of seconds
+	after := since.Time.Truncate(time.Second)
+	var latest *corev1.Pod
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.CreationTimestamp.Time.Before(after) {
+			continue
+		}
+		if latest == nil || latest.CreationTimestamp.Before(&pod.CreationTimestamp) {
+			latest = pod
+		}
+	}
+
+	if latest == nil {
+		return conditionUnknown(bdv1.ConditionPostDeploy, instanceGroupName, "Running", "Waiting for the post-deploy pod to be created"), nil
+	}
+
+	switch latest.Status.Phase {
+	case corev1.PodSucceeded:
+		return conditionTrue(bdv1.ConditionPostDeploy, instanceGroupName, "Succeeded", fmt.Sprintf("Post-deploy scripts succeeded in pod '%s'", latest.Name)), nil
+	case corev1.PodFailed:
+		return conditionFalse(bdv1.ConditionPostDeploy, instanceGroupName, "PostDeployFailed", fmt.Errorf("post-deploy scripts failed in pod '%s'", latest.Name)), nil
+	}
+	return conditionUnknown(bdv1.ConditionPostDeploy, instanceGroupName, "Running", fmt.Sprintf("Post-deploy scripts are running in pod '%s'", latest.Name)), nil
+}
+
+// instanceGroupReady checks whether all StatefulSets of the given version of an instance group are ready.
+// Errands are ready once their ExtendedJob exists.
+func (r *ReconcileBPM) instanceGroupReady(ctx context.Context, namespace string, deploymentName string, instanceGroupName string, version string, resources *bdm.BPMResources) (bool, string, error) {
+	for _, eJob := range resources.Errands {
+		if eJob.Labels[bdm.LabelInstanceGroupName] == instanceGroupName {
+			return true, "Errand has been deployed", nil
+		}
+	}
+
+	return r.statefulSetsReady(ctx, namespace, deploymentName, instanceGroupName, version)
+}
+
+// instanceGroupsBeforeReady checks whether the instance groups, which are deployed before the given
+// instance group, are ready. Their StatefulSets have to be ready for the latest BPM secret.
+func (r *ReconcileBPM) instanceGroupsBeforeReady(ctx context.Context, namespace string, manifest *bdm.Manifest, instanceGroupName string) (bool, string, error) {
+	instanceGroups, err := manifest.InstanceGroupsBefore(instanceGroupName)
+	if err != nil {
+		return false, "", err
+	}
+
+	for _, ig := range instanceGroups {
+		latestVersion, err := r.latestBPMVersion(ctx, namespace, manifest.Name, ig.Name)
+		if err != nil {
+			return false, "", err
+		}
+		if latestVersion == 0 {
+			return false, fmt.Sprintf("Waiting for instance group '%s' to be deployed", ig.Name), nil
+		}
+
+		ready, message, err := r.statefulSetsReady(ctx, namespace, manifest.Name, ig.Name, strconv.Itoa(latestVersion))
+		if err != nil {
+			return false, "", err
+		}
+		if !ready {
+			return false, fmt.Sprintf("Waiting for instance group '%s': %s", ig.Name, message), nil
+		}
+	}
+
+	return true, "", nil
+}
+
+// latestBPMVersion returns the version of the latest BPM secret of an instance group, or 0 if there is none
+func (r *ReconcileBPM) latestBPMVersion(ctx context.Context, namespace string, deploymentName string, instanceGroupName string) (int, error) {
+	secrets, err := r.versionedSecretStore.List(ctx, namespace, names.CalculateIGSecretName(names.DeploymentSecretBpmInformation, deploymentName, instanceGroupName, ""))
+	if err != nil {
+		return 0, errors.Wrapf(err, "listing BPM secrets of instance group '%s'", instanceGroupName)
+	}
+
+	latestVersion := 0
+	for _, secret := range secrets {
+		version, err := vss.Version(secret)
+		if err != nil {
+			return 0, err
+		}
+		if version > latestVersion {
+			latestVersion = version
+		}
+	}
+	return latestVersion, nil
+}
+
+// statefulSetsReady checks whether all StatefulSets of the given version of an instance group
+// are ready and not in the middle of a rollout
+func (r *ReconcileBPM) statefulSetsReady(ctx context.Context, namespace string, deploymentName string, instanceGroupName string, version string) (bool, string, error) {
+	statefulSets := &v1beta2.StatefulSetList{}
+	err := r.client.List(ctx, &client.ListOptions{
+		Namespace: namespace,
+		LabelSelector: labels.SelectorFromSet(labels.Set{
+			bdm.LabelDeploymentName:    deploymentName,
+			bdm.LabelInstanceGroupName: instanceGroupName,
+			bdm.LabelDeploymentVersion: version,
+		}),
+	}, statefulSets)
+	if err != nil {
+		return false, "", errors.Wrapf(err, "listing StatefulSets of instance group '%s'", instanceGroupName)
+	}
+
+	if len(statefulSets.Items) == 0 {
+		return false, fmt.Sprintf("Waiting for StatefulSets of version %s", version), nil
+	}
+
+	for _, sts := range statefulSets.Items {
+		replicas := int32(1)
+		if sts.Spec.Replicas != nil {
+			replicas = *sts.Spec.Replicas
+		}
+		if sts.Status.ReadyReplicas < replicas {
+			return false, fmt.Sprintf("StatefulSet '%s' has %d of %d replicas ready", sts.Name, sts.Status.ReadyReplicas, replicas), nil
+		}
+		if state, ok := sts.Annotations[estsv1.AnnotationUpdateState]; ok && state != estsv1.UpdateStateDone {
+			return false, fmt.Sprintf("StatefulSet '%s' is in rollout state '%s'", sts.Name, state), nil
+		}
+	}
+
+	return true, fmt.Sprintf("All instances of version %s are ready", version), nil
+}
+
 func (r *ReconcileBPM) applyBPMResources(bpmSecret *corev1.Secret, manifest *bdm.Manifest) (*bdm.BPMResources, error) {
 	resources := &bdm.BPMResources{}
 	bpmConfigs := bpm.Configs{}
@@ -164,7 +611,7 @@ func (r *ReconcileBPM) applyBPMResources(bpmSecret *corev1.Secret, manifest *bdm
 		}
 	}
 
-	resources, err := r.kubeConverter.BPMResources(manifest.Name, version, instanceGroup, manifest, bpmConfigs)
+	resources, err := r.kubeConverter.BPMResources(manifest.Name, version, instanceGroup, manifest, bpmConfigs, manifest.CloudConfig, manifest.UsesDNSAddresses(), manifest.ImagePullSecrets)
 	if err != nil {
 		return resources, err
 	}
@@ -172,6 +619,40 @@ func (r *ReconcileBPM) applyBPMResources(bpmSecret *corev1.Secret, manifest *bdm
 	return resources, nil
 }
 
+// propertiesConditions returns the condition of the job property validation, which the
+// data gathering job wrote to the BPM secret. Its findings are reported as warning events,
+// unless the current condition reports them already. BPM secrets written by older versions
+// of the data gathering job have no validation, in which case no condition is returned.
+func (r *ReconcileBPM) propertiesConditions(ctx context.Context, instance *bdv1.BOSHDeployment, bpmSecret *corev1.Secret, instanceGroupName string) ([]bdv1.BOSHDeploymentCondition, error) {
+	val, ok := bpmSecret.Data["validation.yaml"]
+	if !ok {
+		return nil, nil
+	}
+
+	findings := bdm.PropertyFindings{}
+	if err := yaml.Unmarshal(val, &findings); err != nil {
+		return nil, errors.Wrapf(err, "unmarshalling validation.yaml of secret '%s'", bpmSecret.Name)
+	}
+
+	var condition bdv1.BOSHDeploymentCondition
+	if errs := findings.Errors(); len(errs) > 0 {
+		condition = conditionFalse(bdv1.ConditionPropertiesValid, instanceGroupName, "InvalidProperties", errors.New(errs.Summary(maxConditionFindings)))
+	} else if warnings := findings.Warnings(); len(warnings) > 0 {
+		condition = conditionTrue(bdv1.ConditionPropertiesValid, instanceGroupName, "Warnings", warnings.Summary(maxConditionFindings))
+	} else {
+		condition = conditionTrue(bdv1.ConditionPropertiesValid, instanceGroupName, "Valid", "Job properties match the release job specs")
+	}
+
+	existing := instance.Status.GetCondition(bdv1.ConditionPropertiesValid, instanceGroupName)
+	if existing == nil || existing.Message != condition.Message {
+		for _, f := range findings {
+			log.WarningEvent(ctx, instance, "PropertyValidation", f.String())
+		}
+	}
+
+	return []bdv1.BOSHDeploymentCondition{condition}, nil
+}
+
 // deployInstanceGroups create or update ExtendedJobs and ExtendedStatefulSets for instance groups
 func (r *ReconcileBPM) deployInstanceGroups(ctx context.Context, instance *bdv1.BOSHDeployment, instanceGroupName string, resources *bdm.BPMResources) error {
 	log.Debugf(ctx, "Creating extendedJobs and extendedStatefulSets for instance group '%s'", instanceGroupName)
@@ -201,6 +682,33 @@ func (r *ReconcileBPM) deployInstanceGroups(ctx context.Context, instance *bdv1.
 		log.Debugf(ctx, "ExtendedJob '%s' has been %s", eJob.Name, op)
 	}
 
+	for _, eJob := range resources.PostDeploys {
+		if eJob.Labels[bdm.LabelInstanceGroupName] != instanceGroupName {
+			continue
+		}
+
+		if err := r.setReference(instance, &eJob, r.scheme); err != nil {
+			return log.WithEvent(instance, "ExtendedJobForDeploymentError").Errorf(ctx, "Failed to set reference for post-deploy ExtendedJob instance group '%s' : %v", instanceGroupName, err)
+		}
+
+		op, err := controllerutil.CreateOrUpdate(ctx, r.client, eJob.DeepCopy(), func(obj runtime.Object) error {
+			if existingEJob, ok := obj.(*ejv1.ExtendedJob); ok {
+				// The trigger is managed by the post-deploy step
+				eJob.Spec.Trigger = existingEJob.Spec.Trigger
+				eJob.ObjectMeta.ResourceVersion = existingEJob.ObjectMeta.ResourceVersion
+				eJob.DeepCopyInto(existingEJob)
+
+				return nil
+			}
+			return fmt.Errorf("object is not an ExtendedJob")
+		})
+		if err != nil {
+			return log.WithEvent(instance, "ApplyExtendedJobError").Errorf(ctx, "Failed to apply post-deploy ExtendedJob for instance group '%s' : %v", instanceGroupName, err)
+		}
+
+		log.Debugf(ctx, "Post-deploy ExtendedJob '%s' has been %s", eJob.Name, op)
+	}
+
 	for _, svc := range resources.Services {
 		if svc.Labels[bdm.LabelInstanceGroupName] != instanceGroupName {
 			continue
@@ -210,6 +718,10 @@ func (r *ReconcileBPM) deployInstanceGroups(ctx context.Context, instance *bdv1.
 			return log.WithEvent(instance, "ServiceForDeploymentError").Errorf(ctx, "Failed to set reference for Service instance group '%s' : %v", instanceGroupName, err)
 		}
 
+		if err := r.deleteServiceWithChangedClusterIP(ctx, &svc); err != nil {
+			return log.WithEvent(instance, "ApplyServiceError").Errorf(ctx, "Failed to replace Service for instance group '%s' : %v", instanceGroupName, err)
+		}
+
 		op, err := controllerutil.CreateOrUpdate(ctx, r.client, svc.DeepCopy(), func(obj runtime.Object) error {
 			if existingSvc, ok := obj.(*corev1.Service); ok {
 				// Should keep current ClusterIP and ResourceVersion when update
@@ -266,6 +778,33 @@ func (r *ReconcileBPM) deployInstanceGroups(ctx context.Context, instance *bdv1.
 	return nil
 }
 
+// deleteServiceWithChangedClusterIP deletes the existing service, if it has to change
+// between headless and having a cluster IP, e.g. because features.use_dns_addresses
+// changed. The cluster IP of a service is immutable, so it has to be created again.
+func (r *ReconcileBPM) deleteServiceWithChangedClusterIP(ctx context.Context, svc *corev1.Service) error {
+	existing := &corev1.Service{}
+	err := r.client.Get(ctx, types.NamespacedName{Name: svc.Name, Namespace: svc.Namespace}, existing)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed to get service '%s'", svc.Name)
+	}
+
+	if existing.Spec.ClusterIP == "" ||
+		(existing.Spec.ClusterIP == corev1.ClusterIPNone) == (svc.Spec.ClusterIP == corev1.ClusterIPNone) {
+		return nil
+	}
+
+	log.Infof(ctx, "Deleting service '%s', its cluster IP '%s' can't be changed", svc.Name, existing.Spec.ClusterIP)
+	err = r.client.Delete(ctx, existing)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return errors.Wrapf(err, "failed to delete service '%s'", svc.Name)
+	}
+
+	return nil
+}
+
 func (r *ReconcileBPM) createPersistentVolumeClaim(ctx context.Context, persistentVolumeClaim *corev1.PersistentVolumeClaim) error {
 	log.Debugf(ctx, "Creating persistentVolumeClaim '%s'", persistentVolumeClaim.Name)
 
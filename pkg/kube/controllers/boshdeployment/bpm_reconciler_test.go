@@ -3,11 +3,13 @@ package boshdeployment_test
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"go.uber.org/zap"
+	"k8s.io/api/apps/v1beta2"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -28,6 +30,7 @@ import (
 	"code.cloudfoundry.org/cf-operator/pkg/kube/controllers"
 	cfd "code.cloudfoundry.org/cf-operator/pkg/kube/controllers/boshdeployment"
 	cfakes "code.cloudfoundry.org/cf-operator/pkg/kube/controllers/fakes"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util"
 	cfcfg "code.cloudfoundry.org/cf-operator/pkg/kube/util/config"
 	"code.cloudfoundry.org/cf-operator/pkg/kube/util/ctxlog"
 	"code.cloudfoundry.org/cf-operator/pkg/kube/util/versionedsecretstore"
@@ -46,6 +49,7 @@ var _ = Describe("ReconcileBPM", func() {
 		log                       *zap.SugaredLogger
 		config                    *cfcfg.Config
 		client                    *cfakes.FakeClient
+		statusWriter              *cfakes.FakeStatusWriter
 		manifestWithVars          *corev1.Secret
 		bpmInformation            *corev1.Secret
 		bpmInformationNoProcesses *corev1.Secret
@@ -193,6 +197,8 @@ variables: []
 		}
 
 		client = &cfakes.FakeClient{}
+		statusWriter = &cfakes.FakeStatusWriter{}
+		client.StatusReturns(statusWriter)
 		client.GetCalls(func(context context.Context, nn types.NamespacedName, object runtime.Object) error {
 			switch object := object.(type) {
 			case *corev1.Secret:
@@ -310,6 +316,492 @@ variables: []
 				err = client.Get(context.Background(), types.NamespacedName{Name: "foo", Namespace: "default"}, newInstance)
 				Expect(err).ToNot(HaveOccurred())
 			})
+
+			It("recreates the services of instances, whose cluster IP has to change", func() {
+				useDNSAddresses := false
+				manifest.Features = &bdm.Feature{UseDNSAddresses: &useDNSAddresses}
+				client.GetCalls(func(context context.Context, nn types.NamespacedName, object runtime.Object) error {
+					switch object := object.(type) {
+					case *corev1.Secret:
+						if nn.Name == manifestWithVars.Name {
+							manifestWithVars.DeepCopyInto(object)
+						}
+						if nn.Name == bpmInformation.Name {
+							bpmInformation.DeepCopyInto(object)
+						}
+					case *corev1.Service:
+						object.Name = nn.Name
+						object.Spec.ClusterIP = "10.0.0.1"
+						if nn.Name == "fake-manifest-fakepod" {
+							object.Spec.ClusterIP = corev1.ClusterIPNone
+						}
+					}
+
+					return nil
+				})
+
+				_, err := reconciler.Reconcile(request)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(client.DeleteCallCount()).To(Equal(1))
+				_, object, _ := client.DeleteArgsForCall(0)
+				Expect(object.(*corev1.Service).Name).To(Equal("fake-manifest-fakepod-0"))
+			})
+
+			It("records the error when the desired manifest can't be read", func() {
+				resolver.DesiredManifestReturns(nil, errors.New("fake-error"))
+				reconciler = cfd.NewBPMReconciler(ctx, config, manager, &resolver,
+					controllerutil.SetControllerReference, bdm.NewKubeConverter(config.Namespace),
+				)
+
+				_, err := reconciler.Reconcile(request)
+				Expect(err).To(HaveOccurred())
+
+				Expect(statusWriter.UpdateCallCount()).To(Equal(1))
+				_, object := statusWriter.UpdateArgsForCall(0)
+				condition := object.(*bdv1.BOSHDeployment).Status.GetCondition(bdv1.ConditionVariablesInterpolated, "")
+				Expect(condition.Status).To(Equal(corev1.ConditionFalse))
+				Expect(condition.LastError).To(ContainSubstring("fake-error"))
+			})
+		})
+
+		Context("when the deployment is paused", func() {
+			BeforeEach(func() {
+				client.GetCalls(func(context context.Context, nn types.NamespacedName, object runtime.Object) error {
+					switch object := object.(type) {
+					case *bdv1.BOSHDeployment:
+						object.Spec.Paused = true
+					case *corev1.Secret:
+						bpmInformation.DeepCopyInto(object)
+					}
+					return nil
+				})
+			})
+
+			It("does not deploy the instance group until the deployment is resumed", func() {
+				result, err := reconciler.Reconcile(request)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.RequeueAfter).To(BeNumerically(">", 0))
+				Expect(resolver.DesiredManifestCallCount()).To(Equal(0))
+				Expect(client.CreateCallCount()).To(Equal(0))
+				Expect(client.UpdateCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when the job properties have been validated", func() {
+			It("doesn't deploy the instance group with invalid properties", func() {
+				bpmInformation.Data["validation.yaml"] = []byte(`- instance_group: fakepod
+  job: foo
+  property: foo.port
+  severity: error
+  message: must be of kind 'scalar', but is of kind 'hash'`)
+
+				result, err := reconciler.Reconcile(request)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result).To(Equal(reconcile.Result{}))
+				Expect(client.CreateCallCount()).To(Equal(0))
+				Expect(client.UpdateCallCount()).To(Equal(0))
+
+				_, object := statusWriter.UpdateArgsForCall(0)
+				status := object.(*bdv1.BOSHDeployment).Status
+				condition := status.GetCondition(bdv1.ConditionPropertiesValid, "fakepod")
+				Expect(condition.Status).To(Equal(corev1.ConditionFalse))
+				Expect(condition.Reason).To(Equal("InvalidProperties"))
+				Expect(condition.LastError).To(Equal("job 'foo' of instance group 'fakepod': property 'foo.port' must be of kind 'scalar', but is of kind 'hash'"))
+				Expect(status.GetCondition(bdv1.ConditionInstanceGroupReady, "fakepod").Status).To(Equal(corev1.ConditionFalse))
+				Expect(<-recorder.Events).To(ContainSubstring("PropertyValidation"))
+			})
+
+			It("deploys the instance group with warnings", func() {
+				bpmInformation.Data["validation.yaml"] = []byte(`- instance_group: fakepod
+  job: foo
+  property: foo.prot
+  severity: warning
+  message: is not defined in the spec of the job`)
+
+				_, err := reconciler.Reconcile(request)
+				Expect(err).NotTo(HaveOccurred())
+
+				_, object := statusWriter.UpdateArgsForCall(0)
+				status := object.(*bdv1.BOSHDeployment).Status
+				Expect(status.GetCondition(bdv1.ConditionInstanceGroupReady, "fakepod").Status).NotTo(Equal(corev1.ConditionFalse))
+				condition := status.GetCondition(bdv1.ConditionPropertiesValid, "fakepod")
+				Expect(condition.Status).To(Equal(corev1.ConditionTrue))
+				Expect(condition.Reason).To(Equal("Warnings"))
+				Expect(condition.Message).To(ContainSubstring("property 'foo.prot' is not defined in the spec of the job"))
+				Expect(<-recorder.Events).To(ContainSubstring("PropertyValidation"))
+			})
+
+			It("lists only the first findings in the condition", func() {
+				findings := ""
+				for i := 0; i < 7; i++ {
+					findings += fmt.Sprintf(`- instance_group: fakepod
+  job: foo
+  property: foo.prop%d
+  severity: warning
+  message: is not defined in the spec of the job
+`, i)
+				}
+				bpmInformation.Data["validation.yaml"] = []byte(findings)
+
+				_, err := reconciler.Reconcile(request)
+				Expect(err).NotTo(HaveOccurred())
+
+				_, object := statusWriter.UpdateArgsForCall(0)
+				condition := object.(*bdv1.BOSHDeployment).Status.GetCondition(bdv1.ConditionPropertiesValid, "fakepod")
+				Expect(condition.Message).To(ContainSubstring("property 'foo.prop4'"))
+				Expect(condition.Message).NotTo(ContainSubstring("property 'foo.prop5'"))
+				Expect(condition.Message).To(HaveSuffix("; and 2 more"))
+			})
+
+			It("reports valid properties", func() {
+				bpmInformation.Data["validation.yaml"] = []byte(`[]`)
+
+				_, err := reconciler.Reconcile(request)
+				Expect(err).NotTo(HaveOccurred())
+
+				_, object := statusWriter.UpdateArgsForCall(0)
+				condition := object.(*bdv1.BOSHDeployment).Status.GetCondition(bdv1.ConditionPropertiesValid, "fakepod")
+				Expect(condition.Status).To(Equal(corev1.ConditionTrue))
+				Expect(condition.Reason).To(Equal("Valid"))
+				Expect(recorder.Events).To(BeEmpty())
+			})
+		})
+
+		Context("when a previous instance group is deployed serially", func() {
+			BeforeEach(func() {
+				manifest.Name = "foo"
+				manifest.InstanceGroups = append([]*bdm.InstanceGroup{{Name: "db", Instances: 1}}, manifest.InstanceGroups...)
+			})
+
+			It("waits for the previous instance group", func() {
+				result, err := reconciler.Reconcile(request)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.RequeueAfter).To(BeNumerically(">", 0))
+				Expect(client.CreateCallCount()).To(Equal(0))
+
+				_, object := statusWriter.UpdateArgsForCall(0)
+				condition := object.(*bdv1.BOSHDeployment).Status.GetCondition(bdv1.ConditionInstanceGroupReady, "fakepod")
+				Expect(condition.Status).To(Equal(corev1.ConditionUnknown))
+				Expect(condition.Reason).To(Equal("Waiting"))
+				Expect(condition.Message).To(Equal("Waiting for instance group 'db' to be deployed"))
+			})
+
+			It("deploys the instance group once the previous instance group is ready", func() {
+				dbBPMInformation := bpmInformation.DeepCopy()
+				dbBPMInformation.Name = "foo.bpm.db-v2"
+				dbBPMInformation.Labels[versionedsecretstore.LabelVersion] = "2"
+				client.ListCalls(func(context context.Context, options *crc.ListOptions, object runtime.Object) error {
+					switch object := object.(type) {
+					case *corev1.SecretList:
+						secretList := corev1.SecretList{}
+						secretList.Items = []corev1.Secret{
+							*manifestWithVars,
+							*bpmInformation,
+							*dbBPMInformation,
+						}
+						secretList.DeepCopyInto(object)
+					case *v1beta2.StatefulSetList:
+						statefulSetList := v1beta2.StatefulSetList{
+							Items: []v1beta2.StatefulSet{
+								{
+									ObjectMeta: metav1.ObjectMeta{Name: "foo-db-v1"},
+									Spec:       v1beta2.StatefulSetSpec{Replicas: util.Int32(1)},
+									Status:     v1beta2.StatefulSetStatus{ReadyReplicas: 1},
+								},
+							},
+						}
+						statefulSetList.DeepCopyInto(object)
+					}
+
+					return nil
+				})
+
+				result, err := reconciler.Reconcile(request)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result).To(Equal(reconcile.Result{}))
+
+				_, object := statusWriter.UpdateArgsForCall(0)
+				condition := object.(*bdv1.BOSHDeployment).Status.GetCondition(bdv1.ConditionInstanceGroupReady, "fakepod")
+				Expect(condition.Reason).NotTo(Equal("Waiting"))
+			})
+
+			It("doesn't wait when the instance groups are not serial", func() {
+				parallel := false
+				manifest.Update = &bdm.Update{Serial: &parallel}
+
+				result, err := reconciler.Reconcile(request)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result).To(Equal(reconcile.Result{}))
+
+				_, object := statusWriter.UpdateArgsForCall(0)
+				condition := object.(*bdv1.BOSHDeployment).Status.GetCondition(bdv1.ConditionInstanceGroupReady, "fakepod")
+				Expect(condition.Reason).NotTo(Equal("Waiting"))
+			})
+		})
+
+		Context("when the instance group has been deployed", func() {
+			var (
+				statefulSet v1beta2.StatefulSet
+				pods        []corev1.Pod
+				postDeploys []ejv1.ExtendedJob
+			)
+
+			BeforeEach(func() {
+				manifest.Name = "foo"
+				pods = []corev1.Pod{}
+				postDeploys = []ejv1.ExtendedJob{}
+				statefulSet = v1beta2.StatefulSet{
+					ObjectMeta: metav1.ObjectMeta{Name: "foo-fakepod-v1"},
+					Spec: v1beta2.StatefulSetSpec{
+						Replicas: util.Int32(1),
+					},
+				}
+
+				client.ListCalls(func(context context.Context, options *crc.ListOptions, object runtime.Object) error {
+					switch object := object.(type) {
+					case *corev1.SecretList:
+						secretList := corev1.SecretList{}
+						secretList.Items = []corev1.Secret{
+							*manifestWithVars,
+							*bpmInformation,
+						}
+						secretList.DeepCopyInto(object)
+					case *v1beta2.StatefulSetList:
+						Expect(options.LabelSelector.String()).To(ContainSubstring("fissile.cloudfoundry.org/deployment-version=1"))
+						statefulSetList := v1beta2.StatefulSetList{
+							Items: []v1beta2.StatefulSet{statefulSet},
+						}
+						statefulSetList.DeepCopyInto(object)
+					case *corev1.PodList:
+						podList := corev1.PodList{Items: pods}
+						podList.DeepCopyInto(object)
+					case *ejv1.ExtendedJobList:
+						eJobList := ejv1.ExtendedJobList{Items: postDeploys}
+						eJobList.DeepCopyInto(object)
+					}
+
+					return nil
+				})
+			})
+
+			It("reports the instance group as deploying until its StatefulSets are ready", func() {
+				_, err := reconciler.Reconcile(request)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(statusWriter.UpdateCallCount()).To(Equal(1))
+				_, object := statusWriter.UpdateArgsForCall(0)
+				status := object.(*bdv1.BOSHDeployment).Status
+				Expect(status.GetCondition(bdv1.ConditionVariablesInterpolated, "").Status).To(Equal(corev1.ConditionTrue))
+				Expect(status.GetCondition(bdv1.ConditionBPMRendered, "").Status).To(Equal(corev1.ConditionTrue))
+				Expect(status.GetCondition(bdv1.ConditionDataGathered, "").Status).To(Equal(corev1.ConditionUnknown))
+
+				condition := status.GetCondition(bdv1.ConditionInstanceGroupReady, "fakepod")
+				Expect(condition.Status).To(Equal(corev1.ConditionUnknown))
+				Expect(condition.Message).To(Equal("StatefulSet 'foo-fakepod-v1' has 0 of 1 replicas ready"))
+			})
+
+			It("reports the instance group as ready", func() {
+				statefulSet.Status.ReadyReplicas = 1
+
+				_, err := reconciler.Reconcile(request)
+				Expect(err).NotTo(HaveOccurred())
+
+				_, object := statusWriter.UpdateArgsForCall(0)
+				condition := object.(*bdv1.BOSHDeployment).Status.GetCondition(bdv1.ConditionInstanceGroupReady, "fakepod")
+				Expect(condition.Status).To(Equal(corev1.ConditionTrue))
+			})
+
+			Context("when the jobs have post-start scripts", func() {
+				BeforeEach(func() {
+					bpmInformation.Data["bpm.yaml"] = append(bpmInformation.Data["bpm.yaml"], []byte("\n  post_start: true")...)
+				})
+
+				It("reports the post-start scripts as running until the instance group is ready", func() {
+					result, err := reconciler.Reconcile(request)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(result.RequeueAfter).To(BeNumerically(">", 0))
+
+					_, object := statusWriter.UpdateArgsForCall(0)
+					condition := object.(*bdv1.BOSHDeployment).Status.GetCondition(bdv1.ConditionPostStart, "fakepod")
+					Expect(condition.Status).To(Equal(corev1.ConditionUnknown))
+					Expect(condition.Reason).To(Equal("Running"))
+				})
+
+				It("reports failed post-start scripts", func() {
+					pods = []corev1.Pod{
+						{
+							ObjectMeta: metav1.ObjectMeta{Name: "foo-fakepod-v1-0"},
+							Status: corev1.PodStatus{
+								ContainerStatuses: []corev1.ContainerStatus{
+									{
+										Name: "foo-fake",
+										LastTerminationState: corev1.ContainerState{
+											Terminated: &corev1.ContainerStateTerminated{ExitCode: 1},
+										},
+									},
+								},
+							},
+						},
+					}
+
+					_, err := reconciler.Reconcile(request)
+					Expect(err).NotTo(HaveOccurred())
+
+					_, object := statusWriter.UpdateArgsForCall(0)
+					condition := object.(*bdv1.BOSHDeployment).Status.GetCondition(bdv1.ConditionPostStart, "fakepod")
+					Expect(condition.Status).To(Equal(corev1.ConditionFalse))
+					Expect(condition.Reason).To(Equal("PostStartFailed"))
+					Expect(condition.Message).To(ContainSubstring("container 'foo-fake' of pod 'foo-fakepod-v1-0' failed with exit code 1"))
+				})
+
+				It("reports failed postStart hooks", func() {
+					pods = []corev1.Pod{
+						{
+							ObjectMeta: metav1.ObjectMeta{Name: "foo-fakepod-v1-0"},
+							Status: corev1.PodStatus{
+								ContainerStatuses: []corev1.ContainerStatus{
+									{
+										Name: "foo-fake",
+										State: corev1.ContainerState{
+											Waiting: &corev1.ContainerStateWaiting{Reason: "PostStartHookError", Message: "exit status 1"},
+										},
+									},
+								},
+							},
+						},
+					}
+
+					_, err := reconciler.Reconcile(request)
+					Expect(err).NotTo(HaveOccurred())
+
+					_, object := statusWriter.UpdateArgsForCall(0)
+					condition := object.(*bdv1.BOSHDeployment).Status.GetCondition(bdv1.ConditionPostStart, "fakepod")
+					Expect(condition.Status).To(Equal(corev1.ConditionFalse))
+					Expect(condition.Message).To(ContainSubstring("postStart hook of container 'foo-fake' of pod 'foo-fakepod-v1-0' failed: exit status 1"))
+				})
+
+				It("reports the post-start scripts as succeeded once the instance group is ready", func() {
+					statefulSet.Status.ReadyReplicas = 1
+
+					result, err := reconciler.Reconcile(request)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(result).To(Equal(reconcile.Result{}))
+
+					_, object := statusWriter.UpdateArgsForCall(0)
+					condition := object.(*bdv1.BOSHDeployment).Status.GetCondition(bdv1.ConditionPostStart, "fakepod")
+					Expect(condition.Status).To(Equal(corev1.ConditionTrue))
+				})
+			})
+
+			Context("when the jobs have post-deploy scripts", func() {
+				BeforeEach(func() {
+					statefulSet.Status.ReadyReplicas = 1
+					postDeploys = []ejv1.ExtendedJob{
+						{
+							ObjectMeta: metav1.ObjectMeta{
+								Name:      "foo-fakepod-post-deploy",
+								Namespace: "default",
+								Labels: map[string]string{
+									bdm.LabelDeploymentName:    "foo",
+									bdm.LabelInstanceGroupName: "fakepod",
+									bdm.LabelPostDeploy:        "true",
+								},
+							},
+							Spec: ejv1.ExtendedJobSpec{
+								Trigger: ejv1.Trigger{Strategy: ejv1.TriggerManual},
+							},
+						},
+					}
+				})
+
+				It("triggers the post-deploy ExtendedJobs once all instance groups are ready", func() {
+					result, err := reconciler.Reconcile(request)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(result.RequeueAfter).To(BeNumerically(">", 0))
+
+					Expect(client.UpdateCallCount()).To(Equal(1))
+					_, object := client.UpdateArgsForCall(0)
+					Expect(object.(*ejv1.ExtendedJob).Spec.Trigger.Strategy).To(Equal(ejv1.TriggerNow))
+
+					_, object = statusWriter.UpdateArgsForCall(0)
+					status := object.(*bdv1.BOSHDeployment).Status
+					Expect(status.PostDeploy.Revision).To(Equal("fakepod=1"))
+					condition := status.GetCondition(bdv1.ConditionPostDeploy, "fakepod")
+					Expect(condition.Status).To(Equal(corev1.ConditionUnknown))
+					Expect(condition.Reason).To(Equal("Running"))
+				})
+
+				It("doesn't trigger the post-deploy ExtendedJobs again for the same revision", func() {
+					_, err := reconciler.Reconcile(request)
+					Expect(err).NotTo(HaveOccurred())
+					_, object := statusWriter.UpdateArgsForCall(0)
+					triggered := object.(*bdv1.BOSHDeployment).Status.PostDeploy.Time
+
+					client.GetCalls(func(context context.Context, nn types.NamespacedName, object runtime.Object) error {
+						switch object := object.(type) {
+						case *bdv1.BOSHDeployment:
+							object.Status.PostDeploy = &bdv1.BOSHDeploymentPostDeploy{Revision: "fakepod=1", Time: triggered}
+						case *corev1.Secret:
+							if nn.Name == manifestWithVars.Name {
+								manifestWithVars.DeepCopyInto(object)
+							}
+							if nn.Name == bpmInformation.Name {
+								bpmInformation.DeepCopyInto(object)
+							}
+						}
+						return nil
+					})
+					pods = []corev1.Pod{
+						{
+							ObjectMeta: metav1.ObjectMeta{Name: "foo-fakepod-post-deploy-abcde", CreationTimestamp: triggered},
+							Status:     corev1.PodStatus{Phase: corev1.PodSucceeded},
+						},
+					}
+
+					result, err := reconciler.Reconcile(request)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(result).To(Equal(reconcile.Result{}))
+					Expect(client.UpdateCallCount()).To(Equal(1))
+
+					_, object = statusWriter.UpdateArgsForCall(statusWriter.UpdateCallCount() - 1)
+					condition := object.(*bdv1.BOSHDeployment).Status.GetCondition(bdv1.ConditionPostDeploy, "fakepod")
+					Expect(condition.Status).To(Equal(corev1.ConditionTrue))
+				})
+
+				It("records the revision only once the post-deploy ExtendedJobs have been triggered", func() {
+					client.UpdateReturnsOnCall(0, fmt.Errorf("conflict"))
+
+					_, err := reconciler.Reconcile(request)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("triggering post-deploy ExtendedJob 'foo-fakepod-post-deploy'"))
+					for i := 0; i < statusWriter.UpdateCallCount(); i++ {
+						_, object := statusWriter.UpdateArgsForCall(i)
+						Expect(object.(*bdv1.BOSHDeployment).Status.PostDeploy).To(BeNil())
+					}
+
+					_, err = reconciler.Reconcile(request)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(client.UpdateCallCount()).To(Equal(2))
+					_, object := client.UpdateArgsForCall(1)
+					Expect(object.(*ejv1.ExtendedJob).Spec.Trigger.Strategy).To(Equal(ejv1.TriggerNow))
+
+					_, object = statusWriter.UpdateArgsForCall(statusWriter.UpdateCallCount() - 1)
+					Expect(object.(*bdv1.BOSHDeployment).Status.PostDeploy.Revision).To(Equal("fakepod=1"))
+				})
+
+				It("waits for all instance groups to be ready", func() {
+					statefulSet.Status.ReadyReplicas = 0
+
+					_, err := reconciler.Reconcile(request)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(client.UpdateCallCount()).To(Equal(0))
+
+					_, object := statusWriter.UpdateArgsForCall(0)
+					condition := object.(*bdv1.BOSHDeployment).Status.GetCondition(bdv1.ConditionPostDeploy, "fakepod")
+					Expect(condition.Reason).To(Equal("Waiting"))
+				})
+			})
 		})
 	})
 })
@@ -5,6 +5,8 @@ import (
 	"reflect"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/event"
@@ -37,8 +39,19 @@ func AddDeployment(ctx context.Context, config *config.Config, mgr manager.Manag
 		return err
 	}
 
-	// Watch for changes to primary resource BOSHDeployment
-	err = c.Watch(&source.Kind{Type: &bdv1.BOSHDeployment{}}, &handler.EnqueueRequestForObject{})
+	// Watch for changes to primary resource BOSHDeployment, but ignore updates of its status
+	// which are written by the reconcilers of this package
+	deploymentPredicates := predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldBdpl := e.ObjectOld.(*bdv1.BOSHDeployment)
+			newBdpl := e.ObjectNew.(*bdv1.BOSHDeployment)
+
+			return !reflect.DeepEqual(oldBdpl.Spec, newBdpl.Spec) ||
+				!reflect.DeepEqual(oldBdpl.GetAnnotations(), newBdpl.GetAnnotations()) ||
+				!reflect.DeepEqual(oldBdpl.GetDeletionTimestamp(), newBdpl.GetDeletionTimestamp())
+		},
+	}
+	err = c.Watch(&source.Kind{Type: &bdv1.BOSHDeployment{}}, &handler.EnqueueRequestForObject{}, deploymentPredicates)
 	if err != nil {
 		return err
 	}
@@ -145,5 +158,78 @@ func AddDeployment(ctx context.Context, config *config.Config, mgr manager.Manag
 		return err
 	}
 
+	// Watch RuntimeConfigs, their addons can apply to any BOSHDeployment.
+	// Only the deployments, whose with-ops manifest changes, are redeployed.
+	runtimeConfigPredicates := predicate.Funcs{
+		GenericFunc: func(e event.GenericEvent) bool { return false },
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldRuntimeConfig := e.ObjectOld.(*bdv1.RuntimeConfig)
+			newRuntimeConfig := e.ObjectNew.(*bdv1.RuntimeConfig)
+
+			return !reflect.DeepEqual(oldRuntimeConfig.Spec, newRuntimeConfig.Spec)
+		},
+	}
+	err = c.Watch(&source.Kind{Type: &bdv1.RuntimeConfig{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(func(a handler.MapObject) []reconcile.Request {
+			deployments := &bdv1.BOSHDeploymentList{}
+			err := mgr.GetClient().List(ctx, &client.ListOptions{Namespace: config.Namespace}, deployments)
+			if err != nil {
+				ctxlog.Errorf(ctx, "Failed to list BOSHDeployments for runtime config '%s': %v", a.Meta.GetName(), err)
+				return []reconcile.Request{}
+			}
+
+			reconciles := []reconcile.Request{}
+			for _, deployment := range deployments.Items {
+				reconciliation := reconcile.Request{NamespacedName: types.NamespacedName{
+					Name:      deployment.Name,
+					Namespace: deployment.Namespace,
+				}}
+				ctxlog.NewMappingEvent(a.Object).Debug(ctx, reconciliation, "BOSHDeployment", a.Meta.GetName(), "runtimeconfig")
+				reconciles = append(reconciles, reconciliation)
+			}
+
+			return reconciles
+		}),
+	}, runtimeConfigPredicates)
+	if err != nil {
+		return err
+	}
+
+	// Watch CloudConfigs, they are referenced by all BOSHDeployments in their namespace
+	cloudConfigPredicates := predicate.Funcs{
+		GenericFunc: func(e event.GenericEvent) bool { return false },
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldCloudConfig := e.ObjectOld.(*bdv1.CloudConfig)
+			newCloudConfig := e.ObjectNew.(*bdv1.CloudConfig)
+
+			return !reflect.DeepEqual(oldCloudConfig.Spec, newCloudConfig.Spec)
+		},
+	}
+	err = c.Watch(&source.Kind{Type: &bdv1.CloudConfig{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(func(a handler.MapObject) []reconcile.Request {
+			deployments := &bdv1.BOSHDeploymentList{}
+			err := mgr.GetClient().List(ctx, &client.ListOptions{Namespace: a.Meta.GetNamespace()}, deployments)
+			if err != nil {
+				ctxlog.Errorf(ctx, "Failed to list BOSHDeployments for cloud config '%s': %v", a.Meta.GetName(), err)
+				return []reconcile.Request{}
+			}
+
+			reconciles := []reconcile.Request{}
+			for _, deployment := range deployments.Items {
+				reconciliation := reconcile.Request{NamespacedName: types.NamespacedName{
+					Name:      deployment.Name,
+					Namespace: deployment.Namespace,
+				}}
+				ctxlog.NewMappingEvent(a.Object).Debug(ctx, reconciliation, "BOSHDeployment", a.Meta.GetName(), "cloudconfig")
+				reconciles = append(reconciles, reconciliation)
+			}
+
+			return reconciles
+		}),
+	}, cloudConfigPredicates)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
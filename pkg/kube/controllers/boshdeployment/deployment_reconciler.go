@@ -4,11 +4,14 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+	"time"
 
+	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
@@ -22,8 +25,13 @@ import (
 	log "code.cloudfoundry.org/cf-operator/pkg/kube/util/ctxlog"
 	"code.cloudfoundry.org/cf-operator/pkg/kube/util/names"
 	"code.cloudfoundry.org/cf-operator/pkg/kube/util/owner"
+	vss "code.cloudfoundry.org/cf-operator/pkg/kube/util/versionedsecretstore"
 )
 
+// urlPollInterval is the interval in which url refs are downloaded again. The
+// deployment is updated only if their content has changed.
+const urlPollInterval = 5 * time.Minute
+
 // Check that ReconcileBOSHDeployment implements the reconcile.Reconciler interface
 var _ reconcile.Reconciler = &ReconcileBOSHDeployment{}
 
@@ -36,7 +44,7 @@ type Owner interface {
 }
 
 type Resolver interface {
-	WithOpsManifest(instance *bdv1.BOSHDeployment, namespace string) (*bdm.Manifest, error)
+	WithOpsManifest(instance *bdv1.BOSHDeployment, namespace string) (*bdm.Manifest, []bdv1.ResolvedCommit, error)
 }
 
 // NewDeploymentReconciler returns a new reconcile.Reconciler
@@ -50,6 +58,8 @@ func NewDeploymentReconciler(ctx context.Context, config *config.Config, mgr man
 		resolver:     resolver,
 		setReference: srf,
 		owner:        owner.NewOwner(mgr.GetClient(), mgr.GetScheme()),
+
+		versionedSecretStore: vss.NewVersionedSecretStore(mgr.GetClient()),
 	}
 }
 
@@ -62,6 +72,8 @@ type ReconcileBOSHDeployment struct {
 	resolver     Resolver
 	setReference setReferenceFunc
 	owner        Owner
+
+	versionedSecretStore vss.VersionedSecretStore
 }
 
 // Reconcile starts the deployment process for a BOSHDeployment and deploys ExtendedJobs to generate required properties for instance groups and rendered BPM
@@ -88,81 +100,217 @@ func (r *ReconcileBOSHDeployment) Reconcile(request reconcile.Request) (reconcil
 			log.WithEvent(instance, "GetBOSHDeploymentError").Errorf(ctx, "Failed to get BOSHDeployment '%s': %v", request.NamespacedName, err)
 	}
 
+	if instance.Spec.Paused {
+		log.WithEvent(instance, "Paused").Infof(ctx, "Skip reconcile: BOSHDeployment '%s' is paused", request.NamespacedName)
+		err = updateStatus(ctx, r.client, instance, func(status *bdv1.BOSHDeploymentStatus) {
+			status.SetPaused(true)
+		})
+		if err != nil {
+			log.WithEvent(instance, "UpdateStatusError").Errorf(ctx, "Failed to update status of BOSHDeployment '%s': %v", request.NamespacedName, err)
+		}
+		return reconcile.Result{}, nil
+	}
+
+	// Changes to variables, which were made while paused, have been ignored.
+	// Running the variable interpolation again applies all of them at once.
+	resuming := instance.Status.Paused
+
+	// A rollback reuses a previous desired manifest, so ops files are not resolved
+	if version, ok := instance.GetAnnotations()[bdv1.AnnotationRollbackVersion]; ok {
+		return r.rollback(ctx, instance, version)
+	}
+
 	// Apply the "with-ops" manifest secret
 	log.Debug(ctx, "Creating with-ops manifest Secret")
-	manifest, err := r.createManifestWithOps(ctx, instance)
+	manifest, commits, op, err := r.createManifestWithOps(ctx, instance)
 	if err != nil {
-		return reconcile.Result{},
-			log.WithEvent(instance, "WithOpsManifestError").Errorf(ctx, "Failed to create with-ops manifest secret for BOSHDeployment '%s': %v", request.NamespacedName, err)
+		err = log.WithEvent(instance, "WithOpsManifestError").Errorf(ctx, "Failed to create with-ops manifest secret for BOSHDeployment '%s': %v", request.NamespacedName, err)
+		setConditions(ctx, r.client, instance, conditionFalse(bdv1.ConditionOpsResolved, "", "WithOpsManifestError", err))
+		return reconcile.Result{}, err
+	}
+
+	err = updateStatus(ctx, r.client, instance, func(status *bdv1.BOSHDeploymentStatus) {
+		conditions := []bdv1.BOSHDeploymentCondition{
+			conditionTrue(bdv1.ConditionOpsResolved, "", "Resolved", "Manifest and ops files have been resolved"),
+		}
+
+		instanceGroups := make([]string, len(manifest.InstanceGroups))
+		for i, ig := range manifest.InstanceGroups {
+			instanceGroups[i] = ig.Name
+		}
+		status.RemoveInstanceGroupConditions(instanceGroups)
+		status.ResolvedCommits = commits
+
+		// A new with-ops manifest restarts all the following steps
+		if op != controllerutil.OperationResultNone || status.GetCondition(bdv1.ConditionVariablesGenerated, "") == nil {
+			for _, t := range bdv1.DeploymentConditionTypes[1:] {
+				conditions = append(conditions, conditionUnknown(t, "", "Pending", "Waiting for the with-ops manifest to be processed"))
+			}
+			for _, ig := range instanceGroups {
+				conditions = append(conditions, conditionUnknown(bdv1.ConditionInstanceGroupReady, ig, "Pending", "Waiting for the instance group to be deployed"))
+			}
+			for _, c := range status.Conditions {
+				switch c.Type {
+				case bdv1.ConditionPostStart, bdv1.ConditionPostDeploy:
+					conditions = append(conditions, conditionUnknown(c.Type, c.InstanceGroup, "Pending", "Waiting for the instance group to be deployed"))
+				case bdv1.ConditionPropertiesValid:
+					conditions = append(conditions, conditionUnknown(c.Type, c.InstanceGroup, "Pending", "Waiting for the job properties to be validated"))
+				}
+			}
+		}
+
+		for _, condition := range conditions {
+			condition.ObservedGeneration = instance.GetGeneration()
+			status.SetCondition(condition)
+		}
+	})
+	if err != nil {
+		log.WithEvent(instance, "UpdateStatusError").Errorf(ctx, "Failed to update status of BOSHDeployment '%s': %v", request.NamespacedName, err)
+	}
+
+	// Warn about BOSH features, which don't behave like on BOSH, whenever the with-ops manifest is created or changed
+	if op != controllerutil.OperationResultNone {
+		for _, f := range manifest.Compatibility() {
+			log.WarningEvent(ctx, instance, "Compatibility", f.String())
+		}
 	}
 
 	// Generate all the kube objects we need for the manifest
 	log.Debug(ctx, "Converting bosh manifest to kube objects")
-	jobFactory := bdm.NewJobFactory(*manifest, instance.GetNamespace())
+	jobFactory := bdm.NewJobFactory(*manifest, instance.GetNamespace(), r.config.ClusterDomain)
 
 	// Apply the "Variable Interpolation" ExtendedJob
 	eJob, err := jobFactory.VariableInterpolationJob()
 	if err != nil {
-		return reconcile.Result{}, log.WithEvent(instance, "VariableGenerationError").Errorf(ctx, "Failed to build variable interpolation eJob: %v", err)
+		err = log.WithEvent(instance, "VariableGenerationError").Errorf(ctx, "Failed to build variable interpolation eJob: %v", err)
+		setConditions(ctx, r.client, instance, conditionFalse(bdv1.ConditionVariablesInterpolated, "", "VariableGenerationError", err))
+		return reconcile.Result{}, err
 	}
 
 	log.Debug(ctx, "Creating variable interpolation ExtendedJob")
-	err = r.createEJob(ctx, instance, eJob)
+	err = r.createEJob(ctx, instance, eJob, resuming)
 	if err != nil {
-		return reconcile.Result{},
-			log.WithEvent(instance, "VarInterpolationError").Errorf(ctx, "Failed to create variable interpolation ExtendedJob for BOSHDeployment '%s': %v", request.NamespacedName, err)
+		err = log.WithEvent(instance, "VarInterpolationError").Errorf(ctx, "Failed to create variable interpolation ExtendedJob for BOSHDeployment '%s': %v", request.NamespacedName, err)
+		setConditions(ctx, r.client, instance, conditionFalse(bdv1.ConditionVariablesInterpolated, "", "VarInterpolationError", err))
+		return reconcile.Result{}, err
 	}
 
 	// Apply the "Data Gathering" ExtendedJob
 	eJob, err = jobFactory.DataGatheringJob()
 	if err != nil {
-		return reconcile.Result{}, log.WithEvent(instance, "DataGatheringError").Errorf(ctx, "Failed to build data gathering eJob: %v", err)
+		err = log.WithEvent(instance, "DataGatheringError").Errorf(ctx, "Failed to build data gathering eJob: %v", err)
+		setConditions(ctx, r.client, instance, conditionFalse(bdv1.ConditionDataGathered, "", "DataGatheringError", err))
+		return reconcile.Result{}, err
 
 	}
 	log.Debug(ctx, "Creating data gathering ExtendedJob")
-	err = r.createEJob(ctx, instance, eJob)
+	err = r.createEJob(ctx, instance, eJob, false)
 	if err != nil {
-		return reconcile.Result{},
-			log.WithEvent(instance, "DataGatheringError").Errorf(ctx, "Failed to create data gathering ExtendedJob for BOSHDeployment '%s': %v", request.NamespacedName, err)
+		err = log.WithEvent(instance, "DataGatheringError").Errorf(ctx, "Failed to create data gathering ExtendedJob for BOSHDeployment '%s': %v", request.NamespacedName, err)
+		setConditions(ctx, r.client, instance, conditionFalse(bdv1.ConditionDataGathered, "", "DataGatheringError", err))
+		return reconcile.Result{}, err
 	}
 
 	// Apply the "BPM Configs" ExtendedJob
 	eJob, err = jobFactory.BPMConfigsJob()
 	if err != nil {
-		return reconcile.Result{}, log.WithEvent(instance, "BPMConfigsError").Errorf(ctx, "Failed to build BPM configs eJob: %v", err)
+		err = log.WithEvent(instance, "BPMConfigsError").Errorf(ctx, "Failed to build BPM configs eJob: %v", err)
+		setConditions(ctx, r.client, instance, conditionFalse(bdv1.ConditionBPMRendered, "", "BPMConfigsError", err))
+		return reconcile.Result{}, err
 
 	}
 	log.Debug(ctx, "Creating BPM configs ExtendedJob")
-	err = r.createEJob(ctx, instance, eJob)
+	err = r.createEJob(ctx, instance, eJob, false)
 	if err != nil {
-		return reconcile.Result{},
-			log.WithEvent(instance, "BPMConfigsError").Errorf(ctx, "Failed to create BPM configs ExtendedJob for BOSHDeployment '%s': %v", request.NamespacedName, err)
+		err = log.WithEvent(instance, "BPMConfigsError").Errorf(ctx, "Failed to create BPM configs ExtendedJob for BOSHDeployment '%s': %v", request.NamespacedName, err)
+		setConditions(ctx, r.client, instance, conditionFalse(bdv1.ConditionBPMRendered, "", "BPMConfigsError", err))
+		return reconcile.Result{}, err
+	}
+
+	// Apply the "Links" ExtendedJob, if the deployment shares links with other deployments
+	eJob, err = jobFactory.LinksJob()
+	if err != nil {
+		err = log.WithEvent(instance, "LinksError").Errorf(ctx, "Failed to build links eJob: %v", err)
+		setConditions(ctx, r.client, instance, conditionFalse(bdv1.ConditionDataGathered, "", "LinksError", err))
+		return reconcile.Result{}, err
+	}
+	if manifest.ProvidesSharedLinks() {
+		log.Debug(ctx, "Creating links ExtendedJob")
+		err = r.createEJob(ctx, instance, eJob, false)
+	} else {
+		err = r.client.Delete(ctx, eJob)
+		if apierrors.IsNotFound(err) {
+			err = nil
+		}
+	}
+	if err != nil {
+		err = log.WithEvent(instance, "LinksError").Errorf(ctx, "Failed to apply links ExtendedJob for BOSHDeployment '%s': %v", request.NamespacedName, err)
+		setConditions(ctx, r.client, instance, conditionFalse(bdv1.ConditionDataGathered, "", "LinksError", err))
+		return reconcile.Result{}, err
+	}
+
+	if resuming {
+		log.WithEvent(instance, "Resumed").Infof(ctx, "Resumed BOSHDeployment '%s'", request.NamespacedName)
+		err = updateStatus(ctx, r.client, instance, func(status *bdv1.BOSHDeploymentStatus) {
+			status.SetPaused(false)
+		})
+		if err != nil {
+			log.WithEvent(instance, "UpdateStatusError").Errorf(ctx, "Failed to update status of BOSHDeployment '%s': %v", request.NamespacedName, err)
+		}
+	}
+
+	if hasURLRefs(instance.Spec) {
+		return reconcile.Result{RequeueAfter: urlPollInterval}, nil
 	}
 
 	return reconcile.Result{}, nil
 }
 
-// createManifestWithOps creates a secret containing the deployment manifest with ops files applied
-func (r *ReconcileBOSHDeployment) createManifestWithOps(ctx context.Context, instance *bdv1.BOSHDeployment) (*bdm.Manifest, error) {
+// hasURLRefs returns true if the manifest or any ops file is read from a url
+func hasURLRefs(spec bdv1.BOSHDeploymentSpec) bool {
+	if spec.Manifest.Type == bdv1.URLType {
+		return true
+	}
+	for _, op := range spec.Ops {
+		if op.Type == bdv1.URLType {
+			return true
+		}
+	}
+	return false
+}
+
+// createManifestWithOps creates a secret containing the deployment manifest with ops files applied.
+// It also returns the commits of git refs and whether the secret has been created, updated or left unchanged.
+func (r *ReconcileBOSHDeployment) createManifestWithOps(ctx context.Context, instance *bdv1.BOSHDeployment) (*bdm.Manifest, []bdv1.ResolvedCommit, controllerutil.OperationResult, error) {
 	log.Debug(ctx, "Resolving manifest")
-	manifest, err := r.resolver.WithOpsManifest(instance, instance.GetNamespace())
+	manifest, commits, err := r.resolver.WithOpsManifest(instance, instance.GetNamespace())
 	if err != nil {
-		return nil, log.WithEvent(instance, "WithOpsManifestError").Errorf(ctx, "Error resolving the manifest %s: %s", instance.GetName(), err)
+		return nil, nil, controllerutil.OperationResultNone, log.WithEvent(instance, "WithOpsManifestError").Errorf(ctx, "Error resolving the manifest %s: %s", instance.GetName(), err)
 	}
 
 	// Replace the name with the name of the BOSHDeployment resource
 	manifest.Name = instance.GetName()
 
+	// Pull the release images from the operator's registry mirror
+	manifest.ApplyRegistryMirror(r.config.RegistryMirror)
+
+	manifestSecretName := names.CalculateSecretName(names.DeploymentSecretTypeManifestWithOps, manifest.Name, "")
+
+	// Keep the instances in their AZs
+	previous, err := r.previousManifestWithOps(ctx, instance.GetNamespace(), manifestSecretName)
+	if err != nil {
+		return nil, nil, controllerutil.OperationResultNone, log.WithEvent(instance, "ManifestWithOpsGetError").Errorf(ctx, "Failed to read the previous manifest of %s: %s", instance.GetName(), err)
+	}
+	manifest.PlaceInstances(previous)
+
 	log.Debug(ctx, "Creating manifest secret with ops")
 
 	// Create manifest with ops as variable interpolation job input.
 	manifestBytes, err := manifest.Marshal()
 	if err != nil {
-		return nil, log.WithEvent(instance, "ManifestWithOpsUnmarshalError").Errorf(ctx, "Error unmarshaling the manifest %s: %s", instance.GetName(), err)
+		return nil, nil, controllerutil.OperationResultNone, log.WithEvent(instance, "ManifestWithOpsUnmarshalError").Errorf(ctx, "Error unmarshaling the manifest %s: %s", instance.GetName(), err)
 	}
 
-	manifestSecretName := names.CalculateSecretName(names.DeploymentSecretTypeManifestWithOps, manifest.Name, "")
-
 	// Create a secret object for the manifest
 	manifestSecret := &corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
@@ -173,7 +321,7 @@ func (r *ReconcileBOSHDeployment) createManifestWithOps(ctx context.Context, ins
 
 	// Set ownership reference
 	if err := r.setReference(instance, manifestSecret, r.scheme); err != nil {
-		return nil, log.WithEvent(instance, "ManifestWithOpsRefError").Errorf(ctx, "Failed to set ownerReference for Secret '%s': %v", manifestSecretName, err)
+		return nil, nil, controllerutil.OperationResultNone, log.WithEvent(instance, "ManifestWithOpsRefError").Errorf(ctx, "Failed to set ownerReference for Secret '%s': %v", manifestSecretName, err)
 	}
 
 	// Apply the secret
@@ -193,16 +341,37 @@ func (r *ReconcileBOSHDeployment) createManifestWithOps(ctx context.Context, ins
 		return fmt.Errorf("object is not a Secret")
 	})
 	if err != nil {
-		return nil, log.WithEvent(instance, "ManifestWithOpsApplyError").Errorf(ctx, "Failed to apply Secret '%s': %v", manifestSecretName, err)
+		return nil, nil, controllerutil.OperationResultNone, log.WithEvent(instance, "ManifestWithOpsApplyError").Errorf(ctx, "Failed to apply Secret '%s': %v", manifestSecretName, err)
 	}
 
 	log.Debugf(ctx, "Manifest secret '%s' has been %s", manifestSecret.Name, op)
 
-	return manifest, nil
+	return manifest, commits, op, nil
+}
+
+// previousManifestWithOps returns the manifest with ops, which has been created before.
+// Returns nil if there is none.
+func (r *ReconcileBOSHDeployment) previousManifestWithOps(ctx context.Context, namespace string, secretName string) (*bdm.Manifest, error) {
+	secret := &corev1.Secret{}
+	err := r.client.Get(ctx, types.NamespacedName{Name: secretName, Namespace: namespace}, secret)
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get secret '%s'", secretName)
+	}
+
+	manifestBytes, ok := secret.Data[bdm.DesiredManifestKeyName]
+	if !ok {
+		return nil, nil
+	}
+
+	return bdm.LoadYAML(manifestBytes)
 }
 
-// createEJob creates a an EJob and sets ownership
-func (r *ReconcileBOSHDeployment) createEJob(ctx context.Context, instance *bdv1.BOSHDeployment, eJob *ejv1.ExtendedJob) error {
+// createEJob creates a an EJob and sets ownership. The trigger strategy of an existing
+// EJob is kept, unless rerun is set, which resets it to run the EJob once more.
+func (r *ReconcileBOSHDeployment) createEJob(ctx context.Context, instance *bdv1.BOSHDeployment, eJob *ejv1.ExtendedJob, rerun bool) error {
 	if err := r.setReference(instance, eJob, r.scheme); err != nil {
 		return fmt.Errorf("failed to set ownerReference for ExtendedJob '%s': %v", eJob.GetName(), err)
 	}
@@ -210,7 +379,9 @@ func (r *ReconcileBOSHDeployment) createEJob(ctx context.Context, instance *bdv1
 	op, err := controllerutil.CreateOrUpdate(ctx, r.client, eJob.DeepCopy(), func(obj runtime.Object) error {
 		if existingEJob, ok := obj.(*ejv1.ExtendedJob); ok {
 			eJob.ObjectMeta.ResourceVersion = existingEJob.ObjectMeta.ResourceVersion
-			eJob.Spec.Trigger.Strategy = existingEJob.Spec.Trigger.Strategy
+			if !rerun {
+				eJob.Spec.Trigger.Strategy = existingEJob.Spec.Trigger.Strategy
+			}
 			eJob.DeepCopyInto(existingEJob)
 			return nil
 		}
@@ -18,6 +18,7 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/tools/record"
+	crc "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
@@ -29,24 +30,27 @@ import (
 	"code.cloudfoundry.org/cf-operator/pkg/kube/controllers"
 	cfd "code.cloudfoundry.org/cf-operator/pkg/kube/controllers/boshdeployment"
 	cfakes "code.cloudfoundry.org/cf-operator/pkg/kube/controllers/fakes"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util"
 	cfcfg "code.cloudfoundry.org/cf-operator/pkg/kube/util/config"
 	"code.cloudfoundry.org/cf-operator/pkg/kube/util/ctxlog"
+	vss "code.cloudfoundry.org/cf-operator/pkg/kube/util/versionedsecretstore"
 	helper "code.cloudfoundry.org/cf-operator/pkg/testhelper"
 )
 
 var _ = Describe("ReconcileBoshDeployment", func() {
 	var (
-		manager    *cfakes.FakeManager
-		reconciler reconcile.Reconciler
-		recorder   *record.FakeRecorder
-		request    reconcile.Request
-		ctx        context.Context
-		resolver   fakes.FakeResolver
-		manifest   *bdm.Manifest
-		log        *zap.SugaredLogger
-		config     *cfcfg.Config
-		client     *cfakes.FakeClient
-		instance   *bdv1.BOSHDeployment
+		manager      *cfakes.FakeManager
+		reconciler   reconcile.Reconciler
+		recorder     *record.FakeRecorder
+		request      reconcile.Request
+		ctx          context.Context
+		resolver     fakes.FakeResolver
+		manifest     *bdm.Manifest
+		log          *zap.SugaredLogger
+		config       *cfcfg.Config
+		client       *cfakes.FakeClient
+		statusWriter *cfakes.FakeStatusWriter
+		instance     *bdv1.BOSHDeployment
 	)
 
 	BeforeEach(func() {
@@ -132,6 +136,8 @@ var _ = Describe("ReconcileBoshDeployment", func() {
 		}
 
 		client = &cfakes.FakeClient{}
+		statusWriter = &cfakes.FakeStatusWriter{}
+		client.StatusReturns(statusWriter)
 		client.GetCalls(func(context context.Context, nn types.NamespacedName, object runtime.Object) error {
 			switch object := object.(type) {
 			case *bdv1.BOSHDeployment:
@@ -145,7 +151,7 @@ var _ = Describe("ReconcileBoshDeployment", func() {
 	})
 
 	JustBeforeEach(func() {
-		resolver.WithOpsManifestReturns(manifest, nil)
+		resolver.WithOpsManifestReturns(manifest, nil, nil)
 		reconciler = cfd.NewDeploymentReconciler(ctx, config, manager,
 			&resolver, controllerutil.SetControllerReference,
 		)
@@ -174,7 +180,7 @@ var _ = Describe("ReconcileBoshDeployment", func() {
 			})
 
 			It("handles an error when resolving the BOSHDeployment", func() {
-				resolver.WithOpsManifestReturns(nil, fmt.Errorf("resolver error"))
+				resolver.WithOpsManifestReturns(nil, nil, fmt.Errorf("resolver error"))
 
 				_, err := reconciler.Reconcile(request)
 				Expect(err).To(HaveOccurred())
@@ -183,12 +189,29 @@ var _ = Describe("ReconcileBoshDeployment", func() {
 				// check for events
 				Expect(<-recorder.Events).To(ContainSubstring("WithOpsManifestError"))
 			})
+
+			It("records the error in the ops resolved condition", func() {
+				resolver.WithOpsManifestReturns(nil, nil, fmt.Errorf("resolver error"))
+
+				_, err := reconciler.Reconcile(request)
+				Expect(err).To(HaveOccurred())
+
+				Expect(statusWriter.UpdateCallCount()).To(Equal(1))
+				_, object := statusWriter.UpdateArgsForCall(0)
+				status := object.(*bdv1.BOSHDeployment).Status
+				Expect(status.State).To(Equal(bdv1.PhaseFailed))
+				condition := status.GetCondition(bdv1.ConditionOpsResolved, "")
+				Expect(condition).NotTo(BeNil())
+				Expect(condition.Status).To(Equal(corev1.ConditionFalse))
+				Expect(condition.Reason).To(Equal("WithOpsManifestError"))
+				Expect(condition.LastError).To(ContainSubstring("resolver error"))
+			})
 		})
 
 		Context("when the manifest can be resolved", func() {
 			It("handles an error when resolving manifest", func() {
 				manifest = &bdm.Manifest{}
-				resolver.WithOpsManifestReturns(manifest, errors.New("fake-error"))
+				resolver.WithOpsManifestReturns(manifest, nil, errors.New("fake-error"))
 
 				_, err := reconciler.Reconcile(request)
 				Expect(err).To(HaveOccurred())
@@ -309,6 +332,302 @@ var _ = Describe("ReconcileBoshDeployment", func() {
 				_, err := reconciler.Reconcile(request)
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("failed to create data gathering ExtendedJob for BOSHDeployment 'default/foo': fake-error"))
+
+				_, object := statusWriter.UpdateArgsForCall(statusWriter.UpdateCallCount() - 1)
+				condition := object.(*bdv1.BOSHDeployment).Status.GetCondition(bdv1.ConditionDataGathered, "")
+				Expect(condition.Status).To(Equal(corev1.ConditionFalse))
+				Expect(condition.LastError).To(ContainSubstring("fake-error"))
+			})
+
+			It("marks the ops as resolved and the following steps as pending", func() {
+				instance.SetGeneration(3)
+
+				_, err := reconciler.Reconcile(request)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(statusWriter.UpdateCallCount()).To(Equal(1))
+				_, object := statusWriter.UpdateArgsForCall(0)
+				status := object.(*bdv1.BOSHDeployment).Status
+				Expect(status.State).To(Equal(bdv1.PhaseDeploying))
+				Expect(status.ObservedGeneration).To(Equal(int64(3)))
+
+				condition := status.GetCondition(bdv1.ConditionOpsResolved, "")
+				Expect(condition.Status).To(Equal(corev1.ConditionTrue))
+				Expect(condition.ObservedGeneration).To(Equal(int64(3)))
+
+				for _, t := range []bdv1.BOSHDeploymentConditionType{
+					bdv1.ConditionVariablesGenerated,
+					bdv1.ConditionVariablesInterpolated,
+					bdv1.ConditionDataGathered,
+					bdv1.ConditionBPMRendered,
+				} {
+					Expect(status.GetCondition(t, "").Status).To(Equal(corev1.ConditionUnknown))
+				}
+				Expect(status.GetCondition(bdv1.ConditionInstanceGroupReady, "fakepod").Status).To(Equal(corev1.ConditionUnknown))
+			})
+
+			It("removes conditions of instance groups which are no longer in the manifest", func() {
+				instance.Status.Conditions = []bdv1.BOSHDeploymentCondition{
+					{Type: bdv1.ConditionInstanceGroupReady, InstanceGroup: "removed", Status: corev1.ConditionTrue},
+				}
+
+				_, err := reconciler.Reconcile(request)
+				Expect(err).NotTo(HaveOccurred())
+
+				_, object := statusWriter.UpdateArgsForCall(0)
+				status := object.(*bdv1.BOSHDeployment).Status
+				Expect(status.GetCondition(bdv1.ConditionInstanceGroupReady, "removed")).To(BeNil())
+				Expect(status.GetCondition(bdv1.ConditionInstanceGroupReady, "fakepod")).NotTo(BeNil())
+			})
+
+			It("deletes the links ExtendedJob if the deployment doesn't share links", func() {
+				_, err := reconciler.Reconcile(request)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(client.DeleteCallCount()).To(Equal(1))
+				_, object, _ := client.DeleteArgsForCall(0)
+				Expect(object.(*ejv1.ExtendedJob).Name).To(Equal("lnk-foo"))
+			})
+
+			It("creates the links ExtendedJob if the deployment shares links", func() {
+				manifest.InstanceGroups[0].Jobs[0].Provides = map[string]interface{}{
+					"foo": map[interface{}]interface{}{"shared": true},
+				}
+				eJobs := map[string]*ejv1.ExtendedJob{}
+				client.GetCalls(func(context context.Context, nn types.NamespacedName, object runtime.Object) error {
+					switch object := object.(type) {
+					case *bdv1.BOSHDeployment:
+						instance.DeepCopyInto(object)
+					case *ejv1.ExtendedJob:
+						*object = ejv1.ExtendedJob{ObjectMeta: metav1.ObjectMeta{Name: nn.Name, Namespace: nn.Namespace}}
+					}
+					return nil
+				})
+				client.UpdateCalls(func(context context.Context, object runtime.Object) error {
+					if eJob, ok := object.(*ejv1.ExtendedJob); ok {
+						eJobs[eJob.Name] = eJob
+					}
+					return nil
+				})
+
+				_, err := reconciler.Reconcile(request)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(client.DeleteCallCount()).To(Equal(0))
+				Expect(eJobs).To(HaveKey("lnk-foo"))
+				Expect(eJobs["lnk-foo"].Spec.Output.NamePrefix).To(Equal("foo."))
+			})
+
+			It("warns about unsupported BOSH features when the with-ops manifest changes", func() {
+				manifest.Tags = map[string]string{"team": "foo"}
+
+				_, err := reconciler.Reconcile(request)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(<-recorder.Events).To(Equal("Warning Compatibility /tags is ignored: tags are not added to the kube resources, use env.bosh.agent.settings.labels of the instance groups"))
+			})
+
+			It("doesn't warn again while the with-ops manifest is unchanged", func() {
+				manifest.Name = "foo"
+				manifest.Tags = map[string]string{"team": "foo"}
+				manifestBytes, err := manifest.Marshal()
+				Expect(err).NotTo(HaveOccurred())
+				client.GetCalls(func(context context.Context, nn types.NamespacedName, object runtime.Object) error {
+					switch object := object.(type) {
+					case *bdv1.BOSHDeployment:
+						instance.DeepCopyInto(object)
+					case *corev1.Secret:
+						object.Data = map[string][]byte{"manifest.yaml": manifestBytes}
+					}
+					return nil
+				})
+
+				_, err = reconciler.Reconcile(request)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(recorder.Events).NotTo(Receive(ContainSubstring("Compatibility")))
+			})
+
+			It("does not requeue deployments without url refs", func() {
+				result, err := reconciler.Reconcile(request)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result).To(Equal(reconcile.Result{}))
+			})
+
+			It("polls url refs for changes", func() {
+				instance.Spec.Ops = append(instance.Spec.Ops, bdv1.Ops{Type: bdv1.URLType, Ref: "https://example.com/ops.yml"})
+
+				result, err := reconciler.Reconcile(request)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.RequeueAfter).To(Equal(5 * time.Minute))
+			})
+		})
+
+		Context("when the deployment is paused", func() {
+			BeforeEach(func() {
+				instance.Spec.Paused = true
+			})
+
+			It("does not resolve the manifest and reports the deployment as paused", func() {
+				_, err := reconciler.Reconcile(request)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(resolver.WithOpsManifestCallCount()).To(Equal(0))
+				Expect(client.CreateCallCount()).To(Equal(0))
+
+				Expect(statusWriter.UpdateCallCount()).To(Equal(1))
+				_, object := statusWriter.UpdateArgsForCall(0)
+				status := object.(*bdv1.BOSHDeployment).Status
+				Expect(status.Paused).To(BeTrue())
+				Expect(status.State).To(Equal(bdv1.PhasePaused))
+			})
+
+			It("keeps a requested rollback until the deployment is resumed", func() {
+				instance.SetAnnotations(map[string]string{bdv1.AnnotationRollbackVersion: "1"})
+
+				_, err := reconciler.Reconcile(request)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(client.UpdateCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when the deployment is resumed", func() {
+			var eJobs map[string]*ejv1.ExtendedJob
+
+			BeforeEach(func() {
+				instance.Status.Paused = true
+				eJobs = map[string]*ejv1.ExtendedJob{}
+
+				client.GetCalls(func(context context.Context, nn types.NamespacedName, object runtime.Object) error {
+					switch object := object.(type) {
+					case *bdv1.BOSHDeployment:
+						instance.DeepCopyInto(object)
+					case *ejv1.ExtendedJob:
+						*object = ejv1.ExtendedJob{ObjectMeta: metav1.ObjectMeta{Name: nn.Name, Namespace: nn.Namespace}}
+						object.Spec.Trigger.Strategy = ejv1.TriggerDone
+					}
+					return nil
+				})
+				client.UpdateCalls(func(context context.Context, object runtime.Object) error {
+					if eJob, ok := object.(*ejv1.ExtendedJob); ok {
+						eJobs[eJob.Name] = eJob
+					}
+					return nil
+				})
+			})
+
+			It("runs the variable interpolation again and reports the deployment as resumed", func() {
+				_, err := reconciler.Reconcile(request)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(eJobs).To(HaveKey("dm-foo"))
+				Expect(eJobs["dm-foo"].Spec.Trigger.Strategy).To(Equal(ejv1.TriggerOnce))
+				Expect(eJobs).To(HaveKey("dg-foo"))
+				Expect(eJobs["dg-foo"].Spec.Trigger.Strategy).To(Equal(ejv1.TriggerDone))
+
+				_, object := statusWriter.UpdateArgsForCall(statusWriter.UpdateCallCount() - 1)
+				status := object.(*bdv1.BOSHDeployment).Status
+				Expect(status.Paused).To(BeFalse())
+				Expect(status.State).To(Equal(bdv1.PhaseDeploying))
+			})
+		})
+
+		Context("when a rollback is requested", func() {
+			var desiredManifests []corev1.Secret
+
+			desiredManifest := func(version string) corev1.Secret {
+				return corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "foo.desired-manifest-v" + version,
+						Namespace: "default",
+						Labels: map[string]string{
+							bdv1.LabelDeploymentName: "foo",
+							vss.LabelSecretKind:      vss.VersionSecretKind,
+							vss.LabelVersion:         version,
+						},
+						OwnerReferences: []metav1.OwnerReference{
+							{Kind: "ExtendedJob", Name: "dm-foo", UID: "dm-uid", Controller: util.Bool(true)},
+						},
+					},
+					Data: map[string][]byte{"manifest.yaml": []byte("name: foo-v" + version)},
+				}
+			}
+
+			BeforeEach(func() {
+				instance.SetAnnotations(map[string]string{bdv1.AnnotationRollbackVersion: "1"})
+				instance.Status.Conditions = []bdv1.BOSHDeploymentCondition{
+					{Type: bdv1.ConditionInstanceGroupReady, InstanceGroup: "fakepod", Status: corev1.ConditionTrue},
+				}
+				desiredManifests = []corev1.Secret{desiredManifest("1"), desiredManifest("2")}
+
+				client.GetCalls(func(context context.Context, nn types.NamespacedName, object runtime.Object) error {
+					switch object := object.(type) {
+					case *bdv1.BOSHDeployment:
+						instance.DeepCopyInto(object)
+						return nil
+					case *corev1.Secret:
+						for _, secret := range desiredManifests {
+							if secret.Name == nn.Name {
+								secret.DeepCopyInto(object)
+								return nil
+							}
+						}
+					}
+					return apierrors.NewNotFound(schema.GroupResource{}, nn.Name)
+				})
+				client.ListCalls(func(context context.Context, options *crc.ListOptions, object runtime.Object) error {
+					if list, ok := object.(*corev1.SecretList); ok {
+						list.Items = desiredManifests
+					}
+					return nil
+				})
+			})
+
+			It("creates a copy of the requested version as the latest desired manifest", func() {
+				_, err := reconciler.Reconcile(request)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(resolver.WithOpsManifestCallCount()).To(Equal(0))
+
+				Expect(client.CreateCallCount()).To(Equal(1))
+				_, object := client.CreateArgsForCall(0)
+				secret := object.(*corev1.Secret)
+				Expect(secret.Name).To(Equal("foo.desired-manifest-v3"))
+				Expect(secret.StringData).To(HaveKeyWithValue("manifest.yaml", "name: foo-v1"))
+				Expect(secret.Labels).To(HaveKeyWithValue(bdv1.LabelDeploymentName, "foo"))
+				Expect(secret.OwnerReferences[0].Name).To(Equal("dm-foo"))
+
+				Expect(<-recorder.Events).To(ContainSubstring("Rolled back BOSHDeployment 'default/foo' from desired manifest version 2 to version 1"))
+			})
+
+			It("records the rollback in the status and removes the annotation", func() {
+				_, err := reconciler.Reconcile(request)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(statusWriter.UpdateCallCount()).To(Equal(1))
+				_, object := statusWriter.UpdateArgsForCall(0)
+				status := object.(*bdv1.BOSHDeployment).Status
+				Expect(status.Rollback.FromVersion).To(Equal(2))
+				Expect(status.Rollback.ToVersion).To(Equal(1))
+				Expect(status.Rollback.Version).To(Equal(3))
+				Expect(status.GetCondition(bdv1.ConditionVariablesInterpolated, "").Reason).To(Equal("RolledBack"))
+				Expect(status.GetCondition(bdv1.ConditionBPMRendered, "").Status).To(Equal(corev1.ConditionUnknown))
+				Expect(status.GetCondition(bdv1.ConditionInstanceGroupReady, "fakepod").Status).To(Equal(corev1.ConditionUnknown))
+
+				Expect(client.UpdateCallCount()).To(Equal(1))
+				_, object = client.UpdateArgsForCall(0)
+				Expect(object.(*bdv1.BOSHDeployment).GetAnnotations()).NotTo(HaveKey(bdv1.AnnotationRollbackVersion))
+			})
+
+			It("removes the annotation if the version does not exist", func() {
+				instance.SetAnnotations(map[string]string{bdv1.AnnotationRollbackVersion: "7"})
+
+				_, err := reconciler.Reconcile(request)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(client.CreateCallCount()).To(Equal(0))
+				Expect(<-recorder.Events).To(ContainSubstring("Desired manifest version 7 of BOSHDeployment 'default/foo' does not exist"))
+				Expect(client.UpdateCallCount()).To(Equal(1))
 			})
 		})
 	})
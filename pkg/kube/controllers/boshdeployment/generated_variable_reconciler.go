@@ -7,13 +7,16 @@ import (
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	bdm "code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
+	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
 	esv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/extendedsecret/v1alpha1"
 	"code.cloudfoundry.org/cf-operator/pkg/kube/util/config"
 	log "code.cloudfoundry.org/cf-operator/pkg/kube/util/ctxlog"
@@ -66,22 +69,35 @@ func (r *ReconcileGeneratedVariable) Reconcile(request reconcile.Request) (recon
 		return reconcile.Result{}, err
 	}
 
+	instance, err := r.deploymentForManifestSecret(ctx, manifestSecret)
+	if err != nil {
+		return reconcile.Result{},
+			log.WithEvent(manifestSecret, "GetBOSHDeploymentError").Errorf(ctx, "Failed to get BOSHDeployment for manifest with ops file secret '%s': %v", request.NamespacedName, err)
+	}
+
+	if instance != nil && instance.Spec.Paused {
+		log.Debugf(ctx, "Skip reconcile: BOSHDeployment '%s' is paused", instance.GetName())
+		return reconcile.Result{}, nil
+	}
+
 	var manifestContents string
 
 	// Get the manifest yaml
 	if val, ok := manifestSecret.Data["manifest.yaml"]; ok {
 		manifestContents = string(val)
 	} else {
-		return reconcile.Result{}, errors.New("Couldn't find manifest.yaml key in manifest secret")
+		err = errors.New("Couldn't find manifest.yaml key in manifest secret")
+		r.setCondition(ctx, instance, conditionFalse(bdv1.ConditionVariablesGenerated, "", "BadManifestError", err))
+		return reconcile.Result{}, err
 	}
 
 	// Unmarshal the manifest
 	log.Debug(ctx, "Unmarshaling BOSHDeployment manifest from manifest with ops secret")
 	manifest, err := bdm.LoadYAML([]byte(manifestContents))
 	if err != nil {
-		return reconcile.Result{},
-			log.WithEvent(manifestSecret, "BadManifestError").Errorf(ctx, "Failed to unmarshal manifest from secret '%s': %v", request.NamespacedName, err)
-
+		err = log.WithEvent(manifestSecret, "BadManifestError").Errorf(ctx, "Failed to unmarshal manifest from secret '%s': %v", request.NamespacedName, err)
+		r.setCondition(ctx, instance, conditionFalse(bdv1.ConditionVariablesGenerated, "", "BadManifestError", err))
+		return reconcile.Result{}, err
 	}
 
 	// Convert the manifest to kube objects
@@ -90,19 +106,51 @@ func (r *ReconcileGeneratedVariable) Reconcile(request reconcile.Request) (recon
 
 	if len(secrets) == 0 {
 		log.Debug(ctx, "Skip generate variable extendedSecrets: there are no variables")
+		r.setCondition(ctx, instance, conditionTrue(bdv1.ConditionVariablesGenerated, "", "NoVariables", "Manifest has no explicit variables"))
 		return reconcile.Result{}, nil
 	}
 
 	// Create/update all explicit BOSH Variables
 	err = r.generateVariableSecrets(ctx, manifestSecret, secrets)
 	if err != nil {
-		return reconcile.Result{},
-			log.WithEvent(manifestSecret, "VariableGenerationError").Errorf(ctx, "Failed to generate variables for bosh manifest '%s': %v", manifest.Name, err)
+		err = log.WithEvent(manifestSecret, "VariableGenerationError").Errorf(ctx, "Failed to generate variables for bosh manifest '%s': %v", manifest.Name, err)
+		r.setCondition(ctx, instance, conditionFalse(bdv1.ConditionVariablesGenerated, "", "VariableGenerationError", err))
+		return reconcile.Result{}, err
 	}
 
+	r.setCondition(ctx, instance, conditionTrue(bdv1.ConditionVariablesGenerated, "", "Generated", fmt.Sprintf("%d ExtendedSecrets have been applied", len(secrets))))
+
 	return reconcile.Result{}, nil
 }
 
+// deploymentForManifestSecret returns the BOSHDeployment owning the manifest with ops secret,
+// or nil if the secret has no such owner
+func (r *ReconcileGeneratedVariable) deploymentForManifestSecret(ctx context.Context, manifestSecret *corev1.Secret) (*bdv1.BOSHDeployment, error) {
+	owner := metav1.GetControllerOf(manifestSecret)
+	if owner == nil || owner.Kind != "BOSHDeployment" {
+		return nil, nil
+	}
+
+	instance := &bdv1.BOSHDeployment{}
+	err := r.client.Get(ctx, types.NamespacedName{Namespace: manifestSecret.GetNamespace(), Name: owner.Name}, instance)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return instance, nil
+}
+
+// setCondition records the condition on the BOSHDeployment, if there is one
+func (r *ReconcileGeneratedVariable) setCondition(ctx context.Context, instance *bdv1.BOSHDeployment, condition bdv1.BOSHDeploymentCondition) {
+	if instance == nil {
+		return
+	}
+	setConditions(ctx, r.client, instance, condition)
+}
+
 // generateVariableSecrets create variables extendedSecrets
 func (r *ReconcileGeneratedVariable) generateVariableSecrets(ctx context.Context, manifestSecret *corev1.Secret, variables []esv1.ExtendedSecret) error {
 	log.Debug(ctx, "Creating ExtendedSecrets for explicit variables")
@@ -25,6 +25,7 @@ import (
 	"code.cloudfoundry.org/cf-operator/pkg/kube/controllers"
 	cfd "code.cloudfoundry.org/cf-operator/pkg/kube/controllers/boshdeployment"
 	cfakes "code.cloudfoundry.org/cf-operator/pkg/kube/controllers/fakes"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util"
 	cfcfg "code.cloudfoundry.org/cf-operator/pkg/kube/util/config"
 	"code.cloudfoundry.org/cf-operator/pkg/kube/util/ctxlog"
 	helper "code.cloudfoundry.org/cf-operator/pkg/testhelper"
@@ -40,6 +41,7 @@ var _ = Describe("ReconcileGeneratedVariable", func() {
 		log                   *zap.SugaredLogger
 		config                *cfcfg.Config
 		client                *cfakes.FakeClient
+		statusWriter          *cfakes.FakeStatusWriter
 		manifestWithOpsSecret *corev1.Secret
 	)
 
@@ -91,6 +93,8 @@ variables:
 		ctx = ctxlog.NewContextWithRecorder(ctx, "TestRecorder", recorder)
 
 		client = &cfakes.FakeClient{}
+		statusWriter = &cfakes.FakeStatusWriter{}
+		client.StatusReturns(statusWriter)
 		client.GetCalls(func(context context.Context, nn types.NamespacedName, object runtime.Object) error {
 			switch object := object.(type) {
 			case *corev1.Secret:
@@ -149,5 +153,57 @@ variables:
 				Expect(err).ToNot(HaveOccurred())
 			})
 		})
+
+		Context("when the manifest with ops secret is owned by a BOSHDeployment", func() {
+			BeforeEach(func() {
+				manifestWithOpsSecret.SetOwnerReferences([]metav1.OwnerReference{
+					{
+						APIVersion: "fissile.cloudfoundry.org/v1alpha1",
+						Kind:       "BOSHDeployment",
+						Name:       "foo",
+						Controller: util.Bool(true),
+					},
+				})
+			})
+
+			It("sets the variables generated condition", func() {
+				_, err := reconciler.Reconcile(request)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(statusWriter.UpdateCallCount()).To(Equal(1))
+				_, object := statusWriter.UpdateArgsForCall(0)
+				status := object.(*bdv1.BOSHDeployment).Status
+				condition := status.GetCondition(bdv1.ConditionVariablesGenerated, "")
+				Expect(condition).NotTo(BeNil())
+				Expect(condition.Status).To(Equal(corev1.ConditionTrue))
+				Expect(condition.Message).To(Equal("1 ExtendedSecrets have been applied"))
+			})
+
+			It("records the error when generating variables fails", func() {
+				client.CreateCalls(func(context context.Context, object runtime.Object) error {
+					return errors.New("fake-error")
+				})
+				client.GetCalls(func(context context.Context, nn types.NamespacedName, object runtime.Object) error {
+					switch object := object.(type) {
+					case *corev1.Secret:
+						manifestWithOpsSecret.DeepCopyInto(object)
+					case *esv1.ExtendedSecret:
+						return apierrors.NewNotFound(schema.GroupResource{}, nn.Name)
+					}
+					return nil
+				})
+
+				_, err := reconciler.Reconcile(request)
+				Expect(err).To(HaveOccurred())
+
+				Expect(statusWriter.UpdateCallCount()).To(Equal(1))
+				_, object := statusWriter.UpdateArgsForCall(0)
+				status := object.(*bdv1.BOSHDeployment).Status
+				Expect(status.State).To(Equal(bdv1.PhaseFailed))
+				condition := status.GetCondition(bdv1.ConditionVariablesGenerated, "")
+				Expect(condition.Status).To(Equal(corev1.ConditionFalse))
+				Expect(condition.LastError).To(ContainSubstring("fake-error"))
+			})
+		})
 	})
 })
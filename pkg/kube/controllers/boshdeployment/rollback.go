@@ -0,0 +1,134 @@
+package boshdeployment
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	log "code.cloudfoundry.org/cf-operator/pkg/kube/util/ctxlog"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/names"
+	vss "code.cloudfoundry.org/cf-operator/pkg/kube/util/versionedsecretstore"
+)
+
+// rollback stores a copy of a previous desired manifest version as the latest version.
+// Like any new desired manifest version, this triggers the data gathering and BPM
+// pipeline, but ops files are not resolved and variables are not interpolated again.
+// The rollback annotation is removed afterwards, unless the rollback can be retried.
+func (r *ReconcileBOSHDeployment) rollback(ctx context.Context, instance *bdv1.BOSHDeployment, requestedVersion string) (reconcile.Result, error) {
+	key := fmt.Sprintf("%s/%s", instance.GetNamespace(), instance.GetName())
+
+	toVersion, err := strconv.Atoi(requestedVersion)
+	if err != nil || toVersion < 1 {
+		log.WithEvent(instance, "RollbackError").Errorf(ctx, "Invalid rollback version '%s' for BOSHDeployment '%s'", requestedVersion, key)
+		return reconcile.Result{}, r.removeRollbackAnnotation(ctx, instance)
+	}
+
+	secretName := names.DesiredManifestName(instance.GetName(), "")
+	latest, err := r.versionedSecretStore.Latest(ctx, instance.GetNamespace(), secretName)
+	if err != nil {
+		return reconcile.Result{}, log.WithEvent(instance, "RollbackError").Errorf(ctx, "Failed to read latest desired manifest of BOSHDeployment '%s': %v", key, err)
+	}
+	fromVersion, err := vss.Version(*latest)
+	if err != nil {
+		return reconcile.Result{}, log.WithEvent(instance, "RollbackError").Errorf(ctx, "Failed to read latest desired manifest version of BOSHDeployment '%s': %v", key, err)
+	}
+
+	if toVersion == fromVersion {
+		log.WithEvent(instance, "Rollback").Infof(ctx, "Desired manifest version %d of BOSHDeployment '%s' is already the latest version", toVersion, key)
+		return reconcile.Result{}, r.removeRollbackAnnotation(ctx, instance)
+	}
+
+	secret, err := r.versionedSecretStore.Get(ctx, instance.GetNamespace(), secretName, toVersion)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			log.WithEvent(instance, "RollbackError").Errorf(ctx, "Desired manifest version %d of BOSHDeployment '%s' does not exist", toVersion, key)
+			return reconcile.Result{}, r.removeRollbackAnnotation(ctx, instance)
+		}
+		return reconcile.Result{}, log.WithEvent(instance, "RollbackError").Errorf(ctx, "Failed to read desired manifest version %d of BOSHDeployment '%s': %v", toVersion, key, err)
+	}
+
+	owner := metav1.GetControllerOf(secret)
+	if owner == nil {
+		owner = metav1.GetControllerOf(latest)
+	}
+	if owner == nil {
+		log.WithEvent(instance, "RollbackError").Errorf(ctx, "Desired manifest '%s' of BOSHDeployment '%s' has no owner", secret.GetName(), key)
+		return reconcile.Result{}, r.removeRollbackAnnotation(ctx, instance)
+	}
+
+	data := map[string]string{}
+	for k, v := range secret.Data {
+		data[k] = string(v)
+	}
+	labels := map[string]string{}
+	for k, v := range secret.GetLabels() {
+		labels[k] = v
+	}
+
+	err = r.versionedSecretStore.Create(ctx, instance.GetNamespace(), owner.Name, owner.UID, secretName, data, labels,
+		fmt.Sprintf("rollback to version %d", toVersion))
+	if err != nil {
+		return reconcile.Result{}, log.WithEvent(instance, "RollbackError").Errorf(ctx, "Failed to create desired manifest for rollback of BOSHDeployment '%s': %v", key, err)
+	}
+
+	log.WithEvent(instance, "Rollback").Infof(ctx, "Rolled back BOSHDeployment '%s' from desired manifest version %d to version %d", key, fromVersion, toVersion)
+
+	err = updateStatus(ctx, r.client, instance, func(status *bdv1.BOSHDeploymentStatus) {
+		status.Rollback = &bdv1.BOSHDeploymentRollback{
+			FromVersion: fromVersion,
+			ToVersion:   toVersion,
+			Version:     fromVersion + 1,
+			Time:        metav1.Now(),
+		}
+
+		conditions := []bdv1.BOSHDeploymentCondition{
+			conditionTrue(bdv1.ConditionVariablesInterpolated, "", "RolledBack", fmt.Sprintf("Desired manifest version %d is a copy of version %d", fromVersion+1, toVersion)),
+			conditionUnknown(bdv1.ConditionDataGathered, "", "Pending", "Waiting for the rolled back manifest to be processed"),
+			conditionUnknown(bdv1.ConditionBPMRendered, "", "Pending", "Waiting for the rolled back manifest to be processed"),
+		}
+		for _, condition := range status.Conditions {
+			if condition.Type == bdv1.ConditionInstanceGroupReady {
+				conditions = append(conditions, conditionUnknown(bdv1.ConditionInstanceGroupReady, condition.InstanceGroup, "Pending", "Waiting for the instance group to be rolled back"))
+			}
+		}
+
+		for _, condition := range conditions {
+			condition.ObservedGeneration = instance.GetGeneration()
+			status.SetCondition(condition)
+		}
+	})
+	if err != nil {
+		log.WithEvent(instance, "UpdateStatusError").Errorf(ctx, "Failed to update status of BOSHDeployment '%s': %v", key, err)
+	}
+
+	return reconcile.Result{}, r.removeRollbackAnnotation(ctx, instance)
+}
+
+// removeRollbackAnnotation removes the rollback annotation from the BOSHDeployment,
+// so the rollback is applied only once
+func (r *ReconcileBOSHDeployment) removeRollbackAnnotation(ctx context.Context, instance *bdv1.BOSHDeployment) error {
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		annotations := instance.GetAnnotations()
+		delete(annotations, bdv1.AnnotationRollbackVersion)
+		instance.SetAnnotations(annotations)
+
+		err := r.client.Update(ctx, instance)
+		if apierrors.IsConflict(err) {
+			key := types.NamespacedName{Namespace: instance.GetNamespace(), Name: instance.GetName()}
+			if getErr := r.client.Get(ctx, key, instance); getErr != nil {
+				return getErr
+			}
+		}
+		return err
+	})
+
+	return errors.Wrapf(err, "could not remove rollback annotation from BOSHDeployment '%s/%s'", instance.GetNamespace(), instance.GetName())
+}
@@ -0,0 +1,88 @@
+package boshdeployment
+
+import (
+	"context"
+	"reflect"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	log "code.cloudfoundry.org/cf-operator/pkg/kube/util/ctxlog"
+)
+
+// updateStatus applies mutate to the status of the BOSHDeployment and writes it to the
+// status subresource. Nothing is written if the status didn't change. On conflicts,
+// which happen because several reconcilers report on the same BOSHDeployment, the
+// instance is refreshed and mutate is applied again.
+func updateStatus(ctx context.Context, c client.Client, instance *bdv1.BOSHDeployment, mutate func(*bdv1.BOSHDeploymentStatus)) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		original := instance.Status.DeepCopy()
+
+		mutate(&instance.Status)
+		instance.Status.ObservedGeneration = instance.GetGeneration()
+		if reflect.DeepEqual(*original, instance.Status) {
+			return nil
+		}
+
+		err := c.Status().Update(ctx, instance)
+		if apierrors.IsConflict(err) {
+			key := types.NamespacedName{Namespace: instance.GetNamespace(), Name: instance.GetName()}
+			if getErr := c.Get(ctx, key, instance); getErr != nil {
+				return getErr
+			}
+		}
+		return err
+	})
+}
+
+// setConditions records the conditions on the BOSHDeployment status. Failing to
+// do so is logged, but doesn't fail the reconciliation.
+func setConditions(ctx context.Context, c client.Client, instance *bdv1.BOSHDeployment, conditions ...bdv1.BOSHDeploymentCondition) {
+	err := updateStatus(ctx, c, instance, func(status *bdv1.BOSHDeploymentStatus) {
+		for _, condition := range conditions {
+			condition.ObservedGeneration = instance.GetGeneration()
+			status.SetCondition(condition)
+		}
+	})
+	if err != nil {
+		log.WithEvent(instance, "UpdateStatusError").Errorf(ctx, "Failed to update status of BOSHDeployment '%s/%s': %v", instance.GetNamespace(), instance.GetName(), err)
+	}
+}
+
+// conditionTrue returns a condition marking a step as done
+func conditionTrue(conditionType bdv1.BOSHDeploymentConditionType, instanceGroup string, reason string, message string) bdv1.BOSHDeploymentCondition {
+	return bdv1.BOSHDeploymentCondition{
+		Type:          conditionType,
+		InstanceGroup: instanceGroup,
+		Status:        corev1.ConditionTrue,
+		Reason:        reason,
+		Message:       message,
+	}
+}
+
+// conditionUnknown returns a condition marking a step as in progress
+func conditionUnknown(conditionType bdv1.BOSHDeploymentConditionType, instanceGroup string, reason string, message string) bdv1.BOSHDeploymentCondition {
+	return bdv1.BOSHDeploymentCondition{
+		Type:          conditionType,
+		InstanceGroup: instanceGroup,
+		Status:        corev1.ConditionUnknown,
+		Reason:        reason,
+		Message:       message,
+	}
+}
+
+// conditionFalse returns a condition marking a step as failed with err
+func conditionFalse(conditionType bdv1.BOSHDeploymentConditionType, instanceGroup string, reason string, err error) bdv1.BOSHDeploymentCondition {
+	return bdv1.BOSHDeploymentCondition{
+		Type:          conditionType,
+		InstanceGroup: instanceGroup,
+		Status:        corev1.ConditionFalse,
+		Reason:        reason,
+		Message:       err.Error(),
+		LastError:     err.Error(),
+	}
+}
@@ -9,13 +9,17 @@ import (
 	bdv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/boshdeployment/v1alpha1"
 	"code.cloudfoundry.org/cf-operator/pkg/kube/util/config"
 	log "code.cloudfoundry.org/cf-operator/pkg/kube/util/ctxlog"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/names"
+	vss "code.cloudfoundry.org/cf-operator/pkg/kube/util/versionedsecretstore"
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
+	yaml "gopkg.in/yaml.v2"
 	"k8s.io/api/admission/v1beta1"
 	admissionregistrationv1beta1 "k8s.io/api/admissionregistration/v1beta1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	ktype "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/runtime/inject"
@@ -34,7 +38,7 @@ const (
 func AddBOSHDeploymentValidator(log *zap.SugaredLogger, config *config.Config, mgr manager.Manager) (webhook.Webhook, error) {
 	log.Info("Setting up validator for BOSHDeployment")
 
-	boshDeploymentValidator := NewValidator(log, config)
+	boshDeploymentValidator := NewValidator(log, config, mgr.GetRecorder("boshdeployment-validator"))
 
 	validatingWebhook, err := builder.NewWebhookBuilder().
 		Name(admissionWebhookName).
@@ -61,20 +65,23 @@ func AddBOSHDeploymentValidator(log *zap.SugaredLogger, config *config.Config, m
 type Validator struct {
 	log          *zap.SugaredLogger
 	config       *config.Config
+	recorder     record.EventRecorder
 	client       client.Client
 	decoder      types.Decoder
 	pollTimeout  time.Duration
 	pollInterval time.Duration
 }
 
-// NewValidator returns a new BOSHDeploymentValidator
-func NewValidator(log *zap.SugaredLogger, config *config.Config) admission.Handler {
+// NewValidator returns a new BOSHDeploymentValidator. Property validation
+// warnings are recorded as events with the recorder.
+func NewValidator(log *zap.SugaredLogger, config *config.Config, recorder record.EventRecorder) admission.Handler {
 	validationLog := log.Named("boshdeployment-validator")
 	validationLog.Info("Creating a validator for BOSHDeployment")
 
 	return &Validator{
 		log:          validationLog,
 		config:       config,
+		recorder:     recorder,
 		pollTimeout:  5 * time.Second,
 		pollInterval: 500 * time.Millisecond,
 	}
@@ -115,6 +122,12 @@ func (v *Validator) OpsResourceExist(ctx context.Context, specOpsResource bdv1.O
 				}
 			}
 		}
+	case bdv1.GitType:
+		// Git refs are read when resolving the manifest
+		return true, fmt.Sprintf("git repository %s", specOpsResource.Ref)
+	case bdv1.URLType:
+		// URL refs are downloaded when resolving the manifest
+		return true, fmt.Sprintf("url %s", specOpsResource.Ref)
 	default:
 		// We only support configmaps so far
 		return false, fmt.Sprintf("resource type %s, is not supported under spec.ops", specOpsResource.Type)
@@ -147,7 +160,7 @@ func (v *Validator) Handle(ctx context.Context, req types.Request) types.Respons
 		}
 	}
 
-	_, err = resolver.WithOpsManifest(boshDeployment, boshDeployment.GetNamespace())
+	manifest, _, err := resolver.WithOpsManifest(boshDeployment, boshDeployment.GetNamespace())
 	if err != nil {
 		return types.Response{
 			Response: &v1beta1.AdmissionResponse{
@@ -159,6 +172,25 @@ func (v *Validator) Handle(ctx context.Context, req types.Request) types.Respons
 		}
 	}
 
+	findings, err := v.propertyFindings(ctx, boshDeployment.GetNamespace(), manifest)
+	if err != nil {
+		// The data gathering jobs validate the properties again
+		v.log.Infof("Skipping job property validation of BOSHDeployment '%s': %v", boshDeployment.GetName(), err)
+	}
+	if errs := findings.Errors(); len(errs) > 0 {
+		return types.Response{
+			Response: &v1beta1.AdmissionResponse{
+				Allowed: false,
+				Result: &metav1.Status{
+					Message: fmt.Sprintf("Invalid job properties: %s", errs.Summary(maxConditionFindings)),
+				},
+			},
+		}
+	}
+	for _, f := range findings.Warnings() {
+		v.recorder.Event(boshDeployment, corev1.EventTypeWarning, "PropertyValidation", f.String())
+	}
+
 	return types.Response{
 		Response: &v1beta1.AdmissionResponse{
 			Allowed: true,
@@ -166,6 +198,50 @@ func (v *Validator) Handle(ctx context.Context, req types.Request) types.Respons
 	}
 }
 
+// propertyFindings validates the job properties of the manifest against the job specs, which
+// the data gathering job stored for the latest version of each instance group. Findings, which
+// have been reported for that version already, are left out. Without a version, e.g. when the
+// BOSHDeployment is created, the properties are only validated by the data gathering job.
+func (v *Validator) propertyFindings(ctx context.Context, namespace string, manifest *bdm.Manifest) (bdm.PropertyFindings, error) {
+	store := vss.NewVersionedSecretStore(v.client)
+
+	findings := bdm.PropertyFindings{}
+	for _, ig := range manifest.InstanceGroups {
+		secrets, err := store.List(ctx, namespace, names.CalculateIGSecretName(names.DeploymentSecretBpmInformation, manifest.Name, ig.Name, ""))
+		if err != nil {
+			return findings, errors.Wrapf(err, "listing BPM secrets of instance group '%s'", ig.Name)
+		}
+
+		var latest *corev1.Secret
+		latestVersion := 0
+		for i := range secrets {
+			version, err := vss.Version(secrets[i])
+			if err != nil {
+				return findings, err
+			}
+			if version > latestVersion {
+				latest, latestVersion = &secrets[i], version
+			}
+		}
+		if latest == nil {
+			continue
+		}
+
+		specs := bdm.ValidatedJobSpecs{}
+		if err := yaml.Unmarshal(latest.Data["specs.yaml"], &specs); err != nil {
+			return findings, errors.Wrapf(err, "unmarshalling specs.yaml of secret '%s'", latest.Name)
+		}
+		reported := bdm.PropertyFindings{}
+		if err := yaml.Unmarshal(latest.Data["validation.yaml"], &reported); err != nil {
+			return findings, errors.Wrapf(err, "unmarshalling validation.yaml of secret '%s'", latest.Name)
+		}
+
+		findings = append(findings, specs.ValidateProperties(manifest, ig).Without(reported)...)
+	}
+
+	return findings, nil
+}
+
 // podAnnotator implements inject.Client.
 // A client will be automatically injected.
 var _ inject.Client = &Validator{}
@@ -39,6 +39,7 @@ func AddErrand(ctx context.Context, config *config.Config, mgr manager.Manager)
 
 	// Trigger when
 	//  * errand jobs are to be run (Spec.Run changes from `manual` to `now` or the job is created with `now`)
+	//  * auto-errands are to be run again (Spec.Run changes from `done` to `once`)
 	//  * auto-errands with UpdateOnConfigChange == true have changed config references
 	p := predicate.Funcs{
 		CreateFunc: func(e event.CreateEvent) bool {
@@ -66,10 +67,13 @@ func AddErrand(ctx context.Context, config *config.Config, mgr manager.Manager)
 
 			enqueueForManualErrand := n.Spec.Trigger.Strategy == ejv1.TriggerNow && o.Spec.Trigger.Strategy == ejv1.TriggerManual
 
+			// enqueuing for auto-errand when it is reset from `done` to `once`
+			enqueueForRerun := n.Spec.Trigger.Strategy == ejv1.TriggerOnce && o.Spec.Trigger.Strategy == ejv1.TriggerDone
+
 			// enqueuing for auto-errand when referenced secrets changed
 			enqueueForConfigChange := n.IsAutoErrand() && n.Spec.UpdateOnConfigChange && hasConfigsChanged(o, n)
 
-			shouldProcessEvent := enqueueForManualErrand || enqueueForConfigChange
+			shouldProcessEvent := enqueueForManualErrand || enqueueForRerun || enqueueForConfigChange
 			if shouldProcessEvent {
 				ctxlog.NewPredicateEvent(o).Debug(
 					ctx, e.MetaNew, ejv1.LabelExtendedJob,
@@ -3,7 +3,6 @@ package extendedjob
 import (
 	"context"
 
-	"github.com/pkg/errors"
 	batchv1 "k8s.io/api/batch/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -12,7 +11,9 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
+	"code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
 	ejv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/extendedjob/v1alpha1"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util"
 	"code.cloudfoundry.org/cf-operator/pkg/kube/util/config"
 	"code.cloudfoundry.org/cf-operator/pkg/kube/util/ctxlog"
 	"code.cloudfoundry.org/cf-operator/pkg/kube/util/names"
@@ -72,7 +73,8 @@ func (r *ErrandReconciler) Reconcile(request reconcile.Request) (reconcile.Resul
 		return result, err
 	}
 
-	if eJob.Spec.Trigger.Strategy == ejv1.TriggerNow {
+	manual := eJob.Spec.Trigger.Strategy == ejv1.TriggerNow
+	if manual {
 		// set Strategy back to manual for errand jobs
 		eJob.Spec.Trigger.Strategy = ejv1.TriggerManual
 		err = r.client.Update(ctx, eJob)
@@ -82,8 +84,34 @@ func (r *ErrandReconciler) Reconcile(request reconcile.Request) (reconcile.Resul
 		}
 	}
 
-	err = r.createJob(ctx, *eJob)
+	jobName, err := names.JobName(eJob.Name, "")
 	if err != nil {
+		err = ctxlog.WithEvent(eJob, "CreateJobError").Errorf(ctx, "Failed to generate job name for '%s': %s", eJob.Name, err)
+		return result, err
+	}
+
+	// Record the run before its job is created. The job reconciler records the
+	// result of the run, which may be right after the job has been created.
+	previousRun := eJob.Status.LastRun
+	eJob.Status.LastRun = &ejv1.ExtendedJobRun{
+		Job:       jobName,
+		State:     ejv1.RunStateRunning,
+		StartTime: metav1.Now(),
+	}
+	err = r.client.Update(ctx, eJob)
+	if err != nil {
+		err = ctxlog.WithEvent(eJob, "UpdateError").Errorf(ctx, "Failed to record run of job '%s': %s", eJob.Name, err)
+		return result, err
+	}
+
+	err = r.createJob(ctx, *eJob, jobName, manual)
+	if err != nil {
+		// The run didn't start
+		eJob.Status.LastRun = previousRun
+		if updateErr := r.client.Update(ctx, eJob); updateErr != nil {
+			ctxlog.WithEvent(eJob, "UpdateError").Errorf(ctx, "Failed to reset the last run of job '%s': %s", eJob.Name, updateErr)
+		}
+
 		if apierrors.IsAlreadyExists(err) {
 			ctxlog.WithEvent(eJob, "AlreadyRunning").Infof(ctx, "Skip '%s' triggered manually: already running", eJob.Name)
 			// we don't want to requeue the job
@@ -105,10 +133,12 @@ func (r *ErrandReconciler) Reconcile(request reconcile.Request) (reconcile.Resul
 		}
 	}
 
-	return result, err
+	return result, nil
 }
 
-func (r *ErrandReconciler) createJob(ctx context.Context, eJob ejv1.ExtendedJob) error {
+// createJob creates the job for an errand run. Like in BOSH, manually
+// triggered errands of BOSH deployments are not retried.
+func (r *ErrandReconciler) createJob(ctx context.Context, eJob ejv1.ExtendedJob, name string, manual bool) error {
 	template := eJob.Spec.Template.DeepCopy()
 
 	if template.Labels == nil {
@@ -118,10 +148,6 @@ func (r *ErrandReconciler) createJob(ctx context.Context, eJob ejv1.ExtendedJob)
 
 	r.versionedSecretStore.SetSecretReferences(ctx, eJob.Namespace, &template.Spec)
 
-	name, err := names.JobName(eJob.Name, "")
-	if err != nil {
-		return errors.Wrapf(err, "could not generate job name for eJob '%s'", eJob.Name)
-	}
 	job := &batchv1.Job{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      name,
@@ -130,17 +156,15 @@ func (r *ErrandReconciler) createJob(ctx context.Context, eJob ejv1.ExtendedJob)
 		},
 		Spec: batchv1.JobSpec{Template: *template},
 	}
-
-	err = r.setOwnerReference(&eJob, job, r.scheme)
-	if err != nil {
-		ctxlog.WithEvent(&eJob, "SetOwnerReferenceError").Errorf(ctx, "failed to set owner reference on job for '%s': %s", eJob.Name, err)
-		return err
+	if manual && eJob.Labels[manifest.LabelErrand] == "true" {
+		job.Spec.BackoffLimit = util.Int32(0)
 	}
 
-	err = r.client.Create(ctx, job)
+	err := r.setOwnerReference(&eJob, job, r.scheme)
 	if err != nil {
+		ctxlog.WithEvent(&eJob, "SetOwnerReferenceError").Errorf(ctx, "failed to set owner reference on job for '%s': %s", eJob.Name, err)
 		return err
 	}
 
-	return nil
+	return r.client.Create(ctx, job)
 }
@@ -21,6 +21,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
+	"code.cloudfoundry.org/cf-operator/pkg/bosh/manifest"
 	ejv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/extendedjob/v1alpha1"
 	"code.cloudfoundry.org/cf-operator/pkg/kube/controllers"
 	. "code.cloudfoundry.org/cf-operator/pkg/kube/controllers/extendedjob"
@@ -150,6 +151,33 @@ var _ = Describe("ErrandReconciler", func() {
 					Expect(err).To(HaveOccurred())
 					Expect(client.CreateCallCount()).To(Equal(1))
 				})
+
+				It("resets the last run", func() {
+					act()
+					Expect(client.UpdateCallCount()).To(Equal(3))
+					_, object := client.UpdateArgsForCall(2)
+					Expect(object.(*ejv1.ExtendedJob).Status.LastRun).To(BeNil())
+				})
+			})
+
+			Context("when the job finishes right after it has been created", func() {
+				It("has recorded the run before", func() {
+					var recorded *ejv1.ExtendedJobRun
+					client.UpdateCalls(func(ctx context.Context, object runtime.Object) error {
+						recorded = object.(*ejv1.ExtendedJob).Status.LastRun
+						return nil
+					})
+					client.CreateCalls(func(ctx context.Context, object runtime.Object) error {
+						Expect(recorded).ToNot(BeNil())
+						Expect(recorded.Job).To(Equal(object.(*batchv1.Job).Name))
+						Expect(recorded.State).To(Equal(ejv1.RunStateRunning))
+						return nil
+					})
+
+					_, err := act()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(client.CreateCallCount()).To(Equal(1))
+				})
 			})
 
 			Context("when client fails to create jobs because it already exists", func() {
@@ -178,6 +206,7 @@ var _ = Describe("ErrandReconciler", func() {
 			Context("and the errand is a manual errand", func() {
 				BeforeEach(func() {
 					eJob = env.ErrandExtendedJob("fake-pod")
+					eJob.Labels = map[string]string{manifest.LabelErrand: "true"}
 					runtimeObjects = []runtime.Object{
 						&eJob,
 					}
@@ -209,6 +238,50 @@ var _ = Describe("ErrandReconciler", func() {
 					)
 					Expect(eJob.Spec.Trigger.Strategy).To(Equal(ejv1.TriggerManual))
 				})
+
+				It("records the run and doesn't retry the job", func() {
+					_, err := act()
+					Expect(err).ToNot(HaveOccurred())
+
+					obj := &batchv1.JobList{}
+					err = client.List(context.Background(), &crc.ListOptions{}, obj)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(*obj.Items[0].Spec.BackoffLimit).To(Equal(int32(0)))
+
+					client.Get(
+						context.Background(),
+						types.NamespacedName{
+							Name:      eJob.Name,
+							Namespace: eJob.Namespace,
+						},
+						&eJob,
+					)
+					Expect(eJob.Status.LastRun.Job).To(Equal(obj.Items[0].Name))
+					Expect(eJob.Status.LastRun.State).To(Equal(ejv1.RunStateRunning))
+				})
+			})
+
+			Context("and the manually triggered job is not an errand of a deployment", func() {
+				BeforeEach(func() {
+					eJob = env.ErrandExtendedJob("fake-pod")
+					runtimeObjects = []runtime.Object{
+						&eJob,
+					}
+					client = fake.NewFakeClient(runtimeObjects...)
+					mgr.GetClientReturns(client)
+
+					request = newRequest(eJob)
+				})
+
+				It("retries the job", func() {
+					_, err := act()
+					Expect(err).ToNot(HaveOccurred())
+
+					obj := &batchv1.JobList{}
+					err = client.List(context.Background(), &crc.ListOptions{}, obj)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(obj.Items[0].Spec.BackoffLimit).To(BeNil())
+				})
 			})
 
 			Context("and the errand is an auto-errand", func() {
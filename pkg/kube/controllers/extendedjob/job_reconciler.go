@@ -15,6 +15,7 @@ import (
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
@@ -98,6 +99,14 @@ func (r *ReconcileJob) Reconcile(request reconcile.Request) (reconcile.Result, e
 		return reconcile.Result{}, errors.Wrap(err, "getting parent ExtendedJob")
 	}
 
+	// Record the result of an errand run, before the pod of a succeeded run is deleted
+	if ej.Status.LastRun != nil && ej.Status.LastRun.Job == instance.Name {
+		err = r.recordRun(ctx, instance, &ej)
+		if err != nil {
+			ctxlog.WithEvent(&ej, "RecordRunError").Errorf(ctx, "Could not record result of job '%s': %s", instance.Name, err)
+		}
+	}
+
 	// Persist output if needed
 	if !reflect.DeepEqual(ejv1.Output{}, ej.Spec.Output) && ej.Spec.Output != nil {
 		if instance.Status.Succeeded == 1 || (instance.Status.Failed == 1 && ej.Spec.Output.WriteOnFailure) {
@@ -167,6 +176,60 @@ func (r *ReconcileJob) jobPod(ctx context.Context, name string, namespace string
 	return &list.Items[0], nil
 }
 
+// recordRun records the result of the job in the status of the ExtendedJob
+func (r *ReconcileJob) recordRun(ctx context.Context, instance *batchv1.Job, ejob *ejv1.ExtendedJob) error {
+	run := ejob.Status.LastRun.DeepCopy()
+	run.State = ejv1.RunStateFailed
+	if instance.Status.Succeeded == 1 {
+		run.State = ejv1.RunStateSucceeded
+	}
+	now := metav1.Now()
+	run.CompletionTime = &now
+
+	pod, err := r.jobPod(ctx, instance.GetName(), instance.GetNamespace())
+	if err != nil {
+		ctxlog.Infof(ctx, "Recording result of job '%s' without exit code: %s", instance.Name, err)
+	} else {
+		run.Pod = pod.Name
+		run.ExitCode = exitCode(pod)
+	}
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		ejob.Status.LastRun = run
+		err := r.client.Update(ctx, ejob)
+		if apierrors.IsConflict(err) {
+			key := types.NamespacedName{Namespace: ejob.GetNamespace(), Name: ejob.GetName()}
+			if getErr := r.client.Get(ctx, key, ejob); getErr != nil {
+				return getErr
+			}
+		}
+		return err
+	})
+}
+
+// exitCode returns the exit code of the first failed container of the pod, 0 if all
+// containers succeeded, or nil if they haven't terminated
+func exitCode(pod *corev1.Pod) *int32 {
+	statuses := append(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses...)
+	if len(statuses) == 0 {
+		return nil
+	}
+
+	for _, status := range statuses {
+		terminated := status.State.Terminated
+		if terminated == nil {
+			return nil
+		}
+		if terminated.ExitCode != 0 {
+			code := terminated.ExitCode
+			return &code
+		}
+	}
+
+	code := int32(0)
+	return &code
+}
+
 func (r *ReconcileJob) persistOutput(ctx context.Context, instance *batchv1.Job, ejob ejv1.ExtendedJob) error {
 
 	pod, err := r.jobPod(ctx, instance.GetName(), instance.GetNamespace())
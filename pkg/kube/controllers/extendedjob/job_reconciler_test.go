@@ -154,6 +154,57 @@ var _ = Describe("ReconcileExtendedJob", func() {
 		})
 	})
 
+	Context("With a succeeded errand run", func() {
+		JustBeforeEach(func() {
+			ejob.Status.LastRun = &ejapi.ExtendedJobRun{Job: job.Name, State: ejapi.RunStateRunning}
+			pod.Status.ContainerStatuses = []corev1.ContainerStatus{
+				{Name: "busybox", State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 0}}},
+			}
+		})
+
+		It("records the result of the run", func() {
+			_, err := reconciler.Reconcile(request)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(client.UpdateCallCount()).To(Equal(1))
+			_, object := client.UpdateArgsForCall(0)
+			run := object.(*ejapi.ExtendedJob).Status.LastRun
+			Expect(run.State).To(Equal(ejapi.RunStateSucceeded))
+			Expect(run.Pod).To(Equal(pod.Name))
+			Expect(*run.ExitCode).To(Equal(int32(0)))
+			Expect(run.CompletionTime).ToNot(BeNil())
+		})
+
+		It("doesn't record the result of other jobs", func() {
+			ejob.Status.LastRun.Job = "other-job"
+
+			_, err := reconciler.Reconcile(request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(client.UpdateCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("With a failed errand run", func() {
+		JustBeforeEach(func() {
+			job.Status.Succeeded = 0
+			job.Status.Failed = 1
+			ejob.Status.LastRun = &ejapi.ExtendedJobRun{Job: job.Name, State: ejapi.RunStateRunning}
+			pod.Status.ContainerStatuses = []corev1.ContainerStatus{
+				{Name: "busybox", State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 2}}},
+			}
+		})
+
+		It("records the exit code of the failed container", func() {
+			_, err := reconciler.Reconcile(request)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, object := client.UpdateArgsForCall(0)
+			run := object.(*ejapi.ExtendedJob).Status.LastRun
+			Expect(run.State).To(Equal(ejapi.RunStateFailed))
+			Expect(*run.ExitCode).To(Equal(int32(2)))
+		})
+	})
+
 	Context("With a failed Job", func() {
 		JustBeforeEach(func() {
 			job.Status.Succeeded = 0
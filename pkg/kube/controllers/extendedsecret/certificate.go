@@ -0,0 +1,640 @@
+package extendedsecret
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/api/apps/v1beta2"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"code.cloudfoundry.org/cf-operator/pkg/credsgen"
+	esv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/extendedsecret/v1alpha1"
+	estsv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/extendedstatefulset/v1alpha1"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/ctxlog"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/reference"
+)
+
+const (
+	// keyCA is the trust bundle of a certificate, for a CA it's the CA itself
+	keyCA = "ca"
+	// keyNextCertificate and keyNextPrivateKey are the CA, which replaces the CA of
+	// the secret, while the CA is rotated
+	keyNextCertificate = "next_certificate"
+	keyNextPrivateKey  = "next_private_key"
+
+	// caRotationInterval is the time after which a CA rotation checks again, if the
+	// certificates signed by the CA and their consumers have been updated
+	caRotationInterval = time.Minute
+
+	// caRotationDwellTime is the minimum duration of each stage of a CA rotation, so
+	// consumers, which aren't restarted, pick up the secrets mounted by their pods
+	caRotationDwellTime = 5 * time.Minute
+)
+
+// certificateAuthority is a CA, which signs certificates, and the trust bundle of
+// the certificates it signs
+type certificateAuthority struct {
+	credsgen.Certificate
+	bundle []byte
+}
+
+// reconcileCertificate generates the certificate of the ExtendedSecret. Existing certificates are
+// kept until they are due for renewal or don't match the request anymore. Certificates are reissued
+// when their CA changes, CAs are rotated in stages.
+func (r *ReconcileExtendedSecret) reconcileCertificate(ctx context.Context, instance *esv1.ExtendedSecret) (reconcile.Result, error) {
+	if err := validateCertificateRequest(instance.Spec.Request.CertificateRequest); err != nil {
+		return reconcile.Result{}, ctxlog.WithEvent(instance, "InvalidCertificateRequest").Errorf(ctx, "Invalid certificate request of ExtendedSecret '%s': %v", instance.GetName(), err)
+	}
+
+	existingSecret := &corev1.Secret{}
+	err := r.client.Get(ctx, types.NamespacedName{Name: instance.Spec.SecretName, Namespace: instance.GetNamespace()}, existingSecret)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return reconcile.Result{}, errors.Wrapf(err, "could not get secret")
+		}
+		existingSecret = nil
+	}
+
+	if instance.Spec.Request.CertificateRequest.IsCA {
+		return r.reconcileCA(ctx, instance, existingSecret)
+	}
+	return r.reconcileSignedCertificate(ctx, instance, existingSecret)
+}
+
+// reconcileSignedCertificate reissues the certificate when it's due, when it doesn't match the request
+// or when it isn't signed by the current CA. Otherwise only the trust bundle is updated.
+func (r *ReconcileExtendedSecret) reconcileSignedCertificate(ctx context.Context, instance *esv1.ExtendedSecret, existingSecret *corev1.Secret) (reconcile.Result, error) {
+	ca, err := r.certificateAuthority(ctx, instance)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	if existingSecret != nil {
+		cert, err := parseCertificate(existingSecret.Data["certificate"])
+		if err == nil {
+			if err := validateRenewal(cert, instance.Spec.Request.CertificateRequest); err != nil {
+				return reconcile.Result{}, ctxlog.WithEvent(instance, "InvalidCertificateRequest").Errorf(ctx, "Invalid certificate request of ExtendedSecret '%s': %v", instance.GetName(), err)
+			}
+		}
+		if err == nil && matchesRequest(cert, instance.Spec.Request.CertificateRequest) && !isDue(cert, instance.Spec.Request.CertificateRequest) && isSignedBy(cert, ca.Certificate.Certificate) {
+			if !bytes.Equal(existingSecret.Data[keyCA], ca.bundle) {
+				ctxlog.Infof(ctx, "Updating the trust bundle of certificate '%s'", instance.Spec.SecretName)
+				existingSecret.Data[keyCA] = ca.bundle
+				if err := r.createSecret(ctx, instance, existingSecret); err != nil {
+					return reconcile.Result{}, err
+				}
+			}
+			return r.updateCertificateStatus(ctx, instance, cert, "")
+		}
+		ctxlog.WithEvent(instance, "CertificateRenewal").Infof(ctx, "Reissuing certificate '%s'", instance.Spec.SecretName)
+	}
+
+	request := r.certificateRequest(instance)
+	request.CA = ca.Certificate
+	cert, err := r.generator.GenerateCertificate(instance.GetName(), request)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      instance.Spec.SecretName,
+			Namespace: instance.GetNamespace(),
+		},
+		Data: map[string][]byte{
+			"certificate": cert.Certificate,
+			"private_key": cert.PrivateKey,
+			"is_ca":       []byte(strconv.FormatBool(false)),
+		},
+	}
+	if len(ca.bundle) > 0 {
+		secret.Data[keyCA] = ca.bundle
+	}
+
+	if err := r.createSecret(ctx, instance, secret); err != nil {
+		return reconcile.Result{}, err
+	}
+	return r.updateGeneratedCertificateStatus(ctx, instance, cert.Certificate, "")
+}
+
+// reconcileCA rotates the CA when it's due or doesn't match the request anymore. The certificates
+// signed by the CA have to trust the new CA, before they are reissued by it. Once they are reissued,
+// the old CA is removed from the trust bundle. Each stage waits for the consumers of the secrets
+// to roll out, before the next one starts.
+func (r *ReconcileExtendedSecret) reconcileCA(ctx context.Context, instance *esv1.ExtendedSecret, existingSecret *corev1.Secret) (reconcile.Result, error) {
+	var cert *x509.Certificate
+	var err error
+	if existingSecret != nil {
+		cert, err = parseCertificate(existingSecret.Data["certificate"])
+		if err != nil {
+			ctxlog.WithEvent(instance, "CARotation").Infof(ctx, "Replacing CA '%s', which can't be parsed: %v", instance.Spec.SecretName, err)
+		}
+	}
+
+	// Nothing can trust a missing or broken CA, so it's replaced right away
+	if cert == nil {
+		ca, err := r.generator.GenerateCertificate(instance.GetName(), r.certificateRequest(instance))
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      instance.Spec.SecretName,
+				Namespace: instance.GetNamespace(),
+			},
+			Data: map[string][]byte{
+				"certificate": ca.Certificate,
+				"private_key": ca.PrivateKey,
+				"is_ca":       []byte(strconv.FormatBool(true)),
+				keyCA:         ca.Certificate,
+			},
+		}
+		if err := r.createSecret(ctx, instance, secret); err != nil {
+			return reconcile.Result{}, err
+		}
+		return r.updateGeneratedCertificateStatus(ctx, instance, ca.Certificate, "")
+	}
+
+	if err := validateRenewal(cert, instance.Spec.Request.CertificateRequest); err != nil {
+		return reconcile.Result{}, ctxlog.WithEvent(instance, "InvalidCertificateRequest").Errorf(ctx, "Invalid certificate request of ExtendedSecret '%s': %v", instance.GetName(), err)
+	}
+
+	switch instance.Status.CARotation {
+	case esv1.CARotationTrustNewCA:
+		trusted, err := r.signedSecretsMatch(ctx, instance, func(secret corev1.Secret) bool {
+			return bytes.Equal(secret.Data[keyCA], existingSecret.Data[keyCA])
+		})
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		if !trusted {
+			ctxlog.Debugf(ctx, "Waiting for the certificates signed by CA '%s' to trust the new CA", instance.Spec.SecretName)
+			return reconcile.Result{RequeueAfter: caRotationInterval}, nil
+		}
+		rolledOut, err := r.caRotationRolledOut(ctx, instance, existingSecret)
+		if err != nil || !rolledOut {
+			return reconcile.Result{RequeueAfter: caRotationInterval}, err
+		}
+
+		nextCert, err := parseCertificate(existingSecret.Data[keyNextCertificate])
+		if err != nil {
+			return reconcile.Result{}, errors.Wrapf(err, "parsing the new CA of secret '%s'", instance.Spec.SecretName)
+		}
+		existingSecret.Data["certificate"] = existingSecret.Data[keyNextCertificate]
+		existingSecret.Data["private_key"] = existingSecret.Data[keyNextPrivateKey]
+		delete(existingSecret.Data, keyNextCertificate)
+		delete(existingSecret.Data, keyNextPrivateKey)
+		if err := r.createSecret(ctx, instance, existingSecret); err != nil {
+			return reconcile.Result{}, err
+		}
+		ctxlog.WithEvent(instance, "CARotation").Infof(ctx, "Reissuing the certificates signed by CA '%s' with the new CA", instance.Spec.SecretName)
+
+		if _, err := r.updateCertificateStatus(ctx, instance, nextCert, esv1.CARotationReissueCertificates); err != nil {
+			return reconcile.Result{}, err
+		}
+		return reconcile.Result{RequeueAfter: caRotationInterval}, nil
+
+	case esv1.CARotationReissueCertificates:
+		reissued, err := r.signedSecretsMatch(ctx, instance, func(secret corev1.Secret) bool {
+			signedCert, err := parseCertificate(secret.Data["certificate"])
+			return err == nil && isSignedBy(signedCert, existingSecret.Data["certificate"])
+		})
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		if !reissued {
+			ctxlog.Debugf(ctx, "Waiting for the certificates signed by CA '%s' to be reissued", instance.Spec.SecretName)
+			return reconcile.Result{RequeueAfter: caRotationInterval}, nil
+		}
+		rolledOut, err := r.caRotationRolledOut(ctx, instance, existingSecret)
+		if err != nil || !rolledOut {
+			return reconcile.Result{RequeueAfter: caRotationInterval}, err
+		}
+
+		existingSecret.Data[keyCA] = existingSecret.Data["certificate"]
+		if err := r.createSecret(ctx, instance, existingSecret); err != nil {
+			return reconcile.Result{}, err
+		}
+		ctxlog.WithEvent(instance, "CARotation").Infof(ctx, "Completed the rotation of CA '%s', the old CA is no longer trusted", instance.Spec.SecretName)
+
+		return r.updateCertificateStatus(ctx, instance, cert, "")
+	}
+
+	if matchesRequest(cert, instance.Spec.Request.CertificateRequest) && !isDue(cert, instance.Spec.Request.CertificateRequest) {
+		return r.updateCertificateStatus(ctx, instance, cert, "")
+	}
+
+	next, err := r.generator.GenerateCertificate(instance.GetName(), r.certificateRequest(instance))
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	bundle := existingSecret.Data[keyCA]
+	if len(bundle) == 0 {
+		bundle = existingSecret.Data["certificate"]
+	}
+	existingSecret.Data[keyCA] = appendPEM(bundle, next.Certificate)
+	existingSecret.Data[keyNextCertificate] = next.Certificate
+	existingSecret.Data[keyNextPrivateKey] = next.PrivateKey
+	if err := r.createSecret(ctx, instance, existingSecret); err != nil {
+		return reconcile.Result{}, err
+	}
+	ctxlog.WithEvent(instance, "CARotation").Infof(ctx, "Rotating CA '%s', the new CA has been added to the trust bundle", instance.Spec.SecretName)
+
+	if _, err := r.updateCertificateStatus(ctx, instance, cert, esv1.CARotationTrustNewCA); err != nil {
+		return reconcile.Result{}, err
+	}
+	return reconcile.Result{RequeueAfter: caRotationInterval}, nil
+}
+
+// certificateRequest returns the generation request for the certificate of the ExtendedSecret
+func (r *ReconcileExtendedSecret) certificateRequest(instance *esv1.ExtendedSecret) credsgen.CertificateGenerationRequest {
+	request := credsgen.CertificateGenerationRequest{
+		IsCA:       instance.Spec.Request.CertificateRequest.IsCA,
+		CommonName: instance.Spec.Request.CertificateRequest.CommonName,
+	}
+	if !request.IsCA {
+		request.AlternativeNames = instance.Spec.Request.CertificateRequest.AlternativeNames
+	}
+	if instance.Spec.Request.CertificateRequest.Duration != nil {
+		request.Duration = instance.Spec.Request.CertificateRequest.Duration.Duration
+	}
+	return request
+}
+
+// certificateAuthority reads the CA, which signs the certificate of the ExtendedSecret.
+// The trust bundle of a generated CA includes the new CA, while the CA is rotated.
+func (r *ReconcileExtendedSecret) certificateAuthority(ctx context.Context, instance *esv1.ExtendedSecret) (certificateAuthority, error) {
+	certRequest := instance.Spec.Request.CertificateRequest
+
+	// Get CA certificate
+	caSecret := &corev1.Secret{}
+	caNamespacedName := types.NamespacedName{
+		Namespace: instance.Namespace,
+		Name:      certRequest.CARef.Name,
+	}
+	err := r.client.Get(ctx, caNamespacedName, caSecret)
+	if err != nil {
+		return certificateAuthority{}, errors.Wrap(err, "getting CA secret")
+	}
+	ca := certificateAuthority{
+		Certificate: credsgen.Certificate{
+			IsCA:        true,
+			Certificate: caSecret.Data[certRequest.CARef.Key],
+		},
+		bundle: caSecret.Data[certRequest.CARef.Key],
+	}
+	if caSecret.GetLabels()[esv1.LabelKind] == esv1.GeneratedSecretKind && len(caSecret.Data[keyCA]) > 0 {
+		ca.bundle = caSecret.Data[keyCA]
+	}
+
+	// Get CA key
+	if certRequest.CAKeyRef.Name != certRequest.CARef.Name {
+		caSecret = &corev1.Secret{}
+		caNamespacedName = types.NamespacedName{
+			Namespace: instance.Namespace,
+			Name:      certRequest.CAKeyRef.Name,
+		}
+		err = r.client.Get(ctx, caNamespacedName, caSecret)
+		if err != nil {
+			return certificateAuthority{}, errors.Wrap(err, "getting CA Key secret")
+		}
+	}
+	ca.PrivateKey = caSecret.Data[certRequest.CAKeyRef.Key]
+
+	return ca, nil
+}
+
+// signedSecretsMatch returns true, if all existing secrets of certificates signed by the CA of the ExtendedSecret match
+func (r *ReconcileExtendedSecret) signedSecretsMatch(ctx context.Context, instance *esv1.ExtendedSecret, match func(corev1.Secret) bool) (bool, error) {
+	secrets, err := r.signedSecrets(ctx, instance)
+	if err != nil {
+		return false, err
+	}
+
+	for _, secret := range secrets {
+		if !match(secret) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// signedSecrets returns the existing secrets of certificates signed by the CA of the ExtendedSecret
+func (r *ReconcileExtendedSecret) signedSecrets(ctx context.Context, instance *esv1.ExtendedSecret) ([]corev1.Secret, error) {
+	extendedSecrets := &esv1.ExtendedSecretList{}
+	err := r.client.List(ctx, &client.ListOptions{Namespace: instance.GetNamespace()}, extendedSecrets)
+	if err != nil {
+		return nil, errors.Wrap(err, "listing ExtendedSecrets")
+	}
+
+	secrets := []corev1.Secret{}
+	for _, es := range extendedSecrets.Items {
+		if !isSignedByCAOf(es, *instance) {
+			continue
+		}
+
+		secret := &corev1.Secret{}
+		err := r.client.Get(ctx, types.NamespacedName{Name: es.Spec.SecretName, Namespace: es.GetNamespace()}, secret)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				// It will be generated with the current CA
+				continue
+			}
+			return nil, errors.Wrapf(err, "getting secret '%s'", es.Spec.SecretName)
+		}
+		secrets = append(secrets, *secret)
+	}
+
+	return secrets, nil
+}
+
+// caRotationRolledOut returns true, once the current stage of a CA rotation lasted for the dwell time
+// and the consumers of the CA and of the certificates it signs rolled out after the secrets changed
+func (r *ReconcileExtendedSecret) caRotationRolledOut(ctx context.Context, instance *esv1.ExtendedSecret, caSecret *corev1.Secret) (bool, error) {
+	var since time.Time
+	if instance.Status.CARotationTime != nil {
+		since = instance.Status.CARotationTime.Time
+		if time.Since(since) < caRotationDwellTime {
+			ctxlog.Debugf(ctx, "Waiting for the stage '%s' of the rotation of CA '%s' to last %s", instance.Status.CARotation, instance.Spec.SecretName, caRotationDwellTime)
+			return false, nil
+		}
+	}
+
+	secrets, err := r.signedSecrets(ctx, instance)
+	if err != nil {
+		return false, err
+	}
+	secrets = append(secrets, *caSecret)
+
+	secretNames := map[string]bool{}
+	for _, secret := range secrets {
+		secretNames[secret.Name] = true
+		updateTime, err := time.Parse(time.RFC3339, secret.GetAnnotations()[esv1.AnnotationUpdateTime])
+		if err == nil && updateTime.After(since) {
+			since = updateTime
+		}
+	}
+
+	consumers, err := r.secretConsumers(ctx, instance, secretNames)
+	if err != nil {
+		return false, err
+	}
+	for _, consumer := range consumers {
+		rolledOut, err := r.rolledOutSince(ctx, &consumer, since)
+		if err != nil {
+			return false, err
+		}
+		if !rolledOut {
+			ctxlog.Debugf(ctx, "Waiting for ExtendedStatefulSet '%s' to roll out the rotation of CA '%s'", consumer.Name, instance.Spec.SecretName)
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// secretConsumers returns the ExtendedStatefulSets, which are updated when one of the secrets changes.
+// These are the ones referencing the secrets and the ones controlled by the owner of the ExtendedSecret,
+// e.g. the instance groups of the BOSHDeployment, whose manifest uses the CA.
+func (r *ReconcileExtendedSecret) secretConsumers(ctx context.Context, instance *esv1.ExtendedSecret, secretNames map[string]bool) ([]estsv1.ExtendedStatefulSet, error) {
+	extendedStatefulSets := &estsv1.ExtendedStatefulSetList{}
+	err := r.client.List(ctx, &client.ListOptions{Namespace: instance.GetNamespace()}, extendedStatefulSets)
+	if err != nil {
+		return nil, errors.Wrap(err, "listing ExtendedStatefulSets")
+	}
+
+	owner := metav1.GetControllerOf(instance)
+	consumers := []estsv1.ExtendedStatefulSet{}
+	for _, exStatefulSet := range extendedStatefulSets.Items {
+		if !exStatefulSet.Spec.UpdateOnConfigChange {
+			continue
+		}
+
+		if owner != nil {
+			if exStatefulSetOwner := metav1.GetControllerOf(&exStatefulSet); exStatefulSetOwner != nil && exStatefulSetOwner.UID == owner.UID {
+				consumers = append(consumers, exStatefulSet)
+				continue
+			}
+		}
+
+		references, err := reference.GetSecretsReferencedBy(exStatefulSet)
+		if err != nil {
+			return nil, err
+		}
+		for name := range secretNames {
+			if references[name] {
+				consumers = append(consumers, exStatefulSet)
+				break
+			}
+		}
+	}
+
+	return consumers, nil
+}
+
+// rolledOutSince returns true, if the StatefulSets of the latest version of the ExtendedStatefulSet
+// have been created since the given time and all their replicas are ready
+func (r *ReconcileExtendedSecret) rolledOutSince(ctx context.Context, exStatefulSet *estsv1.ExtendedStatefulSet, since time.Time) (bool, error) {
+	statefulSets := &v1beta2.StatefulSetList{}
+	err := r.client.List(ctx, &client.ListOptions{Namespace: exStatefulSet.GetNamespace()}, statefulSets)
+	if err != nil {
+		return false, errors.Wrap(err, "listing StatefulSets")
+	}
+
+	latestVersion := 0
+	latest := []v1beta2.StatefulSet{}
+	for _, statefulSet := range statefulSets.Items {
+		if !metav1.IsControlledBy(&statefulSet, exStatefulSet) {
+			continue
+		}
+		version, err := strconv.Atoi(statefulSet.GetAnnotations()[estsv1.AnnotationVersion])
+		if err != nil {
+			continue
+		}
+		switch {
+		case version > latestVersion:
+			latestVersion = version
+			latest = []v1beta2.StatefulSet{statefulSet}
+		case version == latestVersion:
+			latest = append(latest, statefulSet)
+		}
+	}
+
+	for _, statefulSet := range latest {
+		if statefulSet.CreationTimestamp.Time.Before(since) {
+			return false, nil
+		}
+		if state := statefulSet.GetAnnotations()[estsv1.AnnotationUpdateState]; state != "" && state != estsv1.UpdateStateDone {
+			return false, nil
+		}
+		replicas := int32(1)
+		if statefulSet.Spec.Replicas != nil {
+			replicas = *statefulSet.Spec.Replicas
+		}
+		if statefulSet.Status.ReadyReplicas < replicas {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// updateGeneratedCertificateStatus records the expiry of a newly generated certificate
+func (r *ReconcileExtendedSecret) updateGeneratedCertificateStatus(ctx context.Context, instance *esv1.ExtendedSecret, certPEM []byte, stage esv1.CARotationStage) (reconcile.Result, error) {
+	cert, err := parseCertificate(certPEM)
+	if err != nil {
+		ctxlog.Infof(ctx, "Can't record the expiry of certificate '%s': %v", instance.Spec.SecretName, err)
+		return reconcile.Result{}, nil
+	}
+	return r.updateCertificateStatus(ctx, instance, cert, stage)
+}
+
+// updateCertificateStatus records the expiry and renewal time of the certificate and the stage of a
+// CA rotation with its start time. The ExtendedSecret is reconciled again, when the certificate has
+// to be renewed.
+func (r *ReconcileExtendedSecret) updateCertificateStatus(ctx context.Context, instance *esv1.ExtendedSecret, cert *x509.Certificate, stage esv1.CARotationStage) (reconcile.Result, error) {
+	notAfter := metav1.NewTime(cert.NotAfter)
+	renewalTime := metav1.NewTime(renewalTime(cert, instance.Spec.Request.CertificateRequest))
+
+	if !notAfter.Equal(instance.Status.NotAfter) || !renewalTime.Equal(instance.Status.RenewalTime) || stage != instance.Status.CARotation {
+		if stage != instance.Status.CARotation {
+			instance.Status.CARotationTime = nil
+			if stage != "" {
+				now := metav1.Now()
+				instance.Status.CARotationTime = &now
+			}
+		}
+		instance.Status.NotAfter = &notAfter
+		instance.Status.RenewalTime = &renewalTime
+		instance.Status.CARotation = stage
+		if err := r.client.Status().Update(ctx, instance); err != nil {
+			return reconcile.Result{}, errors.Wrapf(err, "could not update status of ExtendedSecret '%s'", instance.GetName())
+		}
+	}
+
+	return reconcile.Result{RequeueAfter: time.Until(renewalTime.Time)}, nil
+}
+
+// isSignedByCAOf returns true, if the certificate of the ExtendedSecret es is signed by the CA of the ExtendedSecret ca
+func isSignedByCAOf(es esv1.ExtendedSecret, ca esv1.ExtendedSecret) bool {
+	return es.Spec.Type == esv1.Certificate &&
+		!es.Spec.Request.CertificateRequest.IsCA &&
+		es.Spec.Request.CertificateRequest.CARef.Name == ca.Spec.SecretName
+}
+
+// renewalTime returns the time a certificate is renewed, by default after two thirds of its validity
+func renewalTime(cert *x509.Certificate, request esv1.CertificateRequest) time.Time {
+	renewBefore := cert.NotAfter.Sub(cert.NotBefore) / 3
+	if request.RenewBefore != nil {
+		renewBefore = request.RenewBefore.Duration
+	}
+	return cert.NotAfter.Add(-renewBefore)
+}
+
+// validateCertificateRequest checks that a certificate is renewed before it expires
+func validateCertificateRequest(request esv1.CertificateRequest) error {
+	if request.RenewBefore == nil {
+		return nil
+	}
+	if request.RenewBefore.Duration <= 0 {
+		return fmt.Errorf("renewBefore '%s' must be positive", request.RenewBefore.Duration)
+	}
+	if request.Duration != nil && request.RenewBefore.Duration >= request.Duration.Duration {
+		return fmt.Errorf("renewBefore '%s' must be shorter than the duration '%s'", request.RenewBefore.Duration, request.Duration.Duration)
+	}
+	return nil
+}
+
+// validateRenewal checks that the certificate isn't renewed right after it has been issued, which
+// happens if its validity, e.g. the generator's default, isn't longer than renewBefore
+func validateRenewal(cert *x509.Certificate, request esv1.CertificateRequest) error {
+	validity := cert.NotAfter.Sub(cert.NotBefore)
+	if request.RenewBefore != nil && request.RenewBefore.Duration >= validity {
+		return fmt.Errorf("renewBefore '%s' must be shorter than the validity '%s' of the certificate", request.RenewBefore.Duration, validity)
+	}
+	return nil
+}
+
+// isDue returns true, if the certificate has to be renewed
+func isDue(cert *x509.Certificate, request esv1.CertificateRequest) bool {
+	return !time.Now().Before(renewalTime(cert, request))
+}
+
+// matchesRequest returns true, if the certificate has been generated for the request. Changes of
+// the duration are applied when the certificate is renewed.
+func matchesRequest(cert *x509.Certificate, request esv1.CertificateRequest) bool {
+	if cert.IsCA != request.IsCA || cert.Subject.CommonName != request.CommonName {
+		return false
+	}
+	if request.IsCA {
+		return true
+	}
+
+	hosts := sortedUnique(append([]string{request.CommonName}, request.AlternativeNames...))
+	names := append([]string{}, cert.DNSNames...)
+	names = append(names, cert.EmailAddresses...)
+	for _, ip := range cert.IPAddresses {
+		names = append(names, ip.String())
+	}
+	names = sortedUnique(names)
+
+	if len(hosts) != len(names) {
+		return false
+	}
+	for i := range hosts {
+		if hosts[i] != names[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// isSignedBy returns true, if the certificate is signed by the CA certificate
+func isSignedBy(cert *x509.Certificate, caPEM []byte) bool {
+	ca, err := parseCertificate(caPEM)
+	if err != nil {
+		return false
+	}
+	return cert.CheckSignatureFrom(ca) == nil
+}
+
+// parseCertificate parses the first certificate of a PEM block
+func parseCertificate(certPEM []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// appendPEM appends a PEM block to a bundle of PEM blocks
+func appendPEM(bundle []byte, block []byte) []byte {
+	result := append([]byte{}, bytes.TrimRight(bundle, "\n")...)
+	result = append(result, '\n')
+	return append(result, block...)
+}
+
+func sortedUnique(values []string) []string {
+	set := map[string]bool{}
+	for _, v := range values {
+		set[v] = true
+	}
+	result := make([]string, 0, len(set))
+	for v := range set {
+		result = append(result, v)
+	}
+	sort.Strings(result)
+	return result
+}
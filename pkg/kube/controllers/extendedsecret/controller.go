@@ -2,10 +2,12 @@ package extendedsecret
 
 import (
 	"context"
+	"reflect"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
 	crc "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
@@ -13,6 +15,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	credsgen "code.cloudfoundry.org/cf-operator/pkg/credsgen/in_memory_generator"
@@ -47,16 +50,94 @@ func Add(ctx context.Context, config *config.Config, mgr manager.Manager) error
 		},
 		DeleteFunc:  func(e event.DeleteEvent) bool { return false },
 		GenericFunc: func(e event.GenericEvent) bool { return false },
-		UpdateFunc:  func(e event.UpdateEvent) bool { return true },
+		// Status updates, e.g. of the certificate expiry, don't regenerate the secret
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldExtendedSecret := e.ObjectOld.(*es.ExtendedSecret)
+			newExtendedSecret := e.ObjectNew.(*es.ExtendedSecret)
+
+			return !reflect.DeepEqual(oldExtendedSecret.Spec, newExtendedSecret.Spec)
+		},
 	}
 	err = c.Watch(&source.Kind{Type: &es.ExtendedSecret{}}, &handler.EnqueueRequestForObject{}, p)
 	if err != nil {
 		return err
 	}
 
+	// Watch generated certificates. The certificates signed by a CA follow the rotation
+	// of the CA, the rotation proceeds once they have been updated.
+	secretPredicates := predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			return e.Meta.GetLabels()[esv1.LabelKind] == esv1.GeneratedSecretKind
+		},
+		DeleteFunc:  func(e event.DeleteEvent) bool { return false },
+		GenericFunc: func(e event.GenericEvent) bool { return false },
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldSecret := e.ObjectOld.(*corev1.Secret)
+			newSecret := e.ObjectNew.(*corev1.Secret)
+
+			return newSecret.GetLabels()[esv1.LabelKind] == esv1.GeneratedSecretKind && !reflect.DeepEqual(oldSecret.Data, newSecret.Data)
+		},
+	}
+	err = c.Watch(&source.Kind{Type: &corev1.Secret{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(func(a handler.MapObject) []reconcile.Request {
+			secret := a.Object.(*corev1.Secret)
+
+			reconciles, err := certificateReconciles(ctx, mgr.GetClient(), secret)
+			if err != nil {
+				ctxlog.Errorf(ctx, "Failed to calculate reconciles for secret '%s': %v", secret.Name, err)
+			}
+
+			for _, reconciliation := range reconciles {
+				ctxlog.NewMappingEvent(a.Object).Debug(ctx, reconciliation, "ExtendedSecret", a.Meta.GetName(), "secret")
+			}
+
+			return reconciles
+		}),
+	}, secretPredicates)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
+// certificateReconciles returns the certificate ExtendedSecrets, which are affected by a change of
+// the secret: the ExtendedSecret of the secret, the ExtendedSecrets of the certificates signed by it
+// and the ExtendedSecret of the CA which signed it.
+func certificateReconciles(ctx context.Context, client crc.Client, secret *corev1.Secret) ([]reconcile.Request, error) {
+	extendedSecrets := &es.ExtendedSecretList{}
+	err := client.List(ctx, &crc.ListOptions{Namespace: secret.Namespace}, extendedSecrets)
+	if err != nil {
+		return nil, err
+	}
+
+	caName := ""
+	for _, exSecret := range extendedSecrets.Items {
+		if exSecret.Spec.SecretName == secret.Name && exSecret.Spec.Type == es.Certificate && !exSecret.Spec.Request.CertificateRequest.IsCA {
+			caName = exSecret.Spec.Request.CertificateRequest.CARef.Name
+		}
+	}
+
+	reconciles := []reconcile.Request{}
+	for _, exSecret := range extendedSecrets.Items {
+		if exSecret.Spec.Type != es.Certificate {
+			continue
+		}
+		certRequest := exSecret.Spec.Request.CertificateRequest
+		if exSecret.Spec.SecretName == secret.Name ||
+			(!certRequest.IsCA && certRequest.CARef.Name == secret.Name) ||
+			(certRequest.IsCA && caName != "" && exSecret.Spec.SecretName == caName) {
+			reconciles = append(reconciles, reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      exSecret.Name,
+					Namespace: exSecret.Namespace,
+				}})
+		}
+	}
+
+	return reconciles, nil
+}
+
 // listSecrets gets all Secrets owned by the ExtendedSecret
 func listSecrets(ctx context.Context, client crc.Client, exSecret *es.ExtendedSecret) ([]corev1.Secret, error) {
 	ctxlog.Debug(ctx, "Listing StatefulSets owned by ExtendedStatefulSet '", exSecret.Name, "'.")
@@ -3,7 +3,8 @@ package extendedsecret
 import (
 	"context"
 	"fmt"
-	"strconv"
+	"reflect"
+	"time"
 
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
@@ -109,11 +110,12 @@ func (r *ReconcileExtendedSecret) Reconcile(request reconcile.Request) (reconcil
 		}
 	case esv1.Certificate:
 		ctxlog.Info(ctx, "Generating certificate")
-		err = r.createCertificateSecret(ctx, instance)
+		result, err := r.reconcileCertificate(ctx, instance)
 		if err != nil {
 			ctxlog.Info(ctx, "Error generating certificate secret: "+err.Error())
 			return reconcile.Result{}, errors.Wrap(err, "generating certificate secret")
 		}
+		return result, nil
 	default:
 		err = ctxlog.WithEvent(instance, "InvalidTypeError").Errorf(ctx, "Invalid type: %s", instance.Spec.Type)
 		return reconcile.Result{}, err
@@ -179,78 +181,6 @@ func (r *ReconcileExtendedSecret) createSSHSecret(ctx context.Context, instance
 	return r.createSecret(ctx, instance, secret)
 }
 
-func (r *ReconcileExtendedSecret) createCertificateSecret(ctx context.Context, instance *esv1.ExtendedSecret) error {
-	var request credsgen.CertificateGenerationRequest
-	if instance.Spec.Request.CertificateRequest.IsCA {
-		// Generate self-signed root CA certificate
-		request = credsgen.CertificateGenerationRequest{
-			IsCA:       instance.Spec.Request.CertificateRequest.IsCA,
-			CommonName: instance.Spec.Request.CertificateRequest.CommonName,
-		}
-	} else {
-		// Get CA certificate
-		caSecret := &corev1.Secret{}
-		caNamespacedName := types.NamespacedName{
-			Namespace: instance.Namespace,
-			Name:      instance.Spec.Request.CertificateRequest.CARef.Name,
-		}
-		err := r.client.Get(ctx, caNamespacedName, caSecret)
-		if err != nil {
-			return errors.Wrap(err, "getting CA secret")
-		}
-		ca := caSecret.Data[instance.Spec.Request.CertificateRequest.CARef.Key]
-
-		// Get CA key
-		if instance.Spec.Request.CertificateRequest.CAKeyRef.Name != instance.Spec.Request.CertificateRequest.CARef.Name {
-			caSecret = &corev1.Secret{}
-			caNamespacedName = types.NamespacedName{
-				Namespace: instance.Namespace,
-				Name:      instance.Spec.Request.CertificateRequest.CAKeyRef.Name,
-			}
-			err = r.client.Get(ctx, caNamespacedName, caSecret)
-			if err != nil {
-				return errors.Wrap(err, "getting CA Key secret")
-			}
-		}
-		key := caSecret.Data[instance.Spec.Request.CertificateRequest.CAKeyRef.Key]
-
-		// Build the generation request
-		request = credsgen.CertificateGenerationRequest{
-			IsCA:             instance.Spec.Request.CertificateRequest.IsCA,
-			CommonName:       instance.Spec.Request.CertificateRequest.CommonName,
-			AlternativeNames: instance.Spec.Request.CertificateRequest.AlternativeNames,
-			CA: credsgen.Certificate{
-				IsCA:        true,
-				PrivateKey:  key,
-				Certificate: ca,
-			},
-		}
-	}
-
-	// Generate certificate
-	cert, err := r.generator.GenerateCertificate(instance.GetName(), request)
-	if err != nil {
-		return err
-	}
-	secret := &corev1.Secret{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      instance.Spec.SecretName,
-			Namespace: instance.GetNamespace(),
-		},
-		Data: map[string][]byte{
-			"certificate": cert.Certificate,
-			"private_key": cert.PrivateKey,
-			"is_ca":       []byte(strconv.FormatBool(instance.Spec.Request.CertificateRequest.IsCA)),
-		},
-	}
-
-	if len(request.CA.Certificate) > 0 {
-		secret.Data["ca"] = request.CA.Certificate
-	}
-
-	return r.createSecret(ctx, instance, secret)
-}
-
 func (r *ReconcileExtendedSecret) canBeGenerated(ctx context.Context, instance *esv1.ExtendedSecret) (bool, error) {
 	secretName := instance.Spec.SecretName
 
@@ -295,7 +225,20 @@ func (r *ReconcileExtendedSecret) createSecret(ctx context.Context, instance *es
 		if !ok {
 			return fmt.Errorf("object is not a Secret")
 		}
+
+		// Record when the data changed, CA rotations wait for the consumers to roll out afterwards
+		updateTime := s.GetAnnotations()[esv1.AnnotationUpdateTime]
+		if s.GetResourceVersion() == "" || updateTime == "" || !reflect.DeepEqual(s.Data, secret.Data) {
+			updateTime = time.Now().UTC().Format(time.RFC3339)
+		}
+
 		secret.DeepCopyInto(s)
+		annotations := s.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[esv1.AnnotationUpdateTime] = updateTime
+		s.SetAnnotations(annotations)
 		return nil
 	})
 	if err != nil {
@@ -2,27 +2,34 @@ package extendedsecret_test
 
 import (
 	"context"
+	"crypto/x509"
+	"encoding/pem"
 	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"go.uber.org/zap"
 
+	"k8s.io/api/apps/v1beta2"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	crc "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	"code.cloudfoundry.org/cf-operator/pkg/credsgen"
 	generatorfakes "code.cloudfoundry.org/cf-operator/pkg/credsgen/fakes"
+	inmemorygenerator "code.cloudfoundry.org/cf-operator/pkg/credsgen/in_memory_generator"
 	esv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/extendedsecret/v1alpha1"
+	estsv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/extendedstatefulset/v1alpha1"
 	"code.cloudfoundry.org/cf-operator/pkg/kube/client/clientset/versioned/scheme"
 	"code.cloudfoundry.org/cf-operator/pkg/kube/controllers"
 	escontroller "code.cloudfoundry.org/cf-operator/pkg/kube/controllers/extendedsecret"
 	cfakes "code.cloudfoundry.org/cf-operator/pkg/kube/controllers/fakes"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util"
 	cfcfg "code.cloudfoundry.org/cf-operator/pkg/kube/util/config"
 	"code.cloudfoundry.org/cf-operator/pkg/kube/util/ctxlog"
 	helper "code.cloudfoundry.org/cf-operator/pkg/testhelper"
@@ -319,4 +326,451 @@ var _ = Describe("ReconcileExtendedSecret", func() {
 			Expect(reconcile.Result{}).To(Equal(result))
 		})
 	})
+
+	Context("when the certificate has been generated before", func() {
+		var (
+			certGenerator        *inmemorygenerator.InMemoryGenerator
+			statusWriter         *cfakes.FakeStatusWriter
+			secrets              map[string]*corev1.Secret
+			extendedSecrets      []esv1.ExtendedSecret
+			extendedStatefulSets []estsv1.ExtendedStatefulSet
+			statefulSets         []v1beta2.StatefulSet
+			ca                   credsgen.Certificate
+		)
+
+		generateCA := func() credsgen.Certificate {
+			cert, err := certGenerator.GenerateCertificate("ca", credsgen.CertificateGenerationRequest{CommonName: "Fake CA", IsCA: true})
+			Expect(err).ToNot(HaveOccurred())
+			return cert
+		}
+
+		generateCertificate := func(ca credsgen.Certificate) credsgen.Certificate {
+			cert, err := certGenerator.GenerateCertificate("cert", credsgen.CertificateGenerationRequest{
+				CommonName:       "foo.com",
+				AlternativeNames: []string{"bar.com"},
+				CA:               ca,
+			})
+			Expect(err).ToNot(HaveOccurred())
+			return cert
+		}
+
+		generatedSecret := func(name string, cert credsgen.Certificate, ca []byte) *corev1.Secret {
+			return &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: "default",
+					Labels:    map[string]string{esv1.LabelKind: esv1.GeneratedSecretKind},
+				},
+				Data: map[string][]byte{
+					"certificate": cert.Certificate,
+					"private_key": cert.PrivateKey,
+					"ca":          ca,
+				},
+			}
+		}
+
+		parseCertificate := func(certPEM []byte) *x509.Certificate {
+			block, _ := pem.Decode(certPEM)
+			Expect(block).ToNot(BeNil())
+			cert, err := x509.ParseCertificate(block.Bytes)
+			Expect(err).ToNot(HaveOccurred())
+			return cert
+		}
+
+		lastStatus := func() esv1.ExtendedSecretStatus {
+			Expect(statusWriter.UpdateCallCount()).To(BeNumerically(">", 0))
+			_, object := statusWriter.UpdateArgsForCall(statusWriter.UpdateCallCount() - 1)
+			return object.(*esv1.ExtendedSecret).Status
+		}
+
+		BeforeEach(func() {
+			certGenerator = inmemorygenerator.NewInMemoryGenerator(log)
+			certGenerator.Algorithm = "ecdsa"
+			certGenerator.Bits = 256
+
+			ca = generateCA()
+			secrets = map[string]*corev1.Secret{
+				"ca-secret": generatedSecret("ca-secret", ca, ca.Certificate),
+			}
+			extendedSecrets = []esv1.ExtendedSecret{}
+			extendedStatefulSets = []estsv1.ExtendedStatefulSet{}
+			statefulSets = []v1beta2.StatefulSet{}
+
+			es.Spec.Type = "certificate"
+			es.Spec.Request.CertificateRequest = esv1.CertificateRequest{
+				CommonName:       "foo.com",
+				AlternativeNames: []string{"bar.com"},
+				CARef:            esv1.SecretReference{Name: "ca-secret", Key: "certificate"},
+				CAKeyRef:         esv1.SecretReference{Name: "ca-secret", Key: "private_key"},
+			}
+
+			client.GetCalls(func(context context.Context, nn types.NamespacedName, object runtime.Object) error {
+				switch object := object.(type) {
+				case *esv1.ExtendedSecret:
+					es.DeepCopyInto(object)
+				case *corev1.Secret:
+					secret, ok := secrets[nn.Name]
+					if !ok {
+						return errors.NewNotFound(schema.GroupResource{}, nn.Name)
+					}
+					secret.DeepCopyInto(object)
+				}
+				return nil
+			})
+			client.ListCalls(func(context context.Context, options *crc.ListOptions, object runtime.Object) error {
+				switch object := object.(type) {
+				case *esv1.ExtendedSecretList:
+					object.Items = extendedSecrets
+				case *estsv1.ExtendedStatefulSetList:
+					object.Items = extendedStatefulSets
+				case *v1beta2.StatefulSetList:
+					object.Items = statefulSets
+				}
+				return nil
+			})
+			client.UpdateCalls(func(context context.Context, object runtime.Object) error {
+				if secret, ok := object.(*corev1.Secret); ok {
+					secrets[secret.Name] = secret.DeepCopy()
+				}
+				return nil
+			})
+			statusWriter = &cfakes.FakeStatusWriter{}
+			client.StatusReturns(statusWriter)
+		})
+
+		Context("when the certificate is signed by a CA", func() {
+			var cert credsgen.Certificate
+
+			BeforeEach(func() {
+				cert = generateCertificate(ca)
+				secrets["generated-secret"] = generatedSecret("generated-secret", cert, ca.Certificate)
+
+				generator.GenerateCertificateReturns(credsgen.Certificate{Certificate: []byte("new_cert"), PrivateKey: []byte("new_key")}, nil)
+			})
+
+			It("keeps a valid certificate and records its expiry", func() {
+				result, err := reconciler.Reconcile(request)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(generator.GenerateCertificateCallCount()).To(Equal(0))
+				Expect(client.UpdateCallCount()).To(Equal(0))
+
+				parsedCert := parseCertificate(cert.Certificate)
+				renewal := parsedCert.NotAfter.Add(-parsedCert.NotAfter.Sub(parsedCert.NotBefore) / 3)
+				status := lastStatus()
+				Expect(status.NotAfter.Time).To(BeTemporally("==", parsedCert.NotAfter))
+				Expect(status.RenewalTime.Time).To(BeTemporally("==", renewal))
+				Expect(result.RequeueAfter).To(BeNumerically("~", time.Until(renewal), time.Minute))
+			})
+
+			It("renews the certificate before it expires", func() {
+				// The generator backdates certificates, so they are due right away
+				es.Spec.Request.CertificateRequest.RenewBefore = &metav1.Duration{Duration: 365*24*time.Hour - time.Minute}
+
+				_, err := reconciler.Reconcile(request)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(generator.GenerateCertificateCallCount()).To(Equal(1))
+				_, genRequest := generator.GenerateCertificateArgsForCall(0)
+				Expect(genRequest.CA.Certificate).To(Equal(ca.Certificate))
+				Expect(secrets["generated-secret"].Data["certificate"]).To(Equal([]byte("new_cert")))
+			})
+
+			It("reissues the certificate when the request changed", func() {
+				es.Spec.Request.CertificateRequest.AlternativeNames = []string{"baz.com"}
+
+				_, err := reconciler.Reconcile(request)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(generator.GenerateCertificateCallCount()).To(Equal(1))
+				_, genRequest := generator.GenerateCertificateArgsForCall(0)
+				Expect(genRequest.AlternativeNames).To(Equal([]string{"baz.com"}))
+			})
+
+			It("reissues the certificate when it's not signed by the CA", func() {
+				otherCA := generateCA()
+				secrets["ca-secret"] = generatedSecret("ca-secret", otherCA, otherCA.Certificate)
+
+				_, err := reconciler.Reconcile(request)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(generator.GenerateCertificateCallCount()).To(Equal(1))
+				_, genRequest := generator.GenerateCertificateArgsForCall(0)
+				Expect(genRequest.CA.Certificate).To(Equal(otherCA.Certificate))
+				Expect(secrets["generated-secret"].Data["ca"]).To(Equal(otherCA.Certificate))
+			})
+
+			It("updates the trust bundle of a valid certificate", func() {
+				bundle := append(append([]byte{}, ca.Certificate...), generateCA().Certificate...)
+				secrets["ca-secret"].Data["ca"] = bundle
+
+				_, err := reconciler.Reconcile(request)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(generator.GenerateCertificateCallCount()).To(Equal(0))
+				Expect(secrets["generated-secret"].Data["certificate"]).To(Equal(cert.Certificate))
+				Expect(secrets["generated-secret"].Data["ca"]).To(Equal(bundle))
+			})
+
+			It("rejects a renewBefore, which isn't shorter than the duration", func() {
+				es.Spec.Request.CertificateRequest.Duration = &metav1.Duration{Duration: 48 * time.Hour}
+				es.Spec.Request.CertificateRequest.RenewBefore = &metav1.Duration{Duration: 48 * time.Hour}
+
+				_, err := reconciler.Reconcile(request)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("must be shorter than the duration"))
+				Expect(generator.GenerateCertificateCallCount()).To(Equal(0))
+				Expect(client.UpdateCallCount()).To(Equal(0))
+			})
+
+			It("rejects a renewBefore, which isn't shorter than the validity of the certificate", func() {
+				es.Spec.Request.CertificateRequest.RenewBefore = &metav1.Duration{Duration: 400 * 24 * time.Hour}
+
+				_, err := reconciler.Reconcile(request)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("must be shorter than the validity"))
+				Expect(generator.GenerateCertificateCallCount()).To(Equal(0))
+				Expect(client.UpdateCallCount()).To(Equal(0))
+			})
+
+			It("generates certificates with the requested duration", func() {
+				delete(secrets, "generated-secret")
+				es.Spec.Request.CertificateRequest.Duration = &metav1.Duration{Duration: 48 * time.Hour}
+
+				_, err := reconciler.Reconcile(request)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(generator.GenerateCertificateCallCount()).To(Equal(1))
+				_, genRequest := generator.GenerateCertificateArgsForCall(0)
+				Expect(genRequest.Duration).To(Equal(48 * time.Hour))
+			})
+		})
+
+		Context("when rotating a CA", func() {
+			var (
+				nextCA credsgen.Certificate
+				bundle []byte
+			)
+
+			BeforeEach(func() {
+				es.Spec.SecretName = "ca-secret"
+				es.Spec.Request.CertificateRequest = esv1.CertificateRequest{CommonName: "Fake CA", IsCA: true}
+
+				extendedSecrets = []esv1.ExtendedSecret{{
+					ObjectMeta: metav1.ObjectMeta{Name: "leaf", Namespace: "default"},
+					Spec: esv1.ExtendedSecretSpec{
+						Type:       esv1.Certificate,
+						SecretName: "leaf-secret",
+						Request: esv1.Request{CertificateRequest: esv1.CertificateRequest{
+							CARef: esv1.SecretReference{Name: "ca-secret", Key: "certificate"},
+						}},
+					},
+				}}
+				secrets["leaf-secret"] = generatedSecret("leaf-secret", generateCertificate(ca), ca.Certificate)
+
+				nextCA = generateCA()
+				bundle = append(append([]byte{}, ca.Certificate...), nextCA.Certificate...)
+				generator.GenerateCertificateReturns(nextCA, nil)
+			})
+
+			It("keeps a valid CA", func() {
+				_, err := reconciler.Reconcile(request)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(generator.GenerateCertificateCallCount()).To(Equal(0))
+				Expect(client.UpdateCallCount()).To(Equal(0))
+				Expect(lastStatus().CARotation).To(BeEmpty())
+			})
+
+			It("adds a new CA to the trust bundle when the CA is due", func() {
+				es.Spec.Request.CertificateRequest.RenewBefore = &metav1.Duration{Duration: 365*24*time.Hour - time.Minute}
+
+				result, err := reconciler.Reconcile(request)
+				Expect(err).ToNot(HaveOccurred())
+
+				caSecret := secrets["ca-secret"]
+				Expect(caSecret.Data["certificate"]).To(Equal(ca.Certificate))
+				Expect(caSecret.Data["next_certificate"]).To(Equal(nextCA.Certificate))
+				Expect(caSecret.Data["next_private_key"]).To(Equal(nextCA.PrivateKey))
+				Expect(caSecret.Data["ca"]).To(Equal(bundle))
+				Expect(caSecret.Annotations).To(HaveKey(esv1.AnnotationUpdateTime))
+				status := lastStatus()
+				Expect(status.CARotation).To(Equal(esv1.CARotationTrustNewCA))
+				Expect(status.CARotationTime.Time).To(BeTemporally("~", time.Now(), time.Minute))
+				Expect(result.RequeueAfter).To(Equal(time.Minute))
+			})
+
+			Context("when the new CA has been added to the trust bundle", func() {
+				BeforeEach(func() {
+					es.Status.CARotation = esv1.CARotationTrustNewCA
+					secrets["ca-secret"].Data["ca"] = bundle
+					secrets["ca-secret"].Data["next_certificate"] = nextCA.Certificate
+					secrets["ca-secret"].Data["next_private_key"] = nextCA.PrivateKey
+				})
+
+				It("waits for the signed certificates to trust the new CA", func() {
+					result, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(client.UpdateCallCount()).To(Equal(0))
+					Expect(result.RequeueAfter).To(Equal(time.Minute))
+				})
+
+				It("signs with the new CA once the signed certificates trust it", func() {
+					secrets["leaf-secret"].Data["ca"] = bundle
+
+					_, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+
+					caSecret := secrets["ca-secret"]
+					Expect(caSecret.Data["certificate"]).To(Equal(nextCA.Certificate))
+					Expect(caSecret.Data["private_key"]).To(Equal(nextCA.PrivateKey))
+					Expect(caSecret.Data["ca"]).To(Equal(bundle))
+					Expect(caSecret.Data).ToNot(HaveKey("next_certificate"))
+					Expect(caSecret.Data).ToNot(HaveKey("next_private_key"))
+					Expect(lastStatus().CARotation).To(Equal(esv1.CARotationReissueCertificates))
+				})
+
+				It("waits for the stage to last the dwell time", func() {
+					secrets["leaf-secret"].Data["ca"] = bundle
+					es.Status.CARotationTime = &metav1.Time{Time: time.Now().Add(-time.Minute)}
+
+					result, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(client.UpdateCallCount()).To(Equal(0))
+					Expect(result.RequeueAfter).To(Equal(time.Minute))
+				})
+
+				Context("when the secrets are consumed by an ExtendedStatefulSet", func() {
+					var updateTime time.Time
+
+					consumerStatefulSet := func(version string, created time.Time) v1beta2.StatefulSet {
+						return v1beta2.StatefulSet{
+							ObjectMeta: metav1.ObjectMeta{
+								Name:              "consumer-v" + version,
+								Namespace:         "default",
+								CreationTimestamp: metav1.Time{Time: created},
+								Annotations:       map[string]string{estsv1.AnnotationVersion: version},
+								OwnerReferences: []metav1.OwnerReference{
+									{Name: "consumer", UID: "consumer-uid", Controller: util.Bool(true)},
+								},
+							},
+							Spec:   v1beta2.StatefulSetSpec{Replicas: util.Int32(2)},
+							Status: v1beta2.StatefulSetStatus{ReadyReplicas: 2},
+						}
+					}
+
+					BeforeEach(func() {
+						es.Status.CARotationTime = &metav1.Time{Time: time.Now().Add(-time.Hour)}
+						updateTime = time.Now().Add(-10 * time.Minute).Truncate(time.Second)
+						secrets["leaf-secret"].Data["ca"] = bundle
+						secrets["leaf-secret"].Annotations = map[string]string{esv1.AnnotationUpdateTime: updateTime.UTC().Format(time.RFC3339)}
+
+						extendedStatefulSets = []estsv1.ExtendedStatefulSet{{
+							ObjectMeta: metav1.ObjectMeta{Name: "consumer", Namespace: "default", UID: "consumer-uid"},
+							Spec: estsv1.ExtendedStatefulSetSpec{
+								UpdateOnConfigChange: true,
+								Template: v1beta2.StatefulSet{Spec: v1beta2.StatefulSetSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+									Volumes: []corev1.Volume{{
+										Name:         "leaf",
+										VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "leaf-secret"}},
+									}},
+								}}}},
+							},
+						}}
+					})
+
+					It("waits for the consumers to roll out a version created after the secrets changed", func() {
+						statefulSets = []v1beta2.StatefulSet{consumerStatefulSet("1", updateTime.Add(-time.Minute))}
+
+						result, err := reconciler.Reconcile(request)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(client.UpdateCallCount()).To(Equal(0))
+						Expect(result.RequeueAfter).To(Equal(time.Minute))
+					})
+
+					It("waits for the rollout of the latest version to finish", func() {
+						statefulSet := consumerStatefulSet("2", updateTime.Add(time.Minute))
+						statefulSet.Annotations[estsv1.AnnotationUpdateState] = estsv1.UpdateStateCanary
+						statefulSets = []v1beta2.StatefulSet{consumerStatefulSet("1", updateTime.Add(-time.Minute)), statefulSet}
+
+						_, err := reconciler.Reconcile(request)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(client.UpdateCallCount()).To(Equal(0))
+					})
+
+					It("waits for the replicas of the latest version to be ready", func() {
+						statefulSet := consumerStatefulSet("2", updateTime.Add(time.Minute))
+						statefulSet.Status.ReadyReplicas = 1
+						statefulSets = []v1beta2.StatefulSet{statefulSet}
+
+						_, err := reconciler.Reconcile(request)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(client.UpdateCallCount()).To(Equal(0))
+					})
+
+					It("signs with the new CA once the consumers rolled out", func() {
+						statefulSets = []v1beta2.StatefulSet{
+							consumerStatefulSet("1", updateTime.Add(-time.Minute)),
+							consumerStatefulSet("2", updateTime.Add(time.Minute)),
+						}
+
+						_, err := reconciler.Reconcile(request)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(secrets["ca-secret"].Data["certificate"]).To(Equal(nextCA.Certificate))
+						Expect(lastStatus().CARotation).To(Equal(esv1.CARotationReissueCertificates))
+					})
+
+					It("waits for ExtendedStatefulSets controlled by the owner of the ExtendedSecret", func() {
+						owner := metav1.OwnerReference{Name: "deployment", UID: "deployment-uid", Controller: util.Bool(true)}
+						es.OwnerReferences = []metav1.OwnerReference{owner}
+						extendedStatefulSets[0].OwnerReferences = []metav1.OwnerReference{owner}
+						extendedStatefulSets[0].Spec.Template.Spec.Template.Spec.Volumes = nil
+						statefulSets = []v1beta2.StatefulSet{consumerStatefulSet("1", updateTime.Add(-time.Minute))}
+
+						_, err := reconciler.Reconcile(request)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(client.UpdateCallCount()).To(Equal(0))
+					})
+
+					It("ignores ExtendedStatefulSets, which don't update on config changes", func() {
+						extendedStatefulSets[0].Spec.UpdateOnConfigChange = false
+						statefulSets = []v1beta2.StatefulSet{consumerStatefulSet("1", updateTime.Add(-time.Minute))}
+
+						_, err := reconciler.Reconcile(request)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(lastStatus().CARotation).To(Equal(esv1.CARotationReissueCertificates))
+					})
+				})
+			})
+
+			Context("when the signed certificates are reissued", func() {
+				BeforeEach(func() {
+					es.Status.CARotation = esv1.CARotationReissueCertificates
+					secrets["ca-secret"] = generatedSecret("ca-secret", nextCA, bundle)
+					secrets["leaf-secret"].Data["ca"] = bundle
+				})
+
+				It("waits for the signed certificates to be reissued", func() {
+					result, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(client.UpdateCallCount()).To(Equal(0))
+					Expect(result.RequeueAfter).To(Equal(time.Minute))
+				})
+
+				It("removes the old CA from the trust bundle once all certificates are reissued", func() {
+					secrets["leaf-secret"] = generatedSecret("leaf-secret", generateCertificate(nextCA), bundle)
+
+					_, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(secrets["ca-secret"].Data["ca"]).To(Equal(nextCA.Certificate))
+					status := lastStatus()
+					Expect(status.CARotation).To(BeEmpty())
+					Expect(status.CARotationTime).To(BeNil())
+				})
+
+				It("waits for the stage to last the dwell time", func() {
+					secrets["leaf-secret"] = generatedSecret("leaf-secret", generateCertificate(nextCA), bundle)
+					es.Status.CARotationTime = &metav1.Time{Time: time.Now().Add(-time.Minute)}
+
+					result, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(client.UpdateCallCount()).To(Equal(0))
+					Expect(result.RequeueAfter).To(Equal(time.Minute))
+				})
+			})
+		})
+	})
 })
@@ -17,6 +17,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	estsv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/extendedstatefulset/v1alpha1"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util"
 	"code.cloudfoundry.org/cf-operator/pkg/kube/util/config"
 	"code.cloudfoundry.org/cf-operator/pkg/kube/util/ctxlog"
 	vss "code.cloudfoundry.org/cf-operator/pkg/kube/util/versionedsecretstore"
@@ -27,7 +28,7 @@ const (
 	EnvKubeAz = "KUBE_AZ"
 	// EnvBoshAz is set by available zone name
 	EnvBoshAz = "BOSH_AZ"
-	// EnvReplicas describes the number of replicas in the ExtendedStatefulSet
+	// EnvReplicas describes the number of replicas in the zone of the ExtendedStatefulSet
 	EnvReplicas = "REPLICAS"
 	// EnvCfOperatorAz is set by available zone name
 	EnvCfOperatorAz = "CF_OPERATOR_AZ"
@@ -111,6 +112,13 @@ func (r *ReconcileExtendedStatefulSet) Reconcile(request reconcile.Request) (rec
 		}
 	}
 
+	// Start a staged rollout with the canaries, unless this is the first version
+	rollingOut := exStatefulSet.Spec.Update != nil && actualVersion > 0
+	var updatedReplicas int32
+	if rollingOut {
+		updatedReplicas = startRollout(exStatefulSet, desiredStatefulSets)
+	}
+
 	for _, desiredStatefulSet := range desiredStatefulSets {
 		desiredStatefulSet.Spec.VolumeClaimTemplates = []corev1.PersistentVolumeClaim{}
 
@@ -124,6 +132,16 @@ func (r *ReconcileExtendedStatefulSet) Reconcile(request reconcile.Request) (rec
 		}
 	}
 
+	if rollingOut {
+		statefulSets, err := listStatefulSets(ctx, r.client, exStatefulSet)
+		if err == nil {
+			err = scaleDownPreviousVersions(ctx, r.client, exStatefulSet, statefulSets, desiredVersion, updatedReplicas)
+		}
+		if err != nil {
+			return reconcile.Result{}, ctxlog.WithEvent(exStatefulSet, "RolloutError").Error(ctx, "Could not scale down previous versions of ExtendedStatefulSet '", request.NamespacedName, "': ", err)
+		}
+	}
+
 	return reconcile.Result{}, nil
 }
 
@@ -283,7 +301,9 @@ func (r *ReconcileExtendedStatefulSet) generateSingleStatefulSet(exStatefulSet *
 	statefulSet.Spec.Template.SetLabels(podLabels)
 	statefulSet.Spec.Template.SetAnnotations(podAnnotations)
 
-	r.injectContainerEnv(&statefulSet.Spec.Template.Spec, zoneIndex, zoneName, exStatefulSet.Spec.Template.Spec.Replicas)
+	statefulSet.Spec.Replicas = util.Int32(zoneReplicas(exStatefulSet, zoneIndex))
+
+	r.injectContainerEnv(&statefulSet.Spec.Template.Spec, zoneIndex, zoneName, *statefulSet.Spec.Replicas)
 
 	annotations[estsv1.AnnotationVersion] = fmt.Sprintf("%d", version)
 
@@ -337,7 +357,7 @@ func (r *ReconcileExtendedStatefulSet) updateAffinity(statefulSet *v1beta2.State
 }
 
 // injectContainerEnv inject AZ info to container envs
-func (r *ReconcileExtendedStatefulSet) injectContainerEnv(podSpec *corev1.PodSpec, zoneIndex int, zoneName string, replicas *int32) {
+func (r *ReconcileExtendedStatefulSet) injectContainerEnv(podSpec *corev1.PodSpec, zoneIndex int, zoneName string, replicas int32) {
 
 	containers := []*corev1.Container{}
 	for i := 0; i < len(podSpec.Containers); i++ {
@@ -358,7 +378,7 @@ func (r *ReconcileExtendedStatefulSet) injectContainerEnv(podSpec *corev1.PodSpe
 			// Default to zone 1
 			envs = upsertEnvs(envs, EnvCfOperatorAzIndex, "1")
 		}
-		envs = upsertEnvs(envs, EnvReplicas, strconv.Itoa(int(*replicas)))
+		envs = upsertEnvs(envs, EnvReplicas, strconv.Itoa(int(replicas)))
 
 		container.Env = envs
 	}
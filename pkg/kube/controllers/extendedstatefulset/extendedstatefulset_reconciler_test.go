@@ -2,6 +2,7 @@ package extendedstatefulset_test
 
 import (
 	"context"
+	"fmt"
 	"strconv"
 	"time"
 
@@ -245,6 +246,63 @@ var _ = Describe("ReconcileExtendedStatefulSet", func() {
 					})
 				})
 
+				When("the total number of replicas is set", func() {
+					BeforeEach(func() {
+						desiredExtendedStatefulSet.Spec.Replicas = util.Int32(4)
+
+						client = fake.NewFakeClient(
+							desiredExtendedStatefulSet,
+						)
+						manager.GetClientReturns(client)
+					})
+
+					It("distributes the replicas round robin across the zones", func() {
+						_, err := reconciler.Reconcile(request)
+						Expect(err).ToNot(HaveOccurred())
+
+						for idx, replicas := range []int32{2, 1, 1} {
+							ss := &v1beta2.StatefulSet{}
+							err = client.Get(context.Background(), types.NamespacedName{Name: fmt.Sprintf("foo-z%d-v1", idx), Namespace: "default"}, ss)
+							Expect(err).ToNot(HaveOccurred())
+							Expect(*ss.Spec.Replicas).To(Equal(replicas))
+
+							Expect(ss.Spec.Template.Spec.Containers[0].Env).Should(ContainElement(corev1.EnvVar{
+								Name:  exssc.EnvReplicas,
+								Value: strconv.Itoa(int(replicas)),
+							}))
+						}
+					})
+				})
+
+				When("the replicas of each zone are set", func() {
+					BeforeEach(func() {
+						desiredExtendedStatefulSet.Spec.Replicas = util.Int32(4)
+						desiredExtendedStatefulSet.Spec.ZoneReplicas = []int32{1, 1, 2}
+
+						client = fake.NewFakeClient(
+							desiredExtendedStatefulSet,
+						)
+						manager.GetClientReturns(client)
+					})
+
+					It("creates the StatefulSets with the replicas of their zone", func() {
+						_, err := reconciler.Reconcile(request)
+						Expect(err).ToNot(HaveOccurred())
+
+						for idx, replicas := range []int32{1, 1, 2} {
+							ss := &v1beta2.StatefulSet{}
+							err = client.Get(context.Background(), types.NamespacedName{Name: fmt.Sprintf("foo-z%d-v1", idx), Namespace: "default"}, ss)
+							Expect(err).ToNot(HaveOccurred())
+							Expect(*ss.Spec.Replicas).To(Equal(replicas))
+
+							Expect(ss.Spec.Template.Spec.Containers[0].Env).Should(ContainElement(corev1.EnvVar{
+								Name:  exssc.EnvReplicas,
+								Value: strconv.Itoa(int(replicas)),
+							}))
+						}
+					})
+				})
+
 				When("When zoneNodeLabel has been specified", func() {
 					var (
 						customizedNodeLabel string
@@ -721,6 +779,46 @@ var _ = Describe("ReconcileExtendedStatefulSet", func() {
 
 				Expect(metav1.IsControlledBy(ss, ess)).To(BeTrue())
 			})
+
+			Context("when an update strategy is set", func() {
+				BeforeEach(func() {
+					desiredExtendedStatefulSet.Spec.Template.Spec.Replicas = util.Int32(3)
+					desiredExtendedStatefulSet.Spec.Update = &exss.UpdateStrategy{
+						Canaries:        1,
+						CanaryWatchTime: "1000-30000",
+					}
+					v1StatefulSet.Spec.Replicas = util.Int32(0)
+					v2StatefulSet.Spec.Replicas = util.Int32(3)
+
+					client = fake.NewFakeClient(
+						desiredExtendedStatefulSet,
+						v1StatefulSet,
+						v2StatefulSet,
+					)
+					manager.GetClientReturns(client)
+				})
+
+				It("creates version 3 with the canaries and scales down version 2", func() {
+					result, err := reconciler.Reconcile(request)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(result).To(Equal(reconcile.Result{}))
+
+					ss := &v1beta2.StatefulSet{}
+					err = client.Get(context.Background(), types.NamespacedName{Name: "foo-v3", Namespace: "default"}, ss)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(*ss.Spec.Replicas).To(Equal(int32(1)))
+					Expect(ss.Annotations).To(HaveKeyWithValue(exss.AnnotationUpdateState, exss.UpdateStateCanary))
+					Expect(ss.Annotations).To(HaveKey(exss.AnnotationUpdateStartTime))
+
+					err = client.Get(context.Background(), types.NamespacedName{Name: "foo-v2", Namespace: "default"}, ss)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(*ss.Spec.Replicas).To(Equal(int32(2)))
+
+					err = client.Get(context.Background(), types.NamespacedName{Name: "foo-v1", Namespace: "default"}, ss)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(*ss.Spec.Replicas).To(Equal(int32(0)))
+				})
+			})
 		})
 	})
 })
@@ -0,0 +1,315 @@
+package extendedstatefulset
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/api/apps/v1beta2"
+	crc "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	estsv1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/extendedstatefulset/v1alpha1"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util/ctxlog"
+)
+
+// startRollout reduces the replicas of the StatefulSets of a new version to
+// the number of canaries and marks them as rolling out
+func startRollout(exStatefulSet *estsv1.ExtendedStatefulSet, statefulSets []v1beta2.StatefulSet) int32 {
+	update := exStatefulSet.Spec.Update
+	total := totalReplicas(exStatefulSet)
+	updated := update.Canaries
+	state := estsv1.UpdateStateCanary
+	if updated == 0 {
+		updated = update.BatchSize(total)
+		state = estsv1.UpdateStateUpdating
+	}
+	if updated > total {
+		updated = total
+	}
+
+	for i := range statefulSets {
+		statefulSet := &statefulSets[i]
+		statefulSet.Spec.Replicas = util.Int32(updatedZoneReplicas(exStatefulSet, updated, zoneIndex(statefulSet)))
+		setUpdateState(statefulSet, state, time.Now())
+	}
+
+	return updated
+}
+
+// rollout advances the rollout of the latest version of the ExtendedStatefulSet.
+// It returns true as long as older versions are still required, i.e. the rollout
+// is in progress or has been halted.
+func rollout(ctx context.Context, client crc.Client, exStatefulSet *estsv1.ExtendedStatefulSet) (reconcile.Result, bool, error) {
+	statefulSets, err := listStatefulSets(ctx, client, exStatefulSet)
+	if err != nil {
+		return reconcile.Result{}, false, errors.Wrap(err, "could not list StatefulSets for rollout")
+	}
+
+	latestVersion, current, err := latestStatefulSets(statefulSets)
+	if err != nil || len(current) == 0 {
+		return reconcile.Result{}, false, err
+	}
+
+	state := current[0].Annotations[estsv1.AnnotationUpdateState]
+	switch state {
+	case "", estsv1.UpdateStateDone:
+		return reconcile.Result{}, false, nil
+	case estsv1.UpdateStateFailed:
+		return reconcile.Result{}, true, nil
+	}
+
+	update := exStatefulSet.Spec.Update
+	watchTime := ""
+	if update != nil {
+		watchTime = update.UpdateWatchTime
+		if state == estsv1.UpdateStateCanary {
+			watchTime = update.CanaryWatchTime
+		}
+	}
+	minWatchTime, maxWatchTime, err := estsv1.ParseWatchTime(watchTime)
+	if err != nil {
+		return reconcile.Result{}, true, err
+	}
+
+	startTime, err := time.Parse(time.RFC3339, current[0].Annotations[estsv1.AnnotationUpdateStartTime])
+	if err != nil {
+		return reconcile.Result{}, true, errors.Wrapf(err, "invalid update start time on StatefulSet '%s'", current[0].Name)
+	}
+	elapsed := time.Since(startTime)
+
+	total := totalReplicas(exStatefulSet)
+	var updated, ready int32
+	for _, statefulSet := range current {
+		updated += *statefulSet.Spec.Replicas
+		ready += statefulSet.Status.ReadyReplicas
+	}
+
+	if ready < updated {
+		if maxWatchTime > 0 && elapsed >= maxWatchTime {
+			for i := range current {
+				setUpdateState(&current[i], estsv1.UpdateStateFailed, startTime)
+				if err := client.Update(ctx, &current[i]); err != nil {
+					return reconcile.Result{}, true, errors.Wrapf(err, "could not mark rollout of StatefulSet '%s' as failed", current[i].Name)
+				}
+			}
+			ctxlog.WithEvent(exStatefulSet, "RolloutFailed").Errorf(ctx, "Halted rollout of version %d of ExtendedStatefulSet '%s/%s': %d of %d updated replicas are ready after %s", latestVersion, exStatefulSet.Namespace, exStatefulSet.Name, ready, updated, maxWatchTime)
+			return reconcile.Result{}, true, nil
+		}
+
+		if maxWatchTime > 0 {
+			return reconcile.Result{RequeueAfter: maxWatchTime - elapsed}, true, nil
+		}
+		return reconcile.Result{}, true, nil
+	}
+
+	if elapsed < minWatchTime {
+		return reconcile.Result{RequeueAfter: minWatchTime - elapsed}, true, nil
+	}
+
+	if updated >= total {
+		for i := range current {
+			setUpdateState(&current[i], estsv1.UpdateStateDone, startTime)
+			if err := client.Update(ctx, &current[i]); err != nil {
+				return reconcile.Result{}, true, errors.Wrapf(err, "could not mark rollout of StatefulSet '%s' as done", current[i].Name)
+			}
+		}
+		ctxlog.WithEvent(exStatefulSet, "RolloutDone").Infof(ctx, "Rolled out version %d of ExtendedStatefulSet '%s/%s'", latestVersion, exStatefulSet.Namespace, exStatefulSet.Name)
+		return reconcile.Result{}, false, nil
+	}
+
+	updated += update.BatchSize(total)
+	if updated > total {
+		updated = total
+	}
+
+	ctxlog.WithEvent(exStatefulSet, "RolloutProgress").Infof(ctx, "Updating %d of %d replicas of ExtendedStatefulSet '%s/%s' to version %d", updated, total, exStatefulSet.Namespace, exStatefulSet.Name, latestVersion)
+	for i := range current {
+		statefulSet := &current[i]
+		statefulSet.Spec.Replicas = util.Int32(updatedZoneReplicas(exStatefulSet, updated, zoneIndex(statefulSet)))
+		setUpdateState(statefulSet, estsv1.UpdateStateUpdating, time.Now())
+		if err := client.Update(ctx, statefulSet); err != nil {
+			return reconcile.Result{}, true, errors.Wrapf(err, "could not scale StatefulSet '%s'", statefulSet.Name)
+		}
+	}
+
+	err = scaleDownPreviousVersions(ctx, client, exStatefulSet, statefulSets, latestVersion, updated)
+	if err != nil {
+		return reconcile.Result{}, true, err
+	}
+
+	if update == nil {
+		return reconcile.Result{}, true, nil
+	}
+	_, maxWatchTime, err = estsv1.ParseWatchTime(update.UpdateWatchTime)
+	if err != nil {
+		return reconcile.Result{}, true, err
+	}
+	return reconcile.Result{RequeueAfter: maxWatchTime}, true, nil
+}
+
+// scaleDownPreviousVersions removes as many replicas from the StatefulSets of
+// older versions as have been updated to the latest version. Replicas of older
+// versions are never scaled up.
+func scaleDownPreviousVersions(ctx context.Context, client crc.Client, exStatefulSet *estsv1.ExtendedStatefulSet, statefulSets []v1beta2.StatefulSet, latestVersion int, updated int32) error {
+	for i := range statefulSets {
+		statefulSet := &statefulSets[i]
+		if isVolumeManagementStatefulSet(statefulSet.Name) {
+			continue
+		}
+
+		version, err := getVersion(statefulSet)
+		if err != nil {
+			return err
+		}
+		if version >= latestVersion {
+			continue
+		}
+
+		desired := zoneReplicas(exStatefulSet, zoneIndex(statefulSet)) - updatedZoneReplicas(exStatefulSet, updated, zoneIndex(statefulSet))
+		if statefulSet.Spec.Replicas != nil && *statefulSet.Spec.Replicas <= desired {
+			continue
+		}
+
+		ctxlog.Debugf(ctx, "Scaling down StatefulSet '%s' to %d replicas", statefulSet.Name, desired)
+		statefulSet.Spec.Replicas = util.Int32(desired)
+		if err := client.Update(ctx, statefulSet); err != nil {
+			return errors.Wrapf(err, "could not scale down StatefulSet '%s'", statefulSet.Name)
+		}
+	}
+
+	return nil
+}
+
+// latestStatefulSets returns the StatefulSets, one per zone, of the latest version
+func latestStatefulSets(statefulSets []v1beta2.StatefulSet) (int, []v1beta2.StatefulSet, error) {
+	latestVersion := 0
+	var latest []v1beta2.StatefulSet
+
+	for _, statefulSet := range statefulSets {
+		if isVolumeManagementStatefulSet(statefulSet.Name) {
+			continue
+		}
+
+		version, err := getVersion(&statefulSet)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		switch {
+		case version > latestVersion:
+			latestVersion = version
+			latest = []v1beta2.StatefulSet{statefulSet}
+		case version == latestVersion:
+			latest = append(latest, statefulSet)
+		}
+	}
+
+	return latestVersion, latest, nil
+}
+
+// getVersion returns the version of a StatefulSet owned by an ExtendedStatefulSet
+func getVersion(statefulSet *v1beta2.StatefulSet) (int, error) {
+	strVersion, found := statefulSet.Annotations[estsv1.AnnotationVersion]
+	if !found {
+		return 0, errors.Errorf("version annotation is not found from: %+v", statefulSet.Annotations)
+	}
+
+	version, err := strconv.Atoi(strVersion)
+	if err != nil {
+		return 0, errors.Wrapf(err, "version annotation is not an int: %s", strVersion)
+	}
+	return version, nil
+}
+
+// replicasForZone distributes the replicas round robin across the zones
+func replicasForZone(replicas int32, zones int, zoneIndex int) int32 {
+	zoneReplicas := replicas / int32(zones)
+	if int32(zoneIndex) < replicas%int32(zones) {
+		zoneReplicas++
+	}
+	return zoneReplicas
+}
+
+// updatedZoneReplicas distributes the updated replicas round robin across the zones,
+// skipping zones whose replicas are all updated
+func updatedZoneReplicas(exStatefulSet *estsv1.ExtendedStatefulSet, updated int32, zoneIndex int) int32 {
+	zones := zoneCount(exStatefulSet)
+	if zoneIndex >= zones {
+		return 0
+	}
+	replicas := make([]int32, zones)
+	for distributed := true; updated > 0 && distributed; {
+		distributed = false
+		for i := 0; i < zones && updated > 0; i++ {
+			if replicas[i] < zoneReplicas(exStatefulSet, i) {
+				replicas[i]++
+				updated--
+				distributed = true
+			}
+		}
+	}
+	return replicas[zoneIndex]
+}
+
+// zoneIndex returns the availability zone index of a StatefulSet, which is 0 without zones
+func zoneIndex(statefulSet *v1beta2.StatefulSet) int {
+	index, err := strconv.Atoi(statefulSet.Labels[estsv1.LabelAZIndex])
+	if err != nil {
+		return 0
+	}
+	return index
+}
+
+// templateReplicas returns the number of replicas per zone of the template
+func templateReplicas(exStatefulSet *estsv1.ExtendedStatefulSet) int32 {
+	if exStatefulSet.Spec.Template.Spec.Replicas == nil {
+		return 1
+	}
+	return *exStatefulSet.Spec.Template.Spec.Replicas
+}
+
+// zoneCount returns the number of zones, which is 1 without zones
+func zoneCount(exStatefulSet *estsv1.ExtendedStatefulSet) int {
+	if len(exStatefulSet.Spec.Zones) == 0 {
+		return 1
+	}
+	return len(exStatefulSet.Spec.Zones)
+}
+
+// totalReplicas returns the number of replicas across all zones
+func totalReplicas(exStatefulSet *estsv1.ExtendedStatefulSet) int32 {
+	if len(exStatefulSet.Spec.ZoneReplicas) == zoneCount(exStatefulSet) {
+		var total int32
+		for _, replicas := range exStatefulSet.Spec.ZoneReplicas {
+			total += replicas
+		}
+		return total
+	}
+	if exStatefulSet.Spec.Replicas != nil {
+		return *exStatefulSet.Spec.Replicas
+	}
+	return templateReplicas(exStatefulSet) * int32(zoneCount(exStatefulSet))
+}
+
+// zoneReplicas returns the number of replicas of the StatefulSet of a zone. Zones,
+// which have been removed, have no replicas.
+func zoneReplicas(exStatefulSet *estsv1.ExtendedStatefulSet, zoneIndex int) int32 {
+	if zoneIndex >= zoneCount(exStatefulSet) {
+		return 0
+	}
+	if len(exStatefulSet.Spec.ZoneReplicas) == zoneCount(exStatefulSet) {
+		return exStatefulSet.Spec.ZoneReplicas[zoneIndex]
+	}
+	return replicasForZone(totalReplicas(exStatefulSet), zoneCount(exStatefulSet), zoneIndex)
+}
+
+func setUpdateState(statefulSet *v1beta2.StatefulSet, state string, startTime time.Time) {
+	if statefulSet.Annotations == nil {
+		statefulSet.Annotations = map[string]string{}
+	}
+	statefulSet.Annotations[estsv1.AnnotationUpdateState] = state
+	statefulSet.Annotations[estsv1.AnnotationUpdateStartTime] = startTime.UTC().Format(time.RFC3339)
+}
@@ -31,13 +31,14 @@ func AddStatefulSetCleanup(ctx context.Context, config *config.Config, mgr manag
 	// Trigger when
 	// - at least one pod of new version is running
 	// - all pods of volume management are running
+	// - a staged rollout is in progress
 	statefulSetPredicates := predicate.Funcs{
 		CreateFunc: func(e event.CreateEvent) bool {
 			newStatefulSet := e.Object.(*v1beta2.StatefulSet)
 			enqueueForVolumeManagementStatefulSet := isVolumeManagementStatefulSet(newStatefulSet.Name) && newStatefulSet.Status.ReadyReplicas > 0 && newStatefulSet.Status.ReadyReplicas == newStatefulSet.Status.CurrentReplicas
 			enqueueForVersionStatefulSet := newStatefulSet.Status.ReadyReplicas > 0
 
-			return enqueueForVersionStatefulSet || enqueueForVolumeManagementStatefulSet
+			return enqueueForVersionStatefulSet || enqueueForVolumeManagementStatefulSet || isRollingOut(newStatefulSet)
 		},
 		DeleteFunc:  func(e event.DeleteEvent) bool { return false },
 		GenericFunc: func(e event.GenericEvent) bool { return false },
@@ -46,7 +47,7 @@ func AddStatefulSetCleanup(ctx context.Context, config *config.Config, mgr manag
 			enqueueForVolumeManagementStatefulSet := isVolumeManagementStatefulSet(newStatefulSet.Name) && newStatefulSet.Status.ReadyReplicas > 0 && newStatefulSet.Status.ReadyReplicas == newStatefulSet.Status.CurrentReplicas
 			enqueueForVersionStatefulSet := newStatefulSet.Status.ReadyReplicas > 0
 
-			return enqueueForVersionStatefulSet || enqueueForVolumeManagementStatefulSet
+			return enqueueForVersionStatefulSet || enqueueForVolumeManagementStatefulSet || isRollingOut(newStatefulSet)
 		},
 	}
 	err = c.Watch(&source.Kind{Type: &v1beta2.StatefulSet{}}, &handler.EnqueueRequestForOwner{
@@ -59,3 +60,9 @@ func AddStatefulSetCleanup(ctx context.Context, config *config.Config, mgr manag
 
 	return nil
 }
+
+// isRollingOut returns true if the StatefulSet is part of an unfinished staged rollout
+func isRollingOut(statefulSet *v1beta2.StatefulSet) bool {
+	state := statefulSet.Annotations[estsv1.AnnotationUpdateState]
+	return state == estsv1.UpdateStateCanary || state == estsv1.UpdateStateUpdating
+}
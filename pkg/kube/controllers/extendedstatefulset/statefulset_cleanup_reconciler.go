@@ -39,7 +39,7 @@ type ReconcileStatefulSetCleanup struct {
 	config *config.Config
 }
 
-// Reconcile cleans up old versions and volumeManagement statefulSet of the ExtendedStatefulSet
+// Reconcile advances staged rollouts and cleans up old versions and volumeManagement statefulSet of the ExtendedStatefulSet
 func (r *ReconcileStatefulSetCleanup) Reconcile(request reconcile.Request) (reconcile.Result, error) {
 
 	// Fetch the ExtendedStatefulSet we need to reconcile
@@ -71,6 +71,15 @@ func (r *ReconcileStatefulSetCleanup) Reconcile(request reconcile.Request) (reco
 		return reconcile.Result{}, err
 	}
 
+	// Advance a staged rollout, older versions are kept until it has finished
+	result, rollingOut, err := rollout(ctx, r.client, exStatefulSet)
+	if err != nil {
+		return reconcile.Result{}, ctxlog.WithEvent(exStatefulSet, "RolloutError").Error(ctx, "Could not roll out ExtendedStatefulSet '", request.NamespacedName, "': ", err)
+	}
+	if rollingOut {
+		return result, nil
+	}
+
 	statefulSetVersions, err := r.listStatefulSetVersions(ctx, exStatefulSet)
 	if err != nil {
 		return reconcile.Result{}, err
@@ -16,6 +16,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/kubernetes/scheme"
 	crc "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
@@ -393,8 +394,118 @@ var _ = Describe("ReconcileStatefulSetCleanup", func() {
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("some error"))
 			Expect(client.GetCallCount()).To(Equal(1))
-			Expect(client.ListCallCount()).To(Equal(5))
+			Expect(client.ListCallCount()).To(Equal(6))
 			Expect(client.DeleteCallCount()).To(Equal(1))
 		})
+
+		Context("when a staged rollout is in progress", func() {
+			BeforeEach(func() {
+				podV2.Status = corev1.PodStatus{
+					Conditions: []corev1.PodCondition{
+						{
+							Type:   corev1.PodReady,
+							Status: corev1.ConditionTrue,
+						},
+					},
+				}
+				desiredExStatefulSet.Spec.Template.Spec.Replicas = util.Int32(4)
+				desiredExStatefulSet.Spec.Update = &exss.UpdateStrategy{
+					Canaries:        1,
+					MaxInFlight:     &intstr.IntOrString{Type: intstr.String, StrVal: "50%"},
+					CanaryWatchTime: "1000-30000",
+					UpdateWatchTime: "60000",
+				}
+				statefulSetV1.Spec.Replicas = util.Int32(3)
+				statefulSetV2.Spec.Replicas = util.Int32(1)
+				statefulSetV2.Annotations[exss.AnnotationUpdateState] = exss.UpdateStateCanary
+				statefulSetV2.Annotations[exss.AnnotationUpdateStartTime] = time.Now().Add(-10 * time.Second).UTC().Format(time.RFC3339)
+
+				client.GetCalls(func(context context.Context, nn types.NamespacedName, object runtime.Object) error {
+					switch object := object.(type) {
+					case *exss.ExtendedStatefulSet:
+						desiredExStatefulSet.DeepCopyInto(object)
+						return nil
+					}
+					return apierrors.NewNotFound(schema.GroupResource{}, nn.Name)
+				})
+				client.ListCalls(func(context context.Context, options *crc.ListOptions, object runtime.Object) error {
+					switch object := object.(type) {
+					case *v1beta2.StatefulSetList:
+						list := v1beta2.StatefulSetList{
+							Items: []v1beta2.StatefulSet{
+								*statefulSetV1,
+								*statefulSetV2,
+							},
+						}
+						list.DeepCopyInto(object)
+					case *corev1.PodList:
+						list := corev1.PodList{
+							Items: []corev1.Pod{
+								*podV1,
+								*podV2,
+							},
+						}
+						list.DeepCopyInto(object)
+					}
+					return nil
+				})
+			})
+
+			It("waits for the canaries to become ready", func() {
+				result, err := reconciler.Reconcile(request)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(result.RequeueAfter).To(BeNumerically("~", 20*time.Second, 2*time.Second))
+				Expect(client.UpdateCallCount()).To(Equal(0))
+				Expect(client.DeleteCallCount()).To(Equal(0))
+			})
+
+			It("halts the rollout when the canaries don't become ready in time", func() {
+				statefulSetV2.Annotations[exss.AnnotationUpdateStartTime] = time.Now().Add(-time.Minute).UTC().Format(time.RFC3339)
+
+				result, err := reconciler.Reconcile(request)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(result).To(Equal(reconcile.Result{}))
+				Expect(client.UpdateCallCount()).To(Equal(1))
+				_, object := client.UpdateArgsForCall(0)
+				Expect(object.(*v1beta2.StatefulSet).Annotations).To(HaveKeyWithValue(exss.AnnotationUpdateState, exss.UpdateStateFailed))
+				Expect(client.DeleteCallCount()).To(Equal(0))
+			})
+
+			It("updates the next batch when the canaries are ready", func() {
+				statefulSetV2.Status.ReadyReplicas = 1
+
+				result, err := reconciler.Reconcile(request)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(result).To(Equal(reconcile.Result{RequeueAfter: time.Minute}))
+				Expect(client.UpdateCallCount()).To(Equal(2))
+
+				_, object := client.UpdateArgsForCall(0)
+				statefulSet := object.(*v1beta2.StatefulSet)
+				Expect(statefulSet.Name).To(Equal("foo-v2"))
+				Expect(*statefulSet.Spec.Replicas).To(Equal(int32(3)))
+				Expect(statefulSet.Annotations).To(HaveKeyWithValue(exss.AnnotationUpdateState, exss.UpdateStateUpdating))
+
+				_, object = client.UpdateArgsForCall(1)
+				statefulSet = object.(*v1beta2.StatefulSet)
+				Expect(statefulSet.Name).To(Equal("foo-v1"))
+				Expect(*statefulSet.Spec.Replicas).To(Equal(int32(1)))
+				Expect(client.DeleteCallCount()).To(Equal(0))
+			})
+
+			It("deletes the old version when all replicas are updated", func() {
+				statefulSetV1.Spec.Replicas = util.Int32(0)
+				statefulSetV2.Spec.Replicas = util.Int32(4)
+				statefulSetV2.Status.ReadyReplicas = 4
+				statefulSetV2.Annotations[exss.AnnotationUpdateState] = exss.UpdateStateUpdating
+
+				result, err := reconciler.Reconcile(request)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(result).To(Equal(reconcile.Result{}))
+				Expect(client.UpdateCallCount()).To(Equal(1))
+				_, object := client.UpdateArgsForCall(0)
+				Expect(object.(*v1beta2.StatefulSet).Annotations).To(HaveKeyWithValue(exss.AnnotationUpdateState, exss.UpdateStateDone))
+				Expect(client.DeleteCallCount()).To(Equal(1))
+			})
+		})
 	})
 })
@@ -15,6 +15,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	essv1a1 "code.cloudfoundry.org/cf-operator/pkg/kube/apis/extendedstatefulset/v1alpha1"
+	"code.cloudfoundry.org/cf-operator/pkg/kube/util"
 	"code.cloudfoundry.org/cf-operator/pkg/kube/util/ctxlog"
 	podutil "code.cloudfoundry.org/cf-operator/pkg/kube/util/pod"
 )
@@ -42,7 +43,7 @@ func (r *ReconcileExtendedStatefulSet) alterVolumeManagementStatefulSet(ctx cont
 
 // getReplicaDifference calculates the difference between replica count
 func (r *ReconcileExtendedStatefulSet) getReplicaDifference(exStatefulSet *essv1a1.ExtendedStatefulSet, statefulSet *v1beta2.StatefulSet) int {
-	return int(*exStatefulSet.Spec.Template.Spec.Replicas) - int(*statefulSet.Spec.Replicas)
+	return int(zoneReplicas(exStatefulSet, zoneIndex(statefulSet))) - int(*statefulSet.Spec.Replicas)
 }
 
 // createVolumeManagementStatefulSet creates a volumeManagement statefulSet
@@ -150,6 +151,11 @@ func (r *ReconcileExtendedStatefulSet) generateVolumeManagementSingleStatefulSet
 		statefulSet = r.updateAffinity(statefulSet, exStatefulSet.Spec.ZoneNodeLabel, zoneIndex, zoneName)
 	}
 
+	if zoneIndex < 0 {
+		zoneIndex = 0
+	}
+	statefulSet.Spec.Replicas = util.Int32(zoneReplicas(exStatefulSet, zoneIndex))
+
 	// Set updated properties
 	statefulSet.SetName(fmt.Sprintf("%s-%s", "volume-management", statefulSetNamePrefix))
 	statefulSet.SetLabels(labels)
@@ -12,5 +12,9 @@ type Config struct {
 	Namespace         string
 	WebhookServerHost string
 	WebhookServerPort int32
-	Fs                afero.Fs
+	// ClusterDomain is the DNS domain of the cluster, used for the addresses of instances
+	ClusterDomain string
+	// RegistryMirror replaces the registry of all release images
+	RegistryMirror string
+	Fs             afero.Fs
 }
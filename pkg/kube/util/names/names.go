@@ -28,6 +28,8 @@ const (
 	DeploymentSecretTypeInstanceGroupResolvedProperties
 	// DeploymentSecretBpmInformation is a YAML file containing the BPM information for one instance group
 	DeploymentSecretBpmInformation
+	// DeploymentSecretTypeLinks is a YAML file containing the links a deployment shares with other deployments
+	DeploymentSecretTypeLinks
 )
 
 func (s DeploymentSecretType) String() string {
@@ -36,7 +38,8 @@ func (s DeploymentSecretType) String() string {
 		"with-vars",
 		"var",
 		"ig-resolved",
-		"bpm"}[s]
+		"bpm",
+		"links"}[s]
 }
 
 // DesiredManifestPrefix returns the prefix of the desired manifest's name:
@@ -55,6 +58,17 @@ func DesiredManifestName(deploymentName string, version string) string {
 	return finalName
 }
 
+// LinksSecretName returns the versioned name of the secret with the links a
+// deployment shares, e.g. 'test.links-v1'
+func LinksSecretName(deploymentName string, version string) string {
+	finalName := DesiredManifestPrefix(deploymentName) + DeploymentSecretTypeLinks.String()
+	if version != "" {
+		finalName = fmt.Sprintf("%s-v%s", finalName, version)
+	}
+
+	return finalName
+}
+
 // CalculateSecretName generates a Secret name for a given name and a deployment
 func CalculateSecretName(secretType DeploymentSecretType, deploymentName, name string) string {
 	if name == "" {
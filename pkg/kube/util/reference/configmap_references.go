@@ -38,6 +38,12 @@ func getConfMapRefFromBdpl(object bdv1.BOSHDeployment) map[string]bool {
 		}
 	}
 
+	for _, v := range object.Spec.Vars {
+		if v.Type == bdv1.ConfigMapType {
+			result[v.Ref] = true
+		}
+	}
+
 	return result
 }
 
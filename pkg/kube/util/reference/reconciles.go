@@ -6,6 +6,7 @@ import (
 
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	crc "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
@@ -40,7 +41,8 @@ func (r ReconcileType) String() string {
 }
 
 // GetReconciles returns reconciliation requests for the BOSHDeployments, ExtendedJobs or ExtendedStatefulSets
-// that reference an object. The object can be a ConfigMap or a Secret
+// that reference an object. The object can be a ConfigMap or a Secret.
+// Paused BOSHDeployments and the resources they own are skipped, they catch up when resumed.
 func GetReconciles(ctx context.Context, client crc.Client, reconcileType ReconcileType, object apis.Object) ([]reconcile.Request, error) {
 	objReferencedBy := func(parent interface{}) (bool, error) {
 		var (
@@ -93,6 +95,9 @@ func GetReconciles(ctx context.Context, client crc.Client, reconcileType Reconci
 		}
 
 		for _, boshDeployment := range boshDeployments.Items {
+			if boshDeployment.Spec.Paused {
+				continue
+			}
 			isRef, err := objReferencedBy(boshDeployment)
 			if err != nil {
 				return nil, err
@@ -112,10 +117,19 @@ func GetReconciles(ctx context.Context, client crc.Client, reconcileType Reconci
 			return nil, errors.Wrap(err, "failed to list ExtendedJobs for ConfigMap reconciles")
 		}
 
+		paused, err := pausedBOSHDeployments(ctx, client, namespace)
+		if err != nil {
+			return nil, err
+		}
+
 		for _, eJob := range extendedJobs.Items {
 			if !(eJob.Spec.UpdateOnConfigChange && eJob.IsAutoErrand()) {
 				continue
 			}
+			if ownedByPaused(paused, &eJob) {
+				log.Debugf(ctx, "Skip reconcile for ExtendedJob '%s': BOSHDeployment is paused", eJob.Name)
+				continue
+			}
 			isRef, err := objReferencedBy(eJob)
 			if err != nil {
 				return nil, err
@@ -135,7 +149,16 @@ func GetReconciles(ctx context.Context, client crc.Client, reconcileType Reconci
 			return nil, errors.Wrap(err, "failed to list ExtendedStatefulSets for ConfigMap reconciles")
 		}
 
+		paused, err := pausedBOSHDeployments(ctx, client, namespace)
+		if err != nil {
+			return nil, err
+		}
+
 		for _, extendedStatefulSet := range extendedStatefulSets.Items {
+			if ownedByPaused(paused, &extendedStatefulSet) {
+				log.Debugf(ctx, "Skip reconcile for ExtendedStatefulSet '%s': BOSHDeployment is paused", extendedStatefulSet.Name)
+				continue
+			}
 			isRef, err := objReferencedBy(extendedStatefulSet)
 			if err != nil {
 				return nil, err
@@ -191,6 +214,33 @@ func SkipReconciles(ctx context.Context, client crc.Client, object apis.Object)
 	return false
 }
 
+// pausedBOSHDeployments returns the namespaced names of all paused BOSHDeployments
+func pausedBOSHDeployments(ctx context.Context, client crc.Client, namespace string) (map[types.NamespacedName]bool, error) {
+	boshDeployments, err := listBOSHDeployments(ctx, client, namespace)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list BOSHDeployments for paused deployments")
+	}
+
+	paused := map[types.NamespacedName]bool{}
+	for _, boshDeployment := range boshDeployments.Items {
+		if boshDeployment.Spec.Paused {
+			paused[types.NamespacedName{Namespace: boshDeployment.Namespace, Name: boshDeployment.Name}] = true
+		}
+	}
+
+	return paused, nil
+}
+
+// ownedByPaused checks if the object is controlled by one of the paused BOSHDeployments
+func ownedByPaused(paused map[types.NamespacedName]bool, object metav1.Object) bool {
+	owner := metav1.GetControllerOf(object)
+	if owner == nil || owner.Kind != "BOSHDeployment" {
+		return false
+	}
+
+	return paused[types.NamespacedName{Namespace: object.GetNamespace(), Name: owner.Name}]
+}
+
 func listBOSHDeployments(ctx context.Context, client crc.Client, namespace string) (*bdv1.BOSHDeploymentList, error) {
 	log.Debugf(ctx, "Listing BOSHDeployments in namespace '%s'", namespace)
 	result := &bdv1.BOSHDeploymentList{}
@@ -31,11 +31,23 @@ func getSecretRefFromBdpl(object bdv1.BOSHDeployment) map[string]bool {
 	if object.Spec.Manifest.Type == bdv1.SecretType {
 		result[object.Spec.Manifest.Ref] = true
 	}
+	if object.Spec.Manifest.SecretRef != "" {
+		result[object.Spec.Manifest.SecretRef] = true
+	}
 
 	for _, ops := range object.Spec.Ops {
 		if ops.Type == bdv1.SecretType {
 			result[ops.Ref] = true
 		}
+		if ops.SecretRef != "" {
+			result[ops.SecretRef] = true
+		}
+	}
+
+	for _, v := range object.Spec.Vars {
+		if v.Type == bdv1.SecretType {
+			result[v.Ref] = true
+		}
 	}
 
 	return result
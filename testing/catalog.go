@@ -219,6 +219,20 @@ func (c *Catalog) InterpolateOpsConfigMap(name string) corev1.ConfigMap {
 	}
 }
 
+// InvalidPropertiesOpsConfigMap for ops, which set a job property to another kind than its default
+func (c *Catalog) InvalidPropertiesOpsConfigMap(name string) corev1.ConfigMap {
+	return corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Data: map[string]string{
+			"ops": `- type: replace
+  path: /instance_groups/name=nats/jobs/name=nats/properties/nats/debug
+  value:
+    enabled: true
+`,
+		},
+	}
+}
+
 // BOSHManifestConfigMapWithTwoInstanceGroups for tests
 func (c *Catalog) BOSHManifestConfigMapWithTwoInstanceGroups(name string) corev1.ConfigMap {
 	return corev1.ConfigMap{